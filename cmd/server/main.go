@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -13,13 +16,26 @@ import (
 	"github.com/nedpals/supabase-go"
 
 	"nory/common/auth"
+	"nory/common/database"
 	"nory/common/healthcheck"
 	"nory/common/middleware"
+	"nory/common/ratelimit"
 	"nory/common/response"
+	"nory/common/signedurl"
+	"nory/domain"
+	"nory/internal/blob"
+	"nory/internal/calendar"
 	"nory/internal/class"
+	classannouncement "nory/internal/class_announcement"
+	classattachment "nory/internal/class_attachment"
+	classaudit "nory/internal/class_audit"
+	classevent "nory/internal/class_event"
+	classinvite "nory/internal/class_invite"
+	classjoinrequest "nory/internal/class_join_request"
 	"nory/internal/class_member"
 	classschedule "nory/internal/class_schedule"
 	"nory/internal/class_task"
+	taskcomment "nory/internal/task_comment"
 	"nory/internal/user"
 )
 
@@ -37,11 +53,70 @@ func main() {
 		panic(err)
 	}
 
+	supabaseUrl := mustGetEnv("SUPABASE_URL")
 	supa := supabase.CreateClient(
-		mustGetEnv("SUPABASE_URL"),
+		supabaseUrl,
 		mustGetEnv("SUPABASE_KEY"),
 	)
 
+	jwtSecret := getEnv("SUPABASE_JWT_SECRET", "")
+	jwksUrl := getEnv("SUPABASE_JWKS_URL", "")
+	defaultAuthMode := string(auth.ModeRemote)
+	if jwtSecret != "" || jwksUrl != "" {
+		defaultAuthMode = string(auth.ModeLocal)
+	}
+	authMode := auth.Mode(getEnv("AUTH_MODE", defaultAuthMode))
+	authCacheTTL, err := time.ParseDuration(getEnv("AUTH_CACHE_TTL", "30s"))
+	if err != nil {
+		panic(err)
+	}
+
+	blobStore, err := blob.NewBlobStoreFs(getEnv("BLOB_DIR", "data/blob"))
+	if err != nil {
+		panic(err)
+	}
+	// without SIGNED_URL_SECRET download urls are invalidated on restart
+	signedUrlSecret := []byte(getEnv("SIGNED_URL_SECRET", ""))
+	if len(signedUrlSecret) == 0 {
+		signedUrlSecret = make([]byte, 32)
+		if _, err := rand.Read(signedUrlSecret); err != nil {
+			panic(err)
+		}
+	}
+	signedUrlTTL, err := time.ParseDuration(getEnv("SIGNED_URL_TTL", "15m"))
+	if err != nil {
+		panic(err)
+	}
+
+	readLimit, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_READ", "300/1m"))
+	if err != nil {
+		panic(err)
+	}
+	writeLimit, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_WRITE", "60/1m"))
+	if err != nil {
+		panic(err)
+	}
+	// progress updates are cheap and frequent, they do not share the write limit
+	lightLimit, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_LIGHT", "300/1m"))
+	if err != nil {
+		panic(err)
+	}
+	// per IP, keep it loose because a whole school may share one address
+	authLimit, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_AUTH", "1200/1m"))
+	if err != nil {
+		panic(err)
+	}
+	// memory store is per instance, use postgres when running multiple instances
+	var rateLimitStore ratelimit.Store
+	switch store := getEnv("RATE_LIMIT_STORE", "memory"); store {
+	case "memory":
+		rateLimitStore = ratelimit.NewStoreMem()
+	case "postgres":
+		rateLimitStore = ratelimit.NewStorePostgres(pool)
+	default:
+		panic(fmt.Sprintf("unknown RATE_LIMIT_STORE %q", store))
+	}
+
 	health := healthcheck.HealthCheck{
 		Pool: pool,
 	}
@@ -51,31 +126,87 @@ func main() {
 	classTaskRepository := classtask.NewClassTaskRepositoryPostgres(pool)
 	classMemberRepository := classmember.NewClassMemberRepositoryPostgres(pool)
 	classScheduleRepository := classschedule.NewClassScheduleRepositoryPg(pool)
+	classInviteRepository := classinvite.NewClassInviteRepositoryPostgres(pool)
+	classAuditRepository := classaudit.NewClassAuditRepositoryPostgres(pool)
+	classAttachmentRepository := classattachment.NewClassAttachmentRepositoryPostgres(pool)
+	taskCommentRepository := taskcomment.NewTaskCommentRepositoryPostgres(pool)
+	classAnnouncementRepository := classannouncement.NewClassAnnouncementRepositoryPostgres(pool)
+	classJoinRequestRepository := classjoinrequest.NewClassJoinRequestRepositoryPostgres(pool)
+	classEventBus := classevent.NewClassEventBusMem(256)
+	txRunner := database.NewTxRunnerPostgres(pool)
+	calendarTokenRepository := calendar.NewCalendarTokenRepositoryPostgres(pool)
 
+	authMiddleware := auth.Auth{
+		SupabaseAuth:   supa.Auth,
+		UserRepository: userRepository,
+		Mode:           authMode,
+		CacheTTL:       authCacheTTL,
+		Verifier: &auth.TokenVerifier{
+			Secret:   []byte(jwtSecret),
+			JWKSURL:  jwksUrl,
+			Audience: getEnv("SUPABASE_JWT_AUDIENCE", "authenticated"),
+			Issuer:   getEnv("SUPABASE_JWT_ISSUER", strings.TrimSuffix(supabaseUrl, "/")+"/auth/v1"),
+			Leeway:   30 * time.Second,
+		},
+	}
 	userRoute := user.Route(user.UserService{
-		UserRepository:        userRepository,
-		ClassRepository:       classRepository,
-		ClassMemberRepository: classMemberRepository,
+		UserRepository:          userRepository,
+		ClassRepository:         classRepository,
+		ClassMemberRepository:   classMemberRepository,
+		ClassTaskRepository:     classTaskRepository,
+		ClassScheduleRepository: classScheduleRepository,
+		UserCache:               &authMiddleware,
 	})
 	classRoute := class.Route(class.ClassService{
-		UserRepository:          userRepository,
+		UserRepository:            userRepository,
+		ClassRepository:           classRepository,
+		ClassTaskRepository:       classTaskRepository,
+		ClassMemberRepository:     classMemberRepository,
+		ClassScheduleRepository:   classScheduleRepository,
+		ClassInviteRepository:     classInviteRepository,
+		ClassAuditRepository:      classAuditRepository,
+		ClassEventBus:             classEventBus,
+		TxRunner:                  txRunner,
+		ClassAttachmentRepository: classAttachmentRepository,
+		BlobStore:                 blobStore,
+		URLSigner: &signedurl.Signer{
+			Secret:  signedUrlSecret,
+			BaseURL: getEnv("PUBLIC_URL", "") + "/class",
+			TTL:     signedUrlTTL,
+		},
+		TaskCommentRepository:       taskCommentRepository,
+		ClassAnnouncementRepository: classAnnouncementRepository,
+		ClassJoinRequestRepository:  classJoinRequestRepository,
+	})
+	calendarRoute := calendar.Route(calendar.CalendarService{
 		ClassRepository:         classRepository,
 		ClassTaskRepository:     classTaskRepository,
 		ClassMemberRepository:   classMemberRepository,
 		ClassScheduleRepository: classScheduleRepository,
+		CalendarTokenRepository: calendarTokenRepository,
 	})
-	authMiddleware := auth.Auth{
-		SupabaseAuth:   supa.Auth,
-		UserRepository: userRepository,
+	rateLimiter := ratelimit.Limiter{
+		Store: rateLimitStore,
+		Read:  readLimit,
+		Write: writeLimit,
+		Light: lightLimit,
+		IsLight: func(c *fiber.Ctx) bool {
+			return c.Method() == fiber.MethodPut && strings.HasSuffix(c.Path(), "/progress")
+		},
+		Auth: authLimit,
 	}
 
 	app := fiber.New(fiber.Config{
 		EnablePrintRoutes: dev,
 		Immutable:         dev,
+		// leave room for multipart overhead of the largest attachment
+		BodyLimit: domain.MaxAttachmentSize + 1<<20,
+		// client IP used by rate limiter, set it when running behind a proxy
+		ProxyHeader: getEnv("PROXY_HEADER", ""),
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			fiberErr, ok := err.(*fiber.Error)
 			if ok {
-				err = response.NewError(fiberErr.Code, fiberErr.Message)
+				err = response.NewError(fiberErr.Code, "request.failed", response.Params{"message": fiberErr.Message})
 			}
 
 			err = response.ErrorHandler(c, err)
@@ -95,10 +226,13 @@ func main() {
 		MaxAge:       86400,
 	}))
 	app.Use(logger.New())
+	app.Use(rateLimiter.AuthMiddleware)
 	app.Use(authMiddleware.Middleware)
+	app.Use(rateLimiter.Middleware)
 	app.Use(middleware.DefaultHeader)
 	app.Route("/user", userRoute, "user")
 	app.Route("/class", classRoute, "class")
+	app.Route("/calendar", calendarRoute, "calendar")
 	app.Route("/health", health.Route, "health")
 
 	if err := app.Listen(addr); err != nil {
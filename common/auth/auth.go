@@ -3,6 +3,8 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/nedpals/supabase-go"
@@ -12,13 +14,28 @@ import (
 	"nory/domain"
 )
 
-var ErrUserNotFound error = response.NewUnathorized("authentication required")
+var ErrUserNotFound error = response.NewUnathorized("auth.required", nil)
 
 const userLocalKey = "authenticated user locals key"
 
+type Mode string
+
+const (
+	// ModeRemote ask supabase for every request, this is the default mode
+	ModeRemote Mode = "remote"
+	// ModeLocal verify token using Verifier without network round-trip
+	ModeLocal Mode = "local"
+)
+
 type Auth struct {
 	SupabaseAuth   *supabase.Auth
 	UserRepository domain.UserRepository
+	Mode           Mode
+	Verifier       *TokenVerifier
+	// CacheTTL is how long resolved user is cached, zero disable the cache
+	CacheTTL time.Duration
+
+	cache userCache
 }
 
 func (a *Auth) Middleware(c *fiber.Ctx) error {
@@ -39,19 +56,25 @@ func (a *Auth) Middleware(c *fiber.Ctx) error {
 
 func (a *Auth) UserFromBearer(ctx context.Context, bearer string) (*domain.User, error) {
 	token := string(bearer[7:])
-	user, err := a.SupabaseAuth.User(ctx, token)
+	userId, email, err := a.identify(ctx, token)
 	if err != nil {
 		return nil, err
 	}
 
-	u, err := a.UserRepository.GetUserByUserId(ctx, user.ID)
+	if a.CacheTTL > 0 {
+		if u, ok := a.cache.get(userId); ok {
+			return u, nil
+		}
+	}
+
+	u, err := a.UserRepository.GetUserByUserId(ctx, userId)
 	if errors.Is(err, domain.ErrUserNotExists) {
 		id := xid.New().String()
 		u = &domain.User{
-			UserId:   user.ID,
+			UserId:   userId,
 			Username: id,
 			Name:     id,
-			Email:    user.Email,
+			Email:    email,
 		}
 		if err := a.UserRepository.CreateUser(ctx, u); err != nil {
 			return nil, err
@@ -60,5 +83,37 @@ func (a *Auth) UserFromBearer(ctx context.Context, bearer string) (*domain.User,
 		return nil, err
 	}
 
+	if a.CacheTTL > 0 {
+		a.cache.set(u, a.CacheTTL)
+	}
+
 	return u, nil
 }
+
+// Invalidate drop cached user, so the next request resolve it from UserRepository again
+func (a *Auth) Invalidate(userId string) {
+	a.cache.delete(userId)
+}
+
+// identify return user id and email of the token owner
+func (a *Auth) identify(ctx context.Context, token string) (string, string, error) {
+	switch a.Mode {
+	case ModeLocal:
+		if a.Verifier == nil {
+			return "", "", errors.New("auth: local mode require Verifier")
+		}
+		claims, err := a.Verifier.Verify(ctx, token)
+		if err != nil {
+			return "", "", err
+		}
+		return claims.Subject, claims.Email, nil
+	case ModeRemote, "":
+		user, err := a.SupabaseAuth.User(ctx, token)
+		if err != nil {
+			return "", "", err
+		}
+		return user.ID, user.Email, nil
+	default:
+		return "", "", fmt.Errorf("auth: unknown mode %q", a.Mode)
+	}
+}
@@ -0,0 +1,357 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"nory/common/response"
+)
+
+var (
+	ErrInvalidToken error = response.NewUnathorized("auth.invalid_token", nil)
+	// ErrJWKSUnavailable is returned when the key of a token is unknown because JWKS can not be fetched
+	ErrJWKSUnavailable error = response.NewError(503, "auth.jwks_unavailable", nil)
+)
+
+// jwksTimeout bound a JWKS fetch, the fetch is shared so it does not use the context of a request
+const jwksTimeout = 10 * time.Second
+
+// TokenVerifier verify supabase access token locally, HS256 token are verified using Secret,
+// RS256 and ES256 token are verified using keys from JWKSURL.
+type TokenVerifier struct {
+	Secret   []byte
+	JWKSURL  string
+	Audience string
+	Issuer   string
+	// Leeway tolerate clock skew when checking exp and nbf
+	Leeway time.Duration
+	// JWKSRefresh is the minimum interval between JWKS fetch, default to 5 minutes
+	JWKSRefresh time.Duration
+	HTTPClient  *http.Client
+
+	mx        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// failures is the number of fetch failed in a row, fetch is retried after a backoff
+	failures int
+	// fetching is closed once the fetch in progress is done, nil when nothing is fetched
+	fetching chan struct{}
+}
+
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Email     string   `json:"email"`
+	Role      string   `json:"role"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	IssuedAt  int64    `json:"iat"`
+}
+
+// audience can be encoded as string or array of string
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+func (a audience) contains(aud string) bool {
+	for _, s := range a {
+		if s == aud {
+			return true
+		}
+	}
+	return false
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Verify check token signature, expiry, audience and issuer, any failure is reported as ErrInvalidToken
+func (tv *TokenVerifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := tv.verifySignature(ctx, header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := tv.verifyClaims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (tv *TokenVerifier) verifySignature(ctx context.Context, header jwtHeader, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch header.Alg {
+	case "HS256":
+		if len(tv.Secret) == 0 {
+			return ErrInvalidToken
+		}
+		mac := hmac.New(sha256.New, tv.Secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrInvalidToken
+		}
+	case "RS256":
+		key, err := tv.getKey(ctx, header.Kid)
+		if err != nil {
+			return err
+		}
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) != nil {
+			return ErrInvalidToken
+		}
+	case "ES256":
+		key, err := tv.getKey(ctx, header.Kid)
+		if err != nil {
+			return err
+		}
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return ErrInvalidToken
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrInvalidToken
+		}
+	default:
+		// including "none"
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (tv *TokenVerifier) verifyClaims(claims *Claims) error {
+	now := time.Now()
+	if claims.Subject == "" {
+		return ErrInvalidToken
+	}
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(tv.Leeway)) {
+		return ErrInvalidToken
+	}
+	if claims.NotBefore != 0 && now.Add(tv.Leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return ErrInvalidToken
+	}
+	if tv.Audience != "" && !claims.Audience.contains(tv.Audience) {
+		return ErrInvalidToken
+	}
+	if tv.Issuer != "" && claims.Issuer != tv.Issuer {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// getKey find public key with given kid, JWKS is fetched again when the kid is unknown.
+// Only one fetch run at a time, concurrent callers wait for it instead of fetching again.
+func (tv *TokenVerifier) getKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if tv.JWKSURL == "" {
+		return nil, ErrInvalidToken
+	}
+
+	for {
+		tv.mx.Lock()
+		if key, ok := tv.keys[kid]; ok {
+			tv.mx.Unlock()
+			return key, nil
+		}
+		if fetching := tv.fetching; fetching != nil {
+			tv.mx.Unlock()
+			select {
+			case <-fetching:
+				continue
+			case <-ctx.Done():
+				return nil, ErrJWKSUnavailable
+			}
+		}
+		if wait := tv.nextFetch(); time.Since(tv.fetchedAt) < wait {
+			failed := tv.failures > 0
+			tv.mx.Unlock()
+			if failed {
+				return nil, ErrJWKSUnavailable
+			}
+			return nil, ErrInvalidToken
+		}
+		fetching := make(chan struct{})
+		tv.fetching = fetching
+		tv.mx.Unlock()
+
+		fetchCtx, cancel := context.WithTimeout(context.Background(), jwksTimeout)
+		keys, err := tv.fetchKeys(fetchCtx)
+		cancel()
+
+		tv.mx.Lock()
+		// failed fetch is recorded too, otherwise every request would fetch again during an outage
+		tv.fetchedAt = time.Now()
+		if err != nil {
+			tv.failures++
+		} else {
+			tv.keys = keys
+			tv.failures = 0
+		}
+		tv.fetching = nil
+		close(fetching)
+		tv.mx.Unlock()
+	}
+}
+
+// nextFetch is the minimum interval since the last fetch before JWKS is fetched again,
+// failed fetch is retried after a backoff that double up to JWKSRefresh. It must be
+// called while holding tv.mx.
+func (tv *TokenVerifier) nextFetch() time.Duration {
+	refresh := tv.JWKSRefresh
+	if refresh == 0 {
+		refresh = 5 * time.Minute
+	}
+	if tv.fetchedAt.IsZero() {
+		return 0
+	}
+	if tv.failures == 0 {
+		return refresh
+	}
+	backoff := time.Second
+	for i := 1; i < tv.failures && backoff < refresh; i++ {
+		backoff *= 2
+	}
+	if backoff > refresh {
+		backoff = refresh
+	}
+	return backoff
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (tv *TokenVerifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	client := tv.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tv.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(body.Keys))
+	for _, k := range body.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			// skip unsupported keys
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, errors.New("unsupported curve")
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,274 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	. "nory/common/auth"
+	"nory/common/response"
+	"nory/domain"
+	"nory/internal/user"
+)
+
+var testSecret = []byte("super-secret-jwt-token")
+
+func encodeSegment(v any) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func signToken(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	signed := encodeSegment(header) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256":
+		s, err := rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+		assert.Nil(t, err)
+		sig = s
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		assert.Nil(t, err)
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims(sub string) map[string]any {
+	return map[string]any{
+		"sub":   sub,
+		"email": "foo@example.com",
+		"aud":   "authenticated",
+		"iss":   "https://example.supabase.co/auth/v1",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+	}
+}
+
+func withClaim(claims map[string]any, key string, value any) map[string]any {
+	claims[key] = value
+	return claims
+}
+
+func TestTokenVerifierHS256(t *testing.T) {
+	t.Parallel()
+	tv := &TokenVerifier{
+		Secret:   testSecret,
+		Audience: "authenticated",
+		Issuer:   "https://example.supabase.co/auth/v1",
+	}
+	sub := uuid.NewString()
+
+	testCases := []struct {
+		Name  string
+		Token string
+		Err   error
+	}{
+		{"valid", signToken(t, "HS256", "", testSecret, validClaims(sub)), nil},
+		{"audience array", signToken(t, "HS256", "", testSecret, withClaim(validClaims(sub), "aud", []string{"foo", "authenticated"})), nil},
+		{"expired", signToken(t, "HS256", "", testSecret, withClaim(validClaims(sub), "exp", time.Now().Add(-time.Minute).Unix())), ErrInvalidToken},
+		{"not yet valid", signToken(t, "HS256", "", testSecret, withClaim(validClaims(sub), "nbf", time.Now().Add(time.Hour).Unix())), ErrInvalidToken},
+		{"wrong audience", signToken(t, "HS256", "", testSecret, withClaim(validClaims(sub), "aud", "anon")), ErrInvalidToken},
+		{"wrong issuer", signToken(t, "HS256", "", testSecret, withClaim(validClaims(sub), "iss", "https://evil.example.com")), ErrInvalidToken},
+		{"missing subject", signToken(t, "HS256", "", testSecret, withClaim(validClaims(sub), "sub", "")), ErrInvalidToken},
+		{"wrong secret", signToken(t, "HS256", "", []byte("foo"), validClaims(sub)), ErrInvalidToken},
+		{"alg none", encodeSegment(map[string]any{"alg": "none"}) + "." + encodeSegment(validClaims(sub)) + ".", ErrInvalidToken},
+		{"malformed", "foo.bar", ErrInvalidToken},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			claims, err := tv.Verify(context.Background(), tc.Token)
+			assert.Equal(t, tc.Err, err)
+			if err == nil {
+				assert.Equal(t, sub, claims.Subject)
+				assert.Equal(t, "foo@example.com", claims.Email)
+			}
+		})
+	}
+}
+
+func TestTokenVerifierJWKS(t *testing.T) {
+	t.Parallel()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	fetched := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "rsa",
+					"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+				},
+				{
+					"kty": "EC",
+					"kid": "ec",
+					"crv": "P-256",
+					"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()),
+					"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes()),
+				},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	tv := &TokenVerifier{
+		JWKSURL:  srv.URL,
+		Audience: "authenticated",
+	}
+	sub := uuid.NewString()
+
+	testCases := []struct {
+		Name  string
+		Token string
+		Err   error
+	}{
+		{"rs256", signToken(t, "RS256", "rsa", rsaKey, validClaims(sub)), nil},
+		{"es256", signToken(t, "ES256", "ec", ecKey, validClaims(sub)), nil},
+		{"unknown kid", signToken(t, "RS256", "foo", rsaKey, validClaims(sub)), ErrInvalidToken},
+		{"wrong key", signToken(t, "RS256", "rsa", otherKey, validClaims(sub)), ErrInvalidToken},
+		{"key type missmatch", signToken(t, "ES256", "rsa", ecKey, validClaims(sub)), ErrInvalidToken},
+		{"hs256 without secret", signToken(t, "HS256", "", testSecret, validClaims(sub)), ErrInvalidToken},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			claims, err := tv.Verify(context.Background(), tc.Token)
+			assert.Equal(t, tc.Err, err)
+			if err == nil {
+				assert.Equal(t, sub, claims.Subject)
+			}
+		})
+	}
+	assert.Equal(t, 1, fetched, "JWKS should be cached between verification")
+}
+
+func TestTokenVerifierJWKSUnavailable(t *testing.T) {
+	t.Parallel()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	var fetched int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetched, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	tv := &TokenVerifier{
+		JWKSURL:  srv.URL,
+		Audience: "authenticated",
+	}
+	token := signToken(t, "RS256", "rsa", rsaKey, validClaims(uuid.NewString()))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = tv.Verify(context.Background(), token)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.Equal(t, ErrJWKSUnavailable, err)
+	}
+	var resErr *response.ResponseError
+	if assert.ErrorAs(t, ErrJWKSUnavailable, &resErr) {
+		assert.Equal(t, 503, resErr.Code)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched), "concurrent verification should share a single fetch")
+
+	_, err = tv.Verify(context.Background(), token)
+	assert.Equal(t, ErrJWKSUnavailable, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched), "failed fetch should not be retried before the backoff")
+}
+
+func TestAuthLocalMode(t *testing.T) {
+	t.Parallel()
+	userRepository := user.NewUserRepositoryMem()
+	a := &Auth{
+		UserRepository: userRepository,
+		Mode:           ModeLocal,
+		CacheTTL:       time.Minute,
+		Verifier: &TokenVerifier{
+			Secret:   testSecret,
+			Audience: "authenticated",
+		},
+	}
+
+	sub := uuid.NewString()
+	bearer := "Bearer " + signToken(t, "HS256", "", testSecret, validClaims(sub))
+
+	u, err := a.UserFromBearer(context.Background(), bearer)
+	assert.Nil(t, err)
+	assert.Equal(t, sub, u.UserId)
+	assert.Equal(t, "foo@example.com", u.Email)
+
+	_, err = userRepository.GetUserByUserId(context.Background(), sub)
+	assert.Nil(t, err, "user should be created on first authentication")
+
+	// modifying returned user must not affect the cache
+	u.Name = "modified"
+	err = userRepository.DeleteUser(context.Background(), sub)
+	assert.Nil(t, err)
+	cached, err := a.UserFromBearer(context.Background(), bearer)
+	assert.Nil(t, err)
+	assert.Equal(t, sub, cached.UserId, "user should be served from cache")
+	assert.NotEqual(t, "modified", cached.Name)
+
+	expired := "Bearer " + signToken(t, "HS256", "", testSecret, withClaim(validClaims(sub), "exp", time.Now().Add(-time.Hour).Unix()))
+	_, err = a.UserFromBearer(context.Background(), expired)
+	assert.Equal(t, ErrInvalidToken, err, "cache must not bypass token verification")
+
+	err = userRepository.CreateUser(context.Background(), &domain.User{UserId: sub, Username: uuid.NewString(), Name: "renamed"})
+	assert.Nil(t, err)
+	cached, err = a.UserFromBearer(context.Background(), bearer)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "renamed", cached.Name, "user should be served from cache")
+	a.Invalidate(sub)
+	fresh, err := a.UserFromBearer(context.Background(), bearer)
+	assert.Nil(t, err)
+	assert.Equal(t, "renamed", fresh.Name, "invalidated user should be read again")
+}
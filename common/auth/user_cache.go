@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"nory/domain"
+)
+
+type cachedUser struct {
+	user      domain.User
+	expiresAt time.Time
+}
+
+// userCache keep resolved users for a short time, so authenticated request does not always hit UserRepository
+type userCache struct {
+	mx sync.Mutex
+	m  map[string]cachedUser
+}
+
+// get return copy of the cached user, handlers are free to modify it
+func (uc *userCache) get(userId string) (*domain.User, bool) {
+	uc.mx.Lock()
+	defer uc.mx.Unlock()
+	cached, ok := uc.m[userId]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(cached.expiresAt) {
+		delete(uc.m, userId)
+		return nil, false
+	}
+	u := cached.user
+	return &u, true
+}
+
+func (uc *userCache) delete(userId string) {
+	uc.mx.Lock()
+	defer uc.mx.Unlock()
+	delete(uc.m, userId)
+}
+
+func (uc *userCache) set(user *domain.User, ttl time.Duration) {
+	uc.mx.Lock()
+	defer uc.mx.Unlock()
+	if uc.m == nil {
+		uc.m = make(map[string]cachedUser)
+	}
+	now := time.Now()
+	// drop expired entries, so the cache does not grow forever
+	for id, cached := range uc.m {
+		if now.After(cached.expiresAt) {
+			delete(uc.m, id)
+		}
+	}
+	uc.m[user.UserId] = cachedUser{
+		user:      *user,
+		expiresAt: now.Add(ttl),
+	}
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LikePrefix build LIKE pattern matching text starting with s, use it with ESCAPE '\'
+func LikePrefix(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return s + "%"
+}
+
+// WordPrefix build condition matching text that has a word starting with each term, the pattern
+// of each term is appended to args. Terms must only contain letters and digits, see domain.SearchTerms.
+// Full-text search is not used because CockroachDB 22.1 does not support it.
+func WordPrefix(text string, terms []string, args []any) (string, []any) {
+	if len(terms) == 0 {
+		return "FALSE", args
+	}
+	conds := make([]string, 0, len(terms))
+	for _, term := range terms {
+		args = append(args, `(^|[^[:alnum:]])`+term)
+		conds = append(conds, fmt.Sprintf("%s ~ $%d", text, len(args)))
+	}
+	return "(" + strings.Join(conds, " AND ") + ")", args
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type txKey struct{}
+
+// txState is carried in the context of a running transaction
+type txState struct {
+	mx       sync.Mutex
+	pg       pgx.Tx
+	rollback []func()
+	commit   []func()
+}
+
+func withTx(ctx context.Context, tx *txState) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+func txFromContext(ctx context.Context) (*txState, bool) {
+	tx, ok := ctx.Value(txKey{}).(*txState)
+	return tx, ok
+}
+
+// InTx report whether ctx carry a running transaction
+func InTx(ctx context.Context) bool {
+	_, ok := txFromContext(ctx)
+	return ok
+}
+
+// OnRollback register fn to be run when the transaction of ctx is rolled
+// back, hooks run in reverse order of registration. It is a no-op outside
+// of a transaction.
+func OnRollback(ctx context.Context, fn func()) {
+	tx, ok := txFromContext(ctx)
+	if !ok {
+		return
+	}
+	tx.mx.Lock()
+	defer tx.mx.Unlock()
+	tx.rollback = append(tx.rollback, fn)
+}
+
+// AfterCommit register fn to be run after the transaction of ctx is
+// committed, outside of a transaction fn is run immediately.
+func AfterCommit(ctx context.Context, fn func()) {
+	tx, ok := txFromContext(ctx)
+	if !ok {
+		fn()
+		return
+	}
+	tx.mx.Lock()
+	defer tx.mx.Unlock()
+	tx.commit = append(tx.commit, fn)
+}
+
+func (tx *txState) committed() {
+	tx.mx.Lock()
+	hooks := tx.commit
+	tx.commit, tx.rollback = nil, nil
+	tx.mx.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+func (tx *txState) rolledBack() {
+	tx.mx.Lock()
+	hooks := tx.rollback
+	tx.commit, tx.rollback = nil, nil
+	tx.mx.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// RestoreOnRollback register a hook that put m[key] back to its current
+// value when the transaction of ctx is rolled back. It must be called
+// before m[key] is changed, while mx is held by the caller.
+func RestoreOnRollback[K comparable, V any](ctx context.Context, mx sync.Locker, m map[K]*V, key K) {
+	if !InTx(ctx) {
+		return
+	}
+	prev, ok := m[key]
+	var saved V
+	if ok {
+		saved = *prev
+	}
+	OnRollback(ctx, func() {
+		mx.Lock()
+		defer mx.Unlock()
+		if !ok {
+			delete(m, key)
+			return
+		}
+		*prev = saved
+		m[key] = prev
+	})
+}
@@ -0,0 +1,36 @@
+package database
+
+import "context"
+
+// TxRunnerMem provide atomicity for memory repositories, changes made inside
+// the transaction are undone through OnRollback hooks. It does not isolate
+// concurrent transactions.
+type TxRunnerMem struct{}
+
+func NewTxRunnerMem() *TxRunnerMem {
+	return &TxRunnerMem{}
+}
+
+func (trm *TxRunnerMem) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if InTx(ctx) {
+		return fn(ctx)
+	}
+	tx := &txState{}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rolledBack()
+		}
+	}()
+
+	if err := fn(withTx(ctx, tx)); err != nil {
+		return err
+	}
+	committed = true
+	tx.committed()
+	return nil
+}
+
+func (trm *TxRunnerMem) AfterCommit(ctx context.Context, fn func()) {
+	AfterCommit(ctx, fn)
+}
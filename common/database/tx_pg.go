@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Querier is implemented by both *pgxpool.Pool and pgx.Tx
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Conn return the transaction carried by ctx, or pool when ctx is not part
+// of a postgres transaction.
+func Conn(ctx context.Context, pool *pgxpool.Pool) Querier {
+	if tx, ok := txFromContext(ctx); ok && tx.pg != nil {
+		return tx.pg
+	}
+	return pool
+}
+
+// maxTxRetry is how many times a transaction is retried after a
+// serialization failure, CockroachDB report those more often than postgres.
+const maxTxRetry = 3
+
+type TxRunnerPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewTxRunnerPostgres(pool *pgxpool.Pool) *TxRunnerPostgres {
+	return &TxRunnerPostgres{pool}
+}
+
+// RunInTx run fn inside a postgres transaction, fn may be called again when
+// the transaction fail to serialize.
+func (trp *TxRunnerPostgres) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if InTx(ctx) {
+		return fn(ctx)
+	}
+	for attempt := 0; ; attempt++ {
+		err := trp.run(ctx, fn)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "40001" && attempt < maxTxRetry {
+			continue
+		}
+		return err
+	}
+}
+
+func (trp *TxRunnerPostgres) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	pgTx, err := trp.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	tx := &txState{pg: pgTx}
+	committed := false
+	defer func() {
+		if !committed {
+			pgTx.Rollback(ctx)
+			tx.rolledBack()
+		}
+	}()
+
+	if err := fn(withTx(ctx, tx)); err != nil {
+		return err
+	}
+	if err := pgTx.Commit(ctx); err != nil {
+		return err
+	}
+	committed = true
+	tx.committed()
+	return nil
+}
+
+func (trp *TxRunnerPostgres) AfterCommit(ctx context.Context, fn func()) {
+	AfterCommit(ctx, fn)
+}
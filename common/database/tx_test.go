@@ -0,0 +1,182 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+
+	. "nory/common/database"
+	"nory/domain"
+	"nory/internal/class"
+	classmember "nory/internal/class_member"
+	"nory/internal/user"
+)
+
+var errRollback = errors.New("rollback")
+
+func TestTxRunner(t *testing.T) {
+	t.Parallel()
+	pool, err := pgxpool.New(context.Background(), os.Getenv("DATABASE_URL"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	repos := []Repository{
+		{
+			Name:                  "memory",
+			TxRunner:              NewTxRunnerMem(),
+			UserRepository:        user.NewUserRepositoryMem(),
+			ClassRepository:       class.NewClassRepositoryMem(),
+			ClassMemberRepository: classmember.NewClassMemberRepositoryMem(),
+		},
+		{
+			Skip:                  os.Getenv("DATABASE_URL") == "",
+			Name:                  "postgres",
+			TxRunner:              NewTxRunnerPostgres(pool),
+			UserRepository:        user.NewUserRepositoryPostgres(pool),
+			ClassRepository:       class.NewClassRepositoryPostgres(pool),
+			ClassMemberRepository: classmember.NewClassMemberRepositoryPostgres(pool),
+		},
+	}
+
+	for _, repo := range repos {
+		repo := repo
+		t.Run(repo.Name, func(t *testing.T) {
+			if repo.Skip {
+				t.Skipf("skipping %s", repo.Name)
+			}
+			t.Parallel()
+			t.Run("commit", repo.testCommit)
+			t.Run("rollback", repo.testRollback)
+			t.Run("nested", repo.testNested)
+		})
+	}
+}
+
+type Repository struct {
+	Name                  string
+	Skip                  bool
+	TxRunner              domain.TxRunner
+	UserRepository        domain.UserRepository
+	ClassRepository       domain.ClassRepository
+	ClassMemberRepository domain.ClassMemberRepository
+}
+
+func (r *Repository) createUser(t *testing.T) string {
+	u := &domain.User{
+		UserId:   uuid.NewString(),
+		Email:    xid.New().String(),
+		Username: xid.New().String(),
+	}
+	err := r.UserRepository.CreateUser(context.Background(), u)
+	assert.Nil(t, err)
+	return u.UserId
+}
+
+// createClass create a class with its owner as member
+func (r *Repository) createClass(ctx context.Context, ownerId string) (*domain.Class, error) {
+	c := &domain.Class{
+		OwnerId: ownerId,
+		Name:    xid.New().String(),
+	}
+	if err := r.ClassRepository.CreateClass(ctx, c); err != nil {
+		return nil, err
+	}
+	err := r.ClassMemberRepository.CreateMember(ctx, &domain.ClassMember{
+		ClassId: c.ClassId,
+		UserId:  ownerId,
+		Level:   "owner",
+	})
+	return c, err
+}
+
+func (r *Repository) testCommit(t *testing.T) {
+	ownerId := r.createUser(t)
+	committed := false
+	var c *domain.Class
+	err := r.TxRunner.RunInTx(context.Background(), func(ctx context.Context) error {
+		var err error
+		c, err = r.createClass(ctx, ownerId)
+		r.TxRunner.AfterCommit(ctx, func() {
+			committed = true
+		})
+		assert.False(t, committed, "AfterCommit hook must wait for commit")
+		return err
+	})
+	assert.Nil(t, err)
+	assert.True(t, committed, "AfterCommit hook must run after commit")
+
+	_, err = r.ClassRepository.GetClass(context.Background(), c.ClassId)
+	assert.Nil(t, err)
+	_, err = r.ClassMemberRepository.GetMember(context.Background(), &domain.ClassMember{ClassId: c.ClassId, UserId: ownerId})
+	assert.Nil(t, err)
+
+	ran := false
+	r.TxRunner.AfterCommit(context.Background(), func() {
+		ran = true
+	})
+	assert.True(t, ran, "AfterCommit outside of transaction must run immediately")
+}
+
+func (r *Repository) testRollback(t *testing.T) {
+	ownerId := r.createUser(t)
+	existing, err := r.createClass(context.Background(), ownerId)
+	assert.Nil(t, err)
+
+	committed := false
+	var c *domain.Class
+	err = r.TxRunner.RunInTx(context.Background(), func(ctx context.Context) error {
+		var err error
+		c, err = r.createClass(ctx, ownerId)
+		if err != nil {
+			return err
+		}
+		if err := r.ClassRepository.UpdateClass(ctx, &domain.Class{ClassId: existing.ClassId, Description: "foo"}); err != nil {
+			return err
+		}
+		if err := r.ClassMemberRepository.DeleteMember(ctx, &domain.ClassMember{ClassId: existing.ClassId, UserId: ownerId}); err != nil {
+			return err
+		}
+		r.TxRunner.AfterCommit(ctx, func() {
+			committed = true
+		})
+		return errRollback
+	})
+	assert.Equal(t, errRollback, err)
+	assert.False(t, committed, "AfterCommit hook must be dropped on rollback")
+
+	_, err = r.ClassRepository.GetClass(context.Background(), c.ClassId)
+	assert.Equal(t, domain.ErrClassNotExists, err, "created class must be rolled back")
+	_, err = r.ClassMemberRepository.GetMember(context.Background(), &domain.ClassMember{ClassId: c.ClassId, UserId: ownerId})
+	assert.Equal(t, domain.ErrClassMemberNotExists, err, "created member must be rolled back")
+
+	class, err := r.ClassRepository.GetClass(context.Background(), existing.ClassId)
+	assert.Nil(t, err)
+	assert.Equal(t, "", class.Description, "update must be rolled back")
+	_, err = r.ClassMemberRepository.GetMember(context.Background(), &domain.ClassMember{ClassId: existing.ClassId, UserId: ownerId})
+	assert.Nil(t, err, "deleted member must be restored")
+}
+
+func (r *Repository) testNested(t *testing.T) {
+	ownerId := r.createUser(t)
+	var c *domain.Class
+	err := r.TxRunner.RunInTx(context.Background(), func(ctx context.Context) error {
+		err := r.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+			var err error
+			c, err = r.createClass(ctx, ownerId)
+			return err
+		})
+		assert.Nil(t, err)
+		return errRollback
+	})
+	assert.Equal(t, errRollback, err)
+
+	_, err = r.ClassRepository.GetClass(context.Background(), c.ClassId)
+	assert.Equal(t, domain.ErrClassNotExists, err, "nested transaction must join the outer transaction")
+}
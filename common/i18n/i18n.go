@@ -0,0 +1,116 @@
+// Package i18n translate message codes into the language requested by the client.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLang is used when the client does not accept any bundled language
+const DefaultLang = "en"
+
+// Params fill the {name} placeholders of a message
+type Params map[string]any
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// catalogs map language to message code to message template
+var catalogs = map[string]map[string]string{}
+
+func init() {
+	entries, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		b, err := locales.ReadFile("locales/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		catalog := map[string]string{}
+		if err := json.Unmarshal(b, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog %s: %s", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+}
+
+// Codes list message codes of lang in sorted order
+func Codes(lang string) []string {
+	codes := make([]string, 0, len(catalogs[lang]))
+	for code := range catalogs[lang] {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Has report whether the message code exists in the default catalog
+func Has(code string) bool {
+	_, ok := catalogs[DefaultLang][code]
+	return ok
+}
+
+// Translate render message code in lang, it fall back to DefaultLang when lang
+// does not has the code. ok is false when no catalog has the code.
+func Translate(lang, code string, params Params) (msg string, ok bool) {
+	template, ok := catalogs[lang][code]
+	if !ok {
+		template, ok = catalogs[DefaultLang][code]
+	}
+	if !ok {
+		return "", false
+	}
+	return render(template, params), true
+}
+
+func render(template string, params Params) string {
+	if len(params) == 0 {
+		return template
+	}
+	oldnew := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		oldnew = append(oldnew, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(oldnew...).Replace(template)
+}
+
+// Lang pick the bundled language that best match Accept-Language header,
+// region is ignored so "id-ID" select "id".
+func Lang(acceptLanguage string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	tags := make([]tag, 0)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		lang, param, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(lang)), "-")
+		q := 1.0
+		if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if lang == "" || q <= 0 {
+			continue
+		}
+		tags = append(tags, tag{lang, q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+	for _, t := range tags {
+		if _, ok := catalogs[t.lang]; ok {
+			return t.lang
+		}
+	}
+	return DefaultLang
+}
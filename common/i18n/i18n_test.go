@@ -0,0 +1,72 @@
+package i18n_test
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+
+	. "nory/common/i18n"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLang(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		header string
+		lang   string
+	}{
+		{"", "en"},
+		{"id", "id"},
+		{"id-ID,id;q=0.9,en-US;q=0.8,en;q=0.7", "id"},
+		{"en-US,en;q=0.9,id;q=0.8", "en"},
+		{"fr-FR,fr;q=0.9,id;q=0.5", "id"},
+		{"en;q=0.5,ID;q=0.8", "id"},
+		{"id;q=0,en;q=0.1", "en"},
+		{"ja", "en"},
+		{"*", "en"},
+	} {
+		assert.Equalf(t, tc.lang, Lang(tc.header), "Lang(%q)", tc.header)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	t.Parallel()
+	msg, ok := Translate("en", "class.not_found", Params{"classId": "foo"})
+	assert.True(t, ok)
+	assert.Equal(t, `can not find class with id "foo"`, msg)
+
+	msg, ok = Translate("id", "class.not_found", Params{"classId": "foo"})
+	assert.True(t, ok)
+	assert.Equal(t, `kelas dengan id "foo" tidak ditemukan`, msg)
+
+	msg, ok = Translate("ja", "class.not_found", Params{"classId": "foo"})
+	assert.True(t, ok, "unknown language should fall back to the default language")
+	assert.Equal(t, `can not find class with id "foo"`, msg)
+
+	_, ok = Translate("en", "foo.bar", nil)
+	assert.False(t, ok)
+}
+
+var placeholder = regexp.MustCompile(`\{[a-zA-Z]+\}`)
+
+// TestCatalog make sure every bundled language translate the same codes with the same placeholders
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+	codes := Codes(DefaultLang)
+	assert.NotEmpty(t, codes)
+	for _, lang := range []string{"id"} {
+		assert.Equal(t, codes, Codes(lang), "catalog %q should have the same codes as %q", lang, DefaultLang)
+		for _, code := range codes {
+			want, _ := Translate(DefaultLang, code, nil)
+			got, _ := Translate(lang, code, nil)
+			assert.Equal(t, placeholders(want), placeholders(got), "placeholders of %q in %q", code, lang)
+		}
+	}
+}
+
+func placeholders(msg string) []string {
+	found := placeholder.FindAllString(msg, -1)
+	sort.Strings(found)
+	return found
+}
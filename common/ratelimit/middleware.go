@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"nory/common/auth"
+	"nory/common/response"
+)
+
+// Limiter is a fiber middleware limiting requests per authenticated user, or
+// per client IP for anonymous requests. Middleware must be registered after
+// auth.Auth middleware and AuthMiddleware before it.
+type Limiter struct {
+	Store Store
+	// Read limit safe methods such as GET
+	Read Limit
+	// Write limit other methods, it should be stricter than Read
+	Write Limit
+	// Light limit writes matched by IsLight in a bucket of their own, so frequent cheap
+	// writes such as progress updates do not use up Write. Zero Light leave them in Write.
+	Light   Limit
+	IsLight func(c *fiber.Ctx) bool
+	// Auth limit requests carrying a bearer token per client IP before the token is
+	// verified, so invalid tokens can not flood token verification
+	Auth Limit
+	// Now is used by tests, default to time.Now
+	Now func() time.Time
+}
+
+func (l *Limiter) Middleware(c *fiber.Ctx) error {
+	bucket, limit := "write", l.Write
+	switch {
+	case c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead || c.Method() == fiber.MethodOptions:
+		bucket, limit = "read", l.Read
+	case !l.Light.IsZero() && l.IsLight != nil && l.IsLight(c):
+		bucket, limit = "light", l.Light
+	}
+	return l.take(c, bucket+":"+clientKey(c), limit)
+}
+
+// AuthMiddleware limit requests carrying a bearer token by client IP, it must be
+// registered before auth.Auth middleware
+func (l *Limiter) AuthMiddleware(c *fiber.Ctx) error {
+	if c.Get(fiber.HeaderAuthorization) == "" {
+		return c.Next()
+	}
+	return l.take(c, "auth:ip:"+c.IP(), l.Auth)
+}
+
+// take consume a token from bucket key and continue when it is allowed
+func (l *Limiter) take(c *fiber.Ctx, key string, limit Limit) error {
+	if limit.IsZero() {
+		return c.Next()
+	}
+
+	now := time.Now
+	if l.Now != nil {
+		now = l.Now
+	}
+	res, err := l.Store.Take(c.Context(), key, limit, now())
+	if err != nil {
+		return err
+	}
+
+	c.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Burst, ceilSeconds(limit.Period)))
+	if !res.Allowed {
+		retryAfter := ceilSeconds(res.RetryAfter)
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		return response.NewTooManyRequests("request.rate_limited", response.Params{"retryAfter": retryAfter})
+	}
+	return c.Next()
+}
+
+func clientKey(c *fiber.Ctx) string {
+	if user, err := auth.GetUser(c); err == nil {
+		return "user:" + user.UserId
+	}
+	return "ip:" + c.IP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
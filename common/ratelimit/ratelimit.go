@@ -0,0 +1,107 @@
+// Package ratelimit limit request rate with token buckets, buckets are kept
+// in a Store so they can be shared by multiple instances.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Limit allow Burst requests at once, the bucket is refilled evenly so Burst
+// requests are allowed again after Period. Zero Limit does not limit anything.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit parse limit written as "<burst>/<period>" such as "60/1m",
+// "off" or empty string is zero Limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+	burst, period, found := strings.Cut(s, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("%w %q", ErrInvalidLimit, s)
+	}
+	l := Limit{}
+	var err error
+	if l.Burst, err = strconv.Atoi(burst); err != nil || l.Burst <= 0 {
+		return Limit{}, fmt.Errorf("%w %q", ErrInvalidLimit, s)
+	}
+	if l.Period, err = time.ParseDuration(period); err != nil || l.Period <= 0 {
+		return Limit{}, fmt.Errorf("%w %q", ErrInvalidLimit, s)
+	}
+	return l, nil
+}
+
+func (l Limit) IsZero() bool {
+	return l.Burst <= 0 || l.Period <= 0
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Burst, l.Period)
+}
+
+// rate is tokens refilled per second
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result of taking a token from a bucket
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token, zero when Allowed
+	RetryAfter time.Duration
+}
+
+// Store keep token buckets, Take should be atomic for each key
+type Store interface {
+	// Take consume a token from bucket key, a missing bucket start full
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// bucket is the state kept by Store
+type bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// take refill b up to now and consume a token when there is one
+func (l Limit) take(b *bucket, now time.Time) (*bucket, Result) {
+	rate := l.rate()
+	tokens := float64(l.Burst)
+	if b != nil {
+		elapsed := now.Sub(b.UpdatedAt).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(float64(l.Burst), b.Tokens+elapsed*rate)
+	}
+
+	res := Result{Limit: l.Burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	res.Remaining = int(tokens)
+	res.Reset = seconds((float64(l.Burst) - tokens) / rate)
+	return &bucket{Tokens: tokens, UpdatedAt: now}, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
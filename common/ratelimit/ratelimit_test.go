@@ -0,0 +1,215 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+
+	. "nory/common/ratelimit"
+	"nory/common/response"
+)
+
+func TestParseLimit(t *testing.T) {
+	l, err := ParseLimit("60/1m")
+	assert.Nil(t, err)
+	assert.Equal(t, Limit{Burst: 60, Period: time.Minute}, l)
+
+	l, err = ParseLimit("off")
+	assert.Nil(t, err)
+	assert.True(t, l.IsZero())
+
+	for _, s := range []string{"60", "0/1m", "x/1m", "60/x", "60/-1s"} {
+		_, err = ParseLimit(s)
+		assert.ErrorIs(t, err, ErrInvalidLimit, s)
+	}
+}
+
+type testStore struct {
+	Skip  bool
+	Name  string
+	Store Store
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	pool, err := pgxpool.New(context.Background(), os.Getenv("DATABASE_URL"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	stores := []testStore{
+		{
+			Name:  "memory",
+			Store: NewStoreMem(),
+		},
+		{
+			Skip:  os.Getenv("DATABASE_URL") == "",
+			Name:  "postgres",
+			Store: NewStorePostgres(pool),
+		},
+	}
+
+	for _, store := range stores {
+		store := store
+		t.Run(store.Name, func(t *testing.T) {
+			if store.Skip {
+				t.Skipf("skipping %s", store.Name)
+			}
+			t.Parallel()
+			t.Run("take", store.testTake)
+		})
+	}
+}
+
+func (ts testStore) testTake(t *testing.T) {
+	ctx := context.Background()
+	key := "test:" + xid.New().String()
+	other := "test:" + xid.New().String()
+	limit := Limit{Burst: 3, Period: 3 * time.Second}
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i := 2; i >= 0; i-- {
+		res, err := ts.Store.Take(ctx, key, limit, now)
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, i, res.Remaining)
+		assert.Equal(t, time.Duration(0), res.RetryAfter)
+	}
+
+	res, err := ts.Store.Take(ctx, key, limit, now)
+	assert.Nil(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	// buckets are independent
+	res, err = ts.Store.Take(ctx, other, limit, now)
+	assert.Nil(t, err)
+	assert.True(t, res.Allowed)
+
+	// one token is refilled every second
+	res, err = ts.Store.Take(ctx, key, limit, now.Add(time.Second))
+	assert.Nil(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	res, err = ts.Store.Take(ctx, key, limit, now.Add(time.Second))
+	assert.Nil(t, err)
+	assert.False(t, res.Allowed)
+
+	// refill never exceed the burst
+	res, err = ts.Store.Take(ctx, key, limit, now.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := Limiter{
+		Store: NewStoreMem(),
+		Read:  Limit{Burst: 2, Period: time.Minute},
+		Write: Limit{Burst: 1, Period: time.Minute},
+		Light: Limit{Burst: 3, Period: time.Minute},
+		IsLight: func(c *fiber.Ctx) bool {
+			return c.Path() == "/progress"
+		},
+		Now: func() time.Time { return now },
+	}
+	app := fiber.New(fiber.Config{
+		ErrorHandler: response.ErrorHandler,
+		ProxyHeader:  fiber.HeaderXForwardedFor,
+	})
+	app.Use(limiter.Middleware)
+	app.All("/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	do := func(method, ip string) *http.Response {
+		return doPath(t, app, method, "/", ip)
+	}
+	res := do(fiber.MethodPost, "10.0.0.1")
+	assert.Equal(t, fiber.StatusNoContent, res.StatusCode)
+	assert.Equal(t, "1", res.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "0", res.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", res.Header.Get("RateLimit-Reset"))
+	assert.Equal(t, "1;w=60", res.Header.Get("RateLimit-Policy"))
+
+	res = do(fiber.MethodPost, "10.0.0.1")
+	assert.Equal(t, fiber.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, "60", res.Header.Get(fiber.HeaderRetryAfter))
+
+	// reads have their own bucket
+	res = do(fiber.MethodGet, "10.0.0.1")
+	assert.Equal(t, fiber.StatusNoContent, res.StatusCode)
+	assert.Equal(t, "2", res.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "1", res.Header.Get("RateLimit-Remaining"))
+
+	// other clients are not affected
+	res = do(fiber.MethodPost, "10.0.0.2")
+	assert.Equal(t, fiber.StatusNoContent, res.StatusCode)
+
+	// light writes have their own bucket
+	res = doPath(t, app, fiber.MethodPut, "/progress", "10.0.0.1")
+	assert.Equal(t, fiber.StatusNoContent, res.StatusCode)
+	assert.Equal(t, "3", res.Header.Get("RateLimit-Limit"))
+
+	now = now.Add(time.Minute)
+	res = do(fiber.MethodPost, "10.0.0.1")
+	assert.Equal(t, fiber.StatusNoContent, res.StatusCode)
+}
+
+func TestLimiterAuth(t *testing.T) {
+	limiter := Limiter{
+		Store: NewStoreMem(),
+		Auth:  Limit{Burst: 1, Period: time.Minute},
+	}
+	app := fiber.New(fiber.Config{
+		ErrorHandler: response.ErrorHandler,
+		ProxyHeader:  fiber.HeaderXForwardedFor,
+	})
+	app.Use(limiter.AuthMiddleware)
+	// stand in for auth.Auth rejecting every token
+	app.Use(func(c *fiber.Ctx) error {
+		if c.Get(fiber.HeaderAuthorization) != "" {
+			return response.NewUnathorized("auth.invalid_token", nil)
+		}
+		return c.Next()
+	})
+	app.All("/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	bearer := func(ip string) *http.Response {
+		req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		req.Header.Set(fiber.HeaderXForwardedFor, ip)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer invalid")
+		res, err := app.Test(req)
+		assert.Nil(t, err)
+		return res
+	}
+
+	assert.Equal(t, fiber.StatusUnauthorized, bearer("10.0.0.1").StatusCode)
+	assert.Equal(t, fiber.StatusTooManyRequests, bearer("10.0.0.1").StatusCode, "invalid tokens should be limited before verification")
+	assert.Equal(t, fiber.StatusUnauthorized, bearer("10.0.0.2").StatusCode)
+	res := doPath(t, app, fiber.MethodGet, "/", "10.0.0.1")
+	assert.Equal(t, fiber.StatusNoContent, res.StatusCode, "anonymous request is not limited by Auth")
+}
+
+func doPath(t *testing.T, app *fiber.App, method, path, ip string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(fiber.HeaderXForwardedFor, ip)
+	res, err := app.Test(req)
+	assert.Nil(t, err)
+	return res
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often full buckets are removed from the store,
+// a full bucket is the same as a missing one.
+const sweepInterval = time.Minute
+
+type memBucket struct {
+	bucket
+	fullAt time.Time
+}
+
+type StoreMem struct {
+	mx      sync.Mutex
+	m       map[string]memBucket
+	sweptAt time.Time
+}
+
+func NewStoreMem() *StoreMem {
+	return &StoreMem{
+		m: map[string]memBucket{},
+	}
+}
+
+func (sm *StoreMem) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	sm.mx.Lock()
+	defer sm.mx.Unlock()
+
+	if now.Sub(sm.sweptAt) >= sweepInterval {
+		sm.sweep(now)
+	}
+
+	var b *bucket
+	if mb, ok := sm.m[key]; ok {
+		b = &mb.bucket
+	}
+	nb, res := limit.take(b, now)
+	sm.m[key] = memBucket{*nb, now.Add(res.Reset)}
+	return res, nil
+}
+
+func (sm *StoreMem) sweep(now time.Time) {
+	for k, b := range sm.m {
+		if !b.fullAt.After(now) {
+			delete(sm.m, k)
+		}
+	}
+	sm.sweptAt = now
+}
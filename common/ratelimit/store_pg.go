@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"nory/common/database"
+)
+
+type StorePostgres struct {
+	pool     *pgxpool.Pool
+	txRunner *database.TxRunnerPostgres
+
+	mx      chan struct{}
+	sweptAt time.Time
+}
+
+func NewStorePostgres(pool *pgxpool.Pool) *StorePostgres {
+	return &StorePostgres{
+		pool:     pool,
+		txRunner: database.NewTxRunnerPostgres(pool),
+		mx:       make(chan struct{}, 1),
+	}
+}
+
+func (sp *StorePostgres) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	sp.sweep(ctx, now)
+
+	now = now.UTC().Truncate(time.Microsecond)
+	var res Result
+	err := sp.txRunner.RunInTx(ctx, func(ctx context.Context) error {
+		conn := database.Conn(ctx, sp.pool)
+
+		// a missing bucket start full, inserting it first let FOR UPDATE
+		// lock the row for concurrent requests on a new key
+		_, err := conn.Exec(ctx, `
+			INSERT INTO rate_limit(bucket_key, tokens, updated_at, full_at)
+			VALUES ($1, $2, $3, $3)
+			ON CONFLICT (bucket_key) DO NOTHING
+		`, key, float64(limit.Burst), now)
+		if err != nil {
+			return err
+		}
+
+		b := &bucket{}
+		err = conn.QueryRow(ctx, `
+			SELECT tokens, updated_at
+			FROM rate_limit
+			WHERE bucket_key = $1
+			FOR UPDATE
+		`, key).Scan(&b.Tokens, &b.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		b, res = limit.take(b, now)
+		_, err = conn.Exec(ctx, `
+			UPDATE rate_limit
+			SET tokens = $2, updated_at = $3, full_at = $4
+			WHERE bucket_key = $1
+		`, key, b.Tokens, b.UpdatedAt, now.Add(res.Reset))
+		return err
+	})
+	return res, err
+}
+
+// sweep delete full buckets at most once every sweepInterval, it is skipped
+// when another request is already sweeping.
+func (sp *StorePostgres) sweep(ctx context.Context, now time.Time) {
+	select {
+	case sp.mx <- struct{}{}:
+	default:
+		return
+	}
+	defer func() { <-sp.mx }()
+
+	if now.Sub(sp.sweptAt) < sweepInterval {
+		return
+	}
+	_, err := sp.pool.Exec(ctx, `DELETE FROM rate_limit WHERE full_at <= $1`, now.UTC())
+	if err == nil {
+		sp.sweptAt = now
+	}
+}
@@ -1,11 +1,24 @@
 package response
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"nory/common/i18n"
+)
 
 type Response[T any] struct {
-	Code    int    `json:"code"`
-	Data    T      `json:"data"`
-	Message string `json:"message,omitempty"`
+	Code       int         `json:"code"`
+	Data       T           `json:"data"`
+	Message    string      `json:"message,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type Pagination struct {
+	Limit int `json:"limit"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 func (r *Response[T]) Respond(c *fiber.Ctx) error {
@@ -22,48 +35,127 @@ func New[T any](code int, data T) *Response[T] {
 	}
 }
 
-type ResponseError Response[*struct{}]
+func NewPaginated[T any](code int, data T, limit int, nextCursor string) *Response[T] {
+	res := New(code, data)
+	res.Pagination = &Pagination{
+		Limit:      limit,
+		NextCursor: nextCursor,
+	}
+	return res
+}
+
+// ResponseError is the body of failed request. ErrorCode is stable and machine
+// readable, Message is rendered from ErrorCode in the language requested by the
+// client. Errors is set when the request fail validation so the client can show
+// each message next to its input.
+type ResponseError struct {
+	Code      int          `json:"code"`
+	ErrorCode string       `json:"errorCode,omitempty"`
+	Message   string       `json:"message,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// Params fill the placeholders of the message
+	Params Params `json:"-"`
+}
+
+// Params fill the {name} placeholders of a message
+type Params = i18n.Params
+
+// FieldError describe a field that fail a validation rule
+type FieldError struct {
+	// Field is json path of the field, nested field is separated by dot
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+	// MessageCode and MessageParams render Message in other language
+	MessageCode   string `json:"-"`
+	MessageParams Params `json:"-"`
+}
 
 func (r *ResponseError) Error() string {
 	return r.Message
 }
 
+// Respond send the error in the language picked from Accept-Language
 func (r *ResponseError) Respond(c *fiber.Ctx) error {
-	return c.Status(r.Code).JSON(r)
+	return c.Status(r.Code).JSON(r.Localize(i18n.Lang(c.Get(fiber.HeaderAcceptLanguage))))
 }
 
-func NewBadRequest(msg string) *ResponseError {
-	return NewError(fiber.StatusBadRequest, msg)
+// Localize return copy of the error with messages rendered in lang, r is
+// left untouched because errors may be shared between requests.
+func (r *ResponseError) Localize(lang string) *ResponseError {
+	res := *r
+	if len(r.Errors) > 0 {
+		res.Errors = make([]FieldError, len(r.Errors))
+		for i, fe := range r.Errors {
+			if msg, ok := i18n.Translate(lang, fe.MessageCode, fe.MessageParams); ok {
+				fe.Message = msg
+			}
+			res.Errors[i] = fe
+		}
+		res.Params = Params{"errors": joinMessages(res.Errors)}
+	}
+	if msg, ok := i18n.Translate(lang, res.ErrorCode, res.Params); ok {
+		res.Message = msg
+	}
+	return &res
+}
+
+func NewBadRequest(errorCode string, params Params) *ResponseError {
+	return NewError(fiber.StatusBadRequest, errorCode, params)
+}
+
+func NewUnathorized(errorCode string, params Params) *ResponseError {
+	return NewError(fiber.StatusUnauthorized, errorCode, params)
 }
 
-func NewUnathorized(msg string) *ResponseError {
-	return NewError(fiber.StatusUnauthorized, msg)
+func NewForbidden(errorCode string, params Params) *ResponseError {
+	return NewError(fiber.StatusForbidden, errorCode, params)
 }
 
-func NewForbidden(msg string) *ResponseError {
-	return NewError(fiber.StatusForbidden, msg)
+func NewNotFound(errorCode string, params Params) *ResponseError {
+	return NewError(fiber.StatusNotFound, errorCode, params)
 }
 
-func NewNotFound(msg string) *ResponseError {
-	return NewError(fiber.StatusNotFound, msg)
+func NewConflict(errorCode string, params Params) *ResponseError {
+	return NewError(fiber.StatusConflict, errorCode, params)
 }
 
-func NewConflict(msg string) *ResponseError {
-	return NewError(fiber.StatusConflict, msg)
+func NewUnprocessableEntity(errorCode string, params Params) *ResponseError {
+	return NewError(fiber.StatusUnprocessableEntity, errorCode, params)
 }
 
-func NewUnprocessableEntity(msg string) *ResponseError {
-	return NewError(fiber.StatusUnprocessableEntity, msg)
+func NewTooManyRequests(errorCode string, params Params) *ResponseError {
+	return NewError(fiber.StatusTooManyRequests, errorCode, params)
 }
 
-func NewTooManyRequests(msg string) *ResponseError {
-	return NewError(fiber.StatusTooManyRequests, msg)
+// NewValidationError is bad request caused by errs, its message join message of every field
+func NewValidationError(errs []FieldError) *ResponseError {
+	res := NewBadRequest("validation.failed", Params{"errors": joinMessages(errs)})
+	res.Errors = errs
+	return res
 }
 
-func NewError(code int, msg string) *ResponseError {
+func joinMessages(errs []FieldError) string {
+	messages := make([]string, 0, len(errs))
+	for _, fe := range errs {
+		messages = append(messages, fe.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// NewError create error with message rendered in the default language,
+// unknown errorCode is used as the message.
+func NewError(code int, errorCode string, params Params) *ResponseError {
+	msg, ok := i18n.Translate(i18n.DefaultLang, errorCode, params)
+	if !ok {
+		msg = errorCode
+	}
 	return &ResponseError{
-		Code:    code,
-		Message: msg,
+		Code:      code,
+		ErrorCode: errorCode,
+		Message:   msg,
+		Params:    params,
 	}
 }
 
@@ -0,0 +1,59 @@
+// Package signedurl create and verify urls that can be used without
+// authentication until they expire.
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid url signature")
+	ErrExpired          = errors.New("signed url is expired")
+)
+
+type Signer struct {
+	Secret []byte
+	// BaseURL is prepended to signed path, it is not part of the signature
+	BaseURL string
+	// TTL is how long signed url is valid
+	TTL time.Duration
+}
+
+// Sign return BaseURL + path with expires and signature query
+func (s *Signer) Sign(path string, now time.Time) string {
+	expires := strconv.FormatInt(now.Add(s.TTL).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.signature(path, expires))
+	return fmt.Sprintf("%s%s?%s", s.BaseURL, path, query.Encode())
+}
+
+// Verify check expires and signature query of signed path
+func (s *Signer) Verify(path, expires, signature string, now time.Time) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(path, expires))) {
+		return ErrInvalidSignature
+	}
+	if !now.Before(time.Unix(unix, 0)) {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (s *Signer) signature(path, expires string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(path))
+	mac.Write([]byte{0})
+	mac.Write([]byte(expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
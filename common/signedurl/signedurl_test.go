@@ -0,0 +1,35 @@
+package signedurl_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "nory/common/signedurl"
+)
+
+func TestSigner(t *testing.T) {
+	t.Parallel()
+	signer := &Signer{Secret: []byte("secret"), BaseURL: "https://example.com/class", TTL: time.Minute}
+	now := time.Now()
+
+	signed := signer.Sign("/foo/bar", now)
+	u, err := url.Parse(signed)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "/class/foo/bar", u.Path)
+	expires := u.Query().Get("expires")
+	signature := u.Query().Get("signature")
+
+	assert.Nil(t, signer.Verify("/foo/bar", expires, signature, now))
+	assert.Equal(t, ErrExpired, signer.Verify("/foo/bar", expires, signature, now.Add(time.Minute)))
+	assert.Equal(t, ErrInvalidSignature, signer.Verify("/foo/baz", expires, signature, now), "signature is bound to the path")
+	assert.Equal(t, ErrInvalidSignature, signer.Verify("/foo/bar", expires+"0", signature, now), "signature is bound to the expiry")
+	assert.Equal(t, ErrInvalidSignature, signer.Verify("/foo/bar", "soon", signature, now))
+
+	other := &Signer{Secret: []byte("other"), TTL: time.Minute}
+	assert.Equal(t, ErrInvalidSignature, other.Verify("/foo/bar", expires, signature, now))
+}
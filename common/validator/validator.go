@@ -1,13 +1,13 @@
 package validator
 
 import (
-	"fmt"
 	"reflect"
 	"regexp"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 
+	"nory/common/i18n"
 	"nory/common/response"
 )
 
@@ -47,9 +47,57 @@ func ValidateStruct(s any) error {
 	if !ok {
 		return err
 	}
-	message := ""
+	errs := make([]response.FieldError, 0, len(vErr))
 	for _, fe := range vErr {
-		message = fmt.Sprintf("failed to validate %q, because %q rule", fe.Namespace(), fe.Tag())
+		field := fieldPath(fe)
+		code, params := fieldMessage(field, fe)
+		msg, _ := i18n.Translate(i18n.DefaultLang, code, params)
+		errs = append(errs, response.FieldError{
+			Field:         field,
+			Rule:          fe.Tag(),
+			Param:         fe.Param(),
+			Message:       msg,
+			MessageCode:   code,
+			MessageParams: params,
+		})
 	}
-	return response.NewBadRequest(message)
+	return response.NewValidationError(errs)
+}
+
+// fieldPath is namespace of the field without the name of validated struct
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+// fieldMessage pick message code that describe the failed rule to the user,
+// length rules has variant for string and collection. Rule without specific
+// message fall back to a generic one.
+func fieldMessage(field string, fe validator.FieldError) (string, response.Params) {
+	params := response.Params{
+		"field": field,
+		"rule":  fe.Tag(),
+		"param": fe.Param(),
+	}
+	if fe.Tag() == "oneof" {
+		params["param"] = strings.Join(strings.Fields(fe.Param()), ", ")
+	}
+
+	code := "validation." + fe.Tag()
+	switch fe.Tag() {
+	case "min", "max", "len":
+		switch fe.Kind() {
+		case reflect.String:
+			code += ".string"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			code += ".items"
+		}
+	}
+	if !i18n.Has(code) {
+		code = "validation.default"
+	}
+	return code, params
 }
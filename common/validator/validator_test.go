@@ -1,9 +1,11 @@
 package validator_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
+	"nory/common/response"
 	. "nory/common/validator"
 
 	"github.com/stretchr/testify/assert"
@@ -77,6 +79,52 @@ func TestValidator(t *testing.T) {
 	}
 }
 
+func TestValidatorFieldErrors(t *testing.T) {
+	t.Parallel()
+	type foo struct {
+		Name  string `validate:"required"`
+		Nick  string `validate:"min=1,max=3" json:"n1ck"`
+		Level string `validate:"oneof=admin member" json:"level"`
+	}
+	type nestedFoo struct {
+		Username string `validate:"username" json:"username"`
+		Foo      foo    `json:"foo"`
+	}
+
+	err := ValidateStruct(nestedFoo{Username: "_", Foo: foo{Nick: "bazz", Level: "member"}})
+	var resErr *response.ResponseError
+	if !assert.True(t, errors.As(err, &resErr)) {
+		return
+	}
+	assert.Equal(t, 400, resErr.Code)
+	assert.Equal(t, "validation.failed", resErr.ErrorCode)
+	// only compare what is sent to the client
+	got := make([]response.FieldError, 0, len(resErr.Errors))
+	for _, fe := range resErr.Errors {
+		got = append(got, response.FieldError{Field: fe.Field, Rule: fe.Rule, Param: fe.Param, Message: fe.Message})
+	}
+	assert.Equal(t, []response.FieldError{
+		{Field: "username", Rule: "username", Message: "username must be 3 to 20 letters, numbers or underscores, and must not start or end with underscore"},
+		{Field: "foo.name", Rule: "required", Message: "foo.name is required"},
+		{Field: "foo.n1ck", Rule: "max", Param: "3", Message: "foo.n1ck must be at most 3 characters"},
+	}, got)
+	assert.Contains(t, resErr.Message, "foo.name is required")
+
+	localized := resErr.Localize("id")
+	if assert.Equal(t, 3, len(localized.Errors)) {
+		assert.Equal(t, "foo.name wajib diisi", localized.Errors[1].Message)
+		assert.Equal(t, "foo.n1ck maksimal 3 karakter", localized.Errors[2].Message)
+	}
+	assert.Contains(t, localized.Message, "foo.name wajib diisi")
+	assert.Equal(t, "foo.name is required", resErr.Errors[1].Message, "Localize should not modify the error")
+
+	err = ValidateStruct(foo{Name: "foo", Nick: "baz", Level: "owner"})
+	if assert.True(t, errors.As(err, &resErr)) && assert.Equal(t, 1, len(resErr.Errors)) {
+		assert.Equal(t, "level must be one of admin, member", resErr.Errors[0].Message)
+		assert.Equal(t, "admin member", resErr.Errors[0].Param)
+	}
+}
+
 func TestValidatorRegex(t *testing.T) {
 	t.Parallel()
 	for _, tc := range []struct {
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+const (
+	AgendaItemTask     = "task"
+	AgendaItemSchedule = "schedule"
+)
+
+// AgendaItem is a single entry of the personal agenda, it is either a task
+// or an occurrence of a weekly schedule.
+type AgendaItem struct {
+	Type      string     `json:"type"`
+	ClassId   string     `json:"classId"`
+	ClassName string     `json:"className"`
+	StartAt   time.Time  `json:"startAt"`
+	EndAt     *time.Time `json:"endAt,omitempty"`
+
+	Task     *ClassTask     `json:"task,omitempty"`
+	Schedule *ClassSchedule `json:"schedule,omitempty"`
+}
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	ErrBlobNotExists  = errors.New("blob does not exists")
+	ErrInvalidBlobKey = errors.New("invalid blob key")
+)
+
+// BlobStore keep binary content by key, keys are slash separated path without
+// leading slash or dot segments. It is implemented by the local filesystem and
+// can be backed by S3-compatible storage.
+type BlobStore interface {
+	// Put create or replace the blob at key with content of r
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get open the blob at key, the caller must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete remove the blob at key, deleting missing blob is not an error
+	Delete(ctx context.Context, key string) error
+}
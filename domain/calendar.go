@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrCalendarTokenNotExists = errors.New("calendar token does not exists")
+)
+
+// CalendarToken is a secret that authenticate calendar feed requests,
+// calendar clients can not send bearer token so the secret is part of the feed url.
+type CalendarToken struct {
+	Token     string    `json:"token"`     // immutable, unique
+	UserId    string    `json:"userId"`    // immutable, unique
+	CreatedAt time.Time `json:"createdAt"` // immutable
+}
+
+type CalendarTokenRepository interface {
+	// CreateToken replace existing token of the user, it should update (*CalendarToken).Token to generated token
+	CreateToken(ctx context.Context, token *CalendarToken) error
+	GetToken(ctx context.Context, token string) (*CalendarToken, error)
+	GetTokenByUserId(ctx context.Context, userId string) (*CalendarToken, error)
+	DeleteToken(ctx context.Context, userId string) error
+}
@@ -13,13 +13,35 @@ var (
 	ErrClassAlreadyExists = errors.New("class already exists")
 )
 
+// Visibility of a class to users that are not its member
+const (
+	// public class can be read by anyone and is listed in user profile
+	ClassVisibilityPublic = "public"
+	// unlisted class can be read by anyone knowing its id but is never listed
+	ClassVisibilityUnlisted = "unlisted"
+	// private class can only be read by its members
+	ClassVisibilityPrivate = "private"
+)
+
 type Class struct {
 	ClassId   string    `json:"classId"`   // immutable, unique
-	OwnerId   string    `json:"ownerId"`   // immutable
+	OwnerId   string    `json:"ownerId"`   // mutable through ClassRepository.TransferClass
 	CreatedAt time.Time `json:"createdAt"` // immutable
 
 	Name        string `json:"name" validate:"required,max=20"` // mutable
 	Description string `json:"description" validate:"max=255"`  // mutable
+	// Visibility is one of ClassVisibility*, empty is the same as public
+	Visibility string `json:"visibility" validate:"omitempty,oneof=public unlisted private"` // mutable
+}
+
+// Listed report whether the class may be listed to non-members
+func (c *Class) Listed() bool {
+	return c.Visibility == "" || c.Visibility == ClassVisibilityPublic
+}
+
+// Private report whether only members may read the class
+func (c *Class) Private() bool {
+	return c.Visibility == ClassVisibilityPrivate
 }
 
 func (c *Class) Update(cc *Class) {
@@ -29,13 +51,25 @@ func (c *Class) Update(cc *Class) {
 	if cc.Description != "" {
 		c.Description = cc.Description
 	}
+	if cc.Visibility != "" {
+		c.Visibility = cc.Visibility
+	}
 }
 
 type ClassRepository interface {
 	GetClass(ctx context.Context, classId string) (*Class, error)
 	GetClassByName(ctx context.Context, ownerId, className string) (*Class, error)
-	GetClassesByOwnerId(ctx context.Context, ownerId string) ([]*Class, error)
+	// GetClassesByOwnerId is ordered by ClassId
+	GetClassesByOwnerId(ctx context.Context, ownerId string, page Pagination) ([]*Class, string, error)
+	// GetClassesByIds is ordered by ClassId, unknown ids are omitted
+	GetClassesByIds(ctx context.Context, classIds []string) ([]*Class, error)
 	CreateClass(ctx context.Context, class *Class) error
 	DeleteClass(ctx context.Context, classId string) error
 	UpdateClass(ctx context.Context, class *Class) error
+	// SearchClasses list at most limit listed classes matching query, ordered by
+	// SearchRank then by lowercase name and ClassId
+	SearchClasses(ctx context.Context, query string, limit int) ([]*Class, error)
+	// TransferClass change OwnerId of the class, ErrClassAlreadyExists is returned
+	// when the new owner already has a class with the same name
+	TransferClass(ctx context.Context, classId, ownerId string) error
 }
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrClassAnnouncementNotExists = errors.New("class announcement does not exists")
+)
+
+// ClassAnnouncement is a notice for class members that is not tied to a due date
+type ClassAnnouncement struct {
+	AnnouncementId string    `json:"announcementId"` // immutable, unique
+	ClassId        string    `json:"classId"`        // immutable
+	AuthorId       string    `json:"authorId"`       // immutable
+	CreatedAt      time.Time `json:"createdAt"`      // immutable
+
+	Title  string `json:"title" validate:"required,max=100"` // mutable
+	Body   string `json:"body" validate:"max=10000"`         // mutable, markdown
+	Pinned bool   `json:"pinned"`                            // mutable
+	// ExpiresAt hide the announcement from members once passed, nil never expire
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // mutable
+
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"` // mutable, set by UpdateAnnouncement
+}
+
+// Expired report whether the announcement is hidden at now
+func (ca *ClassAnnouncement) Expired(now time.Time) bool {
+	return ca.ExpiresAt != nil && !ca.ExpiresAt.After(now)
+}
+
+type ClassAnnouncementRepository interface {
+	// CreateAnnouncement should update (*ClassAnnouncement).AnnouncementId and (*ClassAnnouncement).CreatedAt
+	CreateAnnouncement(ctx context.Context, announcement *ClassAnnouncement) error
+	GetAnnouncement(ctx context.Context, announcementId string) (*ClassAnnouncement, error)
+	// ListAnnouncements list announcements of the class that are not expired at activeAt,
+	// zero activeAt include expired announcements. It is ordered by pinned first then newest first.
+	ListAnnouncements(ctx context.Context, classId string, activeAt time.Time, page Pagination) ([]*ClassAnnouncement, string, error)
+	// UpdateAnnouncement replace Title, Body, Pinned and ExpiresAt and should update (*ClassAnnouncement).UpdatedAt
+	UpdateAnnouncement(ctx context.Context, announcement *ClassAnnouncement) error
+	DeleteAnnouncement(ctx context.Context, announcementId string) error
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrClassAttachmentNotExists = errors.New("class attachment does not exists")
+)
+
+// MaxAttachmentSize is the largest file that can be attached to a task
+const MaxAttachmentSize = 10 << 20
+
+// AttachmentContentTypes is the allowed MIME types of attachment
+var AttachmentContentTypes = map[string]bool{
+	"application/pdf":    true,
+	"application/msword": true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": true,
+	"application/vnd.ms-excel": true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.ms-powerpoint":                                             true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+	"application/zip": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"text/plain":      true,
+}
+
+// ClassAttachment is a file attached to a task, the content is kept in BlobStore under BlobKey
+type ClassAttachment struct {
+	AttachmentId string    `json:"attachmentId"` // immutable, unique
+	ClassId      string    `json:"classId"`      // immutable
+	TaskId       string    `json:"taskId"`       // immutable
+	AuthorId     string    `json:"authorId"`     // immutable
+	CreatedAt    time.Time `json:"createdAt"`    // immutable
+
+	Name        string `json:"name" validate:"required,max=255"` // immutable, original file name
+	ContentType string `json:"contentType" validate:"required"`  // immutable
+	Size        int64  `json:"size" validate:"min=0"`            // immutable, in bytes
+
+	// URL is signed download url that expire, it is not stored with the attachment
+	URL string `json:"url,omitempty"`
+}
+
+// BlobKey is where the content of the attachment is stored
+func (ca *ClassAttachment) BlobKey() string {
+	return ca.ClassId + "/" + ca.TaskId + "/" + ca.AttachmentId
+}
+
+type ClassAttachmentRepository interface {
+	// CreateAttachment should update (*ClassAttachment).AttachmentId and (*ClassAttachment).CreatedAt
+	CreateAttachment(ctx context.Context, attachment *ClassAttachment) error
+	GetAttachment(ctx context.Context, attachmentId string) (*ClassAttachment, error)
+	// ListAttachments list attachments of a task, it is ordered by CreatedAt then AttachmentId
+	ListAttachments(ctx context.Context, taskId string) ([]*ClassAttachment, error)
+	// ListClassAttachments list attachments of every task in the class, it is ordered by CreatedAt then AttachmentId
+	ListClassAttachments(ctx context.Context, classId string) ([]*ClassAttachment, error)
+	DeleteAttachment(ctx context.Context, attachmentId string) error
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditClassCreate        = "class.create"
+	AuditClassUpdate        = "class.update"
+	AuditClassDelete        = "class.delete"
+	AuditClassTransfer      = "class.transfer"
+	AuditTaskCreate         = "task.create"
+	AuditTaskUpdate         = "task.update"
+	AuditTaskDelete         = "task.delete"
+	AuditScheduleCreate     = "schedule.create"
+	AuditScheduleDelete     = "schedule.delete"
+	AuditScheduleClear      = "schedule.clear"
+	AuditScheduleReplace    = "schedule.replace"
+	AuditMemberAdd          = "member.add"
+	AuditMemberUpdate       = "member.update"
+	AuditMemberRemove       = "member.remove"
+	AuditMemberLeave        = "member.leave"
+	AuditInviteCreate       = "invite.create"
+	AuditInviteDelete       = "invite.delete"
+	AuditAttachmentCreate   = "attachment.create"
+	AuditAttachmentDelete   = "attachment.delete"
+	AuditCommentCreate      = "comment.create"
+	AuditCommentUpdate      = "comment.update"
+	AuditCommentDelete      = "comment.delete"
+	AuditAnnouncementCreate = "announcement.create"
+	AuditAnnouncementUpdate = "announcement.update"
+	AuditAnnouncementDelete = "announcement.delete"
+	AuditJoinRequestCreate  = "join_request.create"
+	AuditJoinRequestApprove = "join_request.approve"
+	AuditJoinRequestReject  = "join_request.reject"
+)
+
+// ClassAuditEntry record a mutation on a class, entries are append-only
+// and kept after the class is deleted.
+type ClassAuditEntry struct {
+	EntryId   string    `json:"entryId"`   // immutable, unique
+	ClassId   string    `json:"classId"`   // immutable
+	ActorId   string    `json:"actorId"`   // immutable
+	CreatedAt time.Time `json:"createdAt"` // immutable
+
+	Action   string          `json:"action"`           // immutable
+	TargetId string          `json:"targetId"`         // immutable
+	Before   json.RawMessage `json:"before,omitempty"` // immutable
+	After    json.RawMessage `json:"after,omitempty"`  // immutable
+}
+
+// ClassAuditFilter narrow down listed entries, zero value field is ignored
+type ClassAuditFilter struct {
+	ActorId string    `query:"actor" validate:"omitempty,uuid"`
+	Action  string    `query:"action"`
+	From    time.Time `query:"from"`
+	To      time.Time `query:"to"`
+}
+
+func (f ClassAuditFilter) Match(entry *ClassAuditEntry) bool {
+	if f.ActorId != "" && entry.ActorId != f.ActorId {
+		return false
+	}
+	if f.Action != "" && entry.Action != f.Action {
+		return false
+	}
+	if !f.From.IsZero() && entry.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !entry.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+type ClassAuditRepository interface {
+	// CreateEntry should update (*ClassAuditEntry).EntryId and (*ClassAuditEntry).CreatedAt
+	CreateEntry(ctx context.Context, entry *ClassAuditEntry) error
+	// ListEntries is ordered from the newest entry
+	ListEntries(ctx context.Context, classId string, filter ClassAuditFilter, page Pagination) ([]*ClassAuditEntry, string, error)
+}
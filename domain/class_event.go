@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventTaskCreated         = "task.created"
+	EventTaskUpdated         = "task.updated"
+	EventTaskDeleted         = "task.deleted"
+	EventScheduleCreated     = "schedule.created"
+	EventScheduleDeleted     = "schedule.deleted"
+	EventScheduleCleared     = "schedule.cleared"
+	EventScheduleReplaced    = "schedule.replaced"
+	EventMemberAdded         = "member.added"
+	EventMemberUpdated       = "member.updated"
+	EventMemberRemoved       = "member.removed"
+	EventAttachmentAdded     = "attachment.added"
+	EventAttachmentDeleted   = "attachment.deleted"
+	EventCommentCreated      = "comment.created"
+	EventCommentUpdated      = "comment.updated"
+	EventCommentDeleted      = "comment.deleted"
+	EventAnnouncementPosted  = "announcement.posted"
+	EventAnnouncementUpdated = "announcement.updated"
+	EventAnnouncementDeleted = "announcement.deleted"
+	EventJoinRequestCreated  = "join_request.created"
+	EventClassDeleted        = "class.deleted"
+)
+
+// ClassEvent notify class members about change in the class
+type ClassEvent struct {
+	EventId   string          `json:"eventId"` // opaque, assigned by ClassEventBus
+	ClassId   string          `json:"classId"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+type ClassEventBus interface {
+	// Publish should update (*ClassEvent).EventId and (*ClassEvent).CreatedAt then deliver the event to subscribers
+	Publish(ctx context.Context, event *ClassEvent) error
+	// Subscribe receive events of the class until ctx is done, then the channel is closed.
+	// When lastEventId is not empty, events after it that still buffered are delivered first.
+	// The channel is also closed when the subscriber can not keep up, it should subscribe again using last received EventId.
+	// EventClassDeleted is the last event of the class, the channel is closed and buffered events are dropped after it.
+	Subscribe(ctx context.Context, classId, lastEventId string) (<-chan *ClassEvent, error)
+}
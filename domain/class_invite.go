@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrClassInviteNotExists = errors.New("class invite does not exists")
+	// invite already used MaxUses times
+	ErrClassInviteExhausted = errors.New("class invite exhausted")
+)
+
+type ClassInvite struct {
+	Code      string    `json:"code"`      // immutable, unique
+	ClassId   string    `json:"classId"`   // immutable
+	AuthorId  string    `json:"authorId"`  // immutable
+	CreatedAt time.Time `json:"createdAt"` // immutable
+
+	Level     string     `json:"level" validate:"oneof=admin moderator member viewer"` // immutable, role granted to the joining user
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`                                  // immutable, nil means never expires
+	MaxUses   int        `json:"maxUses" validate:"min=0"`                             // immutable, zero means unlimited
+	Uses      int        `json:"uses"`                                                 // mutable
+}
+
+func (ci *ClassInvite) Expired(now time.Time) bool {
+	return ci.ExpiresAt != nil && !now.Before(*ci.ExpiresAt)
+}
+
+func (ci *ClassInvite) Exhausted() bool {
+	return ci.MaxUses > 0 && ci.Uses >= ci.MaxUses
+}
+
+type ClassInviteRepository interface {
+	// CreateInvite should update (*ClassInvite).Code to generated code
+	CreateInvite(ctx context.Context, invite *ClassInvite) error
+	GetInvite(ctx context.Context, code string) (*ClassInvite, error)
+	ListInvites(ctx context.Context, classId string) ([]*ClassInvite, error)
+	// UseInvite increment uses of the invite, it returns ErrClassInviteExhausted when the invite already used MaxUses times
+	UseInvite(ctx context.Context, code string) error
+	DeleteInvite(ctx context.Context, code string) error
+}
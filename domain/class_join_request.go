@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrClassJoinRequestNotExists = errors.New("class join request does not exists")
+	// user already has pending request to the class
+	ErrClassJoinRequestAlreadyExists = errors.New("class join request already exists")
+)
+
+// JoinRequestTTL is how long a join request wait for a decision before it expires
+const JoinRequestTTL = 14 * 24 * time.Hour
+
+// ClassJoinRequest is a pending membership, it is removed once approved,
+// rejected or expired. A user has at most one request for each class.
+type ClassJoinRequest struct {
+	ClassId   string    `json:"classId"`   // immutable
+	UserId    string    `json:"userId"`    // immutable
+	CreatedAt time.Time `json:"createdAt"` // immutable
+	ExpiresAt time.Time `json:"expiresAt"` // immutable
+
+	Message string `json:"message" validate:"max=255"` // immutable
+}
+
+func (r *ClassJoinRequest) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+type ClassJoinRequestRepository interface {
+	// CreateJoinRequest should update (*ClassJoinRequest).CreatedAt, it returns
+	// ErrClassJoinRequestAlreadyExists when the user already requested to join the class
+	CreateJoinRequest(ctx context.Context, request *ClassJoinRequest) error
+	GetJoinRequest(ctx context.Context, classId, userId string) (*ClassJoinRequest, error)
+	// ListJoinRequests is ordered by CreatedAt then UserId
+	ListJoinRequests(ctx context.Context, classId string, page Pagination) ([]*ClassJoinRequest, string, error)
+	DeleteJoinRequest(ctx context.Context, classId, userId string) error
+	// DeleteExpiredJoinRequests delete requests of the class that are expired at now
+	DeleteExpiredJoinRequests(ctx context.Context, classId string, now time.Time) error
+}
+
+// ClassJoinRequestNotifier is told about decided join requests, it is called
+// after the decision is committed and should not block.
+type ClassJoinRequestNotifier interface {
+	JoinRequestDecided(ctx context.Context, request *ClassJoinRequest, approved bool)
+}
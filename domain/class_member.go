@@ -16,7 +16,7 @@ type ClassMember struct {
 	UserId    string    `json:"userId" validate:"uuid"`    // immutable
 	CreatedAt time.Time `json:"createdAt"`                 // immutable
 
-	Level string `json:"level"` // mutable
+	Level string `json:"level" validate:"oneof=owner admin moderator member viewer"` // mutable, one of the Role constants
 }
 
 func (member *ClassMember) Update(m *ClassMember) {
@@ -26,8 +26,10 @@ func (member *ClassMember) Update(m *ClassMember) {
 }
 
 type ClassMemberRepository interface {
-	ListMembers(ctx context.Context, classId string) ([]*ClassMember, error)
-	ListJoined(ctx context.Context, userId string) ([]*ClassMember, error)
+	// ListMembers is ordered by CreatedAt then UserId
+	ListMembers(ctx context.Context, classId string, page Pagination) ([]*ClassMember, string, error)
+	// ListJoined is ordered by CreatedAt then ClassId
+	ListJoined(ctx context.Context, userId string, page Pagination) ([]*ClassMember, string, error)
 	GetMember(ctx context.Context, member *ClassMember) (*ClassMember, error)
 	CreateMember(ctx context.Context, member *ClassMember) error
 	UpdateMember(ctx context.Context, member *ClassMember) error
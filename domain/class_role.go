@@ -0,0 +1,91 @@
+package domain
+
+// Roles of a class member, stored in ClassMember.Level
+const (
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleMember    = "member"
+	RoleViewer    = "viewer"
+)
+
+// RoleRank order roles from the least to the most privileged,
+// unknown role has rank zero and is granted nothing.
+func RoleRank(role string) int {
+	switch role {
+	case RoleOwner:
+		return 5
+	case RoleAdmin:
+		return 4
+	case RoleModerator:
+		return 3
+	case RoleMember:
+		return 2
+	case RoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ClassCapability is an action in a class that is guarded by member role
+type ClassCapability string
+
+const (
+	CapabilityViewClass       ClassCapability = "class.view"
+	CapabilityTrackProgress   ClassCapability = "progress.track"
+	CapabilityCreateTask      ClassCapability = "task.create"
+	CapabilityManageTasks     ClassCapability = "task.manage"
+	CapabilityViewSummary     ClassCapability = "progress.summary"
+	CapabilityManageSchedules ClassCapability = "schedule.manage"
+	CapabilityManageMembers   ClassCapability = "member.manage"
+	CapabilityManageInvites   ClassCapability = "invite.manage"
+	CapabilityViewAudit       ClassCapability = "audit.view"
+	CapabilityUpdateClass     ClassCapability = "class.update"
+	CapabilityDeleteClass     ClassCapability = "class.delete"
+	CapabilityRenameClass     ClassCapability = "class.rename"
+	CapabilityTransferClass   ClassCapability = "class.transfer"
+	CapabilityComment         ClassCapability = "comment.create"
+	CapabilityModerateComment ClassCapability = "comment.moderate"
+	CapabilityAnnounce        ClassCapability = "announcement.manage"
+)
+
+// capabilityRoles is the least privileged role granted each capability
+var capabilityRoles = map[ClassCapability]string{
+	CapabilityViewClass:       RoleViewer,
+	CapabilityTrackProgress:   RoleMember,
+	CapabilityCreateTask:      RoleMember,
+	CapabilityManageTasks:     RoleModerator,
+	CapabilityViewSummary:     RoleModerator,
+	CapabilityManageSchedules: RoleAdmin,
+	CapabilityManageMembers:   RoleAdmin,
+	CapabilityManageInvites:   RoleAdmin,
+	CapabilityViewAudit:       RoleAdmin,
+	CapabilityUpdateClass:     RoleAdmin,
+	CapabilityDeleteClass:     RoleAdmin,
+	CapabilityRenameClass:     RoleOwner,
+	CapabilityTransferClass:   RoleOwner,
+	CapabilityComment:         RoleMember,
+	CapabilityModerateComment: RoleAdmin,
+	CapabilityAnnounce:        RoleAdmin,
+}
+
+// RoleAllows report whether role is granted capability, unknown capability is never granted
+func RoleAllows(role string, capability ClassCapability) bool {
+	minimum, ok := capabilityRoles[capability]
+	if !ok {
+		return false
+	}
+	rank := RoleRank(role)
+	return rank > 0 && rank >= RoleRank(minimum)
+}
+
+// RoleCanManage report whether a member with role actor may change a member
+// from or to role target. Owner can manage every role, other roles can only
+// manage roles below their own.
+func RoleCanManage(actor, target string) bool {
+	if actor == RoleOwner {
+		return true
+	}
+	return RoleRank(actor) > RoleRank(target)
+}
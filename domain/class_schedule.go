@@ -3,6 +3,7 @@ package domain
 import (
 	"context"
 	"errors"
+	"sort"
 	"time"
 )
 
@@ -16,16 +17,83 @@ type ClassSchedule struct {
 	AuthorId   string    `json:"authorId"`   // immutable
 	CreatedAt  time.Time `json:"createdAt"`  // immutable
 
-	Name     string    `json:"name"`     // immutable
-	StartAt  time.Time `json:"startAt"`  // immutable
-	Duration int16     `json:"duration"` // immutable
-	Day      int8      `json:"day"`      // immutable
+	Name     string    `json:"name" validate:"max=20"`             // immutable
+	StartAt  time.Time `json:"startAt"`                            // immutable, only the time of day is used
+	Duration int16     `json:"duration" validate:"min=0,max=1440"` // immutable, in minutes
+	Day      int8      `json:"day" validate:"min=0,max=6"`         // immutable, 0 is sunday
+}
+
+const minutesPerWeek = 7 * 24 * 60
+
+// span return the start and end of the schedule in minutes since the
+// start of the week.
+func (cs *ClassSchedule) span() (int, int) {
+	start := int(cs.Day)*24*60 + cs.StartAt.Hour()*60 + cs.StartAt.Minute()
+	return start, start + int(cs.Duration)
+}
+
+// Overlaps report whether both schedules take place at the same time, a
+// schedule that run past midnight overlaps with the next day and schedules
+// without duration overlap when they start together.
+func (cs *ClassSchedule) Overlaps(other *ClassSchedule) bool {
+	start, end := cs.span()
+	otherStart, otherEnd := other.span()
+	for _, shift := range []int{-minutesPerWeek, 0, minutesPerWeek} {
+		s, e := otherStart+shift, otherEnd+shift
+		if start == s || (start < e && s < end) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassTimetableDay hold the schedules of a single weekday ordered by the
+// time of day they start.
+type ClassTimetableDay struct {
+	Day       int8             `json:"day"`
+	Schedules []*ClassSchedule `json:"schedules"`
+}
+
+// NewClassTimetable group schedules by weekday, the result always contains
+// 7 days starting from sunday.
+func NewClassTimetable(schedules []*ClassSchedule) []*ClassTimetableDay {
+	days := make([]*ClassTimetableDay, 7)
+	for i := range days {
+		days[i] = &ClassTimetableDay{
+			Day:       int8(i),
+			Schedules: make([]*ClassSchedule, 0),
+		}
+	}
+	for _, schedule := range schedules {
+		if schedule.Day < 0 || int(schedule.Day) >= len(days) {
+			continue
+		}
+		day := days[schedule.Day]
+		day.Schedules = append(day.Schedules, schedule)
+	}
+	for _, day := range days {
+		sort.SliceStable(day.Schedules, func(i, j int) bool {
+			a, _ := day.Schedules[i].span()
+			b, _ := day.Schedules[j].span()
+			if a != b {
+				return a < b
+			}
+			return day.Schedules[i].ScheduleId < day.Schedules[j].ScheduleId
+		})
+	}
+	return days
 }
 
 type ClassScheduleRepository interface {
 	CreateSchedule(ctx context.Context, schedule *ClassSchedule) error
 	GetSchedule(ctx context.Context, scheduleId string) (*ClassSchedule, error)
-	GetSchedules(ctx context.Context, classId string) ([]*ClassSchedule, error)
+	// GetSchedules is ordered by ScheduleId
+	GetSchedules(ctx context.Context, classId string, page Pagination) ([]*ClassSchedule, string, error)
+	// GetSchedulesByClassIds list schedules of several classes, it is ordered by ScheduleId
+	GetSchedulesByClassIds(ctx context.Context, classIds []string) ([]*ClassSchedule, error)
 	DeleteSchedule(ctx context.Context, scheduleId string) error
 	ClearSchedules(ctx context.Context, classId string, day int8) error
+	// ReplaceSchedules clear schedules of the day and create the given
+	// schedules atomically, it must assign ScheduleId like CreateSchedule
+	ReplaceSchedules(ctx context.Context, classId string, day int8, schedules []*ClassSchedule) error
 }
@@ -3,11 +3,19 @@ package domain
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 )
 
 var (
-	ErrClassTaskNotExists = errors.New("class task does not exists")
+	ErrClassTaskNotExists         = errors.New("class task does not exists")
+	ErrClassTaskProgressNotExists = errors.New("class task progress does not exists")
+)
+
+const (
+	ClassTaskStatusTodo       = "todo"
+	ClassTaskStatusInProgress = "in_progress"
+	ClassTaskStatusDone       = "done"
 )
 
 type ClassTask struct {
@@ -20,6 +28,24 @@ type ClassTask struct {
 	Name              string    `json:"name" validate:"max=20"`              // mutable
 	Description       string    `json:"description" validate:"max=1024"`     // mutable
 	DueDate           time.Time `json:"dueDate"`                             // mutable
+
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"` // mutable, set by UpdateTask
+	UpdatedBy string     `json:"updatedBy,omitempty"` // mutable
+
+	// Recurrence is RRULE of a series, see ParseRecurrence. DueDate of a series is the
+	// start of the rule, the series itself is not listed but expanded into its occurrences
+	Recurrence string `json:"recurrence,omitempty" validate:"max=255"` // mutable
+	// SeriesId is the task id of the series of an occurrence, TaskId of an occurrence is
+	// OccurrenceId and it is only stored once it is edited, skipped or worked on
+	SeriesId string `json:"seriesId,omitempty"` // immutable
+	// Skipped occurrence is stored to stop it from being expanded again, it is never listed
+	Skipped bool `json:"-"` // immutable
+	// Edited occurrence is updated on its own, stored occurrence which is not edited follow
+	// its series when the series is updated
+	Edited bool `json:"-"` // set by UpdateTask
+
+	// progress of the user who request the task, it is not stored with the task
+	Progress *ClassTaskProgress `json:"progress,omitempty"`
 }
 
 func (ct *ClassTask) Update(task *ClassTask) {
@@ -32,14 +58,237 @@ func (ct *ClassTask) Update(task *ClassTask) {
 	if !task.DueDate.IsZero() {
 		ct.DueDate = task.DueDate
 	}
+	if task.UpdatedBy != "" {
+		ct.UpdatedBy = task.UpdatedBy
+	}
+	if task.Recurrence != "" {
+		ct.Recurrence = task.Recurrence
+	}
+}
+
+// Recurring report whether the task is a series
+func (ct *ClassTask) Recurring() bool {
+	return ct.Recurrence != ""
+}
+
+// Occurrences expand the series into occurrences due in [from, to), occurrence
+// which id is in stored is left out because it is stored as a task of its own
+func (ct *ClassTask) Occurrences(from, to time.Time, stored map[string]bool) []*ClassTask {
+	rule, err := ParseRecurrence(ct.Recurrence)
+	if err != nil || ct.DueDate.IsZero() {
+		return nil
+	}
+	occurrences := make([]*ClassTask, 0)
+	for _, date := range rule.Occurrences(ct.DueDate, from, to) {
+		id := OccurrenceId(ct.TaskId, date)
+		if stored[id] {
+			continue
+		}
+		occurrences = append(occurrences, ct.occurrenceOn(date))
+	}
+	return occurrences
+}
+
+// occurrenceOn build occurrence of the series due on the day of date at the time of the series
+func (ct *ClassTask) occurrenceOn(date time.Time) *ClassTask {
+	occurrence := *ct
+	occurrence.DueDate = time.Date(date.Year(), date.Month(), date.Day(),
+		ct.DueDate.Hour(), ct.DueDate.Minute(), ct.DueDate.Second(), ct.DueDate.Nanosecond(), ct.DueDate.Location())
+	occurrence.TaskId = OccurrenceId(ct.TaskId, occurrence.DueDate)
+	occurrence.SeriesId = ct.TaskId
+	occurrence.Recurrence = ""
+	occurrence.Progress = nil
+	return &occurrence
+}
+
+// Follow copy mutable fields of the series to the stored occurrence, the occurrence keep its day
+func (ct *ClassTask) Follow(series *ClassTask) {
+	_, date, ok := ParseOccurrenceId(ct.TaskId)
+	if !ok || ct.SeriesId != series.TaskId {
+		return
+	}
+	o := series.occurrenceOn(date)
+	ct.AuthorDisplayName = o.AuthorDisplayName
+	ct.Name = o.Name
+	ct.Description = o.Description
+	ct.DueDate = o.DueDate
+	ct.UpdatedAt = o.UpdatedAt
+	ct.UpdatedBy = o.UpdatedBy
+}
+
+// Occurrence find the occurrence of the series by its id
+func (ct *ClassTask) Occurrence(id string) (*ClassTask, bool) {
+	seriesId, date, ok := ParseOccurrenceId(id)
+	if !ok || seriesId != ct.TaskId {
+		return nil, false
+	}
+	from := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, ct.DueDate.Location())
+	for _, occurrence := range ct.Occurrences(from, from.AddDate(0, 0, 1), nil) {
+		if occurrence.TaskId == id {
+			return occurrence, true
+		}
+	}
+	return nil, false
+}
+
+// ClassTaskProgress is completion state of a task for a single class member.
+type ClassTaskProgress struct {
+	TaskId    string    `json:"taskId"`    // immutable
+	UserId    string    `json:"userId"`    // immutable
+	UpdatedAt time.Time `json:"updatedAt"` // mutable
+
+	Status      string     `json:"status" validate:"oneof=todo in_progress done"` // mutable
+	CompletedAt *time.Time `json:"completedAt,omitempty"`                         // mutable
+}
+
+// ClassTaskSummary count how many class members are in each task status.
+type ClassTaskSummary struct {
+	TaskId     string `json:"taskId"`
+	Members    int    `json:"members"`
+	Todo       int    `json:"todo"`
+	InProgress int    `json:"inProgress"`
+	Done       int    `json:"done"`
+}
+
+// sort order of ClassTaskFilter, prefixed with "-" for descending order
+const (
+	ClassTaskSortDueDate   = "dueDate"
+	ClassTaskSortCreatedAt = "createdAt"
+)
+
+// ClassTaskFilter narrow down tasks found by FindTasks, zero value field is ignored
+type ClassTaskFilter struct {
+	// Query match name and description the same way as SearchRank
+	Query    string    `query:"q" validate:"max=100"`
+	AuthorId string    `query:"author" validate:"omitempty,uuid"`
+	From     time.Time `query:"from"`
+	To       time.Time `query:"to"`
+	// Status is the progress status of UserId, task without progress is todo
+	Status string `query:"status" validate:"omitempty,oneof=todo in_progress done"`
+	// Overdue keep tasks due before Now which UserId has not done
+	Overdue bool `query:"overdue"`
+	// Sort is ClassTaskSortDueDate or ClassTaskSortCreatedAt, empty Sort order by TaskId
+	Sort string `query:"sort" validate:"omitempty,oneof=dueDate -dueDate createdAt -createdAt"`
+
+	UserId string    `query:"-"`
+	Now    time.Time `query:"-"`
+}
+
+// Match report whether task with the progress status of UserId pass the filter
+func (f ClassTaskFilter) Match(task *ClassTask, status string) bool {
+	if f.Query != "" && SearchRank(f.Query, task.Name, task.Description) == SearchRankNone {
+		return false
+	}
+	if f.AuthorId != "" && task.AuthorId != f.AuthorId {
+		return false
+	}
+	if !f.From.IsZero() && task.DueDate.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !task.DueDate.Before(f.To) {
+		return false
+	}
+	if f.Status != "" && status != f.Status {
+		return false
+	}
+	if f.Overdue && (!task.DueDate.Before(f.Now) || status == ClassTaskStatusDone) {
+		return false
+	}
+	return true
+}
+
+// OccurrenceRange is the range series are expanded in, it never start earlier than
+// RecurrenceHorizon before Now and never end later than RecurrenceHorizon after From
+// or Now, whichever is later
+func (f ClassTaskFilter) OccurrenceRange() (time.Time, time.Time) {
+	now := f.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	from := f.From
+	if earliest := now.Add(-RecurrenceHorizon); from.Before(earliest) {
+		from = earliest
+	}
+	latest := now
+	if from.After(latest) {
+		latest = from
+	}
+	to := latest.Add(RecurrenceHorizon)
+	if !f.To.IsZero() && f.To.Before(to) {
+		to = f.To
+	}
+	return from, to
+}
+
+// Less report whether task a is listed before task b
+func (f ClassTaskFilter) Less(a, b *ClassTask) bool {
+	c := CompareKeys(f.TaskKey(a), f.TaskKey(b))
+	if f.Descending() {
+		return c > 0
+	}
+	return c < 0
+}
+
+// After report whether task is listed after the task with pagination key keys
+func (f ClassTaskFilter) After(task *ClassTask, keys []string) bool {
+	c := CompareKeys(f.TaskKey(task), keys)
+	if f.Descending() {
+		return c < 0
+	}
+	return c > 0
+}
+
+// Descending report whether Sort is in descending order
+func (f ClassTaskFilter) Descending() bool {
+	return strings.HasPrefix(f.Sort, "-")
+}
+
+// TaskKey is the pagination key of task in the order of Sort
+func (f ClassTaskFilter) TaskKey(task *ClassTask) []string {
+	switch strings.TrimPrefix(f.Sort, "-") {
+	case ClassTaskSortDueDate:
+		return []string{CursorTime(task.DueDate), task.TaskId}
+	case ClassTaskSortCreatedAt:
+		return []string{CursorTime(task.CreatedAt), task.TaskId}
+	}
+	return []string{task.TaskId}
 }
 
 type ClassTaskRepository interface {
 	// CreateTask should update (*ClassTask).TaskId to generated id from database or etc.
 	CreateTask(ctx context.Context, task *ClassTask) error
+	// GetTask also find occurrence of a series which is not stored, skipped occurrence does not exists
 	GetTask(ctx context.Context, taskId string) (*ClassTask, error)
+	// GetTasks list every task of the class, series are expanded within RecurrenceHorizon of now
 	GetTasks(ctx context.Context, classId string) ([]*ClassTask, error)
-	GetTasksWithRange(ctx context.Context, classId string, from, to time.Time) ([]*ClassTask, error)
+	// GetTasksWithRange is ordered by TaskId
+	GetTasksWithRange(ctx context.Context, classId string, from, to time.Time, page Pagination) ([]*ClassTask, string, error)
+	// FindTasks list tasks of the class passing the filter, ordered by filter.Sort then TaskId.
+	// Series are expanded into occurrences in filter.OccurrenceRange, listed tasks never include a series.
+	FindTasks(ctx context.Context, classId string, filter ClassTaskFilter, page Pagination) ([]*ClassTask, string, error)
+	// GetTasksByClassIds list tasks of several classes due in [from, to), it is ordered by DueDate then TaskId
+	GetTasksByClassIds(ctx context.Context, classIds []string, from, to time.Time) ([]*ClassTask, error)
+	// CreateOccurrence store occurrence returned by GetTask, nothing is done when it is already stored
+	CreateOccurrence(ctx context.Context, task *ClassTask) error
+	// SkipOccurrence store occurrence as skipped and delete its progress
+	SkipOccurrence(ctx context.Context, task *ClassTask) error
+	// GetOccurrences list stored occurrences of a series, skipped occurrences included
+	GetOccurrences(ctx context.Context, seriesId string) ([]*ClassTask, error)
+	// GetSeriesByClassIds list series of several classes followed by their stored occurrences,
+	// skipped occurrences included, it is ordered by TaskId
+	GetSeriesByClassIds(ctx context.Context, classIds []string) ([]*ClassTask, error)
+	// UpdateTask should update (*ClassTask).UpdatedAt
 	UpdateTask(ctx context.Context, task *ClassTask) error
+	// DeleteTask also delete progress of the task and stored occurrences of a series
 	DeleteTask(ctx context.Context, taskId string) error
+
+	// SetProgress create or replace progress of (*ClassTaskProgress).UserId on (*ClassTaskProgress).TaskId
+	SetProgress(ctx context.Context, progress *ClassTaskProgress) error
+	GetProgress(ctx context.Context, taskId, userId string) (*ClassTaskProgress, error)
+	// DeleteUserProgress delete progress of a user on every task of the class
+	DeleteUserProgress(ctx context.Context, classId, userId string) error
+	// GetUserProgress list progress of a user for the given tasks, task without progress is omitted
+	GetUserProgress(ctx context.Context, userId string, taskIds []string) ([]*ClassTaskProgress, error)
+	// GetProgressSummary only count InProgress and Done, Members and Todo are left to the caller
+	GetProgressSummary(ctx context.Context, taskId string) (*ClassTaskSummary, error)
 }
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+)
+
+// cursorTimeLayout is fixed width, so formatted UTC time can be compared as string
+const cursorTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// Pagination request a page of a list, zero Limit means no limit.
+// Cursor is opaque value taken from previous page, empty Cursor start from the first item.
+type Pagination struct {
+	Limit  int    `query:"limit"`
+	Cursor string `query:"cursor"`
+}
+
+// Normalize apply default and maximum limit, used for page requested by the client
+func (p Pagination) Normalize() Pagination {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+	return p
+}
+
+// Keys decode Cursor into n keys, n <= 0 accept any number of keys.
+// nil is returned for empty Cursor.
+func (p Pagination) Keys(n int) ([]string, error) {
+	if p.Cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var keys []string
+	if err := json.Unmarshal(b, &keys); err != nil || len(keys) == 0 || (n > 0 && len(keys) != n) {
+		return nil, ErrInvalidCursor
+	}
+	return keys, nil
+}
+
+func EncodeCursor(keys ...string) string {
+	b, _ := json.Marshal(keys)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func CursorTime(t time.Time) string {
+	return t.UTC().Format(cursorTimeLayout)
+}
+
+func ParseCursorTime(s string) (time.Time, error) {
+	t, err := time.Parse(cursorTimeLayout, s)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// NextPage trim items that fetched with limit + 1 and return cursor to the next page,
+// the cursor is empty when there is no more item.
+func NextPage[T any](items []T, p Pagination, key func(T) []string) ([]T, string) {
+	if p.Limit <= 0 || len(items) <= p.Limit {
+		return items, ""
+	}
+	items = items[:p.Limit]
+	return items, EncodeCursor(key(items[len(items)-1])...)
+}
+
+// Paginate select a page from items, items must be sorted ascending by key
+func Paginate[T any](items []T, p Pagination, key func(T) []string) ([]T, string, error) {
+	return paginate(items, p, key, 1)
+}
+
+// PaginateDesc is Paginate for items sorted descending by key
+func PaginateDesc[T any](items []T, p Pagination, key func(T) []string) ([]T, string, error) {
+	return paginate(items, p, key, -1)
+}
+
+func paginate[T any](items []T, p Pagination, key func(T) []string, direction int) ([]T, string, error) {
+	after, err := p.Keys(0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	start := 0
+	if after != nil {
+		start = len(items)
+		for i, item := range items {
+			if CompareKeys(key(item), after)*direction > 0 {
+				start = i
+				break
+			}
+		}
+	}
+	end := len(items)
+	if p.Limit > 0 && start+p.Limit+1 < end {
+		end = start + p.Limit + 1
+	}
+	page, next := NextPage(items[start:end], p, key)
+	return page, next, nil
+}
+
+// CompareKeys compare cursor keys one by one, shorter keys come first when one is prefix of the other
+func CompareKeys(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
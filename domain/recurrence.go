@@ -0,0 +1,211 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// frequency of Recurrence
+const (
+	RecurrenceDaily  = "DAILY"
+	RecurrenceWeekly = "WEEKLY"
+)
+
+// RecurrenceHorizon bound expansion of a series without end when the listed range has no end
+const RecurrenceHorizon = 366 * 24 * time.Hour
+
+const (
+	recurrenceDateLayout     = "20060102"
+	recurrenceDateTimeLayout = "20060102T150405Z"
+)
+
+var recurrenceWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Recurrence is the supported subset of RFC 5545 RRULE, FREQ is DAILY or WEEKLY
+// and only INTERVAL, BYDAY, UNTIL and COUNT are understood. Weeks start on monday.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	// ByDay filter the days of DAILY rule, WEEKLY rule without ByDay repeat on the day of the start
+	ByDay []time.Weekday
+	// Until is the last time an occurrence can start, zero Until never end
+	Until time.Time
+	// Count limit the number of occurrences, zero Count does not limit
+	Count int
+}
+
+// ParseRecurrence parse RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10",
+// the optional "RRULE:" prefix is accepted.
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	r := &Recurrence{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(rule, ";") {
+		name, value, found := strings.Cut(part, "=")
+		if !found || value == "" || seen[name] {
+			return nil, fmt.Errorf("%w: malformed %q", ErrInvalidRecurrence, part)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			if value != RecurrenceDaily && value != RecurrenceWeekly {
+				return nil, fmt.Errorf("%w: unsupported FREQ %q", ErrInvalidRecurrence, value)
+			}
+			r.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: INTERVAL must be a positive number", ErrInvalidRecurrence)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: COUNT must be a positive number", ErrInvalidRecurrence)
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := time.Parse(recurrenceDateTimeLayout, value)
+			if err != nil {
+				// a date include the whole day
+				until, err = time.Parse(recurrenceDateLayout, value)
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%w: UNTIL must be a date or UTC date-time", ErrInvalidRecurrence)
+			}
+			r.Until = until
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday := indexOf(recurrenceWeekdays, day)
+				if weekday < 0 {
+					return nil, fmt.Errorf("%w: unsupported BYDAY %q", ErrInvalidRecurrence, day)
+				}
+				if indexOf(r.ByDay, time.Weekday(weekday)) < 0 {
+					r.ByDay = append(r.ByDay, time.Weekday(weekday))
+				}
+			}
+			sort.Slice(r.ByDay, func(i, j int) bool {
+				return r.ByDay[i] < r.ByDay[j]
+			})
+		default:
+			return nil, fmt.Errorf("%w: unsupported %q", ErrInvalidRecurrence, name)
+		}
+	}
+	if r.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("%w: UNTIL and COUNT can not be used together", ErrInvalidRecurrence)
+	}
+	return r, nil
+}
+
+// String format the rule in canonical form, it is how the rule is stored
+func (r *Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			days = append(days, recurrenceWeekdays[day])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(recurrenceDateTimeLayout))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences list start time of occurrences in [from, to) of a series starting at start,
+// start itself is only an occurrence when it match the rule.
+func (r *Recurrence) Occurrences(start, from, to time.Time) []time.Time {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	// days between start and monday of its week
+	offset := (int(start.Weekday()) + 6) % 7
+	y, m, d := start.Date()
+
+	first := 0
+	if r.Count == 0 && from.After(start) {
+		// nothing before from need to be counted, jump close to it
+		first = int(from.Sub(start).Hours()/24) - 1
+	}
+	if first < 0 {
+		first = 0
+	}
+	occurrences := make([]time.Time, 0)
+	n := 0
+	for i := first; ; i++ {
+		t := time.Date(y, m, d+i, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		if !t.Before(to) || (!r.Until.IsZero() && t.After(r.Until)) {
+			break
+		}
+
+		var match bool
+		switch r.Freq {
+		case RecurrenceDaily:
+			match = i%interval == 0 && (len(r.ByDay) == 0 || indexOf(r.ByDay, t.Weekday()) >= 0)
+		case RecurrenceWeekly:
+			days := r.ByDay
+			if len(days) == 0 {
+				days = []time.Weekday{start.Weekday()}
+			}
+			match = (i+offset)/7%interval == 0 && indexOf(days, t.Weekday()) >= 0
+		}
+		if !match {
+			continue
+		}
+
+		n++
+		if r.Count > 0 && n > r.Count {
+			break
+		}
+		if !t.Before(from) {
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences
+}
+
+// OccurrenceId is the id of the occurrence of series seriesId which is due on date
+func OccurrenceId(seriesId string, date time.Time) string {
+	return seriesId + "_" + date.Format(recurrenceDateLayout)
+}
+
+// ParseOccurrenceId split occurrence id into its series id and the date of the occurrence
+func ParseOccurrenceId(id string) (string, time.Time, bool) {
+	i := strings.LastIndexByte(id, '_')
+	if i <= 0 {
+		return "", time.Time{}, false
+	}
+	date, err := time.Parse(recurrenceDateLayout, id[i+1:])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return id[:i], date, true
+}
+
+func indexOf[T comparable](items []T, item T) int {
+	for i, v := range items {
+		if v == item {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 50
+	// MaxSearchQuery is the maximum length of a search query in bytes
+	MaxSearchQuery = 100
+)
+
+// Search rank, a higher rank is listed first
+const (
+	SearchRankNone = iota
+	// every term of the query is a prefix of a word in the searched text
+	SearchRankWord
+	// the name start with the query
+	SearchRankPrefix
+	// the name is the query
+	SearchRankExact
+)
+
+// SearchResult of classes and users matching a query, see SearchRank
+type SearchResult struct {
+	Classes []*Class `json:"classes"`
+	Users   []*User  `json:"users"`
+}
+
+// SearchTerms split query into lowercase words of letters and digits,
+// similar to words produced by the postgres 'simple' text search configuration.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchRank rank how well a record named name and described by text match
+// query, matching is case-insensitive. Repositories order matched records by
+// rank, then by lowercase name.
+func SearchRank(query, name string, text ...string) int {
+	query = strings.ToLower(strings.TrimSpace(query))
+	name = strings.ToLower(name)
+	if query == "" {
+		return SearchRankNone
+	}
+	if name == query {
+		return SearchRankExact
+	}
+	if strings.HasPrefix(name, query) {
+		return SearchRankPrefix
+	}
+
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return SearchRankNone
+	}
+	words := SearchTerms(name + " " + strings.Join(text, " "))
+	for _, term := range terms {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return SearchRankNone
+		}
+	}
+	return SearchRankWord
+}
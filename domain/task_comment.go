@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrTaskCommentNotExists = errors.New("task comment does not exists")
+)
+
+// TaskComment is a message on a task, a comment with ParentId is a reply.
+// Replies are one level deep, the parent of a reply must not be a reply.
+type TaskComment struct {
+	CommentId string    `json:"commentId"`          // immutable, unique
+	ClassId   string    `json:"classId"`            // immutable
+	TaskId    string    `json:"taskId"`             // immutable
+	AuthorId  string    `json:"authorId"`           // immutable
+	ParentId  string    `json:"parentId,omitempty"` // immutable, empty for top level comment
+	CreatedAt time.Time `json:"createdAt"`          // immutable
+
+	Body string `json:"body" validate:"required,max=2000"` // mutable
+
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"` // mutable, set by UpdateComment
+}
+
+type TaskCommentRepository interface {
+	// CreateComment should update (*TaskComment).CommentId and (*TaskComment).CreatedAt
+	CreateComment(ctx context.Context, comment *TaskComment) error
+	GetComment(ctx context.Context, commentId string) (*TaskComment, error)
+	// ListComments list comments and replies of a task, it is ordered by CreatedAt then CommentId
+	// so a reply always come after its parent.
+	ListComments(ctx context.Context, taskId string, page Pagination) ([]*TaskComment, string, error)
+	// UpdateComment update Body and should update (*TaskComment).UpdatedAt
+	UpdateComment(ctx context.Context, comment *TaskComment) error
+	// DeleteComment delete the comment and its replies
+	DeleteComment(ctx context.Context, commentId string) error
+}
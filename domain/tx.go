@@ -0,0 +1,15 @@
+package domain
+
+import "context"
+
+// TxRunner run multiple repository calls as a single unit of work.
+type TxRunner interface {
+	// RunInTx run fn inside a transaction, repositories called with the ctx
+	// given to fn take part in the transaction. The transaction is rolled
+	// back when fn return an error. Nested calls join the outer transaction.
+	RunInTx(ctx context.Context, fn func(ctx context.Context) error) error
+	// AfterCommit run fn once the transaction of ctx is committed, fn is
+	// dropped when the transaction is rolled back. Outside of a transaction
+	// fn is run immediately.
+	AfterCommit(ctx context.Context, fn func())
+}
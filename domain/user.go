@@ -40,11 +40,19 @@ func (u *User) Update(uu *User) {
 	}
 }
 
+// UserCache keep resolved users, it is told to forget a user after the user is updated
+type UserCache interface {
+	Invalidate(userId string)
+}
+
 type UserRepository interface {
 	// create user takes an (*User) and use the UserId as id, it becaues the id came from third party authentication service
 	CreateUser(ctx context.Context, user *User) error
 	GetUserByUserId(ctx context.Context, id string) (*User, error)
 	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	// SearchUsers list at most limit users whose username or name match query, ordered
+	// by SearchRank of the username then by lowercase username. Email is not returned.
+	SearchUsers(ctx context.Context, query string, limit int) ([]*User, error)
 	DeleteUser(ctx context.Context, id string) error
 	// update user, consider using (*User).Update(*User) to avoid overwrite immutable fields
 	UpdateUser(ctx context.Context, user *User) error
@@ -0,0 +1,83 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"nory/domain"
+)
+
+// BlobStoreFs keep blobs as files under root directory
+type BlobStoreFs struct {
+	root string
+}
+
+func NewBlobStoreFs(root string) (*BlobStoreFs, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &BlobStoreFs{root}, nil
+}
+
+// file map key to a path under root, key that escape root is rejected
+func (bs *BlobStoreFs) file(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return "", domain.ErrInvalidBlobKey
+	}
+	return filepath.Join(bs.root, filepath.FromSlash(key)), nil
+}
+
+func (bs *BlobStoreFs) Put(ctx context.Context, key string, r io.Reader) error {
+	name, err := bs.file(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	// write to temporary file first, so reader never see partially written blob
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (bs *BlobStoreFs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := bs.file(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, domain.ErrBlobNotExists
+	}
+	return f, err
+}
+
+func (bs *BlobStoreFs) Delete(ctx context.Context, key string) error {
+	name, err := bs.file(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
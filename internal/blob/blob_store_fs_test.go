@@ -0,0 +1,50 @@
+package blob_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"nory/domain"
+	. "nory/internal/blob"
+)
+
+func TestBlobStoreFs(t *testing.T) {
+	t.Parallel()
+	store, err := NewBlobStoreFs(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	ctx := context.Background()
+	_, err = store.Get(ctx, "foo/bar")
+	assert.Equal(t, domain.ErrBlobNotExists, err)
+
+	err = store.Put(ctx, "foo/bar", bytes.NewBufferString("hello"))
+	assert.Nil(t, err)
+	err = store.Put(ctx, "foo/bar", bytes.NewBufferString("world"))
+	assert.Nil(t, err, "Put should replace existing blob")
+
+	r, err := store.Get(ctx, "foo/bar")
+	if assert.Nil(t, err) {
+		b, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		assert.Equal(t, "world", string(b))
+		r.Close()
+	}
+
+	for _, key := range []string{"", "/foo", "../foo", "foo/../../bar", "foo//bar", "./foo"} {
+		err := store.Put(ctx, key, bytes.NewBufferString("x"))
+		assert.Equal(t, domain.ErrInvalidBlobKey, err, key)
+	}
+
+	err = store.Delete(ctx, "foo/bar")
+	assert.Nil(t, err)
+	_, err = store.Get(ctx, "foo/bar")
+	assert.Equal(t, domain.ErrBlobNotExists, err)
+	err = store.Delete(ctx, "foo/bar")
+	assert.Nil(t, err, "deleting missing blob should not fail")
+}
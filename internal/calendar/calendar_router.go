@@ -0,0 +1,103 @@
+package calendar
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"nory/common/auth"
+)
+
+type calendarRouter struct {
+	cs CalendarService
+}
+
+func Route(calendarService CalendarService) func(router fiber.Router) {
+	if calendarService.ClassRepository == nil {
+		panic("calendarRoute: nil CalendarService.ClassRepository")
+	}
+	if calendarService.ClassTaskRepository == nil {
+		panic("calendarRoute: nil CalendarService.ClassTaskRepository")
+	}
+	if calendarService.ClassMemberRepository == nil {
+		panic("calendarRoute: nil CalendarService.ClassMemberRepository")
+	}
+	if calendarService.ClassScheduleRepository == nil {
+		panic("calendarRoute: nil CalendarService.ClassScheduleRepository")
+	}
+	if calendarService.CalendarTokenRepository == nil {
+		panic("calendarRoute: nil CalendarService.CalendarTokenRepository")
+	}
+
+	cr := calendarRouter{calendarService}
+	return func(router fiber.Router) {
+		router.Get("/token", cr.getToken)
+		router.Post("/token", cr.createToken)
+		router.Delete("/token", cr.deleteToken)
+		router.Get("/:token/feed.ics", cr.userFeed)
+		router.Get("/:token/class/:classId/feed.ics", cr.classFeed)
+	}
+}
+
+func (cr calendarRouter) getToken(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cr.cs.GetToken(c.Context(), user.UserId)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr calendarRouter) createToken(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cr.cs.CreateToken(c.Context(), user.UserId)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr calendarRouter) deleteToken(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cr.cs.DeleteToken(c.Context(), user.UserId)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr calendarRouter) userFeed(c *fiber.Ctx) error {
+	feed, err := cr.cs.UserFeed(c.Context(), c.Params("token"))
+	if err != nil {
+		return err
+	}
+
+	return sendCalendar(c, feed)
+}
+
+func (cr calendarRouter) classFeed(c *fiber.Ctx) error {
+	feed, err := cr.cs.ClassFeed(c.Context(), c.Params("token"), c.Params("classId"))
+	if err != nil {
+		return err
+	}
+
+	return sendCalendar(c, feed)
+}
+
+func sendCalendar(c *fiber.Ctx, feed string) error {
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	return c.SendString(feed)
+}
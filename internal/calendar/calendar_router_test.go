@@ -0,0 +1,67 @@
+package calendar_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"nory/common/auth"
+	"nory/common/response"
+	"nory/domain"
+	. "nory/internal/calendar"
+)
+
+func TestCalendarRouter(t *testing.T) {
+	t.Parallel()
+	cs := newCalendarService()
+
+	app := fiber.New(fiber.Config{
+		Immutable:    true,
+		ErrorHandler: response.ErrorHandler,
+	})
+	app.Use(auth.MockMiddleware)
+	app.Route("/", Route(cs))
+
+	userId := uuid.NewString()
+	class := createClass(t, cs, "math", userId)
+
+	req := httptest.NewRequest("POST", "/token", nil)
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 401, resp.StatusCode)
+
+	req.Header.Set("user-id", userId)
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	var token response.Response[*domain.CalendarToken]
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	assert.Nil(t, err)
+
+	for _, p := range []string{
+		fmt.Sprintf("/%s/feed.ics", token.Data.Token),
+		fmt.Sprintf("/%s/class/%s/feed.ics", token.Data.Token, class.ClassId),
+	} {
+		req = httptest.NewRequest("GET", p, nil)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "text/calendar; charset=utf-8", resp.Header.Get("content-type"))
+	}
+
+	req = httptest.NewRequest("DELETE", "/token", nil)
+	req.Header.Set("user-id", userId)
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/%s/feed.ics", token.Data.Token), nil)
+	resp, err = app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+}
@@ -0,0 +1,171 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"nory/common/response"
+	"nory/domain"
+)
+
+// feedWindow bound tasks written to a feed to those due within it from the time the feed is read,
+// recurring tasks are written as a single recurring event instead
+const feedWindow = 366 * 24 * time.Hour
+
+type CalendarService struct {
+	ClassRepository         domain.ClassRepository
+	ClassTaskRepository     domain.ClassTaskRepository
+	ClassMemberRepository   domain.ClassMemberRepository
+	ClassScheduleRepository domain.ClassScheduleRepository
+	CalendarTokenRepository domain.CalendarTokenRepository
+}
+
+func (cs *CalendarService) GetToken(ctx context.Context, userId string) (*response.Response[*domain.CalendarToken], error) {
+	token, err := cs.CalendarTokenRepository.GetTokenByUserId(ctx, userId)
+	if errors.Is(err, domain.ErrCalendarTokenNotExists) {
+		return nil, response.NewNotFound("calendar.token_not_found", response.Params{"userId": userId})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response.New(200, token), nil
+}
+
+// CreateToken create new calendar token, existing token of the user is revoked
+func (cs *CalendarService) CreateToken(ctx context.Context, userId string) (*response.Response[*domain.CalendarToken], error) {
+	token := &domain.CalendarToken{
+		UserId: userId,
+	}
+	if err := cs.CalendarTokenRepository.CreateToken(ctx, token); err != nil {
+		return nil, err
+	}
+	return response.New(200, token), nil
+}
+
+func (cs *CalendarService) DeleteToken(ctx context.Context, userId string) (*response.Response[any], error) {
+	if err := cs.CalendarTokenRepository.DeleteToken(ctx, userId); err != nil {
+		return nil, err
+	}
+	return response.New[any](204, nil), nil
+}
+
+// UserFeed render schedules and tasks of every class joined by owner of the token
+func (cs *CalendarService) UserFeed(ctx context.Context, token string) (string, error) {
+	t, err := cs.getToken(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	members, _, err := cs.ClassMemberRepository.ListJoined(ctx, t.UserId, domain.Pagination{})
+	if err != nil {
+		return "", err
+	}
+
+	classIds := make([]string, 0, len(members))
+	for _, member := range members {
+		classIds = append(classIds, member.ClassId)
+	}
+	// deleted classes are omitted
+	classes, err := cs.ClassRepository.GetClassesByIds(ctx, classIds)
+	if err != nil {
+		return "", err
+	}
+
+	ical := newICalendar("nory", time.Now())
+	if err := cs.writeClasses(ctx, ical, classes); err != nil {
+		return "", err
+	}
+	return ical.String(), nil
+}
+
+// ClassFeed render schedules and tasks of a class, owner of the token must be a member of the class
+func (cs *CalendarService) ClassFeed(ctx context.Context, token, classId string) (string, error) {
+	t, err := cs.getToken(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	notFound := response.NewNotFound("class.not_found", response.Params{"classId": classId})
+	_, err = cs.ClassMemberRepository.GetMember(ctx, &domain.ClassMember{
+		ClassId: classId,
+		UserId:  t.UserId,
+	})
+	if errors.Is(err, domain.ErrClassMemberNotExists) {
+		return "", notFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	class, err := cs.ClassRepository.GetClass(ctx, classId)
+	if errors.Is(err, domain.ErrClassNotExists) {
+		return "", notFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	ical := newICalendar(class.Name, time.Now())
+	if err := cs.writeClasses(ctx, ical, []*domain.Class{class}); err != nil {
+		return "", err
+	}
+	return ical.String(), nil
+}
+
+// writeClasses write schedules and tasks of the classes, every list is read once for all classes
+func (cs *CalendarService) writeClasses(ctx context.Context, ical *icalendar, classes []*domain.Class) error {
+	if len(classes) == 0 {
+		return nil
+	}
+	classIds := make([]string, 0, len(classes))
+	names := make(map[string]string, len(classes))
+	for _, class := range classes {
+		classIds = append(classIds, class.ClassId)
+		names[class.ClassId] = class.Name
+	}
+
+	schedules, err := cs.ClassScheduleRepository.GetSchedulesByClassIds(ctx, classIds)
+	if err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		ical.addSchedule(names[schedule.ClassId], schedule)
+	}
+
+	tasks, err := cs.ClassTaskRepository.GetTasksByClassIds(ctx, classIds, ical.now.Add(-feedWindow), ical.now.Add(feedWindow))
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		// occurrences are written with their series
+		if task.SeriesId == "" {
+			ical.addTask(names[task.ClassId], task)
+		}
+	}
+
+	series, err := cs.ClassTaskRepository.GetSeriesByClassIds(ctx, classIds)
+	if err != nil {
+		return err
+	}
+	occurrences := make(map[string][]*domain.ClassTask)
+	for _, task := range series {
+		if task.SeriesId != "" {
+			occurrences[task.SeriesId] = append(occurrences[task.SeriesId], task)
+		}
+	}
+	for _, task := range series {
+		if task.Recurring() {
+			ical.addSeries(names[task.ClassId], task, occurrences[task.TaskId])
+		}
+	}
+	return nil
+}
+
+func (cs *CalendarService) getToken(ctx context.Context, token string) (*domain.CalendarToken, error) {
+	t, err := cs.CalendarTokenRepository.GetToken(ctx, token)
+	if errors.Is(err, domain.ErrCalendarTokenNotExists) {
+		return nil, response.NewNotFound("calendar.feed_not_found", nil)
+	}
+	return t, err
+}
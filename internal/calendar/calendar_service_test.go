@@ -0,0 +1,158 @@
+package calendar_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"nory/domain"
+	. "nory/internal/calendar"
+	"nory/internal/class"
+	classmember "nory/internal/class_member"
+	classschedule "nory/internal/class_schedule"
+	classtask "nory/internal/class_task"
+
+	"github.com/google/uuid"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCalendarService() CalendarService {
+	return CalendarService{
+		ClassRepository:         class.NewClassRepositoryMem(),
+		ClassTaskRepository:     classtask.NewClassTaskRepositoryMem(),
+		ClassMemberRepository:   classmember.NewClassMemberRepositoryMem(),
+		ClassScheduleRepository: classschedule.NewClassScheduleRepositoryMem(),
+		CalendarTokenRepository: NewCalendarTokenRepositoryMem(),
+	}
+}
+
+func createClass(t *testing.T, cs CalendarService, name, userId string) *domain.Class {
+	c := &domain.Class{OwnerId: uuid.NewString(), Name: name}
+	err := cs.ClassRepository.CreateClass(context.Background(), c)
+	assert.Nil(t, err)
+	err = cs.ClassMemberRepository.CreateMember(context.Background(), &domain.ClassMember{
+		ClassId: c.ClassId,
+		UserId:  userId,
+		Level:   "member",
+	})
+	assert.Nil(t, err)
+	return c
+}
+
+func TestCalendarService(t *testing.T) {
+	t.Parallel()
+	cs := newCalendarService()
+	userId := uuid.NewString()
+
+	math := createClass(t, cs, "math", userId)
+	art := createClass(t, cs, "art, music", userId)
+	// membership of a deleted class is skipped
+	err := cs.ClassMemberRepository.CreateMember(context.Background(), &domain.ClassMember{
+		ClassId: xid.New().String(),
+		UserId:  userId,
+		Level:   "member",
+	})
+	assert.Nil(t, err)
+
+	err = cs.ClassScheduleRepository.CreateSchedule(context.Background(), &domain.ClassSchedule{
+		ClassId:   math.ClassId,
+		AuthorId:  math.OwnerId,
+		CreatedAt: time.Date(2022, time.October, 10, 0, 0, 0, 0, time.UTC), // monday
+		Name:      "algebra",
+		StartAt:   time.Date(0, 1, 1, 7, 30, 0, 0, time.FixedZone("WIB", 7*60*60)),
+		Duration:  90,
+		Day:       int8(time.Wednesday),
+	})
+	assert.Nil(t, err)
+	due := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 2)
+	date := func(days int) string {
+		return due.AddDate(0, 0, days).Format("20060102")
+	}
+	err = cs.ClassTaskRepository.CreateTask(context.Background(), &domain.ClassTask{
+		ClassId:     art.ClassId,
+		AuthorId:    art.OwnerId,
+		Name:        "drawing",
+		Description: strings.Repeat("draw; a cat\n", 10),
+		DueDate:     due,
+	})
+	assert.Nil(t, err)
+	err = cs.ClassTaskRepository.CreateTask(context.Background(), &domain.ClassTask{
+		ClassId:  art.ClassId,
+		AuthorId: art.OwnerId,
+		Name:     "sculpting",
+		DueDate:  time.Date(2022, time.October, 12, 0, 0, 0, 0, time.UTC),
+	})
+	assert.Nil(t, err)
+
+	// recurring task with a skipped and an edited occurrence
+	series := &domain.ClassTask{
+		ClassId:    art.ClassId,
+		AuthorId:   art.OwnerId,
+		Name:       "sketch",
+		DueDate:    due,
+		Recurrence: "FREQ=WEEKLY;UNTIL=" + date(60),
+	}
+	err = cs.ClassTaskRepository.CreateTask(context.Background(), series)
+	assert.Nil(t, err)
+	skipped, err := cs.ClassTaskRepository.GetTask(context.Background(), domain.OccurrenceId(series.TaskId, due.AddDate(0, 0, 7)))
+	assert.Nil(t, err)
+	err = cs.ClassTaskRepository.SkipOccurrence(context.Background(), skipped)
+	assert.Nil(t, err)
+	edited, err := cs.ClassTaskRepository.GetTask(context.Background(), domain.OccurrenceId(series.TaskId, due.AddDate(0, 0, 14)))
+	assert.Nil(t, err)
+	err = cs.ClassTaskRepository.CreateOccurrence(context.Background(), edited)
+	assert.Nil(t, err)
+	err = cs.ClassTaskRepository.UpdateTask(context.Background(), &domain.ClassTask{TaskId: edited.TaskId, Name: "portrait", DueDate: due.AddDate(0, 0, 15)})
+	assert.Nil(t, err)
+
+	_, err = cs.UserFeed(context.Background(), xid.New().String())
+	assert.NotNil(t, err)
+	_, err = cs.GetToken(context.Background(), userId)
+	assert.NotNil(t, err)
+
+	token, err := cs.CreateToken(context.Background(), userId)
+	assert.Nil(t, err)
+
+	feed, err := cs.UserFeed(context.Background(), token.Data.Token)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(feed, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(feed, "END:VCALENDAR\r\n"))
+	assert.Equal(t, 4, strings.Count(feed, "BEGIN:VEVENT"))
+	for _, line := range []string{
+		"DTSTART:20221012T073000",
+		"DURATION:PT90M",
+		"RRULE:FREQ=WEEKLY;BYDAY=WE",
+		"SUMMARY:[math] algebra",
+		"DTSTART;VALUE=DATE:" + date(0),
+		"DTEND;VALUE=DATE:" + date(1),
+		`SUMMARY:[art\, music] drawing`,
+		"RRULE:FREQ=WEEKLY;UNTIL=" + date(60),
+		"EXDATE;VALUE=DATE:" + date(7),
+		"RECURRENCE-ID;VALUE=DATE:" + date(14),
+		"DTSTART;VALUE=DATE:" + date(15),
+		`SUMMARY:[art\, music] portrait`,
+	} {
+		assert.Contains(t, feed, line+"\r\n")
+	}
+	assert.NotContains(t, feed, "sculpting", "task far in the past should not be written")
+	assert.Equal(t, 2, strings.Count(feed, "UID:task-"+series.TaskId+"@nory\r\n"), "occurrences should be written with their series")
+	for _, line := range strings.Split(feed, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, "line should be folded")
+	}
+	assert.Contains(t, strings.ReplaceAll(feed, "\r\n ", ""), `DESCRIPTION:draw\; a cat\ndraw\; a cat\n`)
+
+	feed, err = cs.ClassFeed(context.Background(), token.Data.Token, math.ClassId)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(feed, "BEGIN:VEVENT"))
+
+	other := createClass(t, cs, "other", uuid.NewString())
+	_, err = cs.ClassFeed(context.Background(), token.Data.Token, other.ClassId)
+	assert.NotNil(t, err, "only member can read class feed")
+
+	_, err = cs.DeleteToken(context.Background(), userId)
+	assert.Nil(t, err)
+	_, err = cs.UserFeed(context.Background(), token.Data.Token)
+	assert.NotNil(t, err, "revoked token should not be able to read feed")
+}
@@ -0,0 +1,14 @@
+package calendar
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+func generateToken() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
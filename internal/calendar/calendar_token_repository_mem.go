@@ -0,0 +1,63 @@
+package calendar
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type CalendarTokenRepositoryMem struct {
+	mx sync.Mutex
+	m  map[string]*domain.CalendarToken
+}
+
+func NewCalendarTokenRepositoryMem() *CalendarTokenRepositoryMem {
+	return &CalendarTokenRepositoryMem{
+		m: make(map[string]*domain.CalendarToken),
+	}
+}
+
+func (repo *CalendarTokenRepositoryMem) CreateToken(ctx context.Context, token *domain.CalendarToken) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	token.Token = generateToken()
+	token.CreatedAt = time.Now().UTC()
+	t := *token
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, token.UserId)
+	repo.m[token.UserId] = &t
+	return nil
+}
+
+func (repo *CalendarTokenRepositoryMem) GetToken(ctx context.Context, token string) (*domain.CalendarToken, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	for _, t := range repo.m {
+		if t.Token == token {
+			tt := *t
+			return &tt, nil
+		}
+	}
+	return nil, domain.ErrCalendarTokenNotExists
+}
+
+func (repo *CalendarTokenRepositoryMem) GetTokenByUserId(ctx context.Context, userId string) (*domain.CalendarToken, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	t, ok := repo.m[userId]
+	if !ok {
+		return nil, domain.ErrCalendarTokenNotExists
+	}
+	tt := *t
+	return &tt, nil
+}
+
+func (repo *CalendarTokenRepositoryMem) DeleteToken(ctx context.Context, userId string) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, userId)
+	delete(repo.m, userId)
+	return nil
+}
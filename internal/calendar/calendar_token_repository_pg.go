@@ -0,0 +1,78 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type CalendarTokenRepositoryPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewCalendarTokenRepositoryPostgres(pool *pgxpool.Pool) *CalendarTokenRepositoryPostgres {
+	return &CalendarTokenRepositoryPostgres{pool}
+}
+
+func (repo *CalendarTokenRepositoryPostgres) CreateToken(ctx context.Context, token *domain.CalendarToken) error {
+	token.Token = generateToken()
+	row := database.Conn(ctx, repo.pool).QueryRow(
+		ctx,
+		`INSERT INTO calendar_token(user_id, token) VALUES($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token = excluded.token, created_at = NOW()
+		RETURNING created_at`,
+		token.UserId,
+		token.Token,
+	)
+	return row.Scan(&token.CreatedAt)
+}
+
+func (repo *CalendarTokenRepositoryPostgres) GetToken(ctx context.Context, token string) (*domain.CalendarToken, error) {
+	t := &domain.CalendarToken{
+		Token: token,
+	}
+	row := database.Conn(ctx, repo.pool).QueryRow(ctx, "SELECT user_id, created_at FROM calendar_token WHERE token = $1", token)
+	err := row.Scan(
+		&t.UserId,
+		&t.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCalendarTokenNotExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (repo *CalendarTokenRepositoryPostgres) GetTokenByUserId(ctx context.Context, userId string) (*domain.CalendarToken, error) {
+	t := &domain.CalendarToken{
+		UserId: userId,
+	}
+	row := database.Conn(ctx, repo.pool).QueryRow(ctx, "SELECT token, created_at FROM calendar_token WHERE user_id = $1", userId)
+	err := row.Scan(
+		&t.Token,
+		&t.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCalendarTokenNotExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (repo *CalendarTokenRepositoryPostgres) DeleteToken(ctx context.Context, userId string) error {
+	_, err := database.Conn(ctx, repo.pool).Exec(
+		ctx,
+		"DELETE FROM calendar_token WHERE user_id = $1",
+		userId,
+	)
+	return err
+}
@@ -0,0 +1,88 @@
+package calendar_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"nory/domain"
+	. "nory/internal/calendar"
+	"nory/internal/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendarTokenRepository(t *testing.T) {
+	t.Parallel()
+	pool, err := pgxpool.New(context.Background(), os.Getenv("DATABASE_URL"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, repo := range []struct {
+		Name                    string
+		CalendarTokenRepository domain.CalendarTokenRepository
+		UserRepository          domain.UserRepository
+		Skip                    bool
+	}{
+		{
+			Name:                    "memory",
+			CalendarTokenRepository: NewCalendarTokenRepositoryMem(),
+			UserRepository:          user.NewUserRepositoryMem(),
+		},
+		{
+			Name:                    "postgres",
+			CalendarTokenRepository: NewCalendarTokenRepositoryPostgres(pool),
+			UserRepository:          user.NewUserRepositoryPostgres(pool),
+			Skip:                    os.Getenv("DATABASE_URL") == "",
+		},
+	} {
+		repo := repo
+		t.Run(repo.Name, func(t *testing.T) {
+			if repo.Skip {
+				t.Skipf("skipping %s", repo.Name)
+			}
+			t.Parallel()
+
+			u := &domain.User{
+				UserId:   uuid.NewString(),
+				Email:    xid.New().String(),
+				Username: xid.New().String(),
+			}
+			err := repo.UserRepository.CreateUser(context.Background(), u)
+			assert.Nil(t, err)
+
+			_, err = repo.CalendarTokenRepository.GetTokenByUserId(context.Background(), u.UserId)
+			assert.Equal(t, domain.ErrCalendarTokenNotExists, err)
+
+			first := &domain.CalendarToken{UserId: u.UserId}
+			err = repo.CalendarTokenRepository.CreateToken(context.Background(), first)
+			assert.Nil(t, err)
+			assert.NotEqual(t, "", first.Token, "CreateToken should update (*CalendarToken).Token to generated token")
+
+			token, err := repo.CalendarTokenRepository.GetToken(context.Background(), first.Token)
+			assert.Nil(t, err)
+			assert.Equal(t, u.UserId, token.UserId)
+
+			second := &domain.CalendarToken{UserId: u.UserId}
+			err = repo.CalendarTokenRepository.CreateToken(context.Background(), second)
+			assert.Nil(t, err)
+			assert.NotEqual(t, first.Token, second.Token)
+
+			_, err = repo.CalendarTokenRepository.GetToken(context.Background(), first.Token)
+			assert.Equal(t, domain.ErrCalendarTokenNotExists, err, "old token should be revoked")
+
+			token, err = repo.CalendarTokenRepository.GetTokenByUserId(context.Background(), u.UserId)
+			assert.Nil(t, err)
+			assert.Equal(t, second.Token, token.Token)
+
+			err = repo.CalendarTokenRepository.DeleteToken(context.Background(), u.UserId)
+			assert.Nil(t, err)
+			_, err = repo.CalendarTokenRepository.GetToken(context.Background(), second.Token)
+			assert.Equal(t, domain.ErrCalendarTokenNotExists, err)
+		})
+	}
+}
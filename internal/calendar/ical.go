@@ -0,0 +1,155 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"nory/domain"
+)
+
+const (
+	icalDateTime = "20060102T150405Z"
+	// floating time is shown at the same wall clock time in every time zone
+	icalFloatingTime = "20060102T150405"
+	icalDate         = "20060102"
+	// rfc 5545 section 3.1, lines should not be longer than 75 octets
+	icalLineLimit = 75
+)
+
+var icalWeekdays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// icalendar is a minimal RFC 5545 writer, it only support properties used by nory feeds.
+type icalendar struct {
+	b   strings.Builder
+	now time.Time
+}
+
+func newICalendar(name string, now time.Time) *icalendar {
+	ic := &icalendar{now: now.UTC()}
+	ic.line("BEGIN:VCALENDAR")
+	ic.line("VERSION:2.0")
+	ic.line("PRODID:-//nory//calendar//EN")
+	ic.line("CALSCALE:GREGORIAN")
+	ic.line("METHOD:PUBLISH")
+	ic.line("X-WR-CALNAME:" + escapeText(name))
+	return ic
+}
+
+// addSchedule add schedule as weekly recurring event, the first occurrence is on the week the schedule created.
+// StartAt is a time of day without time zone, so the event start at floating time.
+func (ic *icalendar) addSchedule(className string, schedule *domain.ClassSchedule) {
+	day := (int(schedule.Day)%7 + 7) % 7
+	base := schedule.CreatedAt
+	if base.IsZero() {
+		base = ic.now
+	}
+	base = base.UTC()
+	startAt := schedule.StartAt
+	start := time.Date(base.Year(), base.Month(), base.Day(), startAt.Hour(), startAt.Minute(), startAt.Second(), 0, time.UTC)
+	start = start.AddDate(0, 0, (day-int(start.Weekday())+7)%7)
+
+	ic.line("BEGIN:VEVENT")
+	ic.line(fmt.Sprintf("UID:schedule-%s@nory", schedule.ScheduleId))
+	ic.line("DTSTAMP:" + ic.now.Format(icalDateTime))
+	ic.line("DTSTART:" + start.Format(icalFloatingTime))
+	ic.line(fmt.Sprintf("DURATION:PT%dM", schedule.Duration))
+	ic.line("RRULE:FREQ=WEEKLY;BYDAY=" + icalWeekdays[day])
+	ic.line("SUMMARY:" + escapeText(summary(className, schedule.Name)))
+	ic.line("END:VEVENT")
+}
+
+// addTask add task as all day event on its due date
+func (ic *icalendar) addTask(className string, task *domain.ClassTask) {
+	ic.task(className, task, task.TaskId)
+}
+
+// addSeries add recurring task as a single recurring all day event, skipped occurrences are excluded
+// and every other stored occurrence override the occurrence on its original day
+func (ic *icalendar) addSeries(className string, series *domain.ClassTask, occurrences []*domain.ClassTask) {
+	rule, err := domain.ParseRecurrence(series.Recurrence)
+	if err != nil {
+		return
+	}
+	// UNTIL of all day event must be a date
+	until := ""
+	if !rule.Until.IsZero() {
+		until = ";UNTIL=" + rule.Until.UTC().Format(icalDate)
+		rule.Until = time.Time{}
+	}
+	props := []string{"RRULE:" + rule.String() + until}
+	for _, occurrence := range occurrences {
+		if _, date, ok := domain.ParseOccurrenceId(occurrence.TaskId); ok && occurrence.Skipped {
+			props = append(props, "EXDATE;VALUE=DATE:"+date.Format(icalDate))
+		}
+	}
+	ic.task(className, series, series.TaskId, props...)
+
+	for _, occurrence := range occurrences {
+		if _, date, ok := domain.ParseOccurrenceId(occurrence.TaskId); ok && !occurrence.Skipped {
+			ic.task(className, occurrence, series.TaskId, "RECURRENCE-ID;VALUE=DATE:"+date.Format(icalDate))
+		}
+	}
+}
+
+// task write all day event of a task, uid is the task id of the event, props is written before SUMMARY
+func (ic *icalendar) task(className string, task *domain.ClassTask, uid string, props ...string) {
+	due := task.DueDate.UTC()
+	ic.line("BEGIN:VEVENT")
+	ic.line(fmt.Sprintf("UID:task-%s@nory", uid))
+	ic.line("DTSTAMP:" + ic.now.Format(icalDateTime))
+	ic.line("DTSTART;VALUE=DATE:" + due.Format(icalDate))
+	ic.line("DTEND;VALUE=DATE:" + due.AddDate(0, 0, 1).Format(icalDate))
+	for _, prop := range props {
+		ic.line(prop)
+	}
+	ic.line("SUMMARY:" + escapeText(summary(className, task.Name)))
+	if task.Description != "" {
+		ic.line("DESCRIPTION:" + escapeText(task.Description))
+	}
+	ic.line("END:VEVENT")
+}
+
+func (ic *icalendar) String() string {
+	ic.line("END:VCALENDAR")
+	return ic.b.String()
+}
+
+// line write content line, folding it when it is longer than icalLineLimit
+func (ic *icalendar) line(s string) {
+	limit := icalLineLimit
+	for len(s) > limit {
+		i := limit
+		// do not split multi bytes character
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+		ic.b.WriteString(s[:i])
+		ic.b.WriteString("\r\n ")
+		s = s[i:]
+		// continuation line start with a space
+		limit = icalLineLimit - 1
+	}
+	ic.b.WriteString(s)
+	ic.b.WriteString("\r\n")
+}
+
+func summary(className, name string) string {
+	if className == "" {
+		return name
+	}
+	return fmt.Sprintf("[%s] %s", className, name)
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
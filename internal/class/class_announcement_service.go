@@ -0,0 +1,163 @@
+package class
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"nory/common/response"
+	"nory/common/validator"
+	"nory/domain"
+)
+
+// CreateAnnouncement post announcement to the class, only class admin can post
+func (cs *ClassService) CreateAnnouncement(ctx context.Context, userId, classId string, announcement *domain.ClassAnnouncement) (*response.Response[*domain.ClassAnnouncement], error) {
+	announcement.ClassId = classId
+	announcement.AuthorId = userId
+	if err := validateAnnouncement(announcement); err != nil {
+		return nil, err
+	}
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityAnnounce); err != nil {
+		return nil, err
+	}
+
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		if err := cs.ClassAnnouncementRepository.CreateAnnouncement(ctx, announcement); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditAnnouncementCreate, announcement.AnnouncementId, nil, snapshot(announcement)); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventAnnouncementPosted, snapshot(announcement))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New(200, announcement), nil
+}
+
+// ListAnnouncements list announcements of the class with pinned announcements first,
+// expired announcements are only listed for class admin that ask for them.
+func (cs *ClassService) ListAnnouncements(ctx context.Context, userId, classId string, expired bool, page domain.Pagination) (*response.Response[[]*domain.ClassAnnouncement], error) {
+	capability := domain.CapabilityViewClass
+	if expired {
+		capability = domain.CapabilityAnnounce
+	}
+	if err := cs.AccessClass(ctx, userId, classId, capability); err != nil {
+		return nil, err
+	}
+	activeAt := time.Now()
+	if expired {
+		activeAt = time.Time{}
+	}
+	page = page.Normalize()
+	announcements, next, err := cs.ClassAnnouncementRepository.ListAnnouncements(ctx, classId, activeAt, page)
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return nil, response.NewBadRequest("pagination.invalid_cursor", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response.NewPaginated(200, announcements, page.Limit, next), nil
+}
+
+// GetAnnouncement get announcement of the class, expired announcement is only visible to class admin
+func (cs *ClassService) GetAnnouncement(ctx context.Context, userId, classId, announcementId string) (*response.Response[*domain.ClassAnnouncement], error) {
+	member, err := cs.accessClass(ctx, userId, classId, domain.CapabilityViewClass)
+	if err != nil {
+		return nil, err
+	}
+	announcement, err := cs.getAnnouncement(ctx, classId, announcementId)
+	if err != nil {
+		return nil, err
+	}
+	if announcement.Expired(time.Now()) && !domain.RoleAllows(member.Level, domain.CapabilityAnnounce) {
+		return nil, announcementNotFound(classId, announcementId)
+	}
+	return response.New(200, announcement), nil
+}
+
+// UpdateAnnouncement replace title, body, pinned flag and expiry of the announcement
+func (cs *ClassService) UpdateAnnouncement(ctx context.Context, userId, classId string, announcement *domain.ClassAnnouncement) (*response.Response[*domain.ClassAnnouncement], error) {
+	if err := validateAnnouncement(announcement); err != nil {
+		return nil, err
+	}
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityAnnounce); err != nil {
+		return nil, err
+	}
+	prev, err := cs.getAnnouncement(ctx, classId, announcement.AnnouncementId)
+	if err != nil {
+		return nil, err
+	}
+
+	curr := *prev
+	curr.Title = announcement.Title
+	curr.Body = announcement.Body
+	curr.Pinned = announcement.Pinned
+	curr.ExpiresAt = announcement.ExpiresAt
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		if err := cs.ClassAnnouncementRepository.UpdateAnnouncement(ctx, &curr); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditAnnouncementUpdate, curr.AnnouncementId, snapshot(prev), snapshot(&curr)); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventAnnouncementUpdated, snapshot(&curr))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New(200, &curr), nil
+}
+
+func (cs *ClassService) DeleteAnnouncement(ctx context.Context, userId, classId, announcementId string) (*response.Response[any], error) {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityAnnounce); err != nil {
+		return nil, err
+	}
+	announcement, err := cs.getAnnouncement(ctx, classId, announcementId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		before := snapshot(announcement)
+		if err := cs.ClassAnnouncementRepository.DeleteAnnouncement(ctx, announcementId); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditAnnouncementDelete, announcementId, before, nil); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventAnnouncementDeleted, before)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New[any](204, nil), nil
+}
+
+// validateAnnouncement reject announcement that is already expired when it is posted or updated
+func validateAnnouncement(announcement *domain.ClassAnnouncement) error {
+	if err := validator.ValidateStruct(announcement); err != nil {
+		return err
+	}
+	if announcement.Expired(time.Now()) {
+		return response.NewUnprocessableEntity("announcement.expiry_past", nil)
+	}
+	return nil
+}
+
+// getAnnouncement get announcement and make sure it is belong to the class
+func (cs *ClassService) getAnnouncement(ctx context.Context, classId, announcementId string) (*domain.ClassAnnouncement, error) {
+	announcement, err := cs.ClassAnnouncementRepository.GetAnnouncement(ctx, announcementId)
+	if errors.Is(err, domain.ErrClassAnnouncementNotExists) || (err == nil && announcement.ClassId != classId) {
+		return nil, announcementNotFound(classId, announcementId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return announcement, nil
+}
+
+func announcementNotFound(classId, announcementId string) error {
+	return response.NewNotFound("announcement.not_found", response.Params{"announcementId": announcementId, "classId": classId})
+}
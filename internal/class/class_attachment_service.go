@@ -0,0 +1,222 @@
+package class
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"nory/common/database"
+	"nory/common/response"
+	"nory/common/signedurl"
+	"nory/common/validator"
+	"nory/domain"
+)
+
+// UploadAttachment store content as a new attachment of the task, only the task author or class moderator can attach file.
+// content is read again from the start when the transaction is retried.
+func (cs *ClassService) UploadAttachment(ctx context.Context, userId, classId string, attachment *domain.ClassAttachment, content io.ReadSeeker) (*response.Response[*domain.ClassAttachment], error) {
+	if attachment.Size > domain.MaxAttachmentSize {
+		return nil, response.NewError(413, "attachment.too_large", response.Params{"size": domain.MaxAttachmentSize})
+	}
+	contentType, _, err := mime.ParseMediaType(attachment.ContentType)
+	if err != nil || !domain.AttachmentContentTypes[contentType] {
+		return nil, response.NewError(415, "attachment.type_not_allowed", response.Params{"contentType": attachment.ContentType})
+	}
+	attachment.ContentType = contentType
+	attachment.ClassId = classId
+	attachment.AuthorId = userId
+	if err := validator.ValidateStruct(attachment); err != nil {
+		return nil, err
+	}
+
+	task, err := cs.getClassTask(ctx, classId, attachment.TaskId)
+	if err != nil {
+		return nil, err
+	}
+	required := domain.CapabilityManageTasks
+	if task.AuthorId == userId {
+		required = domain.CapabilityCreateTask
+	}
+	if err := cs.AccessClass(ctx, userId, classId, required); err != nil {
+		return nil, err
+	}
+
+	// content type is sent by the client, make sure the content agree with it
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	head = head[:n]
+	if !contentMatch(attachment.ContentType, head) {
+		return nil, response.NewError(415, "attachment.type_mismatch", response.Params{"contentType": attachment.ContentType})
+	}
+
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		if err := cs.storeOccurrence(ctx, task); err != nil {
+			return err
+		}
+		if err := cs.ClassAttachmentRepository.CreateAttachment(ctx, attachment); err != nil {
+			return err
+		}
+		key := attachment.BlobKey()
+		database.OnRollback(ctx, func() {
+			cs.deleteBlob(key)
+		})
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := cs.BlobStore.Put(ctx, key, io.LimitReader(content, attachment.Size)); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditAttachmentCreate, attachment.AttachmentId, nil, snapshot(attachment)); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventAttachmentAdded, snapshot(attachment))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	cs.signAttachment(attachment)
+	return response.New(200, attachment), nil
+}
+
+// ListAttachments list attachments of the task with signed download url
+func (cs *ClassService) ListAttachments(ctx context.Context, userId, classId, taskId string) (*response.Response[[]*domain.ClassAttachment], error) {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityViewClass); err != nil {
+		return nil, err
+	}
+	if _, err := cs.getClassTask(ctx, classId, taskId); err != nil {
+		return nil, err
+	}
+	attachments, err := cs.ClassAttachmentRepository.ListAttachments(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		cs.signAttachment(attachment)
+	}
+	return response.New(200, attachments), nil
+}
+
+// DeleteAttachment remove attachment and its content, only the attachment author or class moderator can delete it
+func (cs *ClassService) DeleteAttachment(ctx context.Context, userId, classId, taskId, attachmentId string) (*response.Response[any], error) {
+	attachment, err := cs.getAttachment(ctx, classId, taskId, attachmentId)
+	if err != nil {
+		return nil, err
+	}
+	required := domain.CapabilityManageTasks
+	if attachment.AuthorId == userId {
+		required = domain.CapabilityCreateTask
+	}
+	if err := cs.AccessClass(ctx, userId, classId, required); err != nil {
+		return nil, err
+	}
+
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		before := snapshot(attachment)
+		if err := cs.ClassAttachmentRepository.DeleteAttachment(ctx, attachmentId); err != nil {
+			return err
+		}
+		cs.deleteBlobs(ctx, []*domain.ClassAttachment{attachment})
+		if err := cs.audit(ctx, userId, classId, domain.AuditAttachmentDelete, attachmentId, before, nil); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventAttachmentDeleted, before)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New[any](204, nil), nil
+}
+
+// DownloadAttachment open content of the attachment, the request is authorized by the signed url
+// instead of the user. The caller must close the returned reader.
+func (cs *ClassService) DownloadAttachment(ctx context.Context, classId, taskId, attachmentId, expires, signature string) (*domain.ClassAttachment, io.ReadCloser, error) {
+	err := cs.URLSigner.Verify(attachmentPath(classId, taskId, attachmentId), expires, signature, time.Now())
+	if errors.Is(err, signedurl.ErrExpired) {
+		return nil, nil, response.NewForbidden("attachment.url_expired", nil)
+	}
+	if err != nil {
+		return nil, nil, response.NewForbidden("attachment.url_invalid", nil)
+	}
+
+	attachment, err := cs.getAttachment(ctx, classId, taskId, attachmentId)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := cs.BlobStore.Get(ctx, attachment.BlobKey())
+	if errors.Is(err, domain.ErrBlobNotExists) {
+		return nil, nil, response.NewNotFound("attachment.content_missing", response.Params{"attachmentId": attachmentId})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// getAttachment get attachment and make sure it is belong to the task of the class
+func (cs *ClassService) getAttachment(ctx context.Context, classId, taskId, attachmentId string) (*domain.ClassAttachment, error) {
+	attachment, err := cs.ClassAttachmentRepository.GetAttachment(ctx, attachmentId)
+	if errors.Is(err, domain.ErrClassAttachmentNotExists) || (err == nil && (attachment.ClassId != classId || attachment.TaskId != taskId)) {
+		return nil, response.NewNotFound("attachment.not_found", response.Params{"attachmentId": attachmentId, "taskId": taskId})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// attachmentPath is the download route of the attachment, relative to where the class router is mounted
+func attachmentPath(classId, taskId, attachmentId string) string {
+	return fmt.Sprintf("/%s/task/%s/attachment/%s", classId, taskId, attachmentId)
+}
+
+func (cs *ClassService) signAttachment(attachment *domain.ClassAttachment) {
+	attachment.URL = cs.URLSigner.Sign(attachmentPath(attachment.ClassId, attachment.TaskId, attachment.AttachmentId), time.Now())
+}
+
+// deleteBlobs remove content of the attachments once the transaction of ctx is committed
+func (cs *ClassService) deleteBlobs(ctx context.Context, attachments []*domain.ClassAttachment) {
+	if len(attachments) == 0 {
+		return
+	}
+	cs.TxRunner.AfterCommit(ctx, func() {
+		for _, attachment := range attachments {
+			cs.deleteBlob(attachment.BlobKey())
+		}
+	})
+}
+
+// deleteBlob is best effort like publish, orphaned blob does not affect the class
+func (cs *ClassService) deleteBlob(key string) {
+	cs.BlobStore.Delete(context.Background(), key)
+}
+
+// oleSignature start legacy office documents, http.DetectContentType does not recognize them
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// contentMatch report whether head of the content look like contentType. Office documents can
+// only be recognized by their container, zip for the new formats and OLE for the legacy ones.
+func contentMatch(contentType string, head []byte) bool {
+	switch contentType {
+	case "application/msword", "application/vnd.ms-excel", "application/vnd.ms-powerpoint":
+		return bytes.HasPrefix(head, oleSignature)
+	}
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return false
+	}
+	switch contentType {
+	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation":
+		return detected == "application/zip"
+	}
+	return detected == contentType
+}
@@ -0,0 +1,176 @@
+package class
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"nory/common/response"
+	"nory/common/validator"
+	"nory/domain"
+)
+
+// RequestJoin ask class admins to add the user as member, the request expire
+// after domain.JoinRequestTTL. Private class can not be requested.
+func (cs *ClassService) RequestJoin(ctx context.Context, userId, classId string, request *domain.ClassJoinRequest) (*response.Response[*domain.ClassJoinRequest], error) {
+	now := time.Now()
+	request.ClassId = classId
+	request.UserId = userId
+	request.ExpiresAt = now.Add(domain.JoinRequestTTL)
+	if err := validator.ValidateStruct(request); err != nil {
+		return nil, err
+	}
+	class, err := cs.viewClass(ctx, userId, classId)
+	if err != nil {
+		return nil, err
+	}
+	// unlisted class can be read by link, but only public class accept join requests
+	if !class.Listed() {
+		return nil, response.NewForbidden("join_request.class_not_public", response.Params{"classId": classId})
+	}
+
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		_, err := cs.ClassMemberRepository.GetMember(ctx, &domain.ClassMember{ClassId: classId, UserId: userId})
+		if err == nil {
+			return response.NewConflict("member.already_exists", response.Params{"userId": userId, "classId": classId})
+		}
+		if !errors.Is(err, domain.ErrClassMemberNotExists) {
+			return err
+		}
+
+		// expired request must not block a new one
+		if err := cs.ClassJoinRequestRepository.DeleteExpiredJoinRequests(ctx, classId, now); err != nil {
+			return err
+		}
+		err = cs.ClassJoinRequestRepository.CreateJoinRequest(ctx, request)
+		if errors.Is(err, domain.ErrClassJoinRequestAlreadyExists) {
+			return response.NewConflict("join_request.already_exists", response.Params{"userId": userId, "classId": classId})
+		}
+		if err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditJoinRequestCreate, userId, nil, snapshot(request)); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventJoinRequestCreated, snapshot(request))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New(200, request), nil
+}
+
+// ListJoinRequests list pending join requests of the class, expired requests are removed
+func (cs *ClassService) ListJoinRequests(ctx context.Context, userId, classId string, page domain.Pagination) (*response.Response[[]*domain.ClassJoinRequest], error) {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityManageMembers); err != nil {
+		return nil, err
+	}
+	if err := cs.ClassJoinRequestRepository.DeleteExpiredJoinRequests(ctx, classId, time.Now()); err != nil {
+		return nil, err
+	}
+	page = page.Normalize()
+	requests, next, err := cs.ClassJoinRequestRepository.ListJoinRequests(ctx, classId, page)
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return nil, response.NewBadRequest("pagination.invalid_cursor", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response.NewPaginated(200, requests, page.Limit, next), nil
+}
+
+// ApproveJoinRequest add the requesting user as member with level, empty level
+// means domain.RoleMember. The actor can only grant role lower than its own.
+func (cs *ClassService) ApproveJoinRequest(ctx context.Context, userId, classId, requesterId, level string) (*response.Response[*domain.ClassMember], error) {
+	member := &domain.ClassMember{
+		ClassId: classId,
+		UserId:  requesterId,
+		Level:   level,
+	}
+	if member.Level == "" {
+		member.Level = domain.RoleMember
+	}
+	if err := validator.ValidateStruct(member); err != nil {
+		return nil, err
+	}
+	actor, err := cs.accessClass(ctx, userId, classId, domain.CapabilityManageMembers)
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.checkAssignRole(actor, member.Level); err != nil {
+		return nil, err
+	}
+
+	var request *domain.ClassJoinRequest
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		request, err = cs.getJoinRequest(ctx, classId, requesterId)
+		if err != nil {
+			return err
+		}
+		if err := cs.ClassJoinRequestRepository.DeleteJoinRequest(ctx, classId, requesterId); err != nil {
+			return err
+		}
+		err := cs.ClassMemberRepository.CreateMember(ctx, member)
+		if errors.Is(err, domain.ErrClassMemberAlreadyExists) {
+			return response.NewConflict("member.already_exists", response.Params{"userId": requesterId, "classId": classId})
+		}
+		if err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditJoinRequestApprove, requesterId, snapshot(request), snapshot(member)); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventMemberAdded, snapshot(member))
+		cs.notifyJoinRequest(ctx, request, true)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New(200, member), nil
+}
+
+// RejectJoinRequest remove the join request without adding the user
+func (cs *ClassService) RejectJoinRequest(ctx context.Context, userId, classId, requesterId string) (*response.Response[any], error) {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityManageMembers); err != nil {
+		return nil, err
+	}
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		request, err := cs.getJoinRequest(ctx, classId, requesterId)
+		if err != nil {
+			return err
+		}
+		if err := cs.ClassJoinRequestRepository.DeleteJoinRequest(ctx, classId, requesterId); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditJoinRequestReject, requesterId, snapshot(request), nil); err != nil {
+			return err
+		}
+		cs.notifyJoinRequest(ctx, request, false)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New[any](204, nil), nil
+}
+
+// getJoinRequest get pending request, expired request is not found
+func (cs *ClassService) getJoinRequest(ctx context.Context, classId, userId string) (*domain.ClassJoinRequest, error) {
+	request, err := cs.ClassJoinRequestRepository.GetJoinRequest(ctx, classId, userId)
+	if errors.Is(err, domain.ErrClassJoinRequestNotExists) || (err == nil && request.Expired(time.Now())) {
+		return nil, response.NewNotFound("join_request.not_found", response.Params{"userId": userId, "classId": classId})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// notifyJoinRequest tell JoinRequestNotifier about the decision once the transaction of ctx is committed
+func (cs *ClassService) notifyJoinRequest(ctx context.Context, request *domain.ClassJoinRequest, approved bool) {
+	if cs.JoinRequestNotifier == nil {
+		return
+	}
+	cs.TxRunner.AfterCommit(ctx, func() {
+		cs.JoinRequestNotifier.JoinRequestDecided(context.Background(), request, approved)
+	})
+}
@@ -2,8 +2,11 @@ package class
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"sync"
 
+	"nory/common/database"
 	"nory/domain"
 
 	"github.com/rs/xid"
@@ -41,7 +44,7 @@ func (crm *ClassRepositoryMem) GetClassByName(ctx context.Context, ownerId, name
 	return nil, domain.ErrClassNotExists
 }
 
-func (crm *ClassRepositoryMem) GetClassesByOwnerId(ctx context.Context, ownerId string) ([]*domain.Class, error) {
+func (crm *ClassRepositoryMem) GetClassesByOwnerId(ctx context.Context, ownerId string, page domain.Pagination) ([]*domain.Class, string, error) {
 	crm.mx.Lock()
 	defer crm.mx.Unlock()
 	var classes []*domain.Class
@@ -51,13 +54,40 @@ func (crm *ClassRepositoryMem) GetClassesByOwnerId(ctx context.Context, ownerId
 		}
 		classes = append(classes, c)
 	}
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].ClassId < classes[j].ClassId
+	})
+	return domain.Paginate(classes, page, classKey)
+}
+
+func (crm *ClassRepositoryMem) GetClassesByIds(ctx context.Context, classIds []string) ([]*domain.Class, error) {
+	crm.mx.Lock()
+	defer crm.mx.Unlock()
+	classes := make([]*domain.Class, 0, len(classIds))
+	seen := make(map[string]bool, len(classIds))
+	for _, classId := range classIds {
+		c, ok := crm.m[classId]
+		if !ok || seen[classId] {
+			continue
+		}
+		seen[classId] = true
+		classes = append(classes, c)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].ClassId < classes[j].ClassId
+	})
 	return classes, nil
 }
 
+func classKey(c *domain.Class) []string {
+	return []string{c.ClassId}
+}
+
 func (crm *ClassRepositoryMem) CreateClass(ctx context.Context, class *domain.Class) error {
 	crm.mx.Lock()
 	defer crm.mx.Unlock()
 	class.ClassId = xid.New().String()
+	database.RestoreOnRollback(ctx, &crm.mx, crm.m, class.ClassId)
 	crm.m[class.ClassId] = class
 	return nil
 }
@@ -65,15 +95,72 @@ func (crm *ClassRepositoryMem) CreateClass(ctx context.Context, class *domain.Cl
 func (crm *ClassRepositoryMem) DeleteClass(ctx context.Context, classId string) error {
 	crm.mx.Lock()
 	defer crm.mx.Unlock()
+	database.RestoreOnRollback(ctx, &crm.mx, crm.m, classId)
 	delete(crm.m, classId)
 	return nil
 }
 
 func (crm *ClassRepositoryMem) UpdateClass(ctx context.Context, class *domain.Class) error {
-	c, err := crm.GetClass(ctx, class.ClassId)
-	if err != nil {
-		return err
+	crm.mx.Lock()
+	defer crm.mx.Unlock()
+	c, ok := crm.m[class.ClassId]
+	if !ok {
+		return domain.ErrClassNotExists
 	}
+	database.RestoreOnRollback(ctx, &crm.mx, crm.m, class.ClassId)
 	c.Update(class)
 	return nil
 }
+
+func (crm *ClassRepositoryMem) TransferClass(ctx context.Context, classId, ownerId string) error {
+	crm.mx.Lock()
+	defer crm.mx.Unlock()
+	c, ok := crm.m[classId]
+	if !ok {
+		return domain.ErrClassNotExists
+	}
+	for _, other := range crm.m {
+		if other.OwnerId == ownerId && other.Name == c.Name && other.ClassId != classId {
+			return domain.ErrClassAlreadyExists
+		}
+	}
+	database.RestoreOnRollback(ctx, &crm.mx, crm.m, classId)
+	c.OwnerId = ownerId
+	return nil
+}
+
+func (crm *ClassRepositoryMem) SearchClasses(ctx context.Context, query string, limit int) ([]*domain.Class, error) {
+	crm.mx.Lock()
+	defer crm.mx.Unlock()
+	type ranked struct {
+		class *domain.Class
+		rank  int
+		name  string
+	}
+	matches := make([]ranked, 0)
+	for _, c := range crm.m {
+		if !c.Listed() {
+			continue
+		}
+		if rank := domain.SearchRank(query, c.Name, c.Description); rank != domain.SearchRankNone {
+			matches = append(matches, ranked{c, rank, strings.ToLower(c.Name)})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank > matches[j].rank
+		}
+		if matches[i].name != matches[j].name {
+			return matches[i].name < matches[j].name
+		}
+		return matches[i].class.ClassId < matches[j].class.ClassId
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	classes := make([]*domain.Class, 0, len(matches))
+	for _, m := range matches {
+		classes = append(classes, m.class)
+	}
+	return classes, nil
+}
@@ -3,11 +3,15 @@ package class
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/xid"
 
+	"nory/common/database"
 	"nory/domain"
 )
 
@@ -23,12 +27,13 @@ func (crp *ClassRepositoryPostgres) GetClass(ctx context.Context, classId string
 	class := &domain.Class{
 		ClassId: classId,
 	}
-	row := crp.pool.QueryRow(ctx, "SELECT owner_id, created_at, name, description FROM class WHERE class_id = $1", classId)
+	row := database.Conn(ctx, crp.pool).QueryRow(ctx, "SELECT owner_id, created_at, name, description, visibility FROM class WHERE class_id = $1", classId)
 	err := row.Scan(
 		&class.OwnerId,
 		&class.CreatedAt,
 		&class.Name,
 		&class.Description,
+		&class.Visibility,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		err = domain.ErrClassNotExists
@@ -40,12 +45,13 @@ func (crp *ClassRepositoryPostgres) GetClassByName(ctx context.Context, ownerId,
 	class := &domain.Class{
 		OwnerId: ownerId,
 	}
-	row := crp.pool.QueryRow(ctx, "SELECT class_id, created_at, name, description FROM class WHERE owner_id = $1 AND name = $2", ownerId, name)
+	row := database.Conn(ctx, crp.pool).QueryRow(ctx, "SELECT class_id, created_at, name, description, visibility FROM class WHERE owner_id = $1 AND name = $2", ownerId, name)
 	err := row.Scan(
 		&class.ClassId,
 		&class.CreatedAt,
 		&class.Name,
 		&class.Description,
+		&class.Visibility,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, domain.ErrClassNotExists
@@ -53,11 +59,28 @@ func (crp *ClassRepositoryPostgres) GetClassByName(ctx context.Context, ownerId,
 	return class, err
 }
 
-func (crp *ClassRepositoryPostgres) GetClassesByOwnerId(ctx context.Context, ownerId string) ([]*domain.Class, error) {
+func (crp *ClassRepositoryPostgres) GetClassesByOwnerId(ctx context.Context, ownerId string, page domain.Pagination) ([]*domain.Class, string, error) {
+	after, err := page.Keys(1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "SELECT class_id, created_at, name, description, visibility FROM class WHERE owner_id = $1"
+	args := []any{ownerId}
+	if after != nil {
+		args = append(args, after[0])
+		query += " AND class_id > $2"
+	}
+	query += " ORDER BY class_id"
+	if page.Limit > 0 {
+		args = append(args, page.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
 	classes := make([]*domain.Class, 0)
-	rows, err := crp.pool.Query(ctx, "SELECT class_id, created_at, name, description FROM class WHERE owner_id = $1 ORDER BY class_id", ownerId)
+	rows, err := database.Conn(ctx, crp.pool).Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	for rows.Next() {
 		class := &domain.Class{OwnerId: ownerId}
@@ -66,29 +89,59 @@ func (crp *ClassRepositoryPostgres) GetClassesByOwnerId(ctx context.Context, own
 			&class.CreatedAt,
 			&class.Name,
 			&class.Description,
+			&class.Visibility,
+		); err != nil {
+			return nil, "", err
+		}
+		classes = append(classes, class)
+	}
+	classes, next := domain.NextPage(classes, page, classKey)
+	return classes, next, nil
+}
+
+func (crp *ClassRepositoryPostgres) GetClassesByIds(ctx context.Context, classIds []string) ([]*domain.Class, error) {
+	classes := make([]*domain.Class, 0, len(classIds))
+	rows, err := database.Conn(ctx, crp.pool).Query(
+		ctx,
+		"SELECT class_id, owner_id, created_at, name, description, visibility FROM class WHERE class_id = ANY($1) ORDER BY class_id",
+		classIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		class := &domain.Class{}
+		if err := rows.Scan(
+			&class.ClassId,
+			&class.OwnerId,
+			&class.CreatedAt,
+			&class.Name,
+			&class.Description,
+			&class.Visibility,
 		); err != nil {
 			return nil, err
 		}
 		classes = append(classes, class)
 	}
-	return classes, nil
+	return classes, rows.Err()
 }
 
 func (crp *ClassRepositoryPostgres) CreateClass(ctx context.Context, class *domain.Class) error {
 	class.ClassId = xid.New().String()
-	_, err := crp.pool.Exec(
+	_, err := database.Conn(ctx, crp.pool).Exec(
 		ctx,
-		"INSERT INTO class(class_id, owner_id, name, description) VALUES($1, $2, $3, $4)",
+		"INSERT INTO class(class_id, owner_id, name, description, visibility) VALUES($1, $2, $3, $4, $5)",
 		class.ClassId,
 		class.OwnerId,
 		class.Name,
 		class.Description,
+		class.Visibility,
 	)
 	return err
 }
 
 func (crp *ClassRepositoryPostgres) DeleteClass(ctx context.Context, classId string) error {
-	_, err := crp.pool.Exec(
+	_, err := database.Conn(ctx, crp.pool).Exec(
 		ctx,
 		"DELETE FROM class WHERE class_id = $1",
 		classId,
@@ -102,12 +155,79 @@ func (crp *ClassRepositoryPostgres) UpdateClass(ctx context.Context, class *doma
 		return err
 	}
 	c.Update(class)
-	_, err = crp.pool.Exec(
+	_, err = database.Conn(ctx, crp.pool).Exec(
 		ctx,
-		"UPDATE class SET name = $1, description = $2 WHERE class_id = $3",
+		"UPDATE class SET name = $1, description = $2, visibility = $3 WHERE class_id = $4",
 		c.Name,
 		c.Description,
+		c.Visibility,
 		c.ClassId,
 	)
 	return err
 }
+
+func (crp *ClassRepositoryPostgres) TransferClass(ctx context.Context, classId, ownerId string) error {
+	tag, err := database.Conn(ctx, crp.pool).Exec(
+		ctx,
+		"UPDATE class SET owner_id = $1 WHERE class_id = $2",
+		ownerId,
+		classId,
+	)
+	if pgerr, ok := err.(*pgconn.PgError); ok && pgerr.Code == "23505" {
+		return domain.ErrClassAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrClassNotExists
+	}
+	return nil
+}
+
+// SearchClasses match class name by prefix using the class_lower_name_index and words
+// of name and description, rank is computed the same way as domain.SearchRank
+func (crp *ClassRepositoryPostgres) SearchClasses(ctx context.Context, query string, limit int) ([]*domain.Class, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return []*domain.Class{}, nil
+	}
+	var maxRows any
+	if limit > 0 {
+		maxRows = limit
+	}
+	words, args := database.WordPrefix("LOWER(name || ' ' || description)", domain.SearchTerms(query),
+		[]any{query, database.LikePrefix(query), maxRows})
+
+	rows, err := database.Conn(ctx, crp.pool).Query(
+		ctx,
+		`SELECT class_id, owner_id, created_at, name, description, visibility
+		FROM class
+		WHERE visibility IN ('', 'public') AND (LOWER(name) LIKE $2 ESCAPE '\' OR `+words+`)
+		ORDER BY
+			CASE WHEN LOWER(name) = $1 THEN 3 WHEN LOWER(name) LIKE $2 ESCAPE '\' THEN 2 ELSE 1 END DESC,
+			LOWER(name) COLLATE "C",
+			class_id
+		LIMIT $3`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	classes := make([]*domain.Class, 0)
+	for rows.Next() {
+		class := &domain.Class{}
+		if err := rows.Scan(
+			&class.ClassId,
+			&class.OwnerId,
+			&class.CreatedAt,
+			&class.Name,
+			&class.Description,
+			&class.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		classes = append(classes, class)
+	}
+	return classes, rows.Err()
+}
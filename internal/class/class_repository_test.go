@@ -3,6 +3,7 @@ package class_test
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 
 	"nory/domain"
@@ -46,8 +47,11 @@ func TestClassRepository(t *testing.T) {
 			t.Run("create", r.testCreate)
 			t.Run("get by class id", r.testGet)
 			t.Run("get by owner id", r.testGetByOwnerId)
+			t.Run("get by ids", r.testGetByIds)
 			t.Run("update class", r.testUpdate)
+			t.Run("transfer class", r.testTransfer)
 			t.Run("delete", r.testDelete)
+			t.Run("search", r.testSearch)
 		})
 	}
 }
@@ -97,7 +101,7 @@ func (r *Repository) testCreate(t *testing.T) {
 		{"success", domain.Class{Name: "1", ClassId: "foo", OwnerId: foo}, nil},
 		{"success", domain.Class{Name: "2", ClassId: "bar", OwnerId: foo}, nil},
 		{"success", domain.Class{Name: "1", ClassId: "baz", OwnerId: bar}, nil},
-		{"success", domain.Class{Name: "1", ClassId: "baz", OwnerId: baz}, nil},
+		{"success", domain.Class{Name: "1", ClassId: "baz", OwnerId: baz, Visibility: domain.ClassVisibilityPrivate}, nil},
 	}
 
 	for _, tc := range testCases {
@@ -191,7 +195,7 @@ func (r *Repository) testGetByOwnerId(t *testing.T) {
 	for _, tc := range testCases {
 		tc := tc
 		t.Run(tc.Name, func(t *testing.T) {
-			classes, err := r.ClassRepository.GetClassesByOwnerId(context.Background(), tc.OwnerId)
+			classes, _, err := r.ClassRepository.GetClassesByOwnerId(context.Background(), tc.OwnerId, domain.Pagination{})
 			assert.Equal(t, tc.Err, err, "missmatch error")
 			if err == nil {
 				assert.Equal(t, tc.Len, len(classes), "unexpected class received")
@@ -203,6 +207,23 @@ func (r *Repository) testGetByOwnerId(t *testing.T) {
 	}
 }
 
+func (r *Repository) testGetByIds(t *testing.T) {
+	ids := []string{r.classes[2].ClassId, "foo-bar", r.classes[0].ClassId, r.classes[0].ClassId}
+	classes, err := r.ClassRepository.GetClassesByIds(context.Background(), ids)
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(classes), "unknown and duplicate ids should be omitted") {
+		assert.Less(t, classes[0].ClassId, classes[1].ClassId, "classes should be ordered by ClassId")
+		for _, c := range classes {
+			assert.Contains(t, ids, c.ClassId)
+			assert.NotEqual(t, "", c.OwnerId)
+		}
+	}
+
+	classes, err = r.ClassRepository.GetClassesByIds(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(classes))
+}
+
 func (r *Repository) testUpdate(t *testing.T) {
 	testCases := []struct {
 		Name  string
@@ -210,6 +231,7 @@ func (r *Repository) testUpdate(t *testing.T) {
 		Err   error
 	}{
 		{"success", domain.Class{ClassId: r.classes[0].ClassId, Description: "foo"}, nil},
+		{"visibility", domain.Class{ClassId: r.classes[1].ClassId, Description: "bar", Visibility: domain.ClassVisibilityUnlisted}, nil},
 		{"not found", domain.Class{ClassId: "anu", Description: "foo"}, domain.ErrClassNotExists},
 	}
 
@@ -229,7 +251,99 @@ func (r *Repository) testUpdate(t *testing.T) {
 				assert.Equal(t, prev.ClassId, curr.ClassId, "should not update class id")
 				assert.Equal(t, prev.OwnerId, curr.OwnerId, "should not update owner id")
 				assert.Equal(t, tc.Class.Description, curr.Description, "should able update Description")
+				if tc.Class.Visibility != "" {
+					assert.Equal(t, tc.Class.Visibility, curr.Visibility, "should able update Visibility")
+				} else {
+					assert.Equal(t, prev.Visibility, curr.Visibility, "should keep Visibility")
+				}
+			}
+		})
+	}
+}
+
+func (r *Repository) testTransfer(t *testing.T) {
+	testCases := []struct {
+		Name    string
+		ClassId string
+		OwnerId string
+		Err     error
+	}{
+		{"success", r.classes[1].ClassId, r.getUser("baz"), nil},
+		{"name conflict", r.classes[2].ClassId, r.getUser("foo"), domain.ErrClassAlreadyExists},
+		{"not found", "anu", r.getUser("foo"), domain.ErrClassNotExists},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			prev, _ := r.ClassRepository.GetClass(context.Background(), tc.ClassId)
+			var prevOwner string
+			if prev != nil {
+				prevOwner = prev.OwnerId
+			}
+
+			err := r.ClassRepository.TransferClass(context.Background(), tc.ClassId, tc.OwnerId)
+			assert.Equal(t, tc.Err, err, "missmatch error")
+
+			curr, err := r.ClassRepository.GetClass(context.Background(), tc.ClassId)
+			if err != nil {
+				return
+			}
+			if tc.Err == nil {
+				assert.Equal(t, tc.OwnerId, curr.OwnerId, "should update owner id")
+			} else {
+				assert.Equal(t, prevOwner, curr.OwnerId, "should not update owner id on error")
+			}
+		})
+	}
+}
+
+func (r *Repository) testSearch(t *testing.T) {
+	owner := r.getUser("search")
+	// marker keep other classes in the shared database out of the result
+	marker := strings.ReplaceAll(uuid.NewString(), "-", "")[:8]
+	classes := []*domain.Class{
+		{Name: marker},
+		{Name: strings.ToUpper(marker) + "-k"},
+		{Name: "z", Description: "all about " + marker + "x and more"},
+		{Name: marker + "-private", Visibility: domain.ClassVisibilityPrivate},
+		{Name: marker + "-unlisted", Visibility: domain.ClassVisibilityUnlisted},
+		{Name: "q" + marker},
+	}
+	for _, class := range classes {
+		class.OwnerId = owner
+		err := r.ClassRepository.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+	}
+
+	testCases := []struct {
+		Name  string
+		Query string
+		Limit int
+		Want  []*domain.Class
+	}{
+		{"rank exact, prefix then word", marker, 0, classes[:3]},
+		{"case-insensitive", strings.ToUpper(marker), 0, classes[:3]},
+		{"limit", marker, 2, classes[:2]},
+		{"every term must match", "about " + marker, 0, classes[2:3]},
+		{"term is a word prefix", marker[:4] + " and", 0, classes[2:3]},
+		{"prefix of name", marker + "-k", 0, classes[1:2]},
+		{"no match", "none " + marker, 0, nil},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := r.ClassRepository.SearchClasses(context.Background(), tc.Query, tc.Limit)
+			assert.Nil(t, err)
+			ids := make([]string, 0)
+			for _, c := range got {
+				ids = append(ids, c.ClassId)
+			}
+			want := make([]string, 0)
+			for _, c := range tc.Want {
+				want = append(want, c.ClassId)
 			}
+			assert.Equal(t, want, ids)
 		})
 	}
 }
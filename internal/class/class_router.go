@@ -1,6 +1,11 @@
 package class
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -30,27 +35,97 @@ func Route(classService ClassService) func(router fiber.Router) {
 	if classService.ClassScheduleRepository == nil {
 		panic("classRoute: nil ClassService.ClassScheduleRepository")
 	}
+	if classService.ClassInviteRepository == nil {
+		panic("classRoute: nil ClassService.ClassInviteRepository")
+	}
+	if classService.ClassAuditRepository == nil {
+		panic("classRoute: nil ClassService.ClassAuditRepository")
+	}
+	if classService.ClassEventBus == nil {
+		panic("classRoute: nil ClassService.ClassEventBus")
+	}
+	if classService.TxRunner == nil {
+		panic("classRoute: nil ClassService.TxRunner")
+	}
+	if classService.ClassAttachmentRepository == nil {
+		panic("classRoute: nil ClassService.ClassAttachmentRepository")
+	}
+	if classService.BlobStore == nil {
+		panic("classRoute: nil ClassService.BlobStore")
+	}
+	if classService.URLSigner == nil {
+		panic("classRoute: nil ClassService.URLSigner")
+	}
+	if classService.TaskCommentRepository == nil {
+		panic("classRoute: nil ClassService.TaskCommentRepository")
+	}
+	if classService.ClassAnnouncementRepository == nil {
+		panic("classRoute: nil ClassService.ClassAnnouncementRepository")
+	}
+	if classService.ClassJoinRequestRepository == nil {
+		panic("classRoute: nil ClassService.ClassJoinRequestRepository")
+	}
 
 	cr := classRouter{classService}
 	return func(router fiber.Router) {
 		router.Delete("/:classId", cr.deleteClass)
 		router.Delete("/:classId/member/:memberId", cr.deleteMember)
+		router.Delete("/:classId/membership", cr.leaveClass)
 		router.Delete("/:classId/task/:taskId", cr.deleteClassTask)
 		router.Delete("/:classId/schedule/:scheduleId", cr.deleteClassSchedule)
+		router.Delete("/:classId/invite/:code", cr.deleteInvite)
+		router.Delete("/:classId/task/:taskId/attachment/:attachmentId", cr.deleteAttachment)
+		router.Delete("/:classId/task/:taskId/comment/:commentId", cr.deleteComment)
+		router.Delete("/:classId/announcement/:announcementId", cr.deleteAnnouncement)
 		router.Patch("/:classId/member/:memberId", cr.updateMember)
 		router.Patch("/:classId", cr.updateClass)
+		router.Patch("/:classId/task/:taskId", cr.updateClassTask)
+		router.Patch("/:classId/task/:taskId/comment/:commentId", cr.updateComment)
 		router.Get("/:classId/info", cr.getClassInfo)
 		router.Get("/info", cr.getClassInfoByName)
+		router.Get("/search", cr.search)
 		router.Get("/:classId/task", cr.getClassTask)
+		router.Get("/:classId/task/:taskId/progress", cr.getTaskProgress)
+		router.Get("/:classId/task/:taskId/progress/summary", cr.getTaskSummary)
 		router.Get("/:classId/member", cr.listMember)
 		router.Get("/:classId/schedule", cr.getClassSchedule)
+		router.Get("/:classId/timetable", cr.getClassTimetable)
+		router.Get("/:classId/invite", cr.listInvites)
+		router.Get("/:classId/audit", cr.listAudit)
+		router.Get("/:classId/events", cr.classEvents)
+		router.Get("/:classId/task/:taskId/attachment", cr.listAttachments)
+		router.Get("/:classId/task/:taskId/attachment/:attachmentId", cr.downloadAttachment)
+		router.Get("/:classId/task/:taskId/comment", cr.listComments)
+		router.Get("/:classId/announcement", cr.listAnnouncements)
+		router.Get("/:classId/announcement/:announcementId", cr.getAnnouncement)
+		router.Get("/:classId/join-request", cr.listJoinRequests)
+		router.Post("/join/:code", cr.joinClass)
 		router.Post("/:classId/task", cr.createClassTask)
 		router.Post("/:classId/schedule", cr.createClassSchedule)
 		router.Post("/:classId/member", cr.addMember)
+		router.Post("/:classId/invite", cr.createInvite)
+		router.Post("/:classId/transfer", cr.transferOwnership)
+		router.Post("/:classId/task/:taskId/attachment", cr.uploadAttachment)
+		router.Post("/:classId/task/:taskId/comment", cr.createComment)
+		router.Post("/:classId/announcement", cr.createAnnouncement)
+		router.Post("/:classId/join-request", cr.requestJoin)
+		router.Post("/:classId/join-request/:userId/approve", cr.approveJoinRequest)
+		router.Post("/:classId/join-request/:userId/reject", cr.rejectJoinRequest)
 		router.Post("/create", cr.createClass)
+		router.Put("/:classId/task/:taskId/progress", cr.setTaskProgress)
+		router.Put("/:classId/schedule/day/:day", cr.replaceClassSchedule)
+		router.Put("/:classId/announcement/:announcementId", cr.updateAnnouncement)
 	}
 }
 
+// optionalUserId return id of the authenticated user, or empty string for anonymous request
+func optionalUserId(c *fiber.Ctx) string {
+	if user, err := auth.GetUser(c); err == nil {
+		return user.UserId
+	}
+	return ""
+}
+
 func (cr classRouter) deleteClass(c *fiber.Ctx) error {
 	classId := c.Params("classId")
 
@@ -89,7 +164,23 @@ func (cr classRouter) updateClass(c *fiber.Ctx) error {
 func (cr classRouter) getClassInfoByName(c *fiber.Ctx) error {
 	name := c.Query("name")
 	ownerUsername := c.Query("ownerUsername")
-	res, err := cr.cs.GetClassInfoByName(c.Context(), ownerUsername, name)
+	res, err := cr.cs.GetClassInfoByName(c.Context(), optionalUserId(c), ownerUsername, name)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) search(c *fiber.Ctx) error {
+	var q struct {
+		Query string `query:"q"`
+		Limit int    `query:"limit"`
+	}
+	if err := c.QueryParser(&q); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
+	res, err := cr.cs.Search(c.Context(), q.Query, q.Limit)
 	if err != nil {
 		return err
 	}
@@ -99,7 +190,7 @@ func (cr classRouter) getClassInfoByName(c *fiber.Ctx) error {
 
 func (cr classRouter) getClassInfo(c *fiber.Ctx) error {
 	classId := c.Params("classId")
-	res, err := cr.cs.GetClassInfo(c.Context(), classId)
+	res, err := cr.cs.GetClassInfo(c.Context(), optionalUserId(c), classId)
 	if err != nil {
 		return err
 	}
@@ -108,15 +199,74 @@ func (cr classRouter) getClassInfo(c *fiber.Ctx) error {
 }
 
 func (cr classRouter) getClassTask(c *fiber.Ctx) error {
-	var q struct {
-		From time.Time
-		To   time.Time
+	var filter domain.ClassTaskFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
 	}
-	if err := c.QueryParser(&q); err != nil {
-		return response.NewBadRequest(err.Error())
+	classId := c.Params("classId")
+	userId := optionalUserId(c)
+	var page domain.Pagination
+	if err := c.QueryParser(&page); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
+	res, err := cr.cs.GetClassTasks(c.Context(), userId, classId, filter, page)
+	if err != nil {
+		return err
 	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) getTaskProgress(c *fiber.Ctx) error {
 	classId := c.Params("classId")
-	res, err := cr.cs.GetClassTasks(c.Context(), classId, q.From, q.To)
+	taskId := c.Params("taskId")
+
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cr.cs.GetTaskProgress(c.Context(), user.UserId, classId, taskId)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) setTaskProgress(c *fiber.Ctx) error {
+	classId := c.Params("classId")
+
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	var progress domain.ClassTaskProgress
+	if err := c.BodyParser(&progress); err != nil {
+		return err
+	}
+
+	progress.TaskId = c.Params("taskId")
+	progress.UserId = user.UserId
+	res, err := cr.cs.SetTaskProgress(c.Context(), classId, &progress)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) getTaskSummary(c *fiber.Ctx) error {
+	classId := c.Params("classId")
+	taskId := c.Params("taskId")
+
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cr.cs.GetTaskSummary(c.Context(), user.UserId, classId, taskId)
 	if err != nil {
 		return err
 	}
@@ -147,6 +297,27 @@ func (cr classRouter) createClassTask(c *fiber.Ctx) error {
 	return res.Respond(c)
 }
 
+func (cr classRouter) updateClassTask(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	var task domain.ClassTask
+	if err := c.BodyParser(&task); err != nil {
+		return err
+	}
+
+	classId := c.Params("classId")
+	task.TaskId = c.Params("taskId")
+	res, err := cr.cs.UpdateClassTask(c.Context(), user.UserId, classId, &task)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
 func (cr classRouter) deleteClassTask(c *fiber.Ctx) error {
 	taskId := c.Params("taskId")
 
@@ -178,7 +349,8 @@ func (cr classRouter) createClassSchedule(c *fiber.Ctx) error {
 
 	task.ClassId = classId
 	task.AuthorId = user.UserId
-	res, err := cr.cs.CreateSchedule(c.Context(), &task)
+	allowOverlap := c.Query("allowOverlap") == "true"
+	res, err := cr.cs.CreateSchedule(c.Context(), &task, allowOverlap)
 	if err != nil {
 		return err
 	}
@@ -188,7 +360,47 @@ func (cr classRouter) createClassSchedule(c *fiber.Ctx) error {
 
 func (cr classRouter) getClassSchedule(c *fiber.Ctx) error {
 	classId := c.Params("classId")
-	res, err := cr.cs.GetClassSchedules(c.Context(), classId)
+	var page domain.Pagination
+	if err := c.QueryParser(&page); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
+	res, err := cr.cs.GetClassSchedules(c.Context(), optionalUserId(c), classId, page)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) replaceClassSchedule(c *fiber.Ctx) error {
+	classId := c.Params("classId")
+	day, err := c.ParamsInt("day")
+	if err != nil || day < 0 || day > 6 {
+		return response.NewBadRequest("schedule.invalid_day", nil)
+	}
+
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	schedules := make([]*domain.ClassSchedule, 0)
+	if err := c.BodyParser(&schedules); err != nil {
+		return err
+	}
+
+	allowOverlap := c.Query("allowOverlap") == "true"
+	res, err := cr.cs.ReplaceSchedules(c.Context(), user.UserId, classId, int8(day), schedules, allowOverlap)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) getClassTimetable(c *fiber.Ctx) error {
+	classId := c.Params("classId")
+	res, err := cr.cs.GetClassTimetable(c.Context(), optionalUserId(c), classId)
 	if err != nil {
 		return err
 	}
@@ -247,8 +459,157 @@ func (cr classRouter) addMember(c *fiber.Ctx) error {
 
 	res, err := cr.cs.AddMemberByUsername(c.Context(), user.UserId, body.Username, &domain.ClassMember{
 		ClassId: classId,
-		Level:   "member",
+		Level:   domain.RoleMember,
+	})
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) createInvite(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	var invite domain.ClassInvite
+	if err := c.BodyParser(&invite); err != nil {
+		return err
+	}
+
+	invite.ClassId = c.Params("classId")
+	invite.AuthorId = user.UserId
+	res, err := cr.cs.CreateInvite(c.Context(), &invite)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) listInvites(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	classId := c.Params("classId")
+
+	res, err := cr.cs.ListInvites(c.Context(), user.UserId, classId)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) listAudit(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	var filter domain.ClassAuditFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
+	var page domain.Pagination
+	if err := c.QueryParser(&page); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
+	classId := c.Params("classId")
+
+	res, err := cr.cs.ListAudit(c.Context(), user.UserId, classId, filter, page)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+// eventHeartbeat keep idle event stream alive through proxies and detect closed connection
+const eventHeartbeat = 15 * time.Second
+
+// classEvents stream class events as Server-Sent Events
+func (cr classRouter) classEvents(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	classId := c.Params("classId")
+	lastEventId := c.Get("Last-Event-ID", c.Query("lastEventId"))
+
+	// the stream outlive the handler, it is cancelled when writing to the client fail
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := cr.cs.SubscribeEvents(ctx, user.UserId, classId, lastEventId)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	// Done is closed when the server shutting down, the request ctx itself must not be used in stream writer
+	shutdown := c.Context().Done()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		heartbeat := time.NewTicker(eventHeartbeat)
+		defer heartbeat.Stop()
+
+		fmt.Fprint(w, ": connected\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					return
+				}
+				fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.EventId, event.Type, data)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+			case <-shutdown:
+				return
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
 	})
+	return nil
+}
+
+func (cr classRouter) deleteInvite(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	classId := c.Params("classId")
+	code := c.Params("code")
+
+	res, err := cr.cs.DeleteInvite(c.Context(), user.UserId, classId, code)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) joinClass(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	code := c.Params("code")
+
+	res, err := cr.cs.JoinClass(c.Context(), user.UserId, code)
 	if err != nil {
 		return err
 	}
@@ -258,8 +619,12 @@ func (cr classRouter) addMember(c *fiber.Ctx) error {
 
 func (cr classRouter) listMember(c *fiber.Ctx) error {
 	classId := c.Params("classId")
+	var page domain.Pagination
+	if err := c.QueryParser(&page); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
 
-	res, err := cr.cs.ListMember(c.Context(), classId)
+	res, err := cr.cs.ListMember(c.Context(), optionalUserId(c), classId, page)
 	if err != nil {
 		return err
 	}
@@ -305,3 +670,359 @@ func (cr classRouter) deleteMember(c *fiber.Ctx) error {
 
 	return res.Respond(c)
 }
+
+func (cr classRouter) transferOwnership(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	classId := c.Params("classId")
+
+	var body struct {
+		UserId  string `json:"userId"`
+		Confirm string `json:"confirm"` // must be the class name
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return err
+	}
+
+	res, err := cr.cs.TransferOwnership(c.Context(), user.UserId, classId, body.UserId, body.Confirm)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) leaveClass(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	classId := c.Params("classId")
+
+	res, err := cr.cs.LeaveClass(c.Context(), user.UserId, classId)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) uploadAttachment(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	classId := c.Params("classId")
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return response.NewBadRequest("request.file_required", nil)
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	res, err := cr.cs.UploadAttachment(c.Context(), user.UserId, classId, &domain.ClassAttachment{
+		TaskId:      c.Params("taskId"),
+		Name:        fh.Filename,
+		ContentType: fh.Header.Get(fiber.HeaderContentType),
+		Size:        fh.Size,
+	}, file)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) listAttachments(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	classId := c.Params("classId")
+	taskId := c.Params("taskId")
+
+	res, err := cr.cs.ListAttachments(c.Context(), user.UserId, classId, taskId)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+// downloadAttachment does not require authentication, the url is signed instead
+func (cr classRouter) downloadAttachment(c *fiber.Ctx) error {
+	attachment, content, err := cr.cs.DownloadAttachment(
+		c.Context(),
+		c.Params("classId"),
+		c.Params("taskId"),
+		c.Params("attachmentId"),
+		c.Query("expires"),
+		c.Query("signature"),
+	)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, attachment.ContentType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	// the stream is closed by fasthttp once it is sent
+	return c.SendStream(content, int(attachment.Size))
+}
+
+func (cr classRouter) deleteAttachment(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cr.cs.DeleteAttachment(c.Context(), user.UserId, c.Params("classId"), c.Params("taskId"), c.Params("attachmentId"))
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) createComment(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	var comment domain.TaskComment
+	if err := c.BodyParser(&comment); err != nil {
+		return err
+	}
+
+	classId := c.Params("classId")
+	comment.TaskId = c.Params("taskId")
+	res, err := cr.cs.CreateComment(c.Context(), user.UserId, classId, &comment)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) listComments(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	var page domain.Pagination
+	if err := c.QueryParser(&page); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
+
+	res, err := cr.cs.ListComments(c.Context(), user.UserId, c.Params("classId"), c.Params("taskId"), page)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) updateComment(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	var comment domain.TaskComment
+	if err := c.BodyParser(&comment); err != nil {
+		return err
+	}
+
+	classId := c.Params("classId")
+	comment.TaskId = c.Params("taskId")
+	comment.CommentId = c.Params("commentId")
+	res, err := cr.cs.UpdateComment(c.Context(), user.UserId, classId, &comment)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) deleteComment(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cr.cs.DeleteComment(c.Context(), user.UserId, c.Params("classId"), c.Params("taskId"), c.Params("commentId"))
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) createAnnouncement(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	var announcement domain.ClassAnnouncement
+	if err := c.BodyParser(&announcement); err != nil {
+		return err
+	}
+
+	res, err := cr.cs.CreateAnnouncement(c.Context(), user.UserId, c.Params("classId"), &announcement)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) listAnnouncements(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	var page domain.Pagination
+	if err := c.QueryParser(&page); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
+	expired := c.Query("expired") == "true"
+
+	res, err := cr.cs.ListAnnouncements(c.Context(), user.UserId, c.Params("classId"), expired, page)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) getAnnouncement(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cr.cs.GetAnnouncement(c.Context(), user.UserId, c.Params("classId"), c.Params("announcementId"))
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+// updateAnnouncement replace the announcement, fields missing from the body are cleared
+func (cr classRouter) updateAnnouncement(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	var announcement domain.ClassAnnouncement
+	if err := c.BodyParser(&announcement); err != nil {
+		return err
+	}
+
+	announcement.AnnouncementId = c.Params("announcementId")
+	res, err := cr.cs.UpdateAnnouncement(c.Context(), user.UserId, c.Params("classId"), &announcement)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) deleteAnnouncement(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cr.cs.DeleteAnnouncement(c.Context(), user.UserId, c.Params("classId"), c.Params("announcementId"))
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) requestJoin(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	var request domain.ClassJoinRequest
+	if err := c.BodyParser(&request); err != nil {
+		return err
+	}
+
+	res, err := cr.cs.RequestJoin(c.Context(), user.UserId, c.Params("classId"), &request)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) listJoinRequests(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+	var page domain.Pagination
+	if err := c.QueryParser(&page); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
+
+	res, err := cr.cs.ListJoinRequests(c.Context(), user.UserId, c.Params("classId"), page)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+// approveJoinRequest accept optional body with the level granted to the user
+func (cr classRouter) approveJoinRequest(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Level string `json:"level"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return err
+		}
+	}
+
+	res, err := cr.cs.ApproveJoinRequest(c.Context(), user.UserId, c.Params("classId"), c.Params("userId"), body.Level)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (cr classRouter) rejectJoinRequest(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cr.cs.RejectJoinRequest(c.Context(), user.UserId, c.Params("classId"), c.Params("userId"))
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
@@ -1,11 +1,19 @@
 package class_test
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,12 +23,21 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"nory/common/auth"
+	"nory/common/database"
 	"nory/common/response"
+	"nory/common/signedurl"
 	"nory/domain"
 	. "nory/internal/class"
+	classannouncement "nory/internal/class_announcement"
+	classattachment "nory/internal/class_attachment"
+	classaudit "nory/internal/class_audit"
+	classevent "nory/internal/class_event"
+	classinvite "nory/internal/class_invite"
+	classjoinrequest "nory/internal/class_join_request"
 	classmember "nory/internal/class_member"
 	classschedule "nory/internal/class_schedule"
 	classtask "nory/internal/class_task"
+	taskcomment "nory/internal/task_comment"
 	"nory/internal/user"
 )
 
@@ -28,11 +45,21 @@ func TestClassRouter(t *testing.T) {
 	t.Parallel()
 
 	classService := ClassService{
-		UserRepository:          user.NewUserRepositoryMem(),
-		ClassRepository:         NewClassRepositoryMem(),
-		ClassTaskRepository:     classtask.NewClassTaskRepositoryMem(),
-		ClassMemberRepository:   classmember.NewClassMemberRepositoryMem(),
-		ClassScheduleRepository: classschedule.NewClassScheduleRepositoryMem(),
+		UserRepository:              user.NewUserRepositoryMem(),
+		ClassRepository:             NewClassRepositoryMem(),
+		ClassTaskRepository:         classtask.NewClassTaskRepositoryMem(),
+		ClassMemberRepository:       classmember.NewClassMemberRepositoryMem(),
+		ClassScheduleRepository:     classschedule.NewClassScheduleRepositoryMem(),
+		ClassInviteRepository:       classinvite.NewClassInviteRepositoryMem(),
+		ClassAuditRepository:        classaudit.NewClassAuditRepositoryMem(),
+		ClassEventBus:               classevent.NewClassEventBusMem(100),
+		TxRunner:                    database.NewTxRunnerMem(),
+		ClassAttachmentRepository:   classattachment.NewClassAttachmentRepositoryMem(),
+		BlobStore:                   newBlobStore(t),
+		URLSigner:                   &signedurl.Signer{Secret: []byte("secret"), TTL: time.Minute},
+		TaskCommentRepository:       taskcomment.NewTaskCommentRepositoryMem(),
+		ClassAnnouncementRepository: classannouncement.NewClassAnnouncementRepositoryMem(),
+		ClassJoinRequestRepository:  classjoinrequest.NewClassJoinRequestRepositoryMem(),
 	}
 	classRoute := Route(classService)
 
@@ -76,6 +103,7 @@ func TestClassRouter(t *testing.T) {
 			}{
 				{"DELETE", fmt.Sprintf("/%s", class.ClassId)},
 				{"DELETE", fmt.Sprintf("/%s/member/%s", class.ClassId, userId)},
+				{"DELETE", fmt.Sprintf("/%s/membership", class.ClassId)},
 				{"DELETE", fmt.Sprintf("/%s/task/%s", class.ClassId, "s")},
 				{"POST", fmt.Sprintf("/%s/member", class.ClassId)},
 				{"POST", fmt.Sprintf("/%s/task", class.ClassId)},
@@ -89,6 +117,749 @@ func TestClassRouter(t *testing.T) {
 		})
 	})
 
+	t.Run("task progress", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		task := &domain.ClassTask{ClassId: class.ClassId, AuthorId: class.OwnerId, DueDate: time.Now().UTC().Add(time.Hour)}
+		_, err = classService.CreateClassTask(context.Background(), class.OwnerId, task)
+		assert.Nil(t, err)
+
+		buff := bytes.NewBufferString(`{"status":"done"}`)
+		p := fmt.Sprintf("/%s/task/%s/progress", class.ClassId, task.TaskId)
+		req := httptest.NewRequest("PUT", p, buff)
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		req = httptest.NewRequest("GET", p, nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var progress response.Response[*domain.ClassTaskProgress]
+		err = json.NewDecoder(resp.Body).Decode(&progress)
+		assert.Nil(t, err)
+		assert.Equal(t, domain.ClassTaskStatusDone, progress.Data.Status)
+
+		req = httptest.NewRequest("GET", p+"/summary", nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var summary response.Response[*domain.ClassTaskSummary]
+		err = json.NewDecoder(resp.Body).Decode(&summary)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, summary.Data.Done)
+
+		req = httptest.NewRequest("GET", p+"/summary", nil)
+		req.Header.Set("user-id", uuid.NewString())
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+	})
+
+	t.Run("invite", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+
+		buff := bytes.NewBufferString(`{"maxUses":1}`)
+		req := httptest.NewRequest("POST", fmt.Sprintf("/%s/invite", class.ClassId), buff)
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var invite response.Response[*domain.ClassInvite]
+		err = json.NewDecoder(resp.Body).Decode(&invite)
+		assert.Nil(t, err)
+
+		req = httptest.NewRequest("GET", fmt.Sprintf("/%s/invite", class.ClassId), nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		req = httptest.NewRequest("POST", "/join/"+invite.Data.Code, nil)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 401, resp.StatusCode)
+
+		req.Header.Set("user-id", uuid.NewString())
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		req.Header.Set("user-id", uuid.NewString())
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 422, resp.StatusCode)
+
+		req = httptest.NewRequest("DELETE", fmt.Sprintf("/%s/invite/%s", class.ClassId, invite.Data.Code), nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+	})
+
+	t.Run("update task", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		res, err := classService.CreateClassTask(context.Background(), class.OwnerId, &domain.ClassTask{
+			ClassId:  class.ClassId,
+			AuthorId: class.OwnerId,
+			Name:     "foo",
+		})
+		assert.Nil(t, err)
+
+		p := fmt.Sprintf("/%s/task/%s", class.ClassId, res.Data.TaskId)
+		req := httptest.NewRequest("PATCH", p, bytes.NewBufferString(`{"name":"bar"}`))
+		req.Header.Set("content-type", "application/json")
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 401, resp.StatusCode)
+
+		req = httptest.NewRequest("PATCH", p, bytes.NewBufferString(`{"name":"bar"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", uuid.NewString())
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+
+		req = httptest.NewRequest("PATCH", fmt.Sprintf("/%s/task/%s", xid.New().String(), res.Data.TaskId), bytes.NewBufferString(`{"name":"bar"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+
+		req = httptest.NewRequest("PATCH", p, bytes.NewBufferString(`{"name":"bar"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var body response.Response[*domain.ClassTask]
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		assert.Nil(t, err)
+		assert.Equal(t, "bar", body.Data.Name)
+		assert.Equal(t, class.OwnerId, body.Data.UpdatedBy)
+		assert.NotNil(t, body.Data.UpdatedAt)
+	})
+
+	t.Run("member role", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		member := uuid.NewString()
+		_, err = classService.AddMember(context.Background(), class.OwnerId, &domain.ClassMember{
+			ClassId: class.ClassId,
+			UserId:  member,
+		})
+		assert.Nil(t, err)
+
+		p := fmt.Sprintf("/%s/member/%s", class.ClassId, member)
+		req := httptest.NewRequest("PATCH", p, bytes.NewBufferString(`{"level":"garbage"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+
+		req = httptest.NewRequest("PATCH", p, bytes.NewBufferString(`{"level":"owner"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 422, resp.StatusCode)
+
+		p = fmt.Sprintf("/%s/transfer", class.ClassId)
+		body := fmt.Sprintf(`{"userId":%q,"confirm":%q}`, member, class.Name)
+		req = httptest.NewRequest("POST", p, bytes.NewBufferString(body))
+		req.Header.Set("content-type", "application/json")
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 401, resp.StatusCode)
+
+		req = httptest.NewRequest("POST", p, bytes.NewBufferString(body))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", member)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+
+		req = httptest.NewRequest("POST", p, bytes.NewBufferString(body))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var classBody response.Response[*domain.Class]
+		err = json.NewDecoder(resp.Body).Decode(&classBody)
+		assert.Nil(t, err)
+		assert.Equal(t, member, classBody.Data.OwnerId)
+
+		m, err := classService.ClassMemberRepository.GetMember(context.Background(), &domain.ClassMember{ClassId: class.ClassId, UserId: member})
+		assert.Nil(t, err)
+		assert.Equal(t, domain.RoleOwner, m.Level)
+	})
+
+	t.Run("attachment", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		res, err := classService.CreateClassTask(context.Background(), class.OwnerId, &domain.ClassTask{
+			ClassId:  class.ClassId,
+			AuthorId: class.OwnerId,
+			Name:     "foo",
+		})
+		assert.Nil(t, err)
+		p := fmt.Sprintf("/%s/task/%s/attachment", class.ClassId, res.Data.TaskId)
+
+		buff := bytes.NewBuffer(nil)
+		form := multipart.NewWriter(buff)
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="file"; filename="worksheet.pdf"`)
+		header.Set("Content-Type", "application/pdf")
+		part, err := form.CreatePart(header)
+		assert.Nil(t, err)
+		part.Write([]byte("%PDF-1.4"))
+		form.Close()
+
+		req := httptest.NewRequest("POST", p, bytes.NewReader(buff.Bytes()))
+		req.Header.Set("content-type", form.FormDataContentType())
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 401, resp.StatusCode)
+
+		req = httptest.NewRequest("POST", p, bytes.NewBufferString("{}"))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 400, resp.StatusCode, "upload must be multipart")
+
+		req = httptest.NewRequest("POST", p, bytes.NewReader(buff.Bytes()))
+		req.Header.Set("content-type", form.FormDataContentType())
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var body response.Response[*domain.ClassAttachment]
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		assert.Nil(t, err)
+		assert.Equal(t, "worksheet.pdf", body.Data.Name)
+		assert.Equal(t, int64(8), body.Data.Size)
+
+		// download does not need authentication
+		req = httptest.NewRequest("GET", body.Data.URL, nil)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "application/pdf", resp.Header.Get("content-type"))
+		assert.Contains(t, resp.Header.Get("content-disposition"), "worksheet.pdf")
+		content, err := io.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, "%PDF-1.4", string(content))
+
+		req = httptest.NewRequest("GET", p+"/"+body.Data.AttachmentId, nil)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 403, resp.StatusCode, "download without signature should be rejected")
+
+		p = fmt.Sprintf("%s/%s", p, body.Data.AttachmentId)
+		req = httptest.NewRequest("DELETE", p, nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+
+		req = httptest.NewRequest("GET", body.Data.URL, nil)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+
+	t.Run("comment", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		res, err := classService.CreateClassTask(context.Background(), class.OwnerId, &domain.ClassTask{
+			ClassId:  class.ClassId,
+			AuthorId: class.OwnerId,
+			Name:     "foo",
+		})
+		assert.Nil(t, err)
+		p := fmt.Sprintf("/%s/task/%s/comment", class.ClassId, res.Data.TaskId)
+
+		req := httptest.NewRequest("POST", p, bytes.NewBufferString(`{"body":"question"}`))
+		req.Header.Set("content-type", "application/json")
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 401, resp.StatusCode)
+
+		req = httptest.NewRequest("POST", p, bytes.NewBufferString(`{"body":"question"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var body response.Response[*domain.TaskComment]
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		assert.Nil(t, err)
+		assert.Equal(t, "question", body.Data.Body)
+
+		reply := fmt.Sprintf(`{"body":"answer","parentId":%q}`, body.Data.CommentId)
+		req = httptest.NewRequest("POST", p, bytes.NewBufferString(reply))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		req = httptest.NewRequest("GET", p+"?limit=1", nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var list response.Response[[]*domain.TaskComment]
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(list.Data)) {
+			assert.Equal(t, body.Data.CommentId, list.Data[0].CommentId)
+		}
+		if assert.NotNil(t, list.Pagination) {
+			assert.NotEqual(t, "", list.Pagination.NextCursor)
+		}
+
+		p = fmt.Sprintf("%s/%s", p, body.Data.CommentId)
+		req = httptest.NewRequest("PATCH", p, bytes.NewBufferString(`{"body":"edited"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		req = httptest.NewRequest("DELETE", p, nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+
+		req = httptest.NewRequest("DELETE", p, nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+
+	t.Run("announcement", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		p := fmt.Sprintf("/%s/announcement", class.ClassId)
+
+		req := httptest.NewRequest("POST", p, bytes.NewBufferString(`{"title":"exam"}`))
+		req.Header.Set("content-type", "application/json")
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 401, resp.StatusCode)
+
+		req = httptest.NewRequest("POST", p, bytes.NewBufferString(`{"body":"moved to Friday"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+		var resErr response.ResponseError
+		err = json.NewDecoder(resp.Body).Decode(&resErr)
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(resErr.Errors), "validation error should list the failing field") {
+			assert.Equal(t, "title", resErr.Errors[0].Field)
+			assert.Equal(t, "required", resErr.Errors[0].Rule)
+		}
+
+		req = httptest.NewRequest("POST", p, bytes.NewBufferString(`{"title":"exam","body":"moved to Friday","pinned":true}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var body response.Response[*domain.ClassAnnouncement]
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		assert.Nil(t, err)
+		assert.True(t, body.Data.Pinned)
+
+		req = httptest.NewRequest("GET", p, nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var list response.Response[[]*domain.ClassAnnouncement]
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(list.Data))
+
+		p = fmt.Sprintf("%s/%s", p, body.Data.AnnouncementId)
+		req = httptest.NewRequest("PUT", p, bytes.NewBufferString(`{"title":"exam","body":"moved to Monday"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		req = httptest.NewRequest("GET", p, nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		assert.Nil(t, err)
+		assert.Equal(t, "moved to Monday", body.Data.Body)
+		assert.False(t, body.Data.Pinned, "update should replace the announcement")
+
+		req = httptest.NewRequest("DELETE", p, nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+
+		req = httptest.NewRequest("GET", p, nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+
+	t.Run("localized error", func(t *testing.T) {
+		classId := xid.New().String()
+		for _, tc := range []struct {
+			lang    string
+			message string
+		}{
+			{"", fmt.Sprintf("can not find class with id %q", classId)},
+			{"id-ID,id;q=0.9", fmt.Sprintf("kelas dengan id %q tidak ditemukan", classId)},
+		} {
+			req := httptest.NewRequest("GET", fmt.Sprintf("/%s/info", classId), nil)
+			req.Header.Set("accept-language", tc.lang)
+			resp, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, 404, resp.StatusCode)
+			var resErr response.ResponseError
+			err = json.NewDecoder(resp.Body).Decode(&resErr)
+			assert.Nil(t, err)
+			assert.Equal(t, "class.not_found", resErr.ErrorCode)
+			assert.Equal(t, tc.message, resErr.Message)
+		}
+	})
+
+	t.Run("visibility", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo", Visibility: domain.ClassVisibilityPrivate}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+
+		for _, p := range []string{"info", "task", "schedule", "timetable", "member"} {
+			for _, tc := range []struct {
+				userId string
+				code   int
+			}{
+				{"", 404},
+				{uuid.NewString(), 404},
+				{class.OwnerId, 200},
+			} {
+				req := httptest.NewRequest("GET", fmt.Sprintf("/%s/%s", class.ClassId, p), nil)
+				if tc.userId != "" {
+					req.Header.Set("user-id", tc.userId)
+				}
+				resp, err := app.Test(req)
+				assert.Nil(t, err)
+				assert.Equal(t, tc.code, resp.StatusCode, "GET %s by %q", p, tc.userId)
+			}
+		}
+	})
+
+	t.Run("join request", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		requester := uuid.NewString()
+		p := fmt.Sprintf("/%s/join-request", class.ClassId)
+
+		req := httptest.NewRequest("POST", p, bytes.NewBufferString(`{"message":"let me in"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", requester)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		req = httptest.NewRequest("GET", p, nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var list response.Response[[]*domain.ClassJoinRequest]
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(list.Data)) {
+			assert.Equal(t, "let me in", list.Data[0].Message)
+		}
+
+		// approve without body grant member role
+		req = httptest.NewRequest("POST", fmt.Sprintf("%s/%s/approve", p, requester), nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var member response.Response[*domain.ClassMember]
+		err = json.NewDecoder(resp.Body).Decode(&member)
+		assert.Nil(t, err)
+		assert.Equal(t, domain.RoleMember, member.Data.Level)
+
+		req = httptest.NewRequest("POST", fmt.Sprintf("%s/%s/reject", p, requester), nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+
+	t.Run("filter task", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		for _, task := range []*domain.ClassTask{
+			{Name: "essay", DueDate: time.Now().Add(-48 * time.Hour)},
+			{Name: "quiz", DueDate: time.Now().Add(48 * time.Hour)},
+			{Name: "essay review", DueDate: time.Now().Add(72 * time.Hour)},
+		} {
+			task.ClassId = class.ClassId
+			task.AuthorId = class.OwnerId
+			err := classService.ClassTaskRepository.CreateTask(context.Background(), task)
+			assert.Nil(t, err)
+		}
+
+		p := fmt.Sprintf("/%s/task", class.ClassId)
+		for _, tc := range []struct {
+			Query string
+			Code  int
+			Names []string
+		}{
+			{"", 200, []string{"quiz", "essay review"}},
+			{"?q=essay&sort=-dueDate", 200, []string{"essay review", "essay"}},
+			{"?overdue=true", 200, []string{"essay"}},
+			{"?author=" + class.OwnerId + "&sort=dueDate&status=todo", 200, []string{"essay", "quiz", "essay review"}},
+			{"?sort=name", 400, nil},
+			{"?overdue=maybe", 400, nil},
+		} {
+			req := httptest.NewRequest("GET", p+tc.Query, nil)
+			req.Header.Set("user-id", class.OwnerId)
+			resp, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.Code, resp.StatusCode, tc.Query)
+			if tc.Code != 200 {
+				continue
+			}
+			var body response.Response[[]*domain.ClassTask]
+			err = json.NewDecoder(resp.Body).Decode(&body)
+			assert.Nil(t, err)
+			names := make([]string, 0)
+			for _, task := range body.Data {
+				names = append(names, task.Name)
+			}
+			if tc.Query == "" {
+				assert.ElementsMatch(t, tc.Names, names, tc.Query)
+			} else {
+				assert.Equal(t, tc.Names, names, tc.Query)
+			}
+		}
+	})
+
+	t.Run("recurring task", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		start := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+		p := fmt.Sprintf("/%s/task", class.ClassId)
+		for _, tc := range []struct {
+			Recurrence string
+			Code       int
+		}{
+			{"FREQ=DAILY;COUNT=0", 400},
+			{"FREQ=WEEKLY;UNTIL=20300101;COUNT=2", 400},
+			{"FREQ=DAILY;INTERVAL=2;COUNT=3", 200},
+		} {
+			body, _ := json.Marshal(domain.ClassTask{Name: "quiz", DueDate: start, Recurrence: tc.Recurrence})
+			req := httptest.NewRequest("POST", p, bytes.NewBuffer(body))
+			req.Header.Set("content-type", "application/json")
+			req.Header.Set("user-id", class.OwnerId)
+			resp, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.Code, resp.StatusCode, tc.Recurrence)
+		}
+
+		list := func() []*domain.ClassTask {
+			req := httptest.NewRequest("GET", p+"?from="+url.QueryEscape(start.Format(time.RFC3339))+"&sort=dueDate", nil)
+			req.Header.Set("user-id", class.OwnerId)
+			resp, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			var body response.Response[[]*domain.ClassTask]
+			err = json.NewDecoder(resp.Body).Decode(&body)
+			assert.Nil(t, err)
+			return body.Data
+		}
+		tasks := list()
+		if !assert.Equal(t, 3, len(tasks)) {
+			return
+		}
+		assert.True(t, tasks[1].DueDate.Equal(start.Add(48*time.Hour)), "every other day")
+		assert.NotEqual(t, "", tasks[1].SeriesId)
+
+		req := httptest.NewRequest("DELETE", fmt.Sprintf("%s/%s", p, tasks[1].TaskId), nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+		assert.Equal(t, 2, len(list()), "occurrence should be skipped")
+
+		req = httptest.NewRequest("DELETE", fmt.Sprintf("%s/%s", p, tasks[1].SeriesId), nil)
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+		assert.Equal(t, 0, len(list()), "series should be deleted")
+	})
+
+	t.Run("search", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: xid.New().String()}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+
+		req := httptest.NewRequest("GET", "/search?q=", nil)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+
+		req = httptest.NewRequest("GET", "/search?q="+class.Name+"&limit=5", nil)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var res response.Response[*domain.SearchResult]
+		err = json.NewDecoder(resp.Body).Decode(&res)
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(res.Data.Classes)) {
+			assert.Equal(t, class.ClassId, res.Data.Classes[0].ClassId)
+		}
+	})
+
+	t.Run("timetable", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+
+		schedule := `{"name":"foo","startAt":"0000-01-01T08:00:00Z","duration":60,"day":1}`
+		p := fmt.Sprintf("/%s/schedule", class.ClassId)
+		for _, code := range []int{204, 409} {
+			req := httptest.NewRequest("POST", p, bytes.NewBufferString(schedule))
+			req.Header.Set("content-type", "application/json")
+			req.Header.Set("user-id", class.OwnerId)
+			resp, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, code, resp.StatusCode)
+		}
+
+		p = fmt.Sprintf("/%s/schedule/day/2", class.ClassId)
+		body := `[{"name":"bar","startAt":"0000-01-01T10:00:00Z","duration":60},{"name":"baz","startAt":"0000-01-01T07:00:00Z","duration":60}]`
+		req := httptest.NewRequest("PUT", p, bytes.NewBufferString(body))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", uuid.NewString())
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+
+		req = httptest.NewRequest("PUT", fmt.Sprintf("/%s/schedule/day/9", class.ClassId), bytes.NewBufferString(body))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+
+		req = httptest.NewRequest("PUT", p, bytes.NewBufferString(body))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", class.OwnerId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		req = httptest.NewRequest("GET", fmt.Sprintf("/%s/timetable", class.ClassId), nil)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var timetable response.Response[[]*domain.ClassTimetableDay]
+		err = json.NewDecoder(resp.Body).Decode(&timetable)
+		assert.Nil(t, err)
+		if assert.Equal(t, 7, len(timetable.Data)) {
+			assert.Equal(t, 1, len(timetable.Data[1].Schedules))
+			if assert.Equal(t, 2, len(timetable.Data[2].Schedules)) {
+				assert.Equal(t, "baz", timetable.Data[2].Schedules[0].Name)
+				assert.Equal(t, "bar", timetable.Data[2].Schedules[1].Name)
+			}
+		}
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		_, err := classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		_, err = classService.CreateClassTask(context.Background(), class.OwnerId, &domain.ClassTask{
+			ClassId:  class.ClassId,
+			AuthorId: class.OwnerId,
+			Name:     "foo",
+		})
+		assert.Nil(t, err)
+
+		p := fmt.Sprintf("/%s/audit", class.ClassId)
+		req := httptest.NewRequest("GET", p, nil)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 401, resp.StatusCode)
+
+		req.Header.Set("user-id", uuid.NewString())
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+
+		for _, tc := range []struct {
+			Query string
+			Code  int
+			Len   int
+		}{
+			{"", 200, 2},
+			{"?action=" + domain.AuditTaskCreate, 200, 1},
+			{"?actor=" + class.OwnerId + "&limit=1", 200, 1},
+			{"?actor=" + uuid.NewString(), 200, 0},
+			{"?from=" + url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339)), 200, 0},
+			{"?cursor=foo", 400, 0},
+		} {
+			req := httptest.NewRequest("GET", p+tc.Query, nil)
+			req.Header.Set("user-id", class.OwnerId)
+			resp, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.Code, resp.StatusCode, tc.Query)
+			if tc.Code != 200 {
+				continue
+			}
+			var body response.Response[[]*domain.ClassAuditEntry]
+			err = json.NewDecoder(resp.Body).Decode(&body)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.Len, len(body.Data), tc.Query)
+		}
+	})
+
 	t.Run("create", func(t *testing.T) {
 		for _, tc := range []struct {
 			Name string
@@ -212,7 +983,7 @@ func TestClassRouter(t *testing.T) {
 						Day:      int8(0),
 					})
 					assert.Nil(t, err)
-					p = fmt.Sprintf("/%s/schedule", body.Data.ClassId)
+					p = fmt.Sprintf("/%s/schedule?allowOverlap=true", body.Data.ClassId)
 					req = httptest.NewRequest("POST", p, buff)
 					req.Header.Set("content-type", "application/json")
 					req.Header.Set("user-id", tc.User.UserId)
@@ -276,6 +1047,29 @@ func TestClassRouter(t *testing.T) {
 				err = json.NewDecoder(resp.Body).Decode(&memBody)
 				assert.Nil(t, err)
 				assert.Equal(t, 2, len(memBody.Data))
+				if assert.NotNil(t, memBody.Pagination) {
+					assert.Equal(t, domain.DefaultPageLimit, memBody.Pagination.Limit)
+					assert.Equal(t, "", memBody.Pagination.NextCursor)
+				}
+
+				req = httptest.NewRequest("GET", p+"?limit=1", nil)
+				resp, err = app.Test(req)
+				assert.Nil(t, err)
+				assert.Equal(t, 200, resp.StatusCode)
+
+				memBody = response.Response[[]*domain.ClassMember]{}
+				err = json.NewDecoder(resp.Body).Decode(&memBody)
+				assert.Nil(t, err)
+				assert.Equal(t, 1, len(memBody.Data))
+				if assert.NotNil(t, memBody.Pagination) {
+					assert.Equal(t, 1, memBody.Pagination.Limit)
+					assert.NotEqual(t, "", memBody.Pagination.NextCursor)
+				}
+
+				req = httptest.NewRequest("GET", p+"?limit=1&cursor=foo", nil)
+				resp, err = app.Test(req)
+				assert.Nil(t, err)
+				assert.Equal(t, 400, resp.StatusCode)
 
 				p = fmt.Sprintf("/%s/member/%s", body.Data.ClassId, user.UserId)
 				buff.Reset()
@@ -380,3 +1174,145 @@ func TestClassRouter(t *testing.T) {
 		}
 	})
 }
+
+func TestClassEvents(t *testing.T) {
+	t.Parallel()
+
+	classService := ClassService{
+		UserRepository:              user.NewUserRepositoryMem(),
+		ClassRepository:             NewClassRepositoryMem(),
+		ClassTaskRepository:         classtask.NewClassTaskRepositoryMem(),
+		ClassMemberRepository:       classmember.NewClassMemberRepositoryMem(),
+		ClassScheduleRepository:     classschedule.NewClassScheduleRepositoryMem(),
+		ClassInviteRepository:       classinvite.NewClassInviteRepositoryMem(),
+		ClassAuditRepository:        classaudit.NewClassAuditRepositoryMem(),
+		ClassEventBus:               classevent.NewClassEventBusMem(100),
+		TxRunner:                    database.NewTxRunnerMem(),
+		ClassAttachmentRepository:   classattachment.NewClassAttachmentRepositoryMem(),
+		BlobStore:                   newBlobStore(t),
+		URLSigner:                   &signedurl.Signer{Secret: []byte("secret"), TTL: time.Minute},
+		TaskCommentRepository:       taskcomment.NewTaskCommentRepositoryMem(),
+		ClassAnnouncementRepository: classannouncement.NewClassAnnouncementRepositoryMem(),
+		ClassJoinRequestRepository:  classjoinrequest.NewClassJoinRequestRepositoryMem(),
+	}
+
+	app := fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+		ErrorHandler:          response.ErrorHandler,
+	})
+	app.Use(auth.MockMiddleware)
+	app.Route("/", Route(classService))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() {
+		app.Shutdown()
+	})
+
+	class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+	_, err = classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+	endpoint := fmt.Sprintf("http://%s/%s/events", ln.Addr(), class.ClassId)
+
+	open := func(userId, lastEventId string) (*http.Response, context.CancelFunc) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		assert.Nil(t, err)
+		req.Header.Set("user-id", userId)
+		if lastEventId != "" {
+			req.Header.Set("Last-Event-ID", lastEventId)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(t, err) {
+			cancel()
+			t.FailNow()
+		}
+		return resp, cancel
+	}
+
+	// readEvent return id and type of the next event, comments are skipped
+	readEvent := func(r *bufio.Reader) (string, string) {
+		var id, event string
+		for {
+			line, err := r.ReadString('\n')
+			if !assert.Nil(t, err) {
+				t.FailNow()
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case line == "" && event != "":
+				return id, event
+			}
+		}
+	}
+
+	resp, cancel := open(uuid.NewString(), "")
+	assert.Equal(t, 403, resp.StatusCode)
+	resp.Body.Close()
+	cancel()
+
+	resp, cancel = open(class.OwnerId, "")
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("content-type"))
+	r := bufio.NewReader(resp.Body)
+	// wait until subscribed
+	line, err := r.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, ": connected\n", line)
+
+	_, err = classService.CreateClassTask(context.Background(), class.OwnerId, &domain.ClassTask{
+		ClassId:  class.ClassId,
+		AuthorId: class.OwnerId,
+		Name:     "foo",
+	})
+	assert.Nil(t, err)
+	lastEventId, event := readEvent(r)
+	assert.Equal(t, domain.EventTaskCreated, event)
+	resp.Body.Close()
+	cancel()
+
+	_, err = classService.CreateSchedule(context.Background(), &domain.ClassSchedule{
+		ClassId:  class.ClassId,
+		AuthorId: class.OwnerId,
+		Name:     "foo",
+	}, false)
+	assert.Nil(t, err)
+
+	resp, cancel = open(class.OwnerId, lastEventId)
+	defer cancel()
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+	_, event = readEvent(bufio.NewReader(resp.Body))
+	assert.Equal(t, domain.EventScheduleCreated, event, "missed event should be replayed")
+
+	member := uuid.NewString()
+	_, err = classService.AddMember(context.Background(), class.OwnerId, &domain.ClassMember{
+		ClassId: class.ClassId,
+		UserId:  member,
+	})
+	assert.Nil(t, err)
+	memberResp, memberCancel := open(member, "")
+	defer memberCancel()
+	defer memberResp.Body.Close()
+	assert.Equal(t, 200, memberResp.StatusCode)
+	r = bufio.NewReader(memberResp.Body)
+	line, err = r.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, ": connected\n", line)
+
+	_, err = classService.LeaveClass(context.Background(), member, class.ClassId)
+	assert.Nil(t, err)
+	_, event = readEvent(r)
+	assert.Equal(t, domain.EventMemberRemoved, event)
+	for err == nil {
+		_, err = r.ReadString('\n')
+	}
+	assert.Equal(t, io.EOF, err, "stream should end once the subscriber left the class")
+}
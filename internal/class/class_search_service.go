@@ -0,0 +1,40 @@
+package class
+
+import (
+	"context"
+	"strings"
+
+	"nory/common/response"
+	"nory/domain"
+)
+
+// Search find public classes by name and description and users by username
+// and name, each list has at most limit items.
+func (cs *ClassService) Search(ctx context.Context, query string, limit int) (*response.Response[*domain.SearchResult], error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, response.NewBadRequest("search.query_required", nil)
+	}
+	if len(query) > domain.MaxSearchQuery {
+		return nil, response.NewBadRequest("search.query_too_long", response.Params{"max": domain.MaxSearchQuery})
+	}
+	if limit <= 0 {
+		limit = domain.DefaultSearchLimit
+	}
+	if limit > domain.MaxSearchLimit {
+		limit = domain.MaxSearchLimit
+	}
+
+	classes, err := cs.ClassRepository.SearchClasses(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	users, err := cs.UserRepository.SearchUsers(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return response.New(200, &domain.SearchResult{
+		Classes: classes,
+		Users:   users,
+	}), nil
+}
@@ -2,11 +2,13 @@ package class
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
+	"strconv"
 	"time"
 
 	"nory/common/response"
+	"nory/common/signedurl"
 	"nory/common/validator"
 	"nory/domain"
 )
@@ -17,14 +19,24 @@ type ClassService struct {
 	ClassTaskRepository     domain.ClassTaskRepository
 	ClassMemberRepository   domain.ClassMemberRepository
 	ClassScheduleRepository domain.ClassScheduleRepository
+	ClassInviteRepository   domain.ClassInviteRepository
+	ClassAuditRepository    domain.ClassAuditRepository
+	ClassEventBus           domain.ClassEventBus
+	TxRunner                domain.TxRunner
+	// attachment content is kept in BlobStore and downloaded through url signed by URLSigner
+	ClassAttachmentRepository   domain.ClassAttachmentRepository
+	BlobStore                   domain.BlobStore
+	URLSigner                   *signedurl.Signer
+	TaskCommentRepository       domain.TaskCommentRepository
+	ClassAnnouncementRepository domain.ClassAnnouncementRepository
+	ClassJoinRequestRepository  domain.ClassJoinRequestRepository
+	// JoinRequestNotifier is optional, it is told when a join request is decided
+	JoinRequestNotifier domain.ClassJoinRequestNotifier
 }
 
-func (cs *ClassService) GetClassInfo(ctx context.Context, classId string) (*response.Response[*domain.Class], error) {
-	class, err := cs.ClassRepository.GetClass(ctx, classId)
-	if errors.Is(err, domain.ErrClassNotExists) {
-		msg := fmt.Sprintf("can not find class with id %q", classId)
-		return nil, response.NewNotFound(msg)
-	}
+// GetClassInfo get the class, userId may be empty for anonymous user
+func (cs *ClassService) GetClassInfo(ctx context.Context, userId, classId string) (*response.Response[*domain.Class], error) {
+	class, err := cs.viewClass(ctx, userId, classId)
 	if err != nil {
 		return nil, err
 	}
@@ -32,67 +44,265 @@ func (cs *ClassService) GetClassInfo(ctx context.Context, classId string) (*resp
 }
 
 func (cs *ClassService) UpdateClass(ctx context.Context, userId string, class *domain.Class) (*response.Response[any], error) {
-	permission := permissionAdmin
+	capability := domain.CapabilityUpdateClass
 	if class.Name != "" {
-		permission |= permissionOwner
+		capability = domain.CapabilityRenameClass
 	}
 
 	if err := validator.ValidateStruct(class); err != nil {
 		return nil, err
 	}
-	if err := cs.AccessClass(ctx, userId, class.ClassId, permission.String()); err != nil {
+	if err := cs.AccessClass(ctx, userId, class.ClassId, capability); err != nil {
 		return nil, err
 	}
-	if err := cs.ClassRepository.UpdateClass(ctx, class); err != nil {
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		prev, err := cs.ClassRepository.GetClass(ctx, class.ClassId)
+		if err != nil {
+			return err
+		}
+		before := snapshot(prev)
+		if err := cs.ClassRepository.UpdateClass(ctx, class); err != nil {
+			return err
+		}
+		curr, err := cs.ClassRepository.GetClass(ctx, class.ClassId)
+		if err != nil {
+			return err
+		}
+		return cs.audit(ctx, userId, class.ClassId, domain.AuditClassUpdate, class.ClassId, before, snapshot(curr))
+	}); err != nil {
 		return nil, err
 	}
-
 	return response.New[any](204, nil), nil
 }
 
-func (cs *ClassService) GetClassInfoByName(ctx context.Context, username, name string) (*response.Response[*domain.Class], error) {
+// GetClassInfoByName find class of a user by its name, only public class can be found by non-members
+func (cs *ClassService) GetClassInfoByName(ctx context.Context, userId, username, name string) (*response.Response[*domain.Class], error) {
 	user, err := cs.UserRepository.GetUserByUsername(ctx, username)
 	if errors.Is(err, domain.ErrUserNotExists) {
-		msg := fmt.Sprintf("can not find user with username %q", username)
-		return nil, response.NewNotFound(msg)
+		return nil, response.NewNotFound("user.not_found_by_username", response.Params{"username": username})
 	}
 	if err != nil {
 		return nil, err
 	}
 
+	notFound := response.NewNotFound("class.not_found_by_name", response.Params{"name": name, "username": username})
 	class, err := cs.ClassRepository.GetClassByName(ctx, user.UserId, name)
 	if errors.Is(err, domain.ErrClassNotExists) {
-		msg := fmt.Sprintf("can not find class with name %q that owned by %q", name, username)
-		return nil, response.NewNotFound(msg)
+		return nil, notFound
 	}
 	if err != nil {
 		return nil, err
 	}
+	if !class.Listed() {
+		ok, err := cs.canView(ctx, userId, class.ClassId)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, notFound
+		}
+	}
 	return response.New(200, class), nil
 }
 
-func (cs *ClassService) GetClassTasks(ctx context.Context, classId string, from, to time.Time) (*response.Response[[]*domain.ClassTask], error) {
-	if from.IsZero() {
-		from = time.Now()
+// GetClassTasks list tasks passing the filter, when userId is not empty each task will contain progress of that user.
+// Filter without criteria other than due date list tasks due in a week from the given or current time.
+func (cs *ClassService) GetClassTasks(ctx context.Context, userId, classId string, filter domain.ClassTaskFilter, page domain.Pagination) (*response.Response[[]*domain.ClassTask], error) {
+	if err := validator.ValidateStruct(filter); err != nil {
+		return nil, err
+	}
+	if _, err := cs.viewClass(ctx, userId, classId); err != nil {
+		return nil, err
+	}
+	if filter.Status != "" && userId == "" {
+		return nil, response.NewUnathorized("auth.required", nil)
+	}
+	if filter.Query == "" && filter.AuthorId == "" && filter.Status == "" && !filter.Overdue {
+		if filter.From.IsZero() {
+			filter.From = time.Now()
+		}
+		if filter.To.IsZero() {
+			filter.To = filter.From.Add(7 * 24 * time.Hour)
+		}
+	}
+	filter.UserId = userId
+	filter.Now = time.Now()
+	// recurring tasks are expanded over the range, keep it bounded
+	start := filter.From
+	if start.IsZero() {
+		start = filter.Now
+	}
+	if !filter.To.IsZero() && filter.To.Sub(start) > domain.RecurrenceHorizon {
+		return nil, response.NewBadRequest("task.range_too_long", response.Params{"days": int(domain.RecurrenceHorizon.Hours() / 24)})
+	}
+	page = page.Normalize()
+	tasks, next, err := cs.ClassTaskRepository.FindTasks(ctx, classId, filter, page)
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return nil, response.NewBadRequest("pagination.invalid_cursor", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if userId == "" {
+		return response.NewPaginated(200, tasks, page.Limit, next), nil
+	}
+
+	taskIds := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		taskIds = append(taskIds, task.TaskId)
+	}
+	progress, err := cs.ClassTaskRepository.GetUserProgress(ctx, userId, taskIds)
+	if err != nil {
+		return nil, err
+	}
+	progressMap := make(map[string]*domain.ClassTaskProgress, len(progress))
+	for _, p := range progress {
+		progressMap[p.TaskId] = p
+	}
+
+	result := make([]*domain.ClassTask, 0, len(tasks))
+	for _, task := range tasks {
+		// copy the task, progress belong to the user and should not leak to the stored task
+		t := *task
+		t.Progress = progressMap[t.TaskId]
+		if t.Progress == nil {
+			t.Progress = &domain.ClassTaskProgress{
+				TaskId: t.TaskId,
+				UserId: userId,
+				Status: domain.ClassTaskStatusTodo,
+			}
+		}
+		result = append(result, &t)
+	}
+	return response.NewPaginated(200, result, page.Limit, next), nil
+}
+
+func (cs *ClassService) GetTaskProgress(ctx context.Context, userId, classId, taskId string) (*response.Response[*domain.ClassTaskProgress], error) {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityTrackProgress); err != nil {
+		return nil, err
 	}
-	if to.IsZero() {
-		to = from.Add(7 * 24 * time.Hour)
+	if _, err := cs.getClassTask(ctx, classId, taskId); err != nil {
+		return nil, err
 	}
-	tasks, err := cs.ClassTaskRepository.GetTasksWithRange(ctx, classId, from, to)
-	return response.New(200, tasks), err
+
+	progress, err := cs.ClassTaskRepository.GetProgress(ctx, taskId, userId)
+	if errors.Is(err, domain.ErrClassTaskProgressNotExists) {
+		progress = &domain.ClassTaskProgress{
+			TaskId: taskId,
+			UserId: userId,
+			Status: domain.ClassTaskStatusTodo,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return response.New(200, progress), nil
 }
 
-func (cs *ClassService) CreateClass(ctx context.Context, class *domain.Class) (*response.Response[*domain.Class], error) {
-	if err := validator.ValidateStruct(class); err != nil {
+func (cs *ClassService) SetTaskProgress(ctx context.Context, classId string, progress *domain.ClassTaskProgress) (*response.Response[*domain.ClassTaskProgress], error) {
+	if err := validator.ValidateStruct(progress); err != nil {
+		return nil, err
+	}
+	if err := cs.AccessClass(ctx, progress.UserId, classId, domain.CapabilityTrackProgress); err != nil {
 		return nil, err
 	}
-	if err := cs.ClassRepository.CreateClass(ctx, class); err != nil {
+	task, err := cs.getClassTask(ctx, classId, progress.TaskId)
+	if err != nil {
+		return nil, err
+	}
+
+	progress.CompletedAt = nil
+	if progress.Status == domain.ClassTaskStatusDone {
+		now := time.Now().UTC()
+		progress.CompletedAt = &now
+	}
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		if err := cs.storeOccurrence(ctx, task); err != nil {
+			return err
+		}
+		return cs.ClassTaskRepository.SetProgress(ctx, progress)
+	}); err != nil {
+		return nil, err
+	}
+	return response.New(200, progress), nil
+}
+
+func (cs *ClassService) GetTaskSummary(ctx context.Context, userId, classId, taskId string) (*response.Response[*domain.ClassTaskSummary], error) {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityViewSummary); err != nil {
+		return nil, err
+	}
+	if _, err := cs.getClassTask(ctx, classId, taskId); err != nil {
+		return nil, err
+	}
+
+	summary, err := cs.ClassTaskRepository.GetProgressSummary(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	members, _, err := cs.ClassMemberRepository.ListMembers(ctx, classId, domain.Pagination{})
+	if err != nil {
+		return nil, err
+	}
+	summary.Members = len(members)
+	summary.Todo = summary.Members - summary.InProgress - summary.Done
+	if summary.Todo < 0 {
+		summary.Todo = 0
+	}
+	return response.New(200, summary), nil
+}
+
+// getClassTask get task and make sure it is belong to the class
+func (cs *ClassService) getClassTask(ctx context.Context, classId, taskId string) (*domain.ClassTask, error) {
+	task, err := cs.ClassTaskRepository.GetTask(ctx, taskId)
+	if errors.Is(err, domain.ErrClassTaskNotExists) || (err == nil && task.ClassId != classId) {
+		return nil, response.NewNotFound("task.not_found", response.Params{"taskId": taskId})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// storeOccurrence store occurrence of a series before it is edited or worked on, see domain.ClassTask.SeriesId.
+// Stored occurrence keep following its series until it is edited by UpdateTask.
+func (cs *ClassService) storeOccurrence(ctx context.Context, task *domain.ClassTask) error {
+	if task.SeriesId == "" {
+		return nil
+	}
+	return cs.ClassTaskRepository.CreateOccurrence(ctx, task)
+}
+
+// parseRecurrence validate task recurrence and store it in canonical form
+func parseRecurrence(task *domain.ClassTask) error {
+	if task.Recurrence == "" {
+		return nil
+	}
+	rule, err := domain.ParseRecurrence(task.Recurrence)
+	if err != nil {
+		return response.NewBadRequest("task.invalid_recurrence", response.Params{"error": err.Error()})
+	}
+	task.Recurrence = rule.String()
+	return nil
+}
+
+func (cs *ClassService) CreateClass(ctx context.Context, class *domain.Class) (*response.Response[*domain.Class], error) {
+	if class.Visibility == "" {
+		class.Visibility = domain.ClassVisibilityPublic
+	}
+	if err := validator.ValidateStruct(class); err != nil {
 		return nil, err
 	}
-	if err := cs.ClassMemberRepository.CreateMember(ctx, &domain.ClassMember{
-		UserId:  class.OwnerId,
-		ClassId: class.ClassId,
-		Level:   "owner",
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		if err := cs.ClassRepository.CreateClass(ctx, class); err != nil {
+			return err
+		}
+		if err := cs.ClassMemberRepository.CreateMember(ctx, &domain.ClassMember{
+			UserId:  class.OwnerId,
+			ClassId: class.ClassId,
+			Level:   domain.RoleOwner,
+		}); err != nil {
+			return err
+		}
+		return cs.audit(ctx, class.OwnerId, class.ClassId, domain.AuditClassCreate, class.ClassId, nil, snapshot(class))
 	}); err != nil {
 		return nil, err
 	}
@@ -103,44 +313,165 @@ func (cs *ClassService) CreateClassTask(ctx context.Context, userId string, task
 	if err := validator.ValidateStruct(task); err != nil {
 		return nil, err
 	}
-	if err := cs.AccessClass(ctx, userId, task.ClassId, "member"); err != nil {
+	if err := parseRecurrence(task); err != nil {
+		return nil, err
+	}
+	// the series start at DueDate
+	if task.Recurring() && task.DueDate.IsZero() {
+		return nil, response.NewBadRequest("task.recurrence_requires_due_date", nil)
+	}
+	task.SeriesId = ""
+	task.Skipped = false
+	if err := cs.AccessClass(ctx, userId, task.ClassId, domain.CapabilityCreateTask); err != nil {
 		return nil, err
 	}
-	if err := cs.ClassTaskRepository.CreateTask(ctx, task); err != nil {
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		if err := cs.ClassTaskRepository.CreateTask(ctx, task); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, task.ClassId, domain.AuditTaskCreate, task.TaskId, nil, snapshot(task)); err != nil {
+			return err
+		}
+		cs.publish(ctx, task.ClassId, domain.EventTaskCreated, snapshot(task))
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 	return response.New(200, task), nil
 }
 
+// UpdateClassTask update mutable fields of a task, only the task author or class admin can update it.
+// Updating a series change every occurrence which is not edited on its own.
+func (cs *ClassService) UpdateClassTask(ctx context.Context, userId, classId string, task *domain.ClassTask) (*response.Response[*domain.ClassTask], error) {
+	if err := validator.ValidateStruct(task); err != nil {
+		return nil, err
+	}
+	if err := parseRecurrence(task); err != nil {
+		return nil, err
+	}
+	prev, err := cs.getClassTask(ctx, classId, task.TaskId)
+	if err != nil {
+		return nil, err
+	}
+	if task.Recurrence != "" && !prev.Recurring() {
+		return nil, response.NewBadRequest("task.not_recurring", nil)
+	}
+
+	required := domain.CapabilityManageTasks
+	if prev.AuthorId == userId {
+		required = domain.CapabilityCreateTask
+	}
+	if err := cs.AccessClass(ctx, userId, classId, required); err != nil {
+		return nil, err
+	}
+
+	var curr *domain.ClassTask
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) (err error) {
+		before := snapshot(prev)
+		task.UpdatedBy = userId
+		if err := cs.storeOccurrence(ctx, prev); err != nil {
+			return err
+		}
+		if err := cs.ClassTaskRepository.UpdateTask(ctx, task); err != nil {
+			return err
+		}
+		curr, err = cs.ClassTaskRepository.GetTask(ctx, task.TaskId)
+		if err != nil {
+			return err
+		}
+		after := snapshot(curr)
+		if err := cs.audit(ctx, userId, classId, domain.AuditTaskUpdate, task.TaskId, before, after); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventTaskUpdated, after)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New(200, curr), nil
+}
+
 func (cs *ClassService) DeleteClassTask(ctx context.Context, userId, taskId string) (*response.Response[any], error) {
 	task, err := cs.ClassTaskRepository.GetTask(ctx, taskId)
 	if errors.Is(err, domain.ErrClassTaskNotExists) {
-		msg := fmt.Sprintf("can not find task with id %q", taskId)
-		return nil, response.NewUnprocessableEntity(msg)
+		return nil, response.NewUnprocessableEntity("task.not_found", response.Params{"taskId": taskId})
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	if err := cs.AccessClass(ctx, userId, task.ClassId, "admin"); err != nil {
+	if err := cs.AccessClass(ctx, userId, task.ClassId, domain.CapabilityManageTasks); err != nil {
 		return nil, err
 	}
 
-	if err := cs.ClassTaskRepository.DeleteTask(ctx, taskId); err != nil {
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		before := snapshot(task)
+		// stored occurrences are deleted with their series
+		taskIds := []string{taskId}
+		if task.Recurring() {
+			occurrences, err := cs.ClassTaskRepository.GetOccurrences(ctx, taskId)
+			if err != nil {
+				return err
+			}
+			for _, occurrence := range occurrences {
+				taskIds = append(taskIds, occurrence.TaskId)
+			}
+		}
+		for _, id := range taskIds {
+			attachments, err := cs.ClassAttachmentRepository.ListAttachments(ctx, id)
+			if err != nil {
+				return err
+			}
+			for _, attachment := range attachments {
+				if err := cs.ClassAttachmentRepository.DeleteAttachment(ctx, attachment.AttachmentId); err != nil {
+					return err
+				}
+			}
+			cs.deleteBlobs(ctx, attachments)
+		}
+		// deleted occurrence is skipped, otherwise the series would expand it again
+		if task.SeriesId != "" {
+			if err := cs.ClassTaskRepository.SkipOccurrence(ctx, task); err != nil {
+				return err
+			}
+		} else if err := cs.ClassTaskRepository.DeleteTask(ctx, taskId); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, task.ClassId, domain.AuditTaskDelete, taskId, before, nil); err != nil {
+			return err
+		}
+		cs.publish(ctx, task.ClassId, domain.EventTaskDeleted, before)
+		return nil
+	}); err != nil {
 		return nil, err
 	}
-
 	return response.New[any](204, nil), nil
 }
 
 func (cs *ClassService) AddMember(ctx context.Context, userId string, member *domain.ClassMember) (*response.Response[any], error) {
+	if member.Level == "" {
+		member.Level = domain.RoleMember
+	}
 	if err := validator.ValidateStruct(member); err != nil {
 		return nil, err
 	}
-	if err := cs.AccessClass(ctx, userId, member.ClassId, "admin"); err != nil {
+	actor, err := cs.accessClass(ctx, userId, member.ClassId, domain.CapabilityManageMembers)
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.checkAssignRole(actor, member.Level); err != nil {
 		return nil, err
 	}
-	if err := cs.ClassMemberRepository.CreateMember(ctx, member); err != nil {
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		if err := cs.ClassMemberRepository.CreateMember(ctx, member); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, member.ClassId, domain.AuditMemberAdd, member.UserId, nil, snapshot(member)); err != nil {
+			return err
+		}
+		cs.publish(ctx, member.ClassId, domain.EventMemberAdded, snapshot(member))
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 	return response.New[any](204, nil), nil
@@ -149,8 +480,7 @@ func (cs *ClassService) AddMember(ctx context.Context, userId string, member *do
 func (cs *ClassService) AddMemberByUsername(ctx context.Context, userId, username string, member *domain.ClassMember) (*response.Response[any], error) {
 	user, err := cs.UserRepository.GetUserByUsername(ctx, username)
 	if errors.Is(err, domain.ErrUserNotExists) {
-		msg := fmt.Sprintf("can not find user with username %q", username)
-		return nil, response.NewUnprocessableEntity(msg)
+		return nil, response.NewUnprocessableEntity("user.not_found_by_username", response.Params{"username": username})
 	}
 	if err != nil {
 		return nil, err
@@ -159,55 +489,380 @@ func (cs *ClassService) AddMemberByUsername(ctx context.Context, userId, usernam
 	return cs.AddMember(ctx, userId, member)
 }
 
+func (cs *ClassService) CreateInvite(ctx context.Context, invite *domain.ClassInvite) (*response.Response[*domain.ClassInvite], error) {
+	if invite.Level == "" {
+		invite.Level = domain.RoleMember
+	}
+	if err := validator.ValidateStruct(invite); err != nil {
+		return nil, err
+	}
+	if invite.Expired(time.Now()) {
+		return nil, response.NewBadRequest("invite.expiry_past", nil)
+	}
+	actor, err := cs.accessClass(ctx, invite.AuthorId, invite.ClassId, domain.CapabilityManageInvites)
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.checkAssignRole(actor, invite.Level); err != nil {
+		return nil, err
+	}
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		invite.Uses = 0
+		if err := cs.ClassInviteRepository.CreateInvite(ctx, invite); err != nil {
+			return err
+		}
+		return cs.audit(ctx, invite.AuthorId, invite.ClassId, domain.AuditInviteCreate, invite.Code, nil, snapshot(invite))
+	}); err != nil {
+		return nil, err
+	}
+	return response.New(200, invite), nil
+}
+
+// ListInvites list invites that still can be used to join the class
+func (cs *ClassService) ListInvites(ctx context.Context, userId, classId string) (*response.Response[[]*domain.ClassInvite], error) {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityManageInvites); err != nil {
+		return nil, err
+	}
+	invites, err := cs.ClassInviteRepository.ListInvites(ctx, classId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]*domain.ClassInvite, 0, len(invites))
+	for _, invite := range invites {
+		if invite.Expired(now) || invite.Exhausted() {
+			continue
+		}
+		active = append(active, invite)
+	}
+	return response.New(200, active), nil
+}
+
+func (cs *ClassService) DeleteInvite(ctx context.Context, userId, classId, code string) (*response.Response[any], error) {
+	invite, err := cs.ClassInviteRepository.GetInvite(ctx, code)
+	if errors.Is(err, domain.ErrClassInviteNotExists) || (err == nil && invite.ClassId != classId) {
+		return nil, response.NewNotFound("invite.not_found", response.Params{"code": code})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityManageInvites); err != nil {
+		return nil, err
+	}
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		before := snapshot(invite)
+		if err := cs.ClassInviteRepository.DeleteInvite(ctx, code); err != nil {
+			return err
+		}
+		return cs.audit(ctx, userId, classId, domain.AuditInviteDelete, code, before, nil)
+	}); err != nil {
+		return nil, err
+	}
+	return response.New[any](204, nil), nil
+}
+
+// JoinClass add user as class member using invite code
+func (cs *ClassService) JoinClass(ctx context.Context, userId, code string) (*response.Response[*domain.ClassMember], error) {
+	invite, err := cs.ClassInviteRepository.GetInvite(ctx, code)
+	if errors.Is(err, domain.ErrClassInviteNotExists) {
+		return nil, response.NewNotFound("invite.not_found", response.Params{"code": code})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invite.Expired(time.Now()) || invite.Exhausted() {
+		return nil, response.NewUnprocessableEntity("invite.invalid", response.Params{"code": code})
+	}
+
+	member := &domain.ClassMember{
+		ClassId: invite.ClassId,
+		UserId:  userId,
+		Level:   invite.Level,
+	}
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		_, err := cs.ClassMemberRepository.GetMember(ctx, member)
+		if err == nil {
+			return response.NewConflict("member.already_exists", response.Params{"userId": userId, "classId": invite.ClassId})
+		}
+		if !errors.Is(err, domain.ErrClassMemberNotExists) {
+			return err
+		}
+
+		if err := cs.ClassInviteRepository.UseInvite(ctx, code); err != nil {
+			if errors.Is(err, domain.ErrClassInviteExhausted) || errors.Is(err, domain.ErrClassInviteNotExists) {
+				return response.NewUnprocessableEntity("invite.invalid", response.Params{"code": code})
+			}
+			return err
+		}
+		if err := cs.ClassMemberRepository.CreateMember(ctx, member); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, member.ClassId, domain.AuditMemberAdd, userId, nil, snapshot(member)); err != nil {
+			return err
+		}
+		cs.publish(ctx, member.ClassId, domain.EventMemberAdded, snapshot(member))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New(200, member), nil
+}
+
+// DeleteMember remove a member with lower role than the actor, the last owner of the class can not be removed
 func (cs *ClassService) DeleteMember(ctx context.Context, userId, classId, memberId string) (*response.Response[any], error) {
-	if err := cs.AccessClass(ctx, userId, classId, "admin"); err != nil {
+	actor, err := cs.accessClass(ctx, userId, classId, domain.CapabilityManageMembers)
+	if err != nil {
+		return nil, err
+	}
+	member, err := cs.ClassMemberRepository.GetMember(ctx, &domain.ClassMember{ClassId: classId, UserId: memberId})
+	if errors.Is(err, domain.ErrClassMemberNotExists) {
+		return response.New[any](204, nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !domain.RoleCanManage(actor.Level, member.Level) {
+		return nil, response.NewForbidden("member.cannot_remove", response.Params{"userId": userId, "role": member.Level, "classId": classId})
+	}
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		before := snapshot(member)
+		if member.Level == domain.RoleOwner {
+			if err := cs.checkOwnerRemains(ctx, classId, memberId); err != nil {
+				return err
+			}
+		}
+		if err := cs.removeMember(ctx, classId, memberId); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditMemberRemove, memberId, before, nil); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventMemberRemoved, before)
+		return nil
+	}); err != nil {
 		return nil, err
 	}
-	if err := cs.ClassMemberRepository.DeleteMember(ctx, &domain.ClassMember{ClassId: classId, UserId: memberId}); err != nil {
+	return response.New[any](204, nil), nil
+}
+
+// LeaveClass remove the user from the class, owner must transfer ownership before leaving
+func (cs *ClassService) LeaveClass(ctx context.Context, userId, classId string) (*response.Response[any], error) {
+	member, err := cs.accessClass(ctx, userId, classId, domain.CapabilityViewClass)
+	if err != nil {
+		return nil, err
+	}
+	if member.Level == domain.RoleOwner {
+		return nil, response.NewConflict("member.owner_cannot_leave", nil)
+	}
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		before := snapshot(member)
+		if err := cs.removeMember(ctx, classId, userId); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditMemberLeave, userId, before, nil); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventMemberRemoved, before)
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 	return response.New[any](204, nil), nil
 }
 
-func (cs *ClassService) ListMember(ctx context.Context, classId string) (*response.Response[[]*domain.ClassMember], error) {
-	members, err := cs.ClassMemberRepository.ListMembers(ctx, classId)
+// removeMember delete the membership along with data that belong to the user in the class
+func (cs *ClassService) removeMember(ctx context.Context, classId, userId string) error {
+	if err := cs.ClassTaskRepository.DeleteUserProgress(ctx, classId, userId); err != nil {
+		return err
+	}
+	return cs.ClassMemberRepository.DeleteMember(ctx, &domain.ClassMember{ClassId: classId, UserId: userId})
+}
+
+func (cs *ClassService) ListMember(ctx context.Context, userId, classId string, page domain.Pagination) (*response.Response[[]*domain.ClassMember], error) {
+	if _, err := cs.viewClass(ctx, userId, classId); err != nil {
+		return nil, err
+	}
+	page = page.Normalize()
+	members, next, err := cs.ClassMemberRepository.ListMembers(ctx, classId, page)
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return nil, response.NewBadRequest("pagination.invalid_cursor", nil)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return response.New(200, members), nil
+	return response.NewPaginated(200, members, page.Limit, next), nil
 }
 
+// UpdateMember change role of a member, the actor can only change member with lower role
+// to a role lower than its own. Owner role is granted through TransferOwnership.
 func (cs *ClassService) UpdateMember(ctx context.Context, userId string, member *domain.ClassMember) (*response.Response[any], error) {
-	if err := cs.AccessClass(ctx, userId, member.ClassId, "admin"); err != nil {
+	actor, err := cs.accessClass(ctx, userId, member.ClassId, domain.CapabilityManageMembers)
+	if err != nil {
 		return nil, err
 	}
 	if err := validator.ValidateStruct(member); err != nil {
 		return nil, err
 	}
-	if err := cs.ClassMemberRepository.UpdateMember(ctx, member); err != nil {
+	if err := cs.checkAssignRole(actor, member.Level); err != nil {
+		return nil, err
+	}
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		prev, err := cs.ClassMemberRepository.GetMember(ctx, member)
+		if errors.Is(err, domain.ErrClassMemberNotExists) {
+			return response.NewNotFound("member.not_found", response.Params{"userId": member.UserId, "classId": member.ClassId})
+		}
+		if err != nil {
+			return err
+		}
+		if !domain.RoleCanManage(actor.Level, prev.Level) {
+			return response.NewForbidden("member.cannot_change", response.Params{"userId": userId, "role": prev.Level, "classId": member.ClassId})
+		}
+		if prev.Level == domain.RoleOwner {
+			if err := cs.checkOwnerRemains(ctx, member.ClassId, member.UserId); err != nil {
+				return err
+			}
+		}
+		before := snapshot(prev)
+		if err := cs.ClassMemberRepository.UpdateMember(ctx, member); err != nil {
+			return err
+		}
+		curr, err := cs.ClassMemberRepository.GetMember(ctx, member)
+		if err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, member.ClassId, domain.AuditMemberUpdate, member.UserId, before, snapshot(curr)); err != nil {
+			return err
+		}
+		cs.publish(ctx, member.ClassId, domain.EventMemberUpdated, snapshot(curr))
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 	return response.New[any](204, nil), nil
 }
 
-func (cs *ClassService) DeleteClass(ctx context.Context, userId, classId string) (*response.Response[any], error) {
-	if err := cs.AccessClass(ctx, userId, classId, "admin"); err != nil {
+// TransferOwnership make an existing member the owner of the class, the current owner become admin.
+// confirm must be the class name, guarding against accidental transfer.
+func (cs *ClassService) TransferOwnership(ctx context.Context, userId, classId, newOwnerId, confirm string) (*response.Response[*domain.Class], error) {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityTransferClass); err != nil {
+		return nil, err
+	}
+	if newOwnerId == userId {
+		return nil, response.NewBadRequest("transfer.self", nil)
+	}
+
+	var class *domain.Class
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) (err error) {
+		class, err = cs.ClassRepository.GetClass(ctx, classId)
+		if err != nil {
+			return err
+		}
+		if class.OwnerId != userId {
+			return response.NewForbidden("transfer.not_owner", response.Params{"classId": classId})
+		}
+		if confirm != class.Name {
+			return response.NewBadRequest("transfer.confirm_mismatch", nil)
+		}
+		next, err := cs.ClassMemberRepository.GetMember(ctx, &domain.ClassMember{ClassId: classId, UserId: newOwnerId})
+		if errors.Is(err, domain.ErrClassMemberNotExists) {
+			return response.NewUnprocessableEntity("member.not_found", response.Params{"userId": newOwnerId, "classId": classId})
+		}
+		if err != nil {
+			return err
+		}
+		before := snapshot(class)
+
+		err = cs.ClassRepository.TransferClass(ctx, classId, newOwnerId)
+		if errors.Is(err, domain.ErrClassAlreadyExists) {
+			return response.NewConflict("class.name_conflict", response.Params{"userId": newOwnerId, "name": class.Name})
+		}
+		if err != nil {
+			return err
+		}
+		promoted := &domain.ClassMember{ClassId: classId, UserId: next.UserId, Level: domain.RoleOwner}
+		if err := cs.ClassMemberRepository.UpdateMember(ctx, promoted); err != nil {
+			return err
+		}
+		demoted := &domain.ClassMember{ClassId: classId, UserId: userId, Level: domain.RoleAdmin}
+		if err := cs.ClassMemberRepository.UpdateMember(ctx, demoted); err != nil {
+			return err
+		}
+
+		class, err = cs.ClassRepository.GetClass(ctx, classId)
+		if err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditClassTransfer, classId, before, snapshot(class)); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventMemberUpdated, snapshot(promoted))
+		cs.publish(ctx, classId, domain.EventMemberUpdated, snapshot(demoted))
+		return nil
+	}); err != nil {
 		return nil, err
 	}
+	return response.New(200, class), nil
+}
 
-	if err := cs.ClassRepository.DeleteClass(ctx, classId); err != nil {
+func (cs *ClassService) DeleteClass(ctx context.Context, userId, classId string) (*response.Response[any], error) {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityDeleteClass); err != nil {
 		return nil, err
 	}
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		class, err := cs.ClassRepository.GetClass(ctx, classId)
+		if err != nil {
+			return err
+		}
+		before := snapshot(class)
 
+		// attachment rows are deleted along with the class, only the blobs are left to clean
+		attachments, err := cs.ClassAttachmentRepository.ListClassAttachments(ctx, classId)
+		if err != nil {
+			return err
+		}
+		if err := cs.ClassRepository.DeleteClass(ctx, classId); err != nil {
+			return err
+		}
+		cs.deleteBlobs(ctx, attachments)
+		cs.publish(ctx, classId, domain.EventClassDeleted, nil)
+		return cs.audit(ctx, userId, classId, domain.AuditClassDelete, classId, before, nil)
+	}); err != nil {
+		return nil, err
+	}
 	return response.New[any](204, nil), nil
 }
 
-func (cs *ClassService) CreateSchedule(ctx context.Context, schedule *domain.ClassSchedule) (*response.Response[any], error) {
-	if err := cs.AccessClass(ctx, schedule.AuthorId, schedule.ClassId, "admin"); err != nil {
+// CreateSchedule reject schedule that overlaps with existing schedule of the
+// class unless allowOverlap is set.
+func (cs *ClassService) CreateSchedule(ctx context.Context, schedule *domain.ClassSchedule, allowOverlap bool) (*response.Response[any], error) {
+	if err := validator.ValidateStruct(schedule); err != nil {
+		return nil, err
+	}
+	if err := cs.AccessClass(ctx, schedule.AuthorId, schedule.ClassId, domain.CapabilityManageSchedules); err != nil {
 		return nil, err
 	}
-	if err := cs.ClassScheduleRepository.CreateSchedule(ctx, schedule); err != nil {
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		if !allowOverlap {
+			existing, _, err := cs.ClassScheduleRepository.GetSchedules(ctx, schedule.ClassId, domain.Pagination{})
+			if err != nil {
+				return err
+			}
+			if err := checkOverlap(existing, schedule); err != nil {
+				return err
+			}
+		}
+		if err := cs.ClassScheduleRepository.CreateSchedule(ctx, schedule); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, schedule.AuthorId, schedule.ClassId, domain.AuditScheduleCreate, schedule.ScheduleId, nil, snapshot(schedule)); err != nil {
+			return err
+		}
+		cs.publish(ctx, schedule.ClassId, domain.EventScheduleCreated, snapshot(schedule))
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 	return response.New[any](204, nil), nil
@@ -216,103 +871,361 @@ func (cs *ClassService) CreateSchedule(ctx context.Context, schedule *domain.Cla
 func (cs *ClassService) DeleteSchedule(ctx context.Context, userId, scheduleId string) (*response.Response[any], error) {
 	schedule, err := cs.ClassScheduleRepository.GetSchedule(ctx, scheduleId)
 	if errors.Is(err, domain.ErrClassScheduleNotExists) {
-		msg := fmt.Sprintf("can not find class schedule with id %q", scheduleId)
-		return nil, response.NewUnprocessableEntity(msg)
+		return nil, response.NewUnprocessableEntity("schedule.not_found", response.Params{"scheduleId": scheduleId})
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	if err := cs.AccessClass(ctx, userId, schedule.ClassId, "admin"); err != nil {
+	if err := cs.AccessClass(ctx, userId, schedule.ClassId, domain.CapabilityManageSchedules); err != nil {
 		return nil, err
 	}
-	if err := cs.ClassScheduleRepository.DeleteSchedule(ctx, scheduleId); err != nil {
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		before := snapshot(schedule)
+		if err := cs.ClassScheduleRepository.DeleteSchedule(ctx, scheduleId); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, schedule.ClassId, domain.AuditScheduleDelete, scheduleId, before, nil); err != nil {
+			return err
+		}
+		cs.publish(ctx, schedule.ClassId, domain.EventScheduleDeleted, before)
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 	return response.New[any](204, nil), nil
 }
 
 func (cs *ClassService) ClearSchedules(ctx context.Context, userId, classId string, day int8) (*response.Response[any], error) {
-	if err := cs.AccessClass(ctx, userId, classId, "admin"); err != nil {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityManageSchedules); err != nil {
 		return nil, err
 	}
-	if err := cs.ClassScheduleRepository.ClearSchedules(ctx, classId, day); err != nil {
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		schedules, _, err := cs.ClassScheduleRepository.GetSchedules(ctx, classId, domain.Pagination{})
+		if err != nil {
+			return err
+		}
+		cleared := make([]*domain.ClassSchedule, 0)
+		for _, schedule := range schedules {
+			if schedule.Day == day {
+				cleared = append(cleared, schedule)
+			}
+		}
+		before := snapshot(cleared)
+		if err := cs.ClassScheduleRepository.ClearSchedules(ctx, classId, day); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditScheduleClear, strconv.Itoa(int(day)), before, nil); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventScheduleCleared, before)
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 	return response.New[any](204, nil), nil
 }
 
-func (cs *ClassService) GetClassSchedules(ctx context.Context, classId string) (*response.Response[[]*domain.ClassSchedule], error) {
-	schedules, err := cs.ClassScheduleRepository.GetSchedules(ctx, classId)
-	if err != nil {
+// ReplaceSchedules replace every schedule of the day with the given schedules.
+func (cs *ClassService) ReplaceSchedules(ctx context.Context, userId, classId string, day int8, schedules []*domain.ClassSchedule, allowOverlap bool) (*response.Response[[]*domain.ClassSchedule], error) {
+	if day < 0 || day > 6 {
+		return nil, response.NewBadRequest("schedule.invalid_day", nil)
+	}
+	for _, schedule := range schedules {
+		schedule.ClassId = classId
+		schedule.AuthorId = userId
+		schedule.Day = day
+		if err := validator.ValidateStruct(schedule); err != nil {
+			return nil, err
+		}
+	}
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityManageSchedules); err != nil {
+		return nil, err
+	}
+
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		existing, _, err := cs.ClassScheduleRepository.GetSchedules(ctx, classId, domain.Pagination{})
+		if err != nil {
+			return err
+		}
+		cleared := make([]*domain.ClassSchedule, 0)
+		kept := make([]*domain.ClassSchedule, 0, len(existing)+len(schedules))
+		for _, schedule := range existing {
+			if schedule.Day == day {
+				cleared = append(cleared, schedule)
+			} else {
+				kept = append(kept, schedule)
+			}
+		}
+		if !allowOverlap {
+			for _, schedule := range schedules {
+				if err := checkOverlap(kept, schedule); err != nil {
+					return err
+				}
+				kept = append(kept, schedule)
+			}
+		}
+
+		before := snapshot(cleared)
+		if err := cs.ClassScheduleRepository.ReplaceSchedules(ctx, classId, day, schedules); err != nil {
+			return err
+		}
+		after := snapshot(schedules)
+		if err := cs.audit(ctx, userId, classId, domain.AuditScheduleReplace, strconv.Itoa(int(day)), before, after); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventScheduleReplaced, after)
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 	return response.New(200, schedules), nil
 }
 
-func (cs *ClassService) GetSchedule(ctx context.Context, scheduleId string) (*response.Response[*domain.ClassSchedule], error) {
-	schedules, err := cs.ClassScheduleRepository.GetSchedule(ctx, scheduleId)
+func checkOverlap(schedules []*domain.ClassSchedule, schedule *domain.ClassSchedule) error {
+	for _, other := range schedules {
+		if other.Overlaps(schedule) {
+			return response.NewConflict("schedule.overlap", response.Params{"name": other.Name, "scheduleId": other.ScheduleId})
+		}
+	}
+	return nil
+}
+
+// GetClassTimetable return schedules of the class grouped by weekday.
+func (cs *ClassService) GetClassTimetable(ctx context.Context, userId, classId string) (*response.Response[[]*domain.ClassTimetableDay], error) {
+	if _, err := cs.viewClass(ctx, userId, classId); err != nil {
+		return nil, err
+	}
+	schedules, _, err := cs.ClassScheduleRepository.GetSchedules(ctx, classId, domain.Pagination{})
+	if err != nil {
+		return nil, err
+	}
+	return response.New(200, domain.NewClassTimetable(schedules)), nil
+}
+
+func (cs *ClassService) GetClassSchedules(ctx context.Context, userId, classId string, page domain.Pagination) (*response.Response[[]*domain.ClassSchedule], error) {
+	if _, err := cs.viewClass(ctx, userId, classId); err != nil {
+		return nil, err
+	}
+	page = page.Normalize()
+	schedules, next, err := cs.ClassScheduleRepository.GetSchedules(ctx, classId, page)
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return nil, response.NewBadRequest("pagination.invalid_cursor", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response.NewPaginated(200, schedules, page.Limit, next), nil
+}
+
+func (cs *ClassService) GetSchedule(ctx context.Context, userId, scheduleId string) (*response.Response[*domain.ClassSchedule], error) {
+	notFound := response.NewNotFound("schedule.not_found", response.Params{"scheduleId": scheduleId})
+	schedule, err := cs.ClassScheduleRepository.GetSchedule(ctx, scheduleId)
 	if errors.Is(err, domain.ErrClassScheduleNotExists) {
-		msg := fmt.Sprintf("can not find class schedule with id %q", scheduleId)
-		return nil, response.NewNotFound(msg)
+		return nil, notFound
 	}
 	if err != nil {
 		return nil, err
 	}
-	return response.New(200, schedules), nil
+	if _, err := cs.viewClass(ctx, userId, schedule.ClassId); err != nil {
+		var resErr *response.ResponseError
+		if errors.As(err, &resErr) {
+			return nil, notFound
+		}
+		return nil, err
+	}
+	return response.New(200, schedule), nil
 }
 
-type permissionLevel uint8
+func (cs *ClassService) ListAudit(ctx context.Context, userId, classId string, filter domain.ClassAuditFilter, page domain.Pagination) (*response.Response[[]*domain.ClassAuditEntry], error) {
+	if err := validator.ValidateStruct(filter); err != nil {
+		return nil, err
+	}
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityViewAudit); err != nil {
+		return nil, err
+	}
+	page = page.Normalize()
+	entries, next, err := cs.ClassAuditRepository.ListEntries(ctx, classId, filter, page)
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return nil, response.NewBadRequest("pagination.invalid_cursor", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response.NewPaginated(200, entries, page.Limit, next), nil
+}
 
-const (
-	permissionOwner permissionLevel = 0xff >> iota
-	permissionAdmin
-	permissionUser
-)
+// audit append entry to the class audit log, before and after are taken with snapshot
+func (cs *ClassService) audit(ctx context.Context, actorId, classId, action, targetId string, before, after json.RawMessage) error {
+	return cs.ClassAuditRepository.CreateEntry(ctx, &domain.ClassAuditEntry{
+		ClassId:  classId,
+		ActorId:  actorId,
+		Action:   action,
+		TargetId: targetId,
+		Before:   before,
+		After:    after,
+	})
+}
 
-func permissionLevelFromString(s string) permissionLevel {
-	switch s {
-	case "owner":
-		return permissionOwner
-	case "admin":
-		return permissionAdmin
-	default:
-		return permissionUser
+// SubscribeEvents stream events of the class to its member, see domain.ClassEventBus.Subscribe.
+// The stream end after the event that remove the user from the class.
+func (cs *ClassService) SubscribeEvents(ctx context.Context, userId, classId, lastEventId string) (<-chan *domain.ClassEvent, error) {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityViewClass); err != nil {
+		return nil, err
 	}
+	events, err := cs.ClassEventBus.Subscribe(ctx, classId, lastEventId)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *domain.ClassEvent)
+	go func() {
+		defer close(out)
+		for event := range events {
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+			if removes(event, userId) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// removes report whether event remove userId from the class
+func removes(event *domain.ClassEvent, userId string) bool {
+	if event.Type != domain.EventMemberRemoved {
+		return false
+	}
+	var member domain.ClassMember
+	if err := json.Unmarshal(event.Data, &member); err != nil {
+		return false
+	}
+	return member.UserId == userId
+}
+
+// publish send the event once the transaction of ctx is committed, events
+// are best effort and failing to publish does not undo the change.
+func (cs *ClassService) publish(ctx context.Context, classId, eventType string, data json.RawMessage) {
+	cs.TxRunner.AfterCommit(ctx, func() {
+		cs.ClassEventBus.Publish(context.Background(), &domain.ClassEvent{
+			ClassId: classId,
+			Type:    eventType,
+			Data:    data,
+		})
+	})
 }
 
-func (p permissionLevel) String() string {
-	switch p {
-	case permissionOwner:
-		return "owner"
-	case permissionAdmin:
-		return "admin"
-	default:
-		return "user"
+// snapshot encode v immediately, memory repositories share pointers and v may change afterward
+func snapshot(v any) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
 	}
+	return b
+}
+
+// AccessClass make sure user is a member of the class with role that is granted capability
+func (cs *ClassService) AccessClass(ctx context.Context, userId, classId string, capability domain.ClassCapability) error {
+	_, err := cs.accessClass(ctx, userId, classId, capability)
+	return err
 }
 
-func (cs *ClassService) AccessClass(ctx context.Context, userId, classId, minimum string) error {
-	msg := fmt.Sprintf("user with id %q does not has %q access to class with id %q", userId, minimum, classId)
-	resErr := response.NewForbidden(msg)
+// accessClass is AccessClass that also return membership of the user.
+// Non-members of a private class get not found instead of forbidden.
+func (cs *ClassService) accessClass(ctx context.Context, userId, classId string, capability domain.ClassCapability) (*domain.ClassMember, error) {
+	resErr := response.NewForbidden("member.forbidden", response.Params{"userId": userId, "capability": capability, "classId": classId})
 
 	member, err := cs.ClassMemberRepository.GetMember(ctx, &domain.ClassMember{
 		ClassId: classId,
 		UserId:  userId,
 	})
 	if errors.Is(err, domain.ErrClassMemberNotExists) {
-		return resErr
+		class, err := cs.ClassRepository.GetClass(ctx, classId)
+		if errors.Is(err, domain.ErrClassNotExists) || (err == nil && class.Private()) {
+			return nil, response.NewNotFound("class.not_found", response.Params{"classId": classId})
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, resErr
 	}
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if !domain.RoleAllows(member.Level, capability) {
+		return nil, resErr
 	}
+	return member, nil
+}
 
-	required := permissionLevelFromString(minimum)
-	memberLevel := permissionLevelFromString(member.Level)
+// viewClass get the class when userId may read it, userId may be empty for
+// anonymous user. Private class is not found for non-members so its existence
+// is not leaked.
+func (cs *ClassService) viewClass(ctx context.Context, userId, classId string) (*domain.Class, error) {
+	notFound := response.NewNotFound("class.not_found", response.Params{"classId": classId})
+	class, err := cs.ClassRepository.GetClass(ctx, classId)
+	if errors.Is(err, domain.ErrClassNotExists) {
+		return nil, notFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !class.Private() {
+		return class, nil
+	}
+	ok, err := cs.canView(ctx, userId, classId)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, notFound
+	}
+	return class, nil
+}
 
-	if memberLevel >= required {
-		return nil
+// canView report whether userId is a member allowed to view the class
+func (cs *ClassService) canView(ctx context.Context, userId, classId string) (bool, error) {
+	if userId == "" {
+		return false, nil
+	}
+	member, err := cs.ClassMemberRepository.GetMember(ctx, &domain.ClassMember{
+		ClassId: classId,
+		UserId:  userId,
+	})
+	if errors.Is(err, domain.ErrClassMemberNotExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
 	}
+	return domain.RoleAllows(member.Level, domain.CapabilityViewClass), nil
+}
+
+// checkAssignRole make sure actor may grant role to other member
+func (cs *ClassService) checkAssignRole(actor *domain.ClassMember, role string) error {
+	if role == domain.RoleOwner {
+		return response.NewUnprocessableEntity("member.owner_grant", nil)
+	}
+	if !domain.RoleCanManage(actor.Level, role) {
+		return response.NewForbidden("member.cannot_grant", response.Params{"userId": actor.UserId, "role": role, "classId": actor.ClassId})
+	}
+	return nil
+}
 
-	return resErr
+// checkOwnerRemains make sure the class still has an owner other than userId
+func (cs *ClassService) checkOwnerRemains(ctx context.Context, classId, userId string) error {
+	members, _, err := cs.ClassMemberRepository.ListMembers(ctx, classId, domain.Pagination{})
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if member.Level == domain.RoleOwner && member.UserId != userId {
+			return nil
+		}
+	}
+	return response.NewConflict("class.owner_required", nil)
 }
@@ -2,15 +2,30 @@ package class_test
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net/url"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"nory/common/database"
+	"nory/common/response"
+	"nory/common/signedurl"
 	"nory/domain"
+	"nory/internal/blob"
 	. "nory/internal/class"
+	classannouncement "nory/internal/class_announcement"
+	classattachment "nory/internal/class_attachment"
+	classaudit "nory/internal/class_audit"
+	classevent "nory/internal/class_event"
+	classinvite "nory/internal/class_invite"
+	classjoinrequest "nory/internal/class_join_request"
 	classmember "nory/internal/class_member"
 	classschedule "nory/internal/class_schedule"
 	classtask "nory/internal/class_task"
+	taskcomment "nory/internal/task_comment"
 	"nory/internal/user"
 
 	"github.com/google/uuid"
@@ -21,21 +36,57 @@ import (
 func TestClassService(t *testing.T) {
 	t.Parallel()
 	classService := ClassService{
-		UserRepository:          user.NewUserRepositoryMem(),
-		ClassRepository:         NewClassRepositoryMem(),
-		ClassTaskRepository:     classtask.NewClassTaskRepositoryMem(),
-		ClassMemberRepository:   classmember.NewClassMemberRepositoryMem(),
-		ClassScheduleRepository: classschedule.NewClassScheduleRepositoryMem(),
+		UserRepository:              user.NewUserRepositoryMem(),
+		ClassRepository:             NewClassRepositoryMem(),
+		ClassTaskRepository:         classtask.NewClassTaskRepositoryMem(),
+		ClassMemberRepository:       classmember.NewClassMemberRepositoryMem(),
+		ClassScheduleRepository:     classschedule.NewClassScheduleRepositoryMem(),
+		ClassInviteRepository:       classinvite.NewClassInviteRepositoryMem(),
+		ClassAuditRepository:        classaudit.NewClassAuditRepositoryMem(),
+		ClassEventBus:               classevent.NewClassEventBusMem(100),
+		TxRunner:                    database.NewTxRunnerMem(),
+		ClassAttachmentRepository:   classattachment.NewClassAttachmentRepositoryMem(),
+		BlobStore:                   newBlobStore(t),
+		URLSigner:                   &signedurl.Signer{Secret: []byte("secret"), TTL: time.Minute},
+		TaskCommentRepository:       taskcomment.NewTaskCommentRepositoryMem(),
+		ClassAnnouncementRepository: classannouncement.NewClassAnnouncementRepositoryMem(),
+		ClassJoinRequestRepository:  classjoinrequest.NewClassJoinRequestRepositoryMem(),
 	}
 
 	cst := classServiceTest{classService}
 
 	t.Run("get class info", cst.testClassInfo)
 	t.Run("get class tasks", cst.testClassTasks)
+	t.Run("filter class tasks", cst.testFilterClassTasks)
+	t.Run("recurring tasks", cst.testRecurringTasks)
 	t.Run("create class tasks", cst.testCreateClassTask)
 	t.Run("create class Schedule", cst.testClassSchedule)
 	t.Run("create, access and delete class", cst.testClassCreate)
 	t.Run("list member", cst.testListMember)
+	t.Run("task progress", cst.testTaskProgress)
+	t.Run("invite", cst.testInvite)
+	t.Run("audit", cst.testAudit)
+	t.Run("update class task", cst.testUpdateClassTask)
+	t.Run("schedule overlap", cst.testScheduleOverlap)
+	t.Run("replace schedules", cst.testReplaceSchedules)
+	t.Run("atomic create class", cst.testAtomicCreateClass)
+	t.Run("roles", cst.testRoles)
+	t.Run("transfer ownership", cst.testTransferOwnership)
+	t.Run("leave class", cst.testLeaveClass)
+	t.Run("attachment", cst.testAttachment)
+	t.Run("comment", cst.testComment)
+	t.Run("announcement", cst.testAnnouncement)
+	t.Run("visibility", cst.testVisibility)
+	t.Run("join request", cst.testJoinRequest)
+	t.Run("search", cst.testSearch)
+}
+
+func newBlobStore(t *testing.T) *blob.BlobStoreFs {
+	store, err := blob.NewBlobStoreFs(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
 }
 
 type classServiceTest struct {
@@ -45,7 +96,7 @@ type classServiceTest struct {
 func (cst classServiceTest) testClassInfo(t *testing.T) {
 	t.Parallel()
 
-	_, err := cst.classService.GetClassInfo(context.Background(), "foobarbazqux")
+	_, err := cst.classService.GetClassInfo(context.Background(), "", "foobarbazqux")
 	assert.ErrorContains(t, err, "can not find class with id \"foobarbazqux\"")
 	u := &domain.User{
 		UserId:   uuid.NewString(),
@@ -61,11 +112,11 @@ func (cst classServiceTest) testClassInfo(t *testing.T) {
 	_, err = cst.classService.CreateClass(context.Background(), classA)
 	assert.Nil(t, err)
 
-	res, err := cst.classService.GetClassInfo(context.Background(), classA.ClassId)
+	res, err := cst.classService.GetClassInfo(context.Background(), "", classA.ClassId)
 	assert.Nil(t, err)
 	assert.Equal(t, 200, res.Code)
 	assert.Equal(t, classA, res.Data)
-	res, err = cst.classService.GetClassInfoByName(context.Background(), u.Username, classA.Name)
+	res, err = cst.classService.GetClassInfoByName(context.Background(), "", u.Username, classA.Name)
 	assert.Nil(t, err)
 	assert.Equal(t, 200, res.Code)
 	assert.Equal(t, classA, res.Data)
@@ -113,12 +164,12 @@ func (cst classServiceTest) testClassTasks(t *testing.T) {
 		{yesterday, time.Time{}, 7},
 		{yesterday, tommorrow, 2},
 	} {
-		res, err := cst.classService.GetClassTasks(context.Background(), class.ClassId, tc.From, tc.To)
+		res, err := cst.classService.GetClassTasks(context.Background(), "", class.ClassId, domain.ClassTaskFilter{From: tc.From, To: tc.To}, domain.Pagination{})
 		assert.Nil(t, err)
 		assert.Equal(t, tc.Len, len(res.Data))
 	}
 
-	res, err := cst.classService.GetClassTasks(context.Background(), class.ClassId, time.Time{}, time.Time{})
+	res, err := cst.classService.GetClassTasks(context.Background(), "", class.ClassId, domain.ClassTaskFilter{}, domain.Pagination{})
 	assert.Nil(t, err)
 
 	for _, task := range res.Data {
@@ -126,11 +177,205 @@ func (cst classServiceTest) testClassTasks(t *testing.T) {
 		assert.Nil(t, err)
 	}
 
-	res, err = cst.classService.GetClassTasks(context.Background(), class.ClassId, time.Time{}, time.Time{})
+	res, err = cst.classService.GetClassTasks(context.Background(), "", class.ClassId, domain.ClassTaskFilter{}, domain.Pagination{})
 	assert.Nil(t, err)
 	assert.Equal(t, 0, len(res.Data))
 }
 
+func (cst classServiceTest) testFilterClassTasks(t *testing.T) {
+	t.Parallel()
+	cs := cst.classService
+
+	class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+	_, err := cs.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+	lastWeek := time.Now().UTC().Add(-7 * 24 * time.Hour)
+	tasks := []*domain.ClassTask{
+		{ClassId: class.ClassId, AuthorId: class.OwnerId, Name: "essay", DueDate: lastWeek},
+		{ClassId: class.ClassId, AuthorId: class.OwnerId, Name: "quiz", DueDate: lastWeek},
+	}
+	for _, task := range tasks {
+		err := cs.ClassTaskRepository.CreateTask(context.Background(), task)
+		assert.Nil(t, err)
+	}
+	err = cs.ClassTaskRepository.SetProgress(context.Background(), &domain.ClassTaskProgress{
+		TaskId: tasks[1].TaskId,
+		UserId: class.OwnerId,
+		Status: domain.ClassTaskStatusDone,
+	})
+	assert.Nil(t, err)
+
+	var resErr *response.ResponseError
+	for _, tc := range []struct {
+		Name   string
+		UserId string
+		Filter domain.ClassTaskFilter
+		Code   int
+	}{
+		{"unknown sort", class.OwnerId, domain.ClassTaskFilter{Sort: "name"}, 400},
+		{"unknown status", class.OwnerId, domain.ClassTaskFilter{Status: "late"}, 400},
+		{"author is not uuid", class.OwnerId, domain.ClassTaskFilter{AuthorId: "foo"}, 400},
+		{"status without user", "", domain.ClassTaskFilter{Status: domain.ClassTaskStatusDone}, 401},
+		{"range too long", class.OwnerId, domain.ClassTaskFilter{From: time.Now(), To: time.Now().AddDate(2, 0, 0)}, 400},
+		{"no end", class.OwnerId, domain.ClassTaskFilter{Query: "ess", To: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)}, 400},
+	} {
+		_, err := cs.GetClassTasks(context.Background(), tc.UserId, class.ClassId, tc.Filter, domain.Pagination{})
+		if assert.ErrorAs(t, err, &resErr, tc.Name) {
+			assert.Equal(t, tc.Code, resErr.Code, tc.Name)
+		}
+	}
+
+	res, err := cs.GetClassTasks(context.Background(), class.OwnerId, class.ClassId, domain.ClassTaskFilter{}, domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(res.Data), "past tasks are not listed by default")
+
+	res, err = cs.GetClassTasks(context.Background(), class.OwnerId, class.ClassId, domain.ClassTaskFilter{Query: "ess"}, domain.Pagination{})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(res.Data), "query look at every due date") {
+		assert.Equal(t, tasks[0].TaskId, res.Data[0].TaskId)
+	}
+
+	res, err = cs.GetClassTasks(context.Background(), class.OwnerId, class.ClassId, domain.ClassTaskFilter{Overdue: true}, domain.Pagination{})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(res.Data), "done task is not overdue") {
+		assert.Equal(t, tasks[0].TaskId, res.Data[0].TaskId)
+		assert.Equal(t, domain.ClassTaskStatusTodo, res.Data[0].Progress.Status)
+	}
+}
+
+func (cst classServiceTest) testRecurringTasks(t *testing.T) {
+	t.Parallel()
+	cs := cst.classService
+
+	class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+	_, err := cs.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+	owner := class.OwnerId
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+	week := domain.ClassTaskFilter{From: start, To: start.Add(7 * 24 * time.Hour)}
+	list := func() []*domain.ClassTask {
+		t.Helper()
+		res, err := cs.GetClassTasks(context.Background(), owner, class.ClassId, week, domain.Pagination{})
+		assert.Nil(t, err)
+		return res.Data
+	}
+
+	var resErr *response.ResponseError
+	_, err = cs.CreateClassTask(context.Background(), owner, &domain.ClassTask{
+		ClassId:    class.ClassId,
+		AuthorId:   owner,
+		Name:       "reading",
+		DueDate:    start,
+		Recurrence: "FREQ=MONTHLY",
+	})
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 400, resErr.Code, "unsupported recurrence")
+	}
+	_, err = cs.CreateClassTask(context.Background(), owner, &domain.ClassTask{
+		ClassId:    class.ClassId,
+		AuthorId:   owner,
+		Name:       "reading",
+		Recurrence: "FREQ=DAILY",
+	})
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 400, resErr.Code, "series without due date")
+	}
+
+	series := &domain.ClassTask{
+		ClassId:    class.ClassId,
+		AuthorId:   owner,
+		Name:       "reading",
+		DueDate:    start,
+		Recurrence: "rrule:freq=daily;count=5",
+		SeriesId:   "foo",
+	}
+	_, err = cs.CreateClassTask(context.Background(), owner, series)
+	assert.Nil(t, err)
+	assert.Equal(t, "FREQ=DAILY;COUNT=5", series.Recurrence, "recurrence should be stored in canonical form")
+	assert.Equal(t, "", series.SeriesId, "new task is never an occurrence")
+
+	tasks := list()
+	if !assert.Equal(t, 5, len(tasks)) {
+		return
+	}
+	first, second, third := tasks[0].TaskId, tasks[1].TaskId, tasks[2].TaskId
+	assert.Equal(t, domain.OccurrenceId(series.TaskId, start), first)
+
+	// complete a single occurrence
+	_, err = cs.SetTaskProgress(context.Background(), class.ClassId, &domain.ClassTaskProgress{
+		TaskId: first,
+		UserId: owner,
+		Status: domain.ClassTaskStatusDone,
+	})
+	assert.Nil(t, err)
+	// edit a single occurrence
+	_, err = cs.UpdateClassTask(context.Background(), owner, class.ClassId, &domain.ClassTask{TaskId: second, Name: "chapter 2"})
+	assert.Nil(t, err)
+	_, err = cs.UpdateClassTask(context.Background(), owner, class.ClassId, &domain.ClassTask{TaskId: second, Recurrence: "FREQ=DAILY"})
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 400, resErr.Code, "occurrence can not recur")
+	}
+	// comment on a single occurrence
+	_, err = cs.CreateComment(context.Background(), owner, class.ClassId, &domain.TaskComment{TaskId: third, Body: "which chapter?"})
+	assert.Nil(t, err)
+
+	tasks = list()
+	if assert.Equal(t, 5, len(tasks)) {
+		assert.Equal(t, first, tasks[0].TaskId)
+		assert.Equal(t, domain.ClassTaskStatusDone, tasks[0].Progress.Status)
+		assert.Equal(t, "chapter 2", tasks[1].Name)
+		assert.Equal(t, "reading", tasks[2].Name)
+		assert.Equal(t, "reading", tasks[3].Name)
+	}
+	comments, err := cs.ListComments(context.Background(), owner, class.ClassId, third, domain.Pagination{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, 1, len(comments.Data))
+	}
+
+	// skip a single occurrence
+	_, err = cs.DeleteClassTask(context.Background(), owner, second)
+	assert.Nil(t, err)
+	_, err = cs.DeleteClassTask(context.Background(), owner, tasks[3].TaskId)
+	assert.Nil(t, err)
+	tasks = list()
+	assert.Equal(t, 3, len(tasks))
+	_, err = cs.GetTaskProgress(context.Background(), owner, class.ClassId, second)
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 404, resErr.Code, "skipped occurrence")
+	}
+
+	fifth := tasks[2].TaskId
+	_, err = cs.UpdateClassTask(context.Background(), owner, class.ClassId, &domain.ClassTask{TaskId: fifth, Name: "quiz"})
+	assert.Nil(t, err)
+
+	// edit the whole series, only edited occurrences are kept as they are
+	_, err = cs.UpdateClassTask(context.Background(), owner, class.ClassId, &domain.ClassTask{
+		TaskId:      series.TaskId,
+		Name:        "reading list",
+		Description: "bring the book",
+		DueDate:     start.Add(8 * time.Hour),
+		Recurrence:  "FREQ=DAILY;COUNT=7",
+	})
+	assert.Nil(t, err)
+	tasks = list()
+	if assert.Equal(t, 5, len(tasks)) {
+		assert.Equal(t, first, tasks[0].TaskId)
+		assert.Equal(t, "reading list", tasks[0].Name, "occurrence with progress should follow the series")
+		assert.Equal(t, "bring the book", tasks[0].Description)
+		assert.True(t, tasks[0].DueDate.Equal(start.Add(8*time.Hour)), tasks[0].DueDate)
+		assert.Equal(t, domain.ClassTaskStatusDone, tasks[0].Progress.Status)
+		assert.Equal(t, third, tasks[1].TaskId)
+		assert.Equal(t, "reading list", tasks[1].Name, "occurrence with comment should follow the series")
+		assert.Equal(t, fifth, tasks[2].TaskId)
+		assert.Equal(t, "quiz", tasks[2].Name, "edited occurrence should keep its own fields")
+		assert.Equal(t, "", tasks[2].Description)
+		assert.Equal(t, "reading list", tasks[3].Name)
+	}
+
+	// delete the whole series
+	_, err = cs.DeleteClassTask(context.Background(), owner, series.TaskId)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(list()))
+}
+
 func (cst classServiceTest) testClassSchedule(t *testing.T) {
 	t.Parallel()
 
@@ -143,7 +388,7 @@ func (cst classServiceTest) testClassSchedule(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 200, r.Code)
 
-	_, err = cst.classService.GetSchedule(context.Background(), xid.New().String())
+	_, err = cst.classService.GetSchedule(context.Background(), "", xid.New().String())
 	assert.NotNil(t, err)
 
 	for i := 0; i < 7; i++ {
@@ -155,10 +400,10 @@ func (cst classServiceTest) testClassSchedule(t *testing.T) {
 			StartAt:  time.Now().UTC().Round(time.Hour),
 			Duration: int16(20),
 		}
-		_, err := cst.classService.CreateSchedule(context.Background(), schedule)
+		_, err := cst.classService.CreateSchedule(context.Background(), schedule, false)
 		assert.Nil(t, err)
 
-		res, err := cst.classService.GetSchedule(context.Background(), schedule.ScheduleId)
+		res, err := cst.classService.GetSchedule(context.Background(), "", schedule.ScheduleId)
 		res.Data.CreatedAt = time.Time{}
 		assert.Nil(t, err)
 		assert.Equal(t, schedule, res.Data)
@@ -171,7 +416,7 @@ func (cst classServiceTest) testClassSchedule(t *testing.T) {
 		})
 	}
 
-	schedules, err := cst.classService.GetClassSchedules(context.Background(), class.ClassId)
+	schedules, err := cst.classService.GetClassSchedules(context.Background(), "", class.ClassId, domain.Pagination{})
 	assert.Nil(t, err)
 	assert.Equal(t, 7, len(schedules.Data))
 
@@ -195,12 +440,12 @@ func (cst classServiceTest) testClassSchedule(t *testing.T) {
 				StartAt:  time.Now().UTC().Round(time.Hour),
 				Duration: int16(20),
 			}
-			_, err := cst.classService.CreateSchedule(context.Background(), schedule)
+			_, err := cst.classService.CreateSchedule(context.Background(), schedule, false)
 			assert.Nil(t, err)
 		}
 
 		for i := 0; i < 7; i++ {
-			schedules, err := cst.classService.GetClassSchedules(context.Background(), class.ClassId)
+			schedules, err := cst.classService.GetClassSchedules(context.Background(), "", class.ClassId, domain.Pagination{})
 			assert.Nil(t, err)
 			assert.Equal(t, 7-i, len(schedules.Data))
 
@@ -209,7 +454,7 @@ func (cst classServiceTest) testClassSchedule(t *testing.T) {
 			_, err = cst.classService.ClearSchedules(context.Background(), class.OwnerId, class.ClassId, int8(i))
 			assert.Nil(t, err)
 
-			schedules, err = cst.classService.GetClassSchedules(context.Background(), class.ClassId)
+			schedules, err = cst.classService.GetClassSchedules(context.Background(), "", class.ClassId, domain.Pagination{})
 			assert.Nil(t, err)
 			assert.Equal(t, 6-i, len(schedules.Data))
 		}
@@ -256,7 +501,7 @@ func (cst classServiceTest) testClassCreate(t *testing.T) {
 	})
 	assert.Nil(t, err)
 
-	classRes, err := cst.classService.GetClassInfo(context.Background(), class.ClassId)
+	classRes, err := cst.classService.GetClassInfo(context.Background(), "", class.ClassId)
 	assert.Nil(t, err)
 	assert.Equal(t, 200, classRes.Code, "failed to create class")
 	assert.Equal(t, class.Name, classRes.Data.Name)
@@ -265,16 +510,16 @@ func (cst classServiceTest) testClassCreate(t *testing.T) {
 	_, err = cst.classService.ClassRepository.GetClass(context.Background(), class.ClassId)
 	assert.Nil(t, err)
 
-	err = cst.classService.AccessClass(context.Background(), uuid.NewString(), class.ClassId, "admin")
+	err = cst.classService.AccessClass(context.Background(), uuid.NewString(), class.ClassId, domain.CapabilityManageMembers)
 	assert.NotNil(t, err)
 
-	err = cst.classService.AccessClass(context.Background(), class.OwnerId, class.ClassId, "admin")
+	err = cst.classService.AccessClass(context.Background(), class.OwnerId, class.ClassId, domain.CapabilityManageMembers)
 	assert.Nil(t, err)
 
-	err = cst.classService.AccessClass(context.Background(), member, class.ClassId, "admin")
+	err = cst.classService.AccessClass(context.Background(), member, class.ClassId, domain.CapabilityManageMembers)
 	assert.NotNil(t, err)
 
-	err = cst.classService.AccessClass(context.Background(), admin, class.ClassId, "admin")
+	err = cst.classService.AccessClass(context.Background(), admin, class.ClassId, domain.CapabilityManageMembers)
 	assert.Nil(t, err)
 
 	// delete existing class
@@ -373,14 +618,14 @@ func (cst classServiceTest) testListMember(t *testing.T) {
 		assert.Equal(t, 204, res.Code)
 	}
 
-	resMember, err := cst.classService.ListMember(context.Background(), class.ClassId)
+	resMember, err := cst.classService.ListMember(context.Background(), "", class.ClassId, domain.Pagination{})
 	assert.Nil(t, err)
 	assert.Equal(t, 200, resMember.Code)
 	assert.Equal(t, 13, len(resMember.Data))
 
 	_, err = cst.classService.DeleteMember(context.Background(), class.OwnerId, class.ClassId, foo.UserId)
 
-	resMember, err = cst.classService.ListMember(context.Background(), class.ClassId)
+	resMember, err = cst.classService.ListMember(context.Background(), "", class.ClassId, domain.Pagination{})
 	assert.Nil(t, err)
 	assert.Equal(t, 200, resMember.Code)
 	assert.Equal(t, 12, len(resMember.Data))
@@ -393,7 +638,7 @@ func (cst classServiceTest) testListMember(t *testing.T) {
 	})
 	assert.Nil(t, err)
 
-	resMember, err = cst.classService.ListMember(context.Background(), class.ClassId)
+	resMember, err = cst.classService.ListMember(context.Background(), "", class.ClassId, domain.Pagination{})
 	assert.Nil(t, err)
 	for _, i := range resMember.Data {
 		if i.UserId == bar.UserId {
@@ -401,3 +646,1297 @@ func (cst classServiceTest) testListMember(t *testing.T) {
 		}
 	}
 }
+
+func (cst classServiceTest) testTaskProgress(t *testing.T) {
+	t.Parallel()
+
+	class := &domain.Class{
+		OwnerId: uuid.NewString(),
+		Name:    "foo",
+	}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+
+	member := uuid.NewString()
+	_, err = cst.classService.AddMember(context.Background(), class.OwnerId, &domain.ClassMember{
+		UserId:  member,
+		ClassId: class.ClassId,
+		Level:   "member",
+	})
+	assert.Nil(t, err)
+
+	task := &domain.ClassTask{
+		ClassId:  class.ClassId,
+		AuthorId: class.OwnerId,
+		DueDate:  time.Now().UTC().Add(24 * time.Hour),
+	}
+	_, err = cst.classService.CreateClassTask(context.Background(), class.OwnerId, task)
+	assert.Nil(t, err)
+
+	res, err := cst.classService.GetTaskProgress(context.Background(), member, class.ClassId, task.TaskId)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.ClassTaskStatusTodo, res.Data.Status)
+
+	_, err = cst.classService.GetTaskProgress(context.Background(), uuid.NewString(), class.ClassId, task.TaskId)
+	assert.NotNil(t, err)
+	_, err = cst.classService.GetTaskProgress(context.Background(), member, class.ClassId, xid.New().String())
+	assert.NotNil(t, err)
+
+	for _, tc := range []struct {
+		Status string
+		Err    bool
+	}{
+		{domain.ClassTaskStatusInProgress, false},
+		{domain.ClassTaskStatusDone, false},
+		{"finished", true},
+	} {
+		res, err := cst.classService.SetTaskProgress(context.Background(), class.ClassId, &domain.ClassTaskProgress{
+			TaskId: task.TaskId,
+			UserId: member,
+			Status: tc.Status,
+		})
+		if tc.Err {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, tc.Status, res.Data.Status)
+		assert.Equal(t, tc.Status == domain.ClassTaskStatusDone, res.Data.CompletedAt != nil)
+	}
+
+	_, err = cst.classService.SetTaskProgress(context.Background(), xid.New().String(), &domain.ClassTaskProgress{
+		TaskId: task.TaskId,
+		UserId: member,
+		Status: domain.ClassTaskStatusDone,
+	})
+	assert.NotNil(t, err)
+
+	tasks, err := cst.classService.GetClassTasks(context.Background(), member, class.ClassId, domain.ClassTaskFilter{}, domain.Pagination{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(tasks.Data)) {
+		assert.Equal(t, domain.ClassTaskStatusDone, tasks.Data[0].Progress.Status)
+	}
+	tasks, err = cst.classService.GetClassTasks(context.Background(), class.OwnerId, class.ClassId, domain.ClassTaskFilter{}, domain.Pagination{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(tasks.Data)) {
+		assert.Equal(t, domain.ClassTaskStatusTodo, tasks.Data[0].Progress.Status)
+	}
+
+	_, err = cst.classService.GetTaskSummary(context.Background(), member, class.ClassId, task.TaskId)
+	assert.NotNil(t, err)
+	summary, err := cst.classService.GetTaskSummary(context.Background(), class.OwnerId, class.ClassId, task.TaskId)
+	assert.Nil(t, err)
+	assert.Equal(t, &domain.ClassTaskSummary{TaskId: task.TaskId, Members: 2, Todo: 1, Done: 1}, summary.Data)
+
+	// progress of removed member is no longer counted
+	_, err = cst.classService.DeleteMember(context.Background(), class.OwnerId, class.ClassId, member)
+	assert.Nil(t, err)
+	summary, err = cst.classService.GetTaskSummary(context.Background(), class.OwnerId, class.ClassId, task.TaskId)
+	assert.Nil(t, err)
+	assert.Equal(t, &domain.ClassTaskSummary{TaskId: task.TaskId, Members: 1, Todo: 1}, summary.Data)
+}
+
+func (cst classServiceTest) testInvite(t *testing.T) {
+	t.Parallel()
+
+	class := &domain.Class{
+		OwnerId: uuid.NewString(),
+		Name:    "foo",
+	}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+
+	past := time.Now().Add(-time.Hour)
+	for _, invite := range []domain.ClassInvite{
+		{Level: "owner"},
+		{MaxUses: -1},
+		{ExpiresAt: &past},
+	} {
+		invite.ClassId = class.ClassId
+		invite.AuthorId = class.OwnerId
+		_, err := cst.classService.CreateInvite(context.Background(), &invite)
+		assert.NotNilf(t, err, "unexpected at %#+v", invite)
+	}
+
+	_, err = cst.classService.CreateInvite(context.Background(), &domain.ClassInvite{
+		ClassId:  class.ClassId,
+		AuthorId: uuid.NewString(),
+	})
+	assert.NotNil(t, err)
+
+	res, err := cst.classService.CreateInvite(context.Background(), &domain.ClassInvite{
+		ClassId:  class.ClassId,
+		AuthorId: class.OwnerId,
+		MaxUses:  1,
+	})
+	assert.Nil(t, err)
+	invite := res.Data
+	assert.Equal(t, "member", invite.Level)
+
+	invites, err := cst.classService.ListInvites(context.Background(), class.OwnerId, class.ClassId)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(invites.Data))
+
+	foo := uuid.NewString()
+	member, err := cst.classService.JoinClass(context.Background(), foo, invite.Code)
+	assert.Nil(t, err)
+	assert.Equal(t, class.ClassId, member.Data.ClassId)
+	assert.Equal(t, "member", member.Data.Level)
+	err = cst.classService.AccessClass(context.Background(), foo, class.ClassId, domain.CapabilityCreateTask)
+	assert.Nil(t, err)
+
+	// exhausted
+	_, err = cst.classService.JoinClass(context.Background(), uuid.NewString(), invite.Code)
+	assert.NotNil(t, err)
+	invites, err = cst.classService.ListInvites(context.Background(), class.OwnerId, class.ClassId)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(invites.Data))
+
+	res, err = cst.classService.CreateInvite(context.Background(), &domain.ClassInvite{
+		ClassId:  class.ClassId,
+		AuthorId: class.OwnerId,
+	})
+	assert.Nil(t, err)
+
+	// already a member
+	_, err = cst.classService.JoinClass(context.Background(), foo, res.Data.Code)
+	assert.NotNil(t, err)
+
+	_, err = cst.classService.DeleteInvite(context.Background(), foo, class.ClassId, res.Data.Code)
+	assert.NotNil(t, err)
+	_, err = cst.classService.DeleteInvite(context.Background(), class.OwnerId, xid.New().String(), res.Data.Code)
+	assert.NotNil(t, err)
+	_, err = cst.classService.DeleteInvite(context.Background(), class.OwnerId, class.ClassId, res.Data.Code)
+	assert.Nil(t, err)
+
+	_, err = cst.classService.JoinClass(context.Background(), uuid.NewString(), res.Data.Code)
+	assert.NotNil(t, err)
+}
+
+func (cst classServiceTest) testAudit(t *testing.T) {
+	t.Parallel()
+
+	class := &domain.Class{
+		OwnerId: uuid.NewString(),
+		Name:    "foo",
+	}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+
+	member := uuid.NewString()
+	_, err = cst.classService.AddMember(context.Background(), class.OwnerId, &domain.ClassMember{
+		ClassId: class.ClassId,
+		UserId:  member,
+		Level:   "member",
+	})
+	assert.Nil(t, err)
+
+	resTask, err := cst.classService.CreateClassTask(context.Background(), member, &domain.ClassTask{
+		ClassId:  class.ClassId,
+		AuthorId: member,
+		Name:     "foo",
+	})
+	assert.Nil(t, err)
+	_, err = cst.classService.DeleteClassTask(context.Background(), class.OwnerId, resTask.Data.TaskId)
+	assert.Nil(t, err)
+
+	_, err = cst.classService.UpdateMember(context.Background(), class.OwnerId, &domain.ClassMember{
+		ClassId: class.ClassId,
+		UserId:  member,
+		Level:   "admin",
+	})
+	assert.Nil(t, err)
+	_, err = cst.classService.DeleteMember(context.Background(), class.OwnerId, class.ClassId, member)
+	assert.Nil(t, err)
+
+	_, err = cst.classService.ListAudit(context.Background(), member, class.ClassId, domain.ClassAuditFilter{}, domain.Pagination{})
+	assert.NotNil(t, err, "non member should not be able to read audit log")
+
+	res, err := cst.classService.ListAudit(context.Background(), class.OwnerId, class.ClassId, domain.ClassAuditFilter{}, domain.Pagination{})
+	assert.Nil(t, err)
+	actions := make([]string, 0, len(res.Data))
+	for _, entry := range res.Data {
+		actions = append(actions, entry.Action)
+	}
+	assert.Equal(t, []string{
+		domain.AuditMemberRemove,
+		domain.AuditMemberUpdate,
+		domain.AuditTaskDelete,
+		domain.AuditTaskCreate,
+		domain.AuditMemberAdd,
+		domain.AuditClassCreate,
+	}, actions)
+
+	res, err = cst.classService.ListAudit(context.Background(), class.OwnerId, class.ClassId, domain.ClassAuditFilter{Action: domain.AuditTaskDelete}, domain.Pagination{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(res.Data)) {
+		entry := res.Data[0]
+		assert.Equal(t, class.OwnerId, entry.ActorId)
+		assert.Equal(t, resTask.Data.TaskId, entry.TargetId)
+		assert.Contains(t, string(entry.Before), `"name":"foo"`)
+		assert.Nil(t, entry.After)
+	}
+
+	res, err = cst.classService.ListAudit(context.Background(), class.OwnerId, class.ClassId, domain.ClassAuditFilter{Action: domain.AuditMemberUpdate}, domain.Pagination{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(res.Data)) {
+		assert.Contains(t, string(res.Data[0].Before), `"level":"member"`)
+		assert.Contains(t, string(res.Data[0].After), `"level":"admin"`)
+	}
+
+	res, err = cst.classService.ListAudit(context.Background(), class.OwnerId, class.ClassId, domain.ClassAuditFilter{ActorId: member}, domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(res.Data))
+
+	_, err = cst.classService.ListAudit(context.Background(), class.OwnerId, class.ClassId, domain.ClassAuditFilter{ActorId: "foo"}, domain.Pagination{})
+	assert.NotNil(t, err, "actor filter must be uuid")
+
+	_, err = cst.classService.DeleteClass(context.Background(), class.OwnerId, class.ClassId)
+	assert.Nil(t, err)
+	entries, _, err := cst.classService.ClassAuditRepository.ListEntries(context.Background(), class.ClassId, domain.ClassAuditFilter{}, domain.Pagination{Limit: 1})
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(entries)) {
+		assert.Equal(t, domain.AuditClassDelete, entries[0].Action, "audit log should be kept after class deleted")
+	}
+}
+
+func (cst classServiceTest) testUpdateClassTask(t *testing.T) {
+	t.Parallel()
+
+	class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+	otherClass := &domain.Class{OwnerId: class.OwnerId, Name: "bar"}
+	_, err = cst.classService.CreateClass(context.Background(), otherClass)
+	assert.Nil(t, err)
+
+	author := uuid.NewString()
+	member := uuid.NewString()
+	for _, userId := range []string{author, member} {
+		_, err = cst.classService.AddMember(context.Background(), class.OwnerId, &domain.ClassMember{
+			ClassId: class.ClassId,
+			UserId:  userId,
+			Level:   "member",
+		})
+		assert.Nil(t, err)
+	}
+
+	res, err := cst.classService.CreateClassTask(context.Background(), author, &domain.ClassTask{
+		ClassId:  class.ClassId,
+		AuthorId: author,
+		Name:     "foo",
+	})
+	assert.Nil(t, err)
+	taskId := res.Data.TaskId
+
+	testCases := []struct {
+		Name    string
+		UserId  string
+		ClassId string
+		Task    domain.ClassTask
+		Err     bool
+	}{
+		{"invalid", author, class.ClassId, domain.ClassTask{TaskId: taskId, Name: strings.Repeat("a", 21)}, true},
+		{"not author nor admin", member, class.ClassId, domain.ClassTask{TaskId: taskId, Name: "bar"}, true},
+		{"not member", uuid.NewString(), class.ClassId, domain.ClassTask{TaskId: taskId, Name: "bar"}, true},
+		{"other class", class.OwnerId, otherClass.ClassId, domain.ClassTask{TaskId: taskId, Name: "bar"}, true},
+		{"unknown task", class.OwnerId, class.ClassId, domain.ClassTask{TaskId: xid.New().String(), Name: "bar"}, true},
+		{"author", author, class.ClassId, domain.ClassTask{TaskId: taskId, Name: "bar"}, false},
+		{"admin", class.OwnerId, class.ClassId, domain.ClassTask{TaskId: taskId, Description: "baz"}, false},
+	}
+
+	for _, tc := range testCases {
+		task := tc.Task
+		res, err := cst.classService.UpdateClassTask(context.Background(), tc.UserId, tc.ClassId, &task)
+		if tc.Err {
+			assert.NotNil(t, err, tc.Name)
+			continue
+		}
+		if assert.Nil(t, err, tc.Name) {
+			assert.Equal(t, tc.UserId, res.Data.UpdatedBy, tc.Name)
+			assert.NotNil(t, res.Data.UpdatedAt, tc.Name)
+		}
+	}
+
+	task, err := cst.classService.ClassTaskRepository.GetTask(context.Background(), taskId)
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", task.Name)
+	assert.Equal(t, "baz", task.Description)
+	assert.Equal(t, author, task.AuthorId, "author should not change")
+	assert.Equal(t, class.OwnerId, task.UpdatedBy)
+}
+
+func (cst classServiceTest) testScheduleOverlap(t *testing.T) {
+	t.Parallel()
+
+	class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(0, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+	testCases := []struct {
+		Name         string
+		Day          int8
+		StartAt      time.Time
+		Duration     int16
+		AllowOverlap bool
+		Code         int
+	}{
+		{"first", 1, at(8, 0), 90, false, 0},
+		{"start inside", 1, at(9, 0), 30, false, 409},
+		{"contains", 1, at(7, 0), 240, false, 409},
+		{"adjacent", 1, at(9, 30), 30, false, 0},
+		{"other day", 2, at(8, 0), 90, false, 0},
+		{"allowed overlap", 1, at(8, 30), 30, true, 0},
+		{"past midnight", 0, at(23, 30), 60, false, 0},
+		{"after midnight", 1, at(0, 0), 10, false, 409},
+		{"past end of week", 6, at(23, 50), 30, false, 0},
+		{"start of week", 0, at(0, 10), 10, false, 409},
+		{"invalid day", 7, at(8, 0), 30, false, 400},
+		{"invalid duration", 3, at(8, 0), -1, false, 400},
+	}
+
+	for _, tc := range testCases {
+		schedule := &domain.ClassSchedule{
+			AuthorId: class.OwnerId,
+			ClassId:  class.ClassId,
+			Name:     tc.Name,
+			Day:      tc.Day,
+			StartAt:  tc.StartAt,
+			Duration: tc.Duration,
+		}
+		_, err := cst.classService.CreateSchedule(context.Background(), schedule, tc.AllowOverlap)
+		if tc.Code == 0 {
+			assert.Nil(t, err, tc.Name)
+			continue
+		}
+		if assert.NotNil(t, err, tc.Name) {
+			assert.Equal(t, tc.Code, err.(*response.ResponseError).Code, tc.Name)
+		}
+	}
+
+	res, err := cst.classService.GetClassTimetable(context.Background(), "", class.ClassId)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, len(res.Data))
+	names := make([]string, 0)
+	for _, schedule := range res.Data[1].Schedules {
+		assert.Equal(t, int8(1), schedule.Day)
+		names = append(names, schedule.Name)
+	}
+	assert.Equal(t, []string{"first", "allowed overlap", "adjacent"}, names, "timetable should be sorted by start time")
+	assert.Equal(t, 1, len(res.Data[0].Schedules))
+	assert.Equal(t, 1, len(res.Data[2].Schedules))
+	assert.Equal(t, 1, len(res.Data[6].Schedules))
+	assert.Equal(t, 0, len(res.Data[5].Schedules))
+}
+
+func (cst classServiceTest) testReplaceSchedules(t *testing.T) {
+	t.Parallel()
+
+	class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+
+	at := func(hour int) time.Time {
+		return time.Date(0, 1, 1, hour, 0, 0, 0, time.UTC)
+	}
+	for _, day := range []int8{1, 1, 2} {
+		_, err := cst.classService.CreateSchedule(context.Background(), &domain.ClassSchedule{
+			AuthorId: class.OwnerId,
+			ClassId:  class.ClassId,
+			Name:     "foo",
+			Day:      day,
+			StartAt:  at(8),
+			Duration: 30,
+		}, true)
+		assert.Nil(t, err)
+	}
+
+	replacement := func() []*domain.ClassSchedule {
+		return []*domain.ClassSchedule{
+			{Name: "bar", StartAt: at(8), Duration: 60},
+			{Name: "baz", StartAt: at(10), Duration: 60, Day: 4},
+		}
+	}
+
+	_, err = cst.classService.ReplaceSchedules(context.Background(), uuid.NewString(), class.ClassId, 1, replacement(), false)
+	assert.NotNil(t, err, "only admin can replace schedules")
+	_, err = cst.classService.ReplaceSchedules(context.Background(), class.OwnerId, class.ClassId, 7, replacement(), false)
+	assert.NotNil(t, err, "invalid day")
+
+	overlapping := append(replacement(), &domain.ClassSchedule{Name: "qux", StartAt: at(8), Duration: 10})
+	_, err = cst.classService.ReplaceSchedules(context.Background(), class.OwnerId, class.ClassId, 1, overlapping, false)
+	if assert.NotNil(t, err, "replacement should not overlap each other") {
+		assert.Equal(t, 409, err.(*response.ResponseError).Code)
+	}
+
+	res, err := cst.classService.ReplaceSchedules(context.Background(), class.OwnerId, class.ClassId, 1, replacement(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(res.Data))
+
+	timetable, err := cst.classService.GetClassTimetable(context.Background(), "", class.ClassId)
+	assert.Nil(t, err)
+	names := make([]string, 0)
+	for _, schedule := range timetable.Data[1].Schedules {
+		assert.Equal(t, class.OwnerId, schedule.AuthorId)
+		names = append(names, schedule.Name)
+	}
+	assert.Equal(t, []string{"bar", "baz"}, names)
+	assert.Equal(t, 1, len(timetable.Data[2].Schedules), "other day should be kept")
+	assert.Equal(t, 0, len(timetable.Data[4].Schedules), "day of replacement should be ignored")
+
+	audit, err := cst.classService.ListAudit(context.Background(), class.OwnerId, class.ClassId, domain.ClassAuditFilter{Action: domain.AuditScheduleReplace}, domain.Pagination{})
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(audit.Data)) {
+		assert.Equal(t, "1", audit.Data[0].TargetId)
+	}
+}
+
+type failingMemberRepository struct {
+	domain.ClassMemberRepository
+}
+
+func (failingMemberRepository) CreateMember(ctx context.Context, member *domain.ClassMember) error {
+	return errors.New("failed to create member")
+}
+
+func (cst classServiceTest) testAtomicCreateClass(t *testing.T) {
+	t.Parallel()
+
+	cs := cst.classService
+	cs.ClassMemberRepository = failingMemberRepository{cs.ClassMemberRepository}
+	ownerId := uuid.NewString()
+	_, err := cs.CreateClass(context.Background(), &domain.Class{OwnerId: ownerId, Name: "foo"})
+	assert.NotNil(t, err)
+
+	classes, _, err := cs.ClassRepository.GetClassesByOwnerId(context.Background(), ownerId, domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(classes), "class must not be created without its owner")
+}
+
+func (cst classServiceTest) testRoles(t *testing.T) {
+	t.Parallel()
+
+	class := &domain.Class{
+		OwnerId: uuid.NewString(),
+		Name:    "foo",
+	}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+
+	members := map[string]string{}
+	for _, role := range []string{domain.RoleAdmin, domain.RoleModerator, domain.RoleMember, domain.RoleViewer} {
+		members[role] = uuid.NewString()
+		_, err := cst.classService.AddMember(context.Background(), class.OwnerId, &domain.ClassMember{
+			ClassId: class.ClassId,
+			UserId:  members[role],
+			Level:   role,
+		})
+		assert.Nil(t, err)
+	}
+
+	err = cst.classService.AccessClass(context.Background(), members[domain.RoleViewer], class.ClassId, domain.CapabilityViewClass)
+	assert.Nil(t, err)
+	err = cst.classService.AccessClass(context.Background(), members[domain.RoleViewer], class.ClassId, domain.CapabilityCreateTask)
+	assert.NotNil(t, err)
+	err = cst.classService.AccessClass(context.Background(), members[domain.RoleModerator], class.ClassId, domain.CapabilityManageTasks)
+	assert.Nil(t, err)
+	err = cst.classService.AccessClass(context.Background(), members[domain.RoleModerator], class.ClassId, domain.CapabilityManageMembers)
+	assert.NotNil(t, err)
+	err = cst.classService.AccessClass(context.Background(), members[domain.RoleAdmin], class.ClassId, domain.CapabilityTransferClass)
+	assert.NotNil(t, err)
+	err = cst.classService.AccessClass(context.Background(), class.OwnerId, class.ClassId, "unknown")
+	assert.NotNil(t, err, "unknown capability should not be granted")
+
+	// moderator can manage tasks of other members
+	resTask, err := cst.classService.CreateClassTask(context.Background(), members[domain.RoleMember], &domain.ClassTask{
+		ClassId:  class.ClassId,
+		AuthorId: members[domain.RoleMember],
+		Name:     "foo",
+	})
+	assert.Nil(t, err)
+	_, err = cst.classService.DeleteClassTask(context.Background(), members[domain.RoleModerator], resTask.Data.TaskId)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name   string
+		actor  string
+		target string
+		level  string
+		code   int
+	}{
+		{name: "invalid role", actor: class.OwnerId, target: members[domain.RoleMember], level: "garbage", code: 400},
+		{name: "promote to owner", actor: class.OwnerId, target: members[domain.RoleMember], level: domain.RoleOwner, code: 422},
+		{name: "admin grant admin", actor: members[domain.RoleAdmin], target: members[domain.RoleMember], level: domain.RoleAdmin, code: 403},
+		{name: "admin change owner", actor: members[domain.RoleAdmin], target: class.OwnerId, level: domain.RoleMember, code: 403},
+		{name: "moderator grant", actor: members[domain.RoleModerator], target: members[domain.RoleViewer], level: domain.RoleMember, code: 403},
+		{name: "demote last owner", actor: class.OwnerId, target: class.OwnerId, level: domain.RoleAdmin, code: 409},
+	}
+	for _, tt := range tests {
+		_, err := cst.classService.UpdateMember(context.Background(), tt.actor, &domain.ClassMember{
+			ClassId: class.ClassId,
+			UserId:  tt.target,
+			Level:   tt.level,
+		})
+		var resErr *response.ResponseError
+		if assert.True(t, errors.As(err, &resErr), tt.name) {
+			assert.Equal(t, tt.code, resErr.Code, tt.name)
+		}
+	}
+
+	_, err = cst.classService.UpdateMember(context.Background(), members[domain.RoleAdmin], &domain.ClassMember{
+		ClassId: class.ClassId,
+		UserId:  members[domain.RoleViewer],
+		Level:   domain.RoleModerator,
+	})
+	assert.Nil(t, err)
+
+	var resErr *response.ResponseError
+	_, err = cst.classService.DeleteMember(context.Background(), members[domain.RoleAdmin], class.ClassId, class.OwnerId)
+	if assert.True(t, errors.As(err, &resErr)) {
+		assert.Equal(t, 403, resErr.Code)
+	}
+	_, err = cst.classService.DeleteMember(context.Background(), class.OwnerId, class.ClassId, class.OwnerId)
+	if assert.True(t, errors.As(err, &resErr)) {
+		assert.Equal(t, 409, resErr.Code, "last owner should not be removed")
+	}
+	_, err = cst.classService.AddMember(context.Background(), members[domain.RoleAdmin], &domain.ClassMember{
+		ClassId: class.ClassId,
+		UserId:  uuid.NewString(),
+		Level:   domain.RoleAdmin,
+	})
+	assert.NotNil(t, err, "admin should not add another admin")
+	_, err = cst.classService.CreateInvite(context.Background(), &domain.ClassInvite{
+		ClassId:  class.ClassId,
+		AuthorId: members[domain.RoleAdmin],
+		Level:    domain.RoleAdmin,
+	})
+	assert.NotNil(t, err, "admin should not invite another admin")
+}
+
+func (cst classServiceTest) testTransferOwnership(t *testing.T) {
+	t.Parallel()
+
+	// memory repository share the class pointer, keep the original owner
+	owner := uuid.NewString()
+	class := &domain.Class{
+		OwnerId: owner,
+		Name:    xid.New().String(),
+	}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+
+	member := uuid.NewString()
+	_, err = cst.classService.AddMember(context.Background(), owner, &domain.ClassMember{
+		ClassId: class.ClassId,
+		UserId:  member,
+	})
+	assert.Nil(t, err)
+
+	// member already owns a class with the same name
+	taken := uuid.NewString()
+	_, err = cst.classService.CreateClass(context.Background(), &domain.Class{OwnerId: taken, Name: class.Name})
+	assert.Nil(t, err)
+	_, err = cst.classService.AddMember(context.Background(), owner, &domain.ClassMember{
+		ClassId: class.ClassId,
+		UserId:  taken,
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name    string
+		actor   string
+		target  string
+		confirm string
+		code    int
+	}{
+		{name: "not owner", actor: member, target: member, confirm: class.Name, code: 403},
+		{name: "to yourself", actor: owner, target: owner, confirm: class.Name, code: 400},
+		{name: "not member", actor: owner, target: uuid.NewString(), confirm: class.Name, code: 422},
+		{name: "not confirmed", actor: owner, target: member, confirm: "", code: 400},
+		{name: "name conflict", actor: owner, target: taken, confirm: class.Name, code: 409},
+	}
+	for _, tt := range tests {
+		_, err := cst.classService.TransferOwnership(context.Background(), tt.actor, class.ClassId, tt.target, tt.confirm)
+		var resErr *response.ResponseError
+		if assert.True(t, errors.As(err, &resErr), tt.name) {
+			assert.Equal(t, tt.code, resErr.Code, tt.name)
+		}
+	}
+
+	c, err := cst.classService.ClassRepository.GetClass(context.Background(), class.ClassId)
+	assert.Nil(t, err)
+	assert.Equal(t, owner, c.OwnerId, "failed transfer should not change owner")
+	m, err := cst.classService.ClassMemberRepository.GetMember(context.Background(), &domain.ClassMember{ClassId: class.ClassId, UserId: taken})
+	assert.Nil(t, err)
+	assert.Equal(t, domain.RoleMember, m.Level, "failed transfer should be rolled back")
+
+	res, err := cst.classService.TransferOwnership(context.Background(), owner, class.ClassId, member, class.Name)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 200, res.Code)
+		assert.Equal(t, member, res.Data.OwnerId)
+	}
+
+	m, err = cst.classService.ClassMemberRepository.GetMember(context.Background(), &domain.ClassMember{ClassId: class.ClassId, UserId: member})
+	assert.Nil(t, err)
+	assert.Equal(t, domain.RoleOwner, m.Level)
+	m, err = cst.classService.ClassMemberRepository.GetMember(context.Background(), &domain.ClassMember{ClassId: class.ClassId, UserId: owner})
+	assert.Nil(t, err)
+	assert.Equal(t, domain.RoleAdmin, m.Level)
+
+	// previous owner is now an admin and can not touch the new owner
+	_, err = cst.classService.DeleteMember(context.Background(), owner, class.ClassId, member)
+	assert.NotNil(t, err)
+}
+
+func (cst classServiceTest) testLeaveClass(t *testing.T) {
+	t.Parallel()
+
+	owner := uuid.NewString()
+	class := &domain.Class{
+		OwnerId: owner,
+		Name:    xid.New().String(),
+	}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+
+	member := uuid.NewString()
+	_, err = cst.classService.AddMember(context.Background(), owner, &domain.ClassMember{
+		ClassId: class.ClassId,
+		UserId:  member,
+	})
+	assert.Nil(t, err)
+	resTask, err := cst.classService.CreateClassTask(context.Background(), owner, &domain.ClassTask{
+		ClassId:  class.ClassId,
+		AuthorId: owner,
+		Name:     "foo",
+	})
+	assert.Nil(t, err)
+	_, err = cst.classService.SetTaskProgress(context.Background(), class.ClassId, &domain.ClassTaskProgress{
+		TaskId: resTask.Data.TaskId,
+		UserId: member,
+		Status: domain.ClassTaskStatusDone,
+	})
+	assert.Nil(t, err)
+
+	var resErr *response.ResponseError
+	_, err = cst.classService.LeaveClass(context.Background(), uuid.NewString(), class.ClassId)
+	if assert.True(t, errors.As(err, &resErr)) {
+		assert.Equal(t, 403, resErr.Code, "non member can not leave")
+	}
+	_, err = cst.classService.LeaveClass(context.Background(), owner, class.ClassId)
+	if assert.True(t, errors.As(err, &resErr)) {
+		assert.Equal(t, 409, resErr.Code, "owner must transfer ownership first")
+	}
+
+	res, err := cst.classService.LeaveClass(context.Background(), member, class.ClassId)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 204, res.Code)
+	}
+	_, err = cst.classService.ClassMemberRepository.GetMember(context.Background(), &domain.ClassMember{ClassId: class.ClassId, UserId: member})
+	assert.Equal(t, domain.ErrClassMemberNotExists, err)
+	_, err = cst.classService.ClassTaskRepository.GetProgress(context.Background(), resTask.Data.TaskId, member)
+	assert.Equal(t, domain.ErrClassTaskProgressNotExists, err, "progress should be removed with the membership")
+
+	entries, err := cst.classService.ListAudit(context.Background(), owner, class.ClassId, domain.ClassAuditFilter{Action: domain.AuditMemberLeave}, domain.Pagination{})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(entries.Data)) {
+		assert.Equal(t, member, entries.Data[0].ActorId)
+	}
+
+	// owner can leave once ownership is transferred
+	next := uuid.NewString()
+	_, err = cst.classService.AddMember(context.Background(), owner, &domain.ClassMember{
+		ClassId: class.ClassId,
+		UserId:  next,
+	})
+	assert.Nil(t, err)
+	_, err = cst.classService.TransferOwnership(context.Background(), owner, class.ClassId, next, class.Name)
+	assert.Nil(t, err)
+	_, err = cst.classService.LeaveClass(context.Background(), owner, class.ClassId)
+	assert.Nil(t, err)
+}
+
+func (cst classServiceTest) testAttachment(t *testing.T) {
+	t.Parallel()
+
+	owner := uuid.NewString()
+	class := &domain.Class{
+		OwnerId: owner,
+		Name:    xid.New().String(),
+	}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+	member := uuid.NewString()
+	_, err = cst.classService.AddMember(context.Background(), owner, &domain.ClassMember{
+		ClassId: class.ClassId,
+		UserId:  member,
+	})
+	assert.Nil(t, err)
+	resTask, err := cst.classService.CreateClassTask(context.Background(), owner, &domain.ClassTask{
+		ClassId:  class.ClassId,
+		AuthorId: owner,
+		Name:     "worksheet",
+	})
+	assert.Nil(t, err)
+	task := resTask.Data
+
+	// the transaction is retried like a serialization failure, content must be read again
+	retried := cst.classService
+	retried.TxRunner = retryTxRunner{retried.TxRunner}
+	upload := func(userId string, attachment domain.ClassAttachment, content string) (*domain.ClassAttachment, error) {
+		attachment.TaskId = task.TaskId
+		attachment.Size = int64(len(content))
+		res, err := retried.UploadAttachment(context.Background(), userId, class.ClassId, &attachment, strings.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		return res.Data, nil
+	}
+
+	tests := []struct {
+		name       string
+		userId     string
+		attachment domain.ClassAttachment
+		size       int64
+		content    string
+		code       int
+	}{
+		{name: "not allowed type", userId: owner, attachment: domain.ClassAttachment{Name: "foo.exe", ContentType: "application/x-msdownload"}, code: 415},
+		{name: "too large", userId: owner, attachment: domain.ClassAttachment{Name: "foo.pdf", ContentType: "application/pdf"}, size: domain.MaxAttachmentSize + 1, code: 413},
+		{name: "missing name", userId: owner, attachment: domain.ClassAttachment{ContentType: "application/pdf"}, code: 400},
+		{name: "not task author", userId: member, attachment: domain.ClassAttachment{Name: "foo.pdf", ContentType: "application/pdf"}, code: 403},
+		{name: "content does not match type", userId: owner, attachment: domain.ClassAttachment{Name: "foo.png", ContentType: "image/png"}, content: "<html><script>alert(1)</script></html>", code: 415},
+		{name: "legacy office without OLE header", userId: owner, attachment: domain.ClassAttachment{Name: "foo.doc", ContentType: "application/msword"}, content: "%PDF-1.4", code: 415},
+	}
+	for _, tt := range tests {
+		tt.attachment.TaskId = task.TaskId
+		tt.attachment.Size = tt.size
+		_, err := cst.classService.UploadAttachment(context.Background(), tt.userId, class.ClassId, &tt.attachment, strings.NewReader(tt.content))
+		var resErr *response.ResponseError
+		if assert.True(t, errors.As(err, &resErr), tt.name) {
+			assert.Equal(t, tt.code, resErr.Code, tt.name)
+		}
+	}
+
+	attachment, err := upload(owner, domain.ClassAttachment{Name: "foo.pdf", ContentType: "application/pdf; charset=binary"}, "%PDF-1.4")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "application/pdf", attachment.ContentType)
+	assert.NotEqual(t, "", attachment.URL)
+
+	_, err = cst.classService.ListAttachments(context.Background(), uuid.NewString(), class.ClassId, task.TaskId)
+	assert.NotNil(t, err, "non member should not list attachments")
+	list, err := cst.classService.ListAttachments(context.Background(), member, class.ClassId, task.TaskId)
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(list.Data)) {
+		assert.Equal(t, attachment.AttachmentId, list.Data[0].AttachmentId)
+		assert.NotEqual(t, "", list.Data[0].URL)
+	}
+
+	u, err := url.Parse(attachment.URL)
+	assert.Nil(t, err)
+	expires, signature := u.Query().Get("expires"), u.Query().Get("signature")
+	_, _, err = cst.classService.DownloadAttachment(context.Background(), class.ClassId, task.TaskId, attachment.AttachmentId, expires, "foo")
+	assert.NotNil(t, err, "download should require valid signature")
+	got, content, err := cst.classService.DownloadAttachment(context.Background(), class.ClassId, task.TaskId, attachment.AttachmentId, expires, signature)
+	if assert.Nil(t, err) {
+		b, err := io.ReadAll(content)
+		assert.Nil(t, err)
+		assert.Equal(t, "%PDF-1.4", string(b))
+		assert.Equal(t, "foo.pdf", got.Name)
+		content.Close()
+	}
+
+	// deleting the task clean up its blobs
+	_, err = cst.classService.DeleteClassTask(context.Background(), owner, task.TaskId)
+	assert.Nil(t, err)
+	_, err = cst.classService.ClassAttachmentRepository.GetAttachment(context.Background(), attachment.AttachmentId)
+	assert.Equal(t, domain.ErrClassAttachmentNotExists, err)
+	_, err = cst.classService.BlobStore.Get(context.Background(), attachment.BlobKey())
+	assert.Equal(t, domain.ErrBlobNotExists, err)
+}
+
+func (cst classServiceTest) testComment(t *testing.T) {
+	t.Parallel()
+
+	owner := uuid.NewString()
+	class := &domain.Class{
+		OwnerId: owner,
+		Name:    xid.New().String(),
+	}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+	member, viewer, admin := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	for userId, role := range map[string]string{member: domain.RoleMember, viewer: domain.RoleViewer, admin: domain.RoleAdmin} {
+		_, err = cst.classService.AddMember(context.Background(), owner, &domain.ClassMember{
+			ClassId: class.ClassId,
+			UserId:  userId,
+			Level:   role,
+		})
+		assert.Nil(t, err)
+	}
+	resTask, err := cst.classService.CreateClassTask(context.Background(), owner, &domain.ClassTask{
+		ClassId:  class.ClassId,
+		AuthorId: owner,
+		Name:     "homework",
+	})
+	assert.Nil(t, err)
+	task := resTask.Data
+
+	create := func(userId, parentId, body string) (*domain.TaskComment, error) {
+		res, err := cst.classService.CreateComment(context.Background(), userId, class.ClassId, &domain.TaskComment{
+			TaskId:   task.TaskId,
+			ParentId: parentId,
+			Body:     body,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res.Data, nil
+	}
+
+	question, err := create(member, "", "question")
+	if !assert.Nil(t, err) {
+		return
+	}
+	answer, err := create(owner, question.CommentId, "answer")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	tests := []struct {
+		name     string
+		userId   string
+		parentId string
+		body     string
+		code     int
+	}{
+		{name: "empty body", userId: member, body: "", code: 400},
+		{name: "non member", userId: uuid.NewString(), body: "foo", code: 403},
+		{name: "viewer", userId: viewer, body: "foo", code: 403},
+		{name: "missing parent", userId: member, parentId: xid.New().String(), body: "foo", code: 404},
+		{name: "reply to reply", userId: member, parentId: answer.CommentId, body: "foo", code: 422},
+	}
+	for _, tt := range tests {
+		_, err := create(tt.userId, tt.parentId, tt.body)
+		var resErr *response.ResponseError
+		if assert.True(t, errors.As(err, &resErr), tt.name) {
+			assert.Equal(t, tt.code, resErr.Code, tt.name)
+		}
+	}
+
+	list, err := cst.classService.ListComments(context.Background(), viewer, class.ClassId, task.TaskId, domain.Pagination{})
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(list.Data)) {
+		assert.Equal(t, question.CommentId, list.Data[0].CommentId)
+		assert.Equal(t, question.CommentId, list.Data[1].ParentId)
+	}
+	_, err = cst.classService.ListComments(context.Background(), uuid.NewString(), class.ClassId, task.TaskId, domain.Pagination{})
+	assert.NotNil(t, err, "non member should not list comments")
+
+	update := &domain.TaskComment{TaskId: task.TaskId, CommentId: question.CommentId, Body: "edited"}
+	_, err = cst.classService.UpdateComment(context.Background(), owner, class.ClassId, update)
+	assert.NotNil(t, err, "only the author can edit comment")
+	res, err := cst.classService.UpdateComment(context.Background(), member, class.ClassId, update)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "edited", res.Data.Body)
+		assert.NotNil(t, res.Data.UpdatedAt)
+	}
+
+	_, err = cst.classService.DeleteComment(context.Background(), viewer, class.ClassId, task.TaskId, answer.CommentId)
+	assert.NotNil(t, err, "viewer should not delete other comment")
+	_, err = cst.classService.DeleteComment(context.Background(), member, class.ClassId, task.TaskId, answer.CommentId)
+	assert.NotNil(t, err, "member should not delete other comment")
+	_, err = cst.classService.DeleteComment(context.Background(), admin, class.ClassId, task.TaskId, question.CommentId)
+	assert.Nil(t, err, "admin can delete any comment")
+
+	list, err = cst.classService.ListComments(context.Background(), member, class.ClassId, task.TaskId, domain.Pagination{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, 0, len(list.Data), "replies should be deleted with their parent")
+	}
+
+	own, err := create(member, "", "foo")
+	assert.Nil(t, err)
+	_, err = cst.classService.DeleteComment(context.Background(), member, class.ClassId, task.TaskId, own.CommentId)
+	assert.Nil(t, err, "author can delete own comment")
+}
+
+func (cst classServiceTest) testAnnouncement(t *testing.T) {
+	t.Parallel()
+
+	owner := uuid.NewString()
+	class := &domain.Class{
+		OwnerId: owner,
+		Name:    xid.New().String(),
+	}
+	_, err := cst.classService.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+	member := uuid.NewString()
+	_, err = cst.classService.AddMember(context.Background(), owner, &domain.ClassMember{
+		ClassId: class.ClassId,
+		UserId:  member,
+	})
+	assert.Nil(t, err)
+
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name         string
+		userId       string
+		announcement domain.ClassAnnouncement
+		code         int
+	}{
+		{name: "missing title", userId: owner, announcement: domain.ClassAnnouncement{Body: "foo"}, code: 400},
+		{name: "already expired", userId: owner, announcement: domain.ClassAnnouncement{Title: "foo", ExpiresAt: &past}, code: 422},
+		{name: "member", userId: member, announcement: domain.ClassAnnouncement{Title: "foo"}, code: 403},
+	}
+	for _, tt := range tests {
+		_, err := cst.classService.CreateAnnouncement(context.Background(), tt.userId, class.ClassId, &tt.announcement)
+		var resErr *response.ResponseError
+		if assert.True(t, errors.As(err, &resErr), tt.name) {
+			assert.Equal(t, tt.code, resErr.Code, tt.name)
+		}
+	}
+
+	res, err := cst.classService.CreateAnnouncement(context.Background(), owner, class.ClassId, &domain.ClassAnnouncement{Title: "exam", Body: "exam moved to **Friday**"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	exam := res.Data
+	soon := time.Now().Add(time.Hour)
+	res, err = cst.classService.CreateAnnouncement(context.Background(), owner, class.ClassId, &domain.ClassAnnouncement{Title: "rules", Pinned: true, ExpiresAt: &soon})
+	if !assert.Nil(t, err) {
+		return
+	}
+	rules := res.Data
+
+	list, err := cst.classService.ListAnnouncements(context.Background(), member, class.ClassId, false, domain.Pagination{})
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(list.Data)) {
+		assert.Equal(t, rules.AnnouncementId, list.Data[0].AnnouncementId, "pinned announcement should come first")
+		assert.Equal(t, exam.AnnouncementId, list.Data[1].AnnouncementId)
+	}
+	_, err = cst.classService.ListAnnouncements(context.Background(), uuid.NewString(), class.ClassId, false, domain.Pagination{})
+	assert.NotNil(t, err, "non member should not list announcements")
+	_, err = cst.classService.ListAnnouncements(context.Background(), member, class.ClassId, true, domain.Pagination{})
+	assert.NotNil(t, err, "member should not list expired announcements")
+
+	_, err = cst.classService.UpdateAnnouncement(context.Background(), member, class.ClassId, &domain.ClassAnnouncement{AnnouncementId: exam.AnnouncementId, Title: "foo"})
+	assert.NotNil(t, err, "member should not update announcement")
+	updated, err := cst.classService.UpdateAnnouncement(context.Background(), owner, class.ClassId, &domain.ClassAnnouncement{AnnouncementId: exam.AnnouncementId, Title: "exam", Body: "exam moved to Monday"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "exam moved to Monday", updated.Data.Body)
+		assert.Equal(t, owner, updated.Data.AuthorId)
+		assert.NotNil(t, updated.Data.UpdatedAt)
+	}
+
+	// expire the announcement behind the service to hide it from members
+	expired := *rules
+	expired.ExpiresAt = &past
+	err = cst.classService.ClassAnnouncementRepository.UpdateAnnouncement(context.Background(), &expired)
+	assert.Nil(t, err)
+	list, err = cst.classService.ListAnnouncements(context.Background(), member, class.ClassId, false, domain.Pagination{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, 1, len(list.Data))
+	}
+	_, err = cst.classService.GetAnnouncement(context.Background(), member, class.ClassId, rules.AnnouncementId)
+	assert.NotNil(t, err, "member should not get expired announcement")
+	_, err = cst.classService.GetAnnouncement(context.Background(), owner, class.ClassId, rules.AnnouncementId)
+	assert.Nil(t, err)
+	list, err = cst.classService.ListAnnouncements(context.Background(), owner, class.ClassId, true, domain.Pagination{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, 2, len(list.Data))
+	}
+
+	_, err = cst.classService.DeleteAnnouncement(context.Background(), member, class.ClassId, exam.AnnouncementId)
+	assert.NotNil(t, err, "member should not delete announcement")
+	_, err = cst.classService.DeleteAnnouncement(context.Background(), owner, class.ClassId, exam.AnnouncementId)
+	assert.Nil(t, err)
+	_, err = cst.classService.GetAnnouncement(context.Background(), owner, class.ClassId, exam.AnnouncementId)
+	assert.NotNil(t, err)
+}
+
+func (cst classServiceTest) testVisibility(t *testing.T) {
+	t.Parallel()
+
+	owner := &domain.User{
+		UserId:   uuid.NewString(),
+		Name:     xid.New().String(),
+		Username: xid.New().String(),
+		Email:    xid.New().String(),
+	}
+	err := cst.classService.UserRepository.CreateUser(context.Background(), owner)
+	assert.Nil(t, err)
+	member := uuid.NewString()
+	stranger := uuid.NewString()
+
+	_, err = cst.classService.CreateClass(context.Background(), &domain.Class{OwnerId: owner.UserId, Name: xid.New().String(), Visibility: "secret"})
+	var resErr *response.ResponseError
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 400, resErr.Code, "unknown visibility")
+	}
+
+	classes := map[string]*domain.Class{}
+	for _, visibility := range []string{"", domain.ClassVisibilityUnlisted, domain.ClassVisibilityPrivate} {
+		class := &domain.Class{OwnerId: owner.UserId, Name: xid.New().String(), Visibility: visibility}
+		_, err := cst.classService.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		_, err = cst.classService.AddMember(context.Background(), owner.UserId, &domain.ClassMember{
+			ClassId: class.ClassId,
+			UserId:  member,
+		})
+		assert.Nil(t, err)
+		classes[class.Visibility] = class
+	}
+	assert.Contains(t, classes, domain.ClassVisibilityPublic, "class should be public by default")
+
+	reads := map[string]func(userId, classId string) error{
+		"info": func(userId, classId string) error {
+			_, err := cst.classService.GetClassInfo(context.Background(), userId, classId)
+			return err
+		},
+		"tasks": func(userId, classId string) error {
+			_, err := cst.classService.GetClassTasks(context.Background(), userId, classId, domain.ClassTaskFilter{}, domain.Pagination{})
+			return err
+		},
+		"schedules": func(userId, classId string) error {
+			_, err := cst.classService.GetClassSchedules(context.Background(), userId, classId, domain.Pagination{})
+			return err
+		},
+		"timetable": func(userId, classId string) error {
+			_, err := cst.classService.GetClassTimetable(context.Background(), userId, classId)
+			return err
+		},
+		"members": func(userId, classId string) error {
+			_, err := cst.classService.ListMember(context.Background(), userId, classId, domain.Pagination{})
+			return err
+		},
+		"announcements": func(userId, classId string) error {
+			_, err := cst.classService.ListAnnouncements(context.Background(), userId, classId, false, domain.Pagination{})
+			return err
+		},
+	}
+	for name, read := range reads {
+		for visibility, class := range classes {
+			assert.Nil(t, read(member, class.ClassId), "%s of %s class by member", name, visibility)
+		}
+		for _, userId := range []string{"", stranger} {
+			err := read(userId, classes[domain.ClassVisibilityPrivate].ClassId)
+			if assert.ErrorAs(t, err, &resErr, "%s of private class by %q", name, userId) {
+				assert.Equal(t, 404, resErr.Code, "%s of private class by %q", name, userId)
+			}
+		}
+	}
+
+	for visibility, class := range classes {
+		_, err := cst.classService.GetClassInfo(context.Background(), "", class.ClassId)
+		assert.Equal(t, visibility == domain.ClassVisibilityPrivate, err != nil, "%s class by anonymous", visibility)
+
+		_, err = cst.classService.GetClassInfoByName(context.Background(), stranger, owner.Username, class.Name)
+		assert.Equal(t, visibility != domain.ClassVisibilityPublic, err != nil, "only public class can be found by name")
+		_, err = cst.classService.GetClassInfoByName(context.Background(), member, owner.Username, class.Name)
+		assert.Nil(t, err, "member can find %s class by name", visibility)
+	}
+
+	// changing visibility hide the class immediately
+	public := classes[domain.ClassVisibilityPublic]
+	_, err = cst.classService.UpdateClass(context.Background(), owner.UserId, &domain.Class{ClassId: public.ClassId, Name: public.Name, Visibility: domain.ClassVisibilityPrivate})
+	assert.Nil(t, err)
+	_, err = cst.classService.GetClassInfo(context.Background(), stranger, public.ClassId)
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 404, resErr.Code)
+	}
+}
+
+type joinRequestRecorder struct {
+	mx        sync.Mutex
+	decisions map[string]bool
+}
+
+func (r *joinRequestRecorder) JoinRequestDecided(ctx context.Context, request *domain.ClassJoinRequest, approved bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.decisions[request.UserId] = approved
+}
+
+func (r *joinRequestRecorder) decision(userId string) (approved, ok bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	approved, ok = r.decisions[userId]
+	return
+}
+
+func (cst classServiceTest) testJoinRequest(t *testing.T) {
+	t.Parallel()
+
+	notifier := &joinRequestRecorder{decisions: map[string]bool{}}
+	cs := cst.classService
+	cs.JoinRequestNotifier = notifier
+
+	owner := uuid.NewString()
+	class := &domain.Class{OwnerId: owner, Name: xid.New().String()}
+	_, err := cs.CreateClass(context.Background(), class)
+	assert.Nil(t, err)
+	private := &domain.Class{OwnerId: owner, Name: xid.New().String(), Visibility: domain.ClassVisibilityPrivate}
+	_, err = cs.CreateClass(context.Background(), private)
+	assert.Nil(t, err)
+	unlisted := &domain.Class{OwnerId: owner, Name: xid.New().String(), Visibility: domain.ClassVisibilityUnlisted}
+	_, err = cs.CreateClass(context.Background(), unlisted)
+	assert.Nil(t, err)
+	alice, bob, carol := uuid.NewString(), uuid.NewString(), uuid.NewString()
+
+	var resErr *response.ResponseError
+	tests := []struct {
+		name    string
+		userId  string
+		classId string
+		code    int
+	}{
+		{name: "owner", userId: owner, classId: class.ClassId, code: 409},
+		{name: "private class", userId: alice, classId: private.ClassId, code: 404},
+		{name: "unlisted class", userId: alice, classId: unlisted.ClassId, code: 403},
+		{name: "unknown class", userId: alice, classId: xid.New().String(), code: 404},
+	}
+	for _, tt := range tests {
+		_, err := cs.RequestJoin(context.Background(), tt.userId, tt.classId, &domain.ClassJoinRequest{})
+		if assert.ErrorAs(t, err, &resErr, tt.name) {
+			assert.Equal(t, tt.code, resErr.Code, tt.name)
+		}
+	}
+
+	for _, userId := range []string{alice, bob} {
+		res, err := cs.RequestJoin(context.Background(), userId, class.ClassId, &domain.ClassJoinRequest{Message: "hi"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, userId, res.Data.UserId)
+			assert.True(t, res.Data.ExpiresAt.After(time.Now().Add(domain.JoinRequestTTL-time.Minute)))
+		}
+	}
+	_, err = cs.RequestJoin(context.Background(), alice, class.ClassId, &domain.ClassJoinRequest{})
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 409, resErr.Code, "duplicate request")
+	}
+
+	// stale request is not listed and can not be approved
+	err = cs.ClassJoinRequestRepository.CreateJoinRequest(context.Background(), &domain.ClassJoinRequest{
+		ClassId:   class.ClassId,
+		UserId:    carol,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	assert.Nil(t, err)
+	_, err = cs.ApproveJoinRequest(context.Background(), owner, class.ClassId, carol, "")
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 404, resErr.Code, "expired request")
+	}
+
+	_, err = cs.ListJoinRequests(context.Background(), alice, class.ClassId, domain.Pagination{})
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 403, resErr.Code, "requester can not list requests")
+	}
+	list, err := cs.ListJoinRequests(context.Background(), owner, class.ClassId, domain.Pagination{})
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(list.Data)) {
+		assert.Equal(t, alice, list.Data[0].UserId)
+		assert.Equal(t, bob, list.Data[1].UserId)
+	}
+	// the expired request is gone so it can be made again
+	_, err = cs.RequestJoin(context.Background(), carol, class.ClassId, &domain.ClassJoinRequest{})
+	assert.Nil(t, err)
+
+	_, err = cs.ApproveJoinRequest(context.Background(), owner, class.ClassId, alice, domain.RoleOwner)
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 422, resErr.Code, "owner can not be granted")
+	}
+	res, err := cs.ApproveJoinRequest(context.Background(), owner, class.ClassId, alice, domain.RoleViewer)
+	if assert.Nil(t, err) {
+		assert.Equal(t, domain.RoleViewer, res.Data.Level)
+	}
+	member, err := cs.ClassMemberRepository.GetMember(context.Background(), &domain.ClassMember{ClassId: class.ClassId, UserId: alice})
+	if assert.Nil(t, err) {
+		assert.Equal(t, domain.RoleViewer, member.Level)
+	}
+	approved, ok := notifier.decision(alice)
+	assert.True(t, ok && approved, "approval should be notified")
+
+	_, err = cs.RejectJoinRequest(context.Background(), alice, class.ClassId, bob)
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 403, resErr.Code, "viewer can not reject")
+	}
+	_, err = cs.RejectJoinRequest(context.Background(), owner, class.ClassId, bob)
+	assert.Nil(t, err)
+	_, err = cs.ClassMemberRepository.GetMember(context.Background(), &domain.ClassMember{ClassId: class.ClassId, UserId: bob})
+	assert.ErrorIs(t, err, domain.ErrClassMemberNotExists)
+	approved, ok = notifier.decision(bob)
+	assert.True(t, ok && !approved, "rejection should be notified")
+
+	_, err = cs.RejectJoinRequest(context.Background(), owner, class.ClassId, bob)
+	if assert.ErrorAs(t, err, &resErr) {
+		assert.Equal(t, 404, resErr.Code, "request is already decided")
+	}
+	list, err = cs.ListJoinRequests(context.Background(), owner, class.ClassId, domain.Pagination{})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(list.Data)) {
+		assert.Equal(t, carol, list.Data[0].UserId)
+	}
+}
+
+func (cst classServiceTest) testSearch(t *testing.T) {
+	t.Parallel()
+	cs := cst.classService
+
+	var resErr *response.ResponseError
+	for name, query := range map[string]string{
+		"empty":    "  ",
+		"too long": strings.Repeat("a", domain.MaxSearchQuery+1),
+	} {
+		_, err := cs.Search(context.Background(), query, 0)
+		if assert.ErrorAs(t, err, &resErr, name) {
+			assert.Equal(t, 400, resErr.Code, name)
+		}
+	}
+
+	marker := strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	owner := &domain.User{
+		UserId:   uuid.NewString(),
+		Name:     "Owner " + marker,
+		Username: xid.New().String(),
+		Email:    xid.New().String(),
+	}
+	err := cs.UserRepository.CreateUser(context.Background(), owner)
+	assert.Nil(t, err)
+	public := &domain.Class{OwnerId: owner.UserId, Name: marker + " public"}
+	_, err = cs.CreateClass(context.Background(), public)
+	assert.Nil(t, err)
+	_, err = cs.CreateClass(context.Background(), &domain.Class{OwnerId: owner.UserId, Name: marker + " private", Visibility: domain.ClassVisibilityPrivate})
+	assert.Nil(t, err)
+
+	res, err := cs.Search(context.Background(), " "+marker+" ", 0)
+	if assert.Nil(t, err) {
+		if assert.Equal(t, 1, len(res.Data.Classes), "private class must not be found") {
+			assert.Equal(t, public.ClassId, res.Data.Classes[0].ClassId)
+		}
+		if assert.Equal(t, 1, len(res.Data.Users)) {
+			assert.Equal(t, owner.UserId, res.Data.Users[0].UserId)
+			assert.Equal(t, "", res.Data.Users[0].Email)
+		}
+	}
+}
+
+// retryTxRunner run every transaction twice, the first attempt is rolled back
+// like a serialization failure retried by TxRunnerPostgres
+type retryTxRunner struct {
+	domain.TxRunner
+}
+
+func (r retryTxRunner) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if database.InTx(ctx) {
+		return fn(ctx)
+	}
+	errRetry := errors.New("retry")
+	err := r.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		return errRetry
+	})
+	if !errors.Is(err, errRetry) {
+		return err
+	}
+	return r.TxRunner.RunInTx(ctx, fn)
+}
@@ -0,0 +1,146 @@
+package class
+
+import (
+	"context"
+	"errors"
+
+	"nory/common/response"
+	"nory/common/validator"
+	"nory/domain"
+)
+
+// CreateComment add comment to the task, a comment with ParentId reply to a top level comment of the same task
+func (cs *ClassService) CreateComment(ctx context.Context, userId, classId string, comment *domain.TaskComment) (*response.Response[*domain.TaskComment], error) {
+	comment.ClassId = classId
+	comment.AuthorId = userId
+	if err := validator.ValidateStruct(comment); err != nil {
+		return nil, err
+	}
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityComment); err != nil {
+		return nil, err
+	}
+	task, err := cs.getClassTask(ctx, classId, comment.TaskId)
+	if err != nil {
+		return nil, err
+	}
+	if comment.ParentId != "" {
+		parent, err := cs.getComment(ctx, classId, comment.TaskId, comment.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		if parent.ParentId != "" {
+			return nil, response.NewUnprocessableEntity("comment.nested_reply", nil)
+		}
+	}
+
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		if err := cs.storeOccurrence(ctx, task); err != nil {
+			return err
+		}
+		if err := cs.TaskCommentRepository.CreateComment(ctx, comment); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditCommentCreate, comment.CommentId, nil, snapshot(comment)); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventCommentCreated, snapshot(comment))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New(200, comment), nil
+}
+
+// ListComments list comments of the task ordered by time, replies come after their parent
+func (cs *ClassService) ListComments(ctx context.Context, userId, classId, taskId string, page domain.Pagination) (*response.Response[[]*domain.TaskComment], error) {
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityViewClass); err != nil {
+		return nil, err
+	}
+	if _, err := cs.getClassTask(ctx, classId, taskId); err != nil {
+		return nil, err
+	}
+	page = page.Normalize()
+	comments, next, err := cs.TaskCommentRepository.ListComments(ctx, taskId, page)
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return nil, response.NewBadRequest("pagination.invalid_cursor", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response.NewPaginated(200, comments, page.Limit, next), nil
+}
+
+// UpdateComment change body of the comment, only the author can edit it
+func (cs *ClassService) UpdateComment(ctx context.Context, userId, classId string, comment *domain.TaskComment) (*response.Response[*domain.TaskComment], error) {
+	if err := validator.ValidateStruct(comment); err != nil {
+		return nil, err
+	}
+	if err := cs.AccessClass(ctx, userId, classId, domain.CapabilityComment); err != nil {
+		return nil, err
+	}
+	prev, err := cs.getComment(ctx, classId, comment.TaskId, comment.CommentId)
+	if err != nil {
+		return nil, err
+	}
+	if prev.AuthorId != userId {
+		return nil, response.NewForbidden("comment.not_author", nil)
+	}
+
+	curr := *prev
+	curr.Body = comment.Body
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		if err := cs.TaskCommentRepository.UpdateComment(ctx, &curr); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditCommentUpdate, curr.CommentId, snapshot(prev), snapshot(&curr)); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventCommentUpdated, snapshot(&curr))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New(200, &curr), nil
+}
+
+// DeleteComment remove the comment and its replies, only the author or class admin can delete it
+func (cs *ClassService) DeleteComment(ctx context.Context, userId, classId, taskId, commentId string) (*response.Response[any], error) {
+	comment, err := cs.getComment(ctx, classId, taskId, commentId)
+	if err != nil {
+		return nil, err
+	}
+	required := domain.CapabilityModerateComment
+	if comment.AuthorId == userId {
+		required = domain.CapabilityComment
+	}
+	if err := cs.AccessClass(ctx, userId, classId, required); err != nil {
+		return nil, err
+	}
+
+	if err := cs.TxRunner.RunInTx(ctx, func(ctx context.Context) error {
+		before := snapshot(comment)
+		if err := cs.TaskCommentRepository.DeleteComment(ctx, commentId); err != nil {
+			return err
+		}
+		if err := cs.audit(ctx, userId, classId, domain.AuditCommentDelete, commentId, before, nil); err != nil {
+			return err
+		}
+		cs.publish(ctx, classId, domain.EventCommentDeleted, before)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return response.New[any](204, nil), nil
+}
+
+// getComment get comment and make sure it is belong to the task of the class
+func (cs *ClassService) getComment(ctx context.Context, classId, taskId, commentId string) (*domain.TaskComment, error) {
+	comment, err := cs.TaskCommentRepository.GetComment(ctx, commentId)
+	if errors.Is(err, domain.ErrTaskCommentNotExists) || (err == nil && (comment.ClassId != classId || comment.TaskId != taskId)) {
+		return nil, response.NewNotFound("comment.not_found", response.Params{"commentId": commentId, "taskId": taskId})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
@@ -0,0 +1,101 @@
+package classannouncement
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type ClassAnnouncementRepositoryMem struct {
+	mx sync.Mutex
+	m  map[string]*domain.ClassAnnouncement
+}
+
+func NewClassAnnouncementRepositoryMem() *ClassAnnouncementRepositoryMem {
+	return &ClassAnnouncementRepositoryMem{
+		m: make(map[string]*domain.ClassAnnouncement),
+	}
+}
+
+func (repo *ClassAnnouncementRepositoryMem) CreateAnnouncement(ctx context.Context, announcement *domain.ClassAnnouncement) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	announcement.AnnouncementId = xid.New().String()
+	announcement.CreatedAt = time.Now().UTC()
+	a := *announcement
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, announcement.AnnouncementId)
+	repo.m[announcement.AnnouncementId] = &a
+	return nil
+}
+
+func (repo *ClassAnnouncementRepositoryMem) GetAnnouncement(ctx context.Context, announcementId string) (*domain.ClassAnnouncement, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	announcement, ok := repo.m[announcementId]
+	if !ok {
+		return nil, domain.ErrClassAnnouncementNotExists
+	}
+	a := *announcement
+	return &a, nil
+}
+
+func (repo *ClassAnnouncementRepositoryMem) ListAnnouncements(ctx context.Context, classId string, activeAt time.Time, page domain.Pagination) ([]*domain.ClassAnnouncement, string, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	announcements := make([]*domain.ClassAnnouncement, 0)
+	for _, announcement := range repo.m {
+		if announcement.ClassId != classId || (!activeAt.IsZero() && announcement.Expired(activeAt)) {
+			continue
+		}
+		a := *announcement
+		announcements = append(announcements, &a)
+	}
+	sort.Slice(announcements, func(i, j int) bool {
+		return domain.CompareKeys(announcementKey(announcements[i]), announcementKey(announcements[j])) > 0
+	})
+	return domain.PaginateDesc(announcements, page, announcementKey)
+}
+
+// announcementKey sort pinned announcement before the others, both in the
+// descending order, pinned flag is encoded as "1" or "0"
+func announcementKey(a *domain.ClassAnnouncement) []string {
+	pinned := "0"
+	if a.Pinned {
+		pinned = "1"
+	}
+	return []string{pinned, domain.CursorTime(a.CreatedAt), a.AnnouncementId}
+}
+
+func (repo *ClassAnnouncementRepositoryMem) UpdateAnnouncement(ctx context.Context, announcement *domain.ClassAnnouncement) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	a, ok := repo.m[announcement.AnnouncementId]
+	if !ok {
+		return domain.ErrClassAnnouncementNotExists
+	}
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, announcement.AnnouncementId)
+	updated := *a
+	updated.Title = announcement.Title
+	updated.Body = announcement.Body
+	updated.Pinned = announcement.Pinned
+	updated.ExpiresAt = announcement.ExpiresAt
+	now := time.Now().UTC()
+	updated.UpdatedAt = &now
+	announcement.UpdatedAt = &now
+	repo.m[announcement.AnnouncementId] = &updated
+	return nil
+}
+
+func (repo *ClassAnnouncementRepositoryMem) DeleteAnnouncement(ctx context.Context, announcementId string) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, announcementId)
+	delete(repo.m, announcementId)
+	return nil
+}
@@ -0,0 +1,155 @@
+package classannouncement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type ClassAnnouncementRepositoryPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewClassAnnouncementRepositoryPostgres(pool *pgxpool.Pool) *ClassAnnouncementRepositoryPostgres {
+	return &ClassAnnouncementRepositoryPostgres{pool}
+}
+
+func (repo *ClassAnnouncementRepositoryPostgres) CreateAnnouncement(ctx context.Context, announcement *domain.ClassAnnouncement) error {
+	announcement.AnnouncementId = xid.New().String()
+	row := database.Conn(ctx, repo.pool).QueryRow(
+		ctx,
+		`INSERT INTO class_announcement(announcement_id, class_id, author_id, title, body, pinned, expires_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`,
+		announcement.AnnouncementId,
+		announcement.ClassId,
+		announcement.AuthorId,
+		announcement.Title,
+		announcement.Body,
+		announcement.Pinned,
+		announcement.ExpiresAt,
+	)
+	return row.Scan(&announcement.CreatedAt)
+}
+
+// announcementColumns is the columns read by scanAnnouncement
+const announcementColumns = "announcement_id, class_id, author_id, created_at, title, body, pinned, expires_at, updated_at"
+
+func scanAnnouncement(row pgx.Row) (*domain.ClassAnnouncement, error) {
+	announcement := &domain.ClassAnnouncement{}
+	err := row.Scan(
+		&announcement.AnnouncementId,
+		&announcement.ClassId,
+		&announcement.AuthorId,
+		&announcement.CreatedAt,
+		&announcement.Title,
+		&announcement.Body,
+		&announcement.Pinned,
+		&announcement.ExpiresAt,
+		&announcement.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return announcement, nil
+}
+
+func (repo *ClassAnnouncementRepositoryPostgres) GetAnnouncement(ctx context.Context, announcementId string) (*domain.ClassAnnouncement, error) {
+	row := database.Conn(ctx, repo.pool).QueryRow(
+		ctx,
+		"SELECT "+announcementColumns+" FROM class_announcement WHERE announcement_id = $1",
+		announcementId,
+	)
+	announcement, err := scanAnnouncement(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrClassAnnouncementNotExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return announcement, nil
+}
+
+func (repo *ClassAnnouncementRepositoryPostgres) ListAnnouncements(ctx context.Context, classId string, activeAt time.Time, page domain.Pagination) ([]*domain.ClassAnnouncement, string, error) {
+	after, err := page.Keys(3)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "SELECT " + announcementColumns + " FROM class_announcement WHERE class_id = $1"
+	args := []any{classId}
+	if !activeAt.IsZero() {
+		args = append(args, activeAt)
+		query += fmt.Sprintf(" AND (expires_at IS NULL OR expires_at > $%d)", len(args))
+	}
+	if after != nil {
+		createdAt, err := domain.ParseCursorTime(after[1])
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, after[0] == "1", createdAt, after[2])
+		query += fmt.Sprintf(" AND (pinned, created_at, announcement_id) < ($%d, $%d, $%d)", len(args)-2, len(args)-1, len(args))
+	}
+	query += " ORDER BY pinned DESC, created_at DESC, announcement_id DESC"
+	if page.Limit > 0 {
+		args = append(args, page.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	announcements := make([]*domain.ClassAnnouncement, 0)
+	rows, err := database.Conn(ctx, repo.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	for rows.Next() {
+		announcement, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		announcements = append(announcements, announcement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	announcements, next := domain.NextPage(announcements, page, announcementKey)
+	return announcements, next, nil
+}
+
+func (repo *ClassAnnouncementRepositoryPostgres) UpdateAnnouncement(ctx context.Context, announcement *domain.ClassAnnouncement) error {
+	// database only store microsecond
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	tag, err := database.Conn(ctx, repo.pool).Exec(
+		ctx,
+		"UPDATE class_announcement SET title = $1, body = $2, pinned = $3, expires_at = $4, updated_at = $5 WHERE announcement_id = $6",
+		announcement.Title,
+		announcement.Body,
+		announcement.Pinned,
+		announcement.ExpiresAt,
+		now,
+		announcement.AnnouncementId,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrClassAnnouncementNotExists
+	}
+	announcement.UpdatedAt = &now
+	return nil
+}
+
+func (repo *ClassAnnouncementRepositoryPostgres) DeleteAnnouncement(ctx context.Context, announcementId string) error {
+	_, err := database.Conn(ctx, repo.pool).Exec(
+		ctx,
+		"DELETE FROM class_announcement WHERE announcement_id = $1",
+		announcementId,
+	)
+	return err
+}
@@ -0,0 +1,193 @@
+package classannouncement_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"nory/domain"
+	"nory/internal/class"
+	. "nory/internal/class_announcement"
+	"nory/internal/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassAnnouncementRepository(t *testing.T) {
+	t.Parallel()
+	pool, err := pgxpool.New(context.Background(), os.Getenv("DATABASE_URL"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	repos := []Repository{
+		{
+			Name:                        "memory",
+			ClassAnnouncementRepository: NewClassAnnouncementRepositoryMem(),
+			ClassRepository:             class.NewClassRepositoryMem(),
+			UserRepository:              user.NewUserRepositoryMem(),
+		},
+		{
+			Name:                        "postgres",
+			ClassAnnouncementRepository: NewClassAnnouncementRepositoryPostgres(pool),
+			ClassRepository:             class.NewClassRepositoryPostgres(pool),
+			UserRepository:              user.NewUserRepositoryPostgres(pool),
+			Skip:                        os.Getenv("DATABASE_URL") == "",
+		},
+	}
+
+	for _, repo := range repos {
+		repo := repo
+		t.Run(repo.Name, func(t *testing.T) {
+			repo.t = t
+			if repo.Skip {
+				t.Skipf("skipping %s", repo.Name)
+			}
+			t.Parallel()
+			t.Run("CreateAnnouncement", repo.testCreateAnnouncement)
+			t.Run("GetAnnouncement", repo.testGetAnnouncement)
+			t.Run("ListAnnouncements", repo.testListAnnouncements)
+			t.Run("UpdateAnnouncement", repo.testUpdateAnnouncement)
+			t.Run("DeleteAnnouncement", repo.testDeleteAnnouncement)
+		})
+	}
+}
+
+type Repository struct {
+	Name                        string
+	ClassAnnouncementRepository domain.ClassAnnouncementRepository
+	ClassRepository             domain.ClassRepository
+	UserRepository              domain.UserRepository
+	Skip                        bool
+
+	announcements []domain.ClassAnnouncement
+	class         *domain.Class
+	t             *testing.T
+}
+
+func (r *Repository) getClass() *domain.Class {
+	if r.class != nil {
+		return r.class
+	}
+
+	u := &domain.User{
+		UserId:   uuid.NewString(),
+		Email:    xid.New().String(),
+		Username: xid.New().String(),
+	}
+	err := r.UserRepository.CreateUser(context.Background(), u)
+	assert.Nil(r.t, err)
+
+	r.class = &domain.Class{
+		Name:    xid.New().String(),
+		OwnerId: u.UserId,
+	}
+	err = r.ClassRepository.CreateClass(context.Background(), r.class)
+	assert.Nil(r.t, err)
+	return r.class
+}
+
+func (r *Repository) testCreateAnnouncement(t *testing.T) {
+	c := r.getClass()
+	// database only store microsecond
+	expired := time.Now().UTC().Add(-time.Hour).Truncate(time.Microsecond)
+	for _, announcement := range []domain.ClassAnnouncement{
+		{ClassId: c.ClassId, AuthorId: c.OwnerId, Title: "foo", Body: "**foo**"},
+		{ClassId: c.ClassId, AuthorId: c.OwnerId, Title: "bar", Pinned: true},
+		{ClassId: c.ClassId, AuthorId: c.OwnerId, Title: "baz", ExpiresAt: &expired},
+	} {
+		announcement := announcement
+		err := r.ClassAnnouncementRepository.CreateAnnouncement(context.Background(), &announcement)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", announcement.AnnouncementId, "CreateAnnouncement should update (*ClassAnnouncement).AnnouncementId")
+		assert.False(t, announcement.CreatedAt.IsZero(), "CreateAnnouncement should update (*ClassAnnouncement).CreatedAt")
+		r.announcements = append(r.announcements, announcement)
+	}
+}
+
+func (r *Repository) testGetAnnouncement(t *testing.T) {
+	for _, announcement := range r.announcements {
+		got, err := r.ClassAnnouncementRepository.GetAnnouncement(context.Background(), announcement.AnnouncementId)
+		assert.Nil(t, err)
+		assert.Equal(t, announcement.ClassId, got.ClassId)
+		assert.Equal(t, announcement.Title, got.Title)
+		assert.Equal(t, announcement.Body, got.Body)
+		assert.Equal(t, announcement.Pinned, got.Pinned)
+		if announcement.ExpiresAt == nil {
+			assert.Nil(t, got.ExpiresAt)
+		} else if assert.NotNil(t, got.ExpiresAt) {
+			assert.True(t, announcement.ExpiresAt.Equal(*got.ExpiresAt))
+		}
+	}
+
+	_, err := r.ClassAnnouncementRepository.GetAnnouncement(context.Background(), xid.New().String())
+	assert.Equal(t, domain.ErrClassAnnouncementNotExists, err)
+}
+
+func (r *Repository) testListAnnouncements(t *testing.T) {
+	c := r.getClass()
+	ids := func(announcements []*domain.ClassAnnouncement) []string {
+		result := make([]string, 0, len(announcements))
+		for _, a := range announcements {
+			result = append(result, a.AnnouncementId)
+		}
+		return result
+	}
+	foo, bar, baz := r.announcements[0].AnnouncementId, r.announcements[1].AnnouncementId, r.announcements[2].AnnouncementId
+
+	announcements, next, err := r.ClassAnnouncementRepository.ListAnnouncements(context.Background(), c.ClassId, time.Time{}, domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", next)
+	assert.Equal(t, []string{bar, baz, foo}, ids(announcements), "pinned first then newest first")
+
+	announcements, _, err = r.ClassAnnouncementRepository.ListAnnouncements(context.Background(), c.ClassId, time.Now(), domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{bar, foo}, ids(announcements), "expired announcement should be excluded")
+
+	page := domain.Pagination{Limit: 1}
+	var got []string
+	for {
+		announcements, next, err := r.ClassAnnouncementRepository.ListAnnouncements(context.Background(), c.ClassId, time.Time{}, page)
+		if !assert.Nil(t, err) {
+			return
+		}
+		got = append(got, ids(announcements)...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+	assert.Equal(t, []string{bar, baz, foo}, got)
+}
+
+func (r *Repository) testUpdateAnnouncement(t *testing.T) {
+	announcement := r.announcements[0]
+	announcement.Title = "updated"
+	announcement.Pinned = true
+	err := r.ClassAnnouncementRepository.UpdateAnnouncement(context.Background(), &announcement)
+	assert.Nil(t, err)
+	assert.NotNil(t, announcement.UpdatedAt, "UpdateAnnouncement should update (*ClassAnnouncement).UpdatedAt")
+
+	got, err := r.ClassAnnouncementRepository.GetAnnouncement(context.Background(), announcement.AnnouncementId)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated", got.Title)
+	assert.True(t, got.Pinned)
+	assert.Equal(t, r.announcements[0].AuthorId, got.AuthorId)
+
+	err = r.ClassAnnouncementRepository.UpdateAnnouncement(context.Background(), &domain.ClassAnnouncement{AnnouncementId: xid.New().String(), Title: "foo"})
+	assert.Equal(t, domain.ErrClassAnnouncementNotExists, err)
+}
+
+func (r *Repository) testDeleteAnnouncement(t *testing.T) {
+	for _, announcement := range r.announcements {
+		err := r.ClassAnnouncementRepository.DeleteAnnouncement(context.Background(), announcement.AnnouncementId)
+		assert.Nil(t, err)
+
+		_, err = r.ClassAnnouncementRepository.GetAnnouncement(context.Background(), announcement.AnnouncementId)
+		assert.Equal(t, domain.ErrClassAnnouncementNotExists, err)
+	}
+}
@@ -0,0 +1,82 @@
+package classattachment
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type ClassAttachmentRepositoryMem struct {
+	mx sync.Mutex
+	m  map[string]*domain.ClassAttachment
+}
+
+func NewClassAttachmentRepositoryMem() *ClassAttachmentRepositoryMem {
+	return &ClassAttachmentRepositoryMem{
+		m: make(map[string]*domain.ClassAttachment),
+	}
+}
+
+func (repo *ClassAttachmentRepositoryMem) CreateAttachment(ctx context.Context, attachment *domain.ClassAttachment) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	attachment.AttachmentId = xid.New().String()
+	attachment.CreatedAt = time.Now().UTC()
+	a := *attachment
+	a.URL = ""
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, attachment.AttachmentId)
+	repo.m[attachment.AttachmentId] = &a
+	return nil
+}
+
+func (repo *ClassAttachmentRepositoryMem) GetAttachment(ctx context.Context, attachmentId string) (*domain.ClassAttachment, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	attachment, ok := repo.m[attachmentId]
+	if !ok {
+		return nil, domain.ErrClassAttachmentNotExists
+	}
+	a := *attachment
+	return &a, nil
+}
+
+func (repo *ClassAttachmentRepositoryMem) ListAttachments(ctx context.Context, taskId string) ([]*domain.ClassAttachment, error) {
+	return repo.list(func(a *domain.ClassAttachment) bool { return a.TaskId == taskId }), nil
+}
+
+func (repo *ClassAttachmentRepositoryMem) ListClassAttachments(ctx context.Context, classId string) ([]*domain.ClassAttachment, error) {
+	return repo.list(func(a *domain.ClassAttachment) bool { return a.ClassId == classId }), nil
+}
+
+func (repo *ClassAttachmentRepositoryMem) list(match func(*domain.ClassAttachment) bool) []*domain.ClassAttachment {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	attachments := make([]*domain.ClassAttachment, 0)
+	for _, attachment := range repo.m {
+		if match(attachment) {
+			a := *attachment
+			attachments = append(attachments, &a)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool {
+		if !attachments[i].CreatedAt.Equal(attachments[j].CreatedAt) {
+			return attachments[i].CreatedAt.Before(attachments[j].CreatedAt)
+		}
+		return attachments[i].AttachmentId < attachments[j].AttachmentId
+	})
+	return attachments
+}
+
+func (repo *ClassAttachmentRepositoryMem) DeleteAttachment(ctx context.Context, attachmentId string) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, attachmentId)
+	delete(repo.m, attachmentId)
+	return nil
+}
@@ -0,0 +1,112 @@
+package classattachment
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type ClassAttachmentRepositoryPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewClassAttachmentRepositoryPostgres(pool *pgxpool.Pool) *ClassAttachmentRepositoryPostgres {
+	return &ClassAttachmentRepositoryPostgres{pool}
+}
+
+func (repo *ClassAttachmentRepositoryPostgres) CreateAttachment(ctx context.Context, attachment *domain.ClassAttachment) error {
+	attachment.AttachmentId = xid.New().String()
+	row := database.Conn(ctx, repo.pool).QueryRow(
+		ctx,
+		`INSERT INTO class_attachment(attachment_id, class_id, task_id, author_id, name, content_type, size)
+		VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`,
+		attachment.AttachmentId,
+		attachment.ClassId,
+		attachment.TaskId,
+		attachment.AuthorId,
+		attachment.Name,
+		attachment.ContentType,
+		attachment.Size,
+	)
+	return row.Scan(&attachment.CreatedAt)
+}
+
+func (repo *ClassAttachmentRepositoryPostgres) GetAttachment(ctx context.Context, attachmentId string) (*domain.ClassAttachment, error) {
+	attachment := &domain.ClassAttachment{
+		AttachmentId: attachmentId,
+	}
+	row := database.Conn(ctx, repo.pool).QueryRow(
+		ctx,
+		"SELECT class_id, task_id, author_id, created_at, name, content_type, size FROM class_attachment WHERE attachment_id = $1",
+		attachmentId,
+	)
+	err := row.Scan(
+		&attachment.ClassId,
+		&attachment.TaskId,
+		&attachment.AuthorId,
+		&attachment.CreatedAt,
+		&attachment.Name,
+		&attachment.ContentType,
+		&attachment.Size,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrClassAttachmentNotExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (repo *ClassAttachmentRepositoryPostgres) ListAttachments(ctx context.Context, taskId string) ([]*domain.ClassAttachment, error) {
+	return repo.list(ctx, "task_id", taskId)
+}
+
+func (repo *ClassAttachmentRepositoryPostgres) ListClassAttachments(ctx context.Context, classId string) ([]*domain.ClassAttachment, error) {
+	return repo.list(ctx, "class_id", classId)
+}
+
+// list attachments where column equal to value, column must not come from user input
+func (repo *ClassAttachmentRepositoryPostgres) list(ctx context.Context, column, value string) ([]*domain.ClassAttachment, error) {
+	attachments := make([]*domain.ClassAttachment, 0)
+	rows, err := database.Conn(ctx, repo.pool).Query(
+		ctx,
+		"SELECT attachment_id, class_id, task_id, author_id, created_at, name, content_type, size FROM class_attachment WHERE "+column+" = $1 ORDER BY created_at, attachment_id",
+		value,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		attachment := &domain.ClassAttachment{}
+		if err := rows.Scan(
+			&attachment.AttachmentId,
+			&attachment.ClassId,
+			&attachment.TaskId,
+			&attachment.AuthorId,
+			&attachment.CreatedAt,
+			&attachment.Name,
+			&attachment.ContentType,
+			&attachment.Size,
+		); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+func (repo *ClassAttachmentRepositoryPostgres) DeleteAttachment(ctx context.Context, attachmentId string) error {
+	_, err := database.Conn(ctx, repo.pool).Exec(
+		ctx,
+		"DELETE FROM class_attachment WHERE attachment_id = $1",
+		attachmentId,
+	)
+	return err
+}
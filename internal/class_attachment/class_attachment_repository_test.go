@@ -0,0 +1,161 @@
+package classattachment_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"nory/domain"
+	"nory/internal/class"
+	. "nory/internal/class_attachment"
+	classtask "nory/internal/class_task"
+	"nory/internal/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassAttachmentRepository(t *testing.T) {
+	t.Parallel()
+	pool, err := pgxpool.New(context.Background(), os.Getenv("DATABASE_URL"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	repos := []Repository{
+		{
+			Name:                      "memory",
+			ClassAttachmentRepository: NewClassAttachmentRepositoryMem(),
+			ClassTaskRepository:       classtask.NewClassTaskRepositoryMem(),
+			ClassRepository:           class.NewClassRepositoryMem(),
+			UserRepository:            user.NewUserRepositoryMem(),
+		},
+		{
+			Name:                      "postgres",
+			ClassAttachmentRepository: NewClassAttachmentRepositoryPostgres(pool),
+			ClassTaskRepository:       classtask.NewClassTaskRepositoryPostgres(pool),
+			ClassRepository:           class.NewClassRepositoryPostgres(pool),
+			UserRepository:            user.NewUserRepositoryPostgres(pool),
+			Skip:                      os.Getenv("DATABASE_URL") == "",
+		},
+	}
+
+	for _, repo := range repos {
+		repo := repo
+		t.Run(repo.Name, func(t *testing.T) {
+			repo.t = t
+			if repo.Skip {
+				t.Skipf("skipping %s", repo.Name)
+			}
+			t.Parallel()
+			t.Run("CreateAttachment", repo.testCreateAttachment)
+			t.Run("GetAttachment", repo.testGetAttachment)
+			t.Run("ListAttachments", repo.testListAttachments)
+			t.Run("DeleteAttachment", repo.testDeleteAttachment)
+		})
+	}
+}
+
+type Repository struct {
+	Name                      string
+	ClassAttachmentRepository domain.ClassAttachmentRepository
+	ClassTaskRepository       domain.ClassTaskRepository
+	ClassRepository           domain.ClassRepository
+	UserRepository            domain.UserRepository
+	Skip                      bool
+
+	attachments []domain.ClassAttachment
+	tasks       []*domain.ClassTask
+	t           *testing.T
+}
+
+// getTasks create a class with two tasks
+func (r *Repository) getTasks() []*domain.ClassTask {
+	if r.tasks != nil {
+		return r.tasks
+	}
+
+	u := &domain.User{
+		UserId:   uuid.NewString(),
+		Email:    xid.New().String(),
+		Username: xid.New().String(),
+	}
+	err := r.UserRepository.CreateUser(context.Background(), u)
+	assert.Nil(r.t, err)
+
+	c := &domain.Class{
+		Name:    xid.New().String(),
+		OwnerId: u.UserId,
+	}
+	err = r.ClassRepository.CreateClass(context.Background(), c)
+	assert.Nil(r.t, err)
+
+	for i := 0; i < 2; i++ {
+		task := &domain.ClassTask{ClassId: c.ClassId, AuthorId: u.UserId, Name: "foo"}
+		err := r.ClassTaskRepository.CreateTask(context.Background(), task)
+		assert.Nil(r.t, err)
+		r.tasks = append(r.tasks, task)
+	}
+	return r.tasks
+}
+
+func (r *Repository) testCreateAttachment(t *testing.T) {
+	tasks := r.getTasks()
+	for _, attachment := range []domain.ClassAttachment{
+		{ClassId: tasks[0].ClassId, TaskId: tasks[0].TaskId, AuthorId: tasks[0].AuthorId, Name: "foo.pdf", ContentType: "application/pdf", Size: 10},
+		{ClassId: tasks[0].ClassId, TaskId: tasks[0].TaskId, AuthorId: tasks[0].AuthorId, Name: "bar.png", ContentType: "image/png", Size: 20},
+		{ClassId: tasks[1].ClassId, TaskId: tasks[1].TaskId, AuthorId: tasks[1].AuthorId, Name: "baz.txt", ContentType: "text/plain", Size: 30},
+	} {
+		attachment := attachment
+		err := r.ClassAttachmentRepository.CreateAttachment(context.Background(), &attachment)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", attachment.AttachmentId, "CreateAttachment should update (*ClassAttachment).AttachmentId")
+		assert.False(t, attachment.CreatedAt.IsZero(), "CreateAttachment should update (*ClassAttachment).CreatedAt")
+		r.attachments = append(r.attachments, attachment)
+	}
+}
+
+func (r *Repository) testGetAttachment(t *testing.T) {
+	for _, attachment := range r.attachments {
+		got, err := r.ClassAttachmentRepository.GetAttachment(context.Background(), attachment.AttachmentId)
+		assert.Nil(t, err)
+		assert.Equal(t, attachment.TaskId, got.TaskId)
+		assert.Equal(t, attachment.Name, got.Name)
+		assert.Equal(t, attachment.ContentType, got.ContentType)
+		assert.Equal(t, attachment.Size, got.Size)
+		assert.Equal(t, attachment.BlobKey(), got.BlobKey())
+	}
+
+	_, err := r.ClassAttachmentRepository.GetAttachment(context.Background(), xid.New().String())
+	assert.Equal(t, domain.ErrClassAttachmentNotExists, err)
+}
+
+func (r *Repository) testListAttachments(t *testing.T) {
+	tasks := r.getTasks()
+	attachments, err := r.ClassAttachmentRepository.ListAttachments(context.Background(), tasks[0].TaskId)
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(attachments)) {
+		assert.Equal(t, r.attachments[0].AttachmentId, attachments[0].AttachmentId)
+		assert.Equal(t, r.attachments[1].AttachmentId, attachments[1].AttachmentId)
+	}
+
+	attachments, err = r.ClassAttachmentRepository.ListClassAttachments(context.Background(), tasks[0].ClassId)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(attachments))
+
+	attachments, err = r.ClassAttachmentRepository.ListAttachments(context.Background(), xid.New().String())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(attachments))
+}
+
+func (r *Repository) testDeleteAttachment(t *testing.T) {
+	for _, attachment := range r.attachments {
+		err := r.ClassAttachmentRepository.DeleteAttachment(context.Background(), attachment.AttachmentId)
+		assert.Nil(t, err)
+
+		_, err = r.ClassAttachmentRepository.GetAttachment(context.Background(), attachment.AttachmentId)
+		assert.Equal(t, domain.ErrClassAttachmentNotExists, err)
+	}
+}
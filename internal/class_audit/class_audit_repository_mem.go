@@ -0,0 +1,61 @@
+package classaudit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type ClassAuditRepositoryMem struct {
+	mx      sync.Mutex
+	entries []*domain.ClassAuditEntry
+}
+
+func NewClassAuditRepositoryMem() *ClassAuditRepositoryMem {
+	return &ClassAuditRepositoryMem{}
+}
+
+func (repo *ClassAuditRepositoryMem) CreateEntry(ctx context.Context, entry *domain.ClassAuditEntry) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	entry.EntryId = xid.New().String()
+	entry.CreatedAt = time.Now().UTC()
+	e := *entry
+	repo.entries = append(repo.entries, &e)
+	database.OnRollback(ctx, func() {
+		repo.mx.Lock()
+		defer repo.mx.Unlock()
+		for i, entry := range repo.entries {
+			if entry == &e {
+				repo.entries = append(repo.entries[:i], repo.entries[i+1:]...)
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func (repo *ClassAuditRepositoryMem) ListEntries(ctx context.Context, classId string, filter domain.ClassAuditFilter, page domain.Pagination) ([]*domain.ClassAuditEntry, string, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	entries := make([]*domain.ClassAuditEntry, 0)
+	// entries are appended in order, iterate backward to get the newest first
+	for i := len(repo.entries) - 1; i >= 0; i-- {
+		entry := repo.entries[i]
+		if entry.ClassId != classId || !filter.Match(entry) {
+			continue
+		}
+		e := *entry
+		entries = append(entries, &e)
+	}
+	return domain.PaginateDesc(entries, page, entryKey)
+}
+
+func entryKey(entry *domain.ClassAuditEntry) []string {
+	return []string{entry.EntryId}
+}
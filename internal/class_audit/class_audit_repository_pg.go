@@ -0,0 +1,111 @@
+package classaudit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type ClassAuditRepositoryPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewClassAuditRepositoryPostgres(pool *pgxpool.Pool) *ClassAuditRepositoryPostgres {
+	return &ClassAuditRepositoryPostgres{pool}
+}
+
+func (repo *ClassAuditRepositoryPostgres) CreateEntry(ctx context.Context, entry *domain.ClassAuditEntry) error {
+	entry.EntryId = xid.New().String()
+	row := database.Conn(ctx, repo.pool).QueryRow(
+		ctx,
+		"INSERT INTO class_audit(entry_id, class_id, actor_id, action, target_id, before_state, after_state) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING created_at",
+		entry.EntryId,
+		entry.ClassId,
+		entry.ActorId,
+		entry.Action,
+		entry.TargetId,
+		jsonb(entry.Before),
+		jsonb(entry.After),
+	)
+	return row.Scan(&entry.CreatedAt)
+}
+
+func (repo *ClassAuditRepositoryPostgres) ListEntries(ctx context.Context, classId string, filter domain.ClassAuditFilter, page domain.Pagination) ([]*domain.ClassAuditEntry, string, error) {
+	after, err := page.Keys(1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "SELECT entry_id, actor_id, created_at, action, target_id, before_state, after_state FROM class_audit WHERE class_id = $1"
+	args := []any{classId}
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		query += fmt.Sprintf(" AND "+cond, len(args))
+	}
+	if filter.ActorId != "" {
+		where("actor_id = $%d", filter.ActorId)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at < $%d", filter.To)
+	}
+	if after != nil {
+		where("entry_id < $%d", after[0])
+	}
+	query += " ORDER BY entry_id DESC"
+	if page.Limit > 0 {
+		args = append(args, page.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := database.Conn(ctx, repo.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	entries := make([]*domain.ClassAuditEntry, 0)
+	for rows.Next() {
+		entry := &domain.ClassAuditEntry{
+			ClassId: classId,
+		}
+		var before, after []byte
+		if err := rows.Scan(
+			&entry.EntryId,
+			&entry.ActorId,
+			&entry.CreatedAt,
+			&entry.Action,
+			&entry.TargetId,
+			&before,
+			&after,
+		); err != nil {
+			return nil, "", err
+		}
+		entry.Before = before
+		entry.After = after
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	entries, next := domain.NextPage(entries, page, entryKey)
+	return entries, next, nil
+}
+
+// jsonb convert empty message to NULL
+func jsonb(msg []byte) any {
+	if len(msg) == 0 {
+		return nil
+	}
+	return string(msg)
+}
@@ -0,0 +1,166 @@
+package classaudit_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"nory/domain"
+	. "nory/internal/class_audit"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassAuditRepository(t *testing.T) {
+	t.Parallel()
+	pool, err := pgxpool.New(context.Background(), os.Getenv("DATABASE_URL"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	repos := []Repository{
+		{
+			Name:                 "memory",
+			ClassAuditRepository: NewClassAuditRepositoryMem(),
+		},
+		{
+			Name:                 "postgres",
+			ClassAuditRepository: NewClassAuditRepositoryPostgres(pool),
+			Skip:                 os.Getenv("DATABASE_URL") == "",
+		},
+	}
+
+	for _, repo := range repos {
+		repo := repo
+		t.Run(repo.Name, func(t *testing.T) {
+			if repo.Skip {
+				t.Skipf("skipping %s", repo.Name)
+			}
+			t.Parallel()
+			repo.classId = xid.New().String()
+			repo.actors = []string{uuid.NewString(), uuid.NewString()}
+			t.Run("CreateEntry", repo.testCreateEntry)
+			t.Run("ListEntries", repo.testListEntries)
+			t.Run("ListEntries pagination", repo.testListEntriesPagination)
+		})
+	}
+}
+
+type Repository struct {
+	Name                 string
+	ClassAuditRepository domain.ClassAuditRepository
+	Skip                 bool
+
+	classId string
+	actors  []string
+	entries []domain.ClassAuditEntry
+}
+
+func (r *Repository) testCreateEntry(t *testing.T) {
+	for i, action := range []string{
+		domain.AuditClassCreate,
+		domain.AuditTaskCreate,
+		domain.AuditTaskDelete,
+		domain.AuditTaskCreate,
+	} {
+		entry := domain.ClassAuditEntry{
+			ClassId:  r.classId,
+			ActorId:  r.actors[i%2],
+			Action:   action,
+			TargetId: xid.New().String(),
+		}
+		if action == domain.AuditTaskDelete {
+			entry.Before = json.RawMessage(`{"name":"foo"}`)
+		} else {
+			entry.After = json.RawMessage(`{"name":"foo"}`)
+		}
+		err := r.ClassAuditRepository.CreateEntry(context.Background(), &entry)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", entry.EntryId, "CreateEntry must update (ClassAuditEntry).EntryId")
+		assert.False(t, entry.CreatedAt.IsZero(), "CreateEntry must update (ClassAuditEntry).CreatedAt")
+		r.entries = append(r.entries, entry)
+	}
+
+	// entry from other class
+	err := r.ClassAuditRepository.CreateEntry(context.Background(), &domain.ClassAuditEntry{
+		ClassId:  xid.New().String(),
+		ActorId:  r.actors[0],
+		Action:   domain.AuditClassCreate,
+		TargetId: xid.New().String(),
+	})
+	assert.Nil(t, err)
+}
+
+func (r *Repository) testListEntries(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Filter domain.ClassAuditFilter
+		Len    int
+	}{
+		{"no filter", domain.ClassAuditFilter{}, 4},
+		{"actor", domain.ClassAuditFilter{ActorId: r.actors[0]}, 2},
+		{"action", domain.ClassAuditFilter{Action: domain.AuditTaskCreate}, 2},
+		{"actor and action", domain.ClassAuditFilter{ActorId: r.actors[1], Action: domain.AuditTaskCreate}, 2},
+		{"from", domain.ClassAuditFilter{From: time.Now().Add(time.Hour)}, 0},
+		{"to", domain.ClassAuditFilter{To: time.Now().Add(-time.Hour)}, 0},
+		{"range", domain.ClassAuditFilter{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour)}, 4},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			entries, next, err := r.ClassAuditRepository.ListEntries(context.Background(), r.classId, tc.Filter, domain.Pagination{})
+			assert.Nil(t, err)
+			assert.Equal(t, "", next)
+			assert.Equal(t, tc.Len, len(entries))
+			for _, entry := range entries {
+				assert.Equal(t, r.classId, entry.ClassId)
+			}
+		})
+	}
+
+	entries, _, err := r.ClassAuditRepository.ListEntries(context.Background(), r.classId, domain.ClassAuditFilter{}, domain.Pagination{})
+	assert.Nil(t, err)
+	if assert.Equal(t, len(r.entries), len(entries)) {
+		for i, entry := range entries {
+			expected := r.entries[len(r.entries)-1-i]
+			assert.Equal(t, expected.EntryId, entry.EntryId, "entries should be ordered from the newest")
+			assert.Equal(t, expected.Action, entry.Action)
+			assert.JSONEq(t, string(orNull(expected.Before)), string(orNull(entry.Before)))
+			assert.JSONEq(t, string(orNull(expected.After)), string(orNull(entry.After)))
+		}
+	}
+}
+
+func (r *Repository) testListEntriesPagination(t *testing.T) {
+	var paged []*domain.ClassAuditEntry
+	page := domain.Pagination{Limit: 3}
+	for i := 0; i < 3; i++ {
+		entries, next, err := r.ClassAuditRepository.ListEntries(context.Background(), r.classId, domain.ClassAuditFilter{}, page)
+		assert.Nil(t, err)
+		assert.LessOrEqual(t, len(entries), page.Limit)
+		paged = append(paged, entries...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+	if assert.Equal(t, len(r.entries), len(paged)) {
+		assert.Equal(t, r.entries[0].EntryId, paged[len(paged)-1].EntryId)
+	}
+
+	_, _, err := r.ClassAuditRepository.ListEntries(context.Background(), r.classId, domain.ClassAuditFilter{}, domain.Pagination{Cursor: "foo"})
+	assert.Equal(t, domain.ErrInvalidCursor, err)
+}
+
+func orNull(msg json.RawMessage) json.RawMessage {
+	if len(msg) == 0 {
+		return json.RawMessage("null")
+	}
+	return msg
+}
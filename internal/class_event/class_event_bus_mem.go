@@ -0,0 +1,141 @@
+package classevent
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nory/domain"
+)
+
+// subscriberBuffer is how many events can be pending before slow subscriber is dropped
+const subscriberBuffer = 64
+
+// bufferIdle is how long the replay buffer of a class without new event is kept
+const bufferIdle = time.Hour
+
+// ClassEventBusMem deliver events inside a single process, each class keep last replaySize events for resume.
+type ClassEventBusMem struct {
+	mx          sync.Mutex
+	seq         uint64
+	replaySize  int
+	sweptAt     time.Time
+	buffers     map[string][]*domain.ClassEvent
+	subscribers map[string]map[chan *domain.ClassEvent]struct{}
+}
+
+func NewClassEventBusMem(replaySize int) *ClassEventBusMem {
+	return &ClassEventBusMem{
+		replaySize:  replaySize,
+		buffers:     make(map[string][]*domain.ClassEvent),
+		subscribers: make(map[string]map[chan *domain.ClassEvent]struct{}),
+	}
+}
+
+func (bus *ClassEventBusMem) Publish(ctx context.Context, event *domain.ClassEvent) error {
+	bus.mx.Lock()
+	defer bus.mx.Unlock()
+
+	bus.seq++
+	event.EventId = strconv.FormatUint(bus.seq, 10)
+	event.CreatedAt = time.Now().UTC()
+	e := *event
+	// the id may point into a buffer reused by the caller, e.g. fiber route params
+	e.ClassId = strings.Clone(e.ClassId)
+
+	bus.sweep(e.CreatedAt)
+	if e.Type == domain.EventClassDeleted {
+		// subscribers receive the event then their channels are closed, nothing is left to replay
+		delete(bus.buffers, e.ClassId)
+		for ch := range bus.subscribers[e.ClassId] {
+			select {
+			case ch <- &e:
+			default:
+			}
+			bus.unsubscribe(e.ClassId, ch)
+		}
+		return nil
+	}
+
+	if bus.replaySize > 0 {
+		buffer := append(bus.buffers[e.ClassId], &e)
+		if len(buffer) > bus.replaySize {
+			buffer = buffer[len(buffer)-bus.replaySize:]
+		}
+		bus.buffers[e.ClassId] = buffer
+	}
+
+	for ch := range bus.subscribers[e.ClassId] {
+		select {
+		case ch <- &e:
+		default:
+			// drop slow subscriber instead of blocking publisher
+			bus.unsubscribe(e.ClassId, ch)
+		}
+	}
+	return nil
+}
+
+func (bus *ClassEventBusMem) Subscribe(ctx context.Context, classId, lastEventId string) (<-chan *domain.ClassEvent, error) {
+	bus.mx.Lock()
+	defer bus.mx.Unlock()
+	classId = strings.Clone(classId)
+
+	var replay []*domain.ClassEvent
+	if lastEventId != "" {
+		// unknown id is treated as very old id, whole buffer is replayed
+		last, _ := strconv.ParseUint(lastEventId, 10, 64)
+		for _, e := range bus.buffers[classId] {
+			if id, _ := strconv.ParseUint(e.EventId, 10, 64); id > last {
+				replay = append(replay, e)
+			}
+		}
+	}
+
+	ch := make(chan *domain.ClassEvent, subscriberBuffer+len(replay))
+	for _, e := range replay {
+		ch <- e
+	}
+	if bus.subscribers[classId] == nil {
+		bus.subscribers[classId] = make(map[chan *domain.ClassEvent]struct{})
+	}
+	bus.subscribers[classId][ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		bus.mx.Lock()
+		defer bus.mx.Unlock()
+		bus.unsubscribe(classId, ch)
+	}()
+
+	return ch, nil
+}
+
+// unsubscribe must be called while holding bus.mx
+func (bus *ClassEventBusMem) unsubscribe(classId string, ch chan *domain.ClassEvent) {
+	subscribers := bus.subscribers[classId]
+	if _, ok := subscribers[ch]; !ok {
+		return
+	}
+	delete(subscribers, ch)
+	if len(subscribers) == 0 {
+		delete(bus.subscribers, classId)
+	}
+	close(ch)
+}
+
+// sweep drop replay buffers of classes without event for bufferIdle, at most once per bufferIdle.
+// It must be called while holding bus.mx.
+func (bus *ClassEventBusMem) sweep(now time.Time) {
+	if now.Sub(bus.sweptAt) < bufferIdle {
+		return
+	}
+	bus.sweptAt = now
+	for classId, buffer := range bus.buffers {
+		if now.Sub(buffer[len(buffer)-1].CreatedAt) >= bufferIdle {
+			delete(bus.buffers, classId)
+		}
+	}
+}
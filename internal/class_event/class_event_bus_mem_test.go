@@ -0,0 +1,165 @@
+package classevent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/stretchr/testify/assert"
+
+	"nory/domain"
+	. "nory/internal/class_event"
+)
+
+func receive(t *testing.T, ch <-chan *domain.ClassEvent) *domain.ClassEvent {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event")
+		return nil
+	}
+}
+
+func TestClassEventBusMem(t *testing.T) {
+	t.Parallel()
+
+	t.Run("publish and subscribe", func(t *testing.T) {
+		bus := NewClassEventBusMem(10)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		foo, err := bus.Subscribe(ctx, "foo", "")
+		assert.Nil(t, err)
+		bar, err := bus.Subscribe(ctx, "bar", "")
+		assert.Nil(t, err)
+
+		event := &domain.ClassEvent{ClassId: "foo", Type: domain.EventTaskCreated}
+		err = bus.Publish(context.Background(), event)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", event.EventId, "Publish must update (ClassEvent).EventId")
+		assert.False(t, event.CreatedAt.IsZero(), "Publish must update (ClassEvent).CreatedAt")
+
+		e := receive(t, foo)
+		assert.Equal(t, event.EventId, e.EventId)
+		assert.Equal(t, domain.EventTaskCreated, e.Type)
+
+		select {
+		case e := <-bar:
+			t.Errorf("unexpected event from other class %#+v", e)
+		default:
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		bus := NewClassEventBusMem(3)
+		var ids []string
+		for i := 0; i < 5; i++ {
+			event := &domain.ClassEvent{ClassId: "foo", Type: domain.EventTaskCreated}
+			assert.Nil(t, bus.Publish(context.Background(), event))
+			ids = append(ids, event.EventId)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ch, err := bus.Subscribe(ctx, "foo", ids[2])
+		assert.Nil(t, err)
+		assert.Equal(t, ids[3], receive(t, ch).EventId)
+		assert.Equal(t, ids[4], receive(t, ch).EventId)
+
+		// ids[0] is no longer buffered, the whole buffer is replayed
+		ch, err = bus.Subscribe(ctx, "foo", ids[0])
+		assert.Nil(t, err)
+		assert.Equal(t, ids[2], receive(t, ch).EventId)
+		assert.Equal(t, ids[3], receive(t, ch).EventId)
+		assert.Equal(t, ids[4], receive(t, ch).EventId)
+
+		event := &domain.ClassEvent{ClassId: "foo", Type: domain.EventTaskDeleted}
+		assert.Nil(t, bus.Publish(context.Background(), event))
+		assert.Equal(t, event.EventId, receive(t, ch).EventId, "live event should follow replayed events")
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		bus := NewClassEventBusMem(10)
+		ctx, cancel := context.WithCancel(context.Background())
+		ch, err := bus.Subscribe(ctx, "foo", "")
+		assert.Nil(t, err)
+		cancel()
+
+		select {
+		case _, ok := <-ch:
+			assert.False(t, ok, "channel should be closed")
+		case <-time.After(time.Second):
+			t.Fatal("channel is not closed after ctx done")
+		}
+		assert.Nil(t, bus.Publish(context.Background(), &domain.ClassEvent{ClassId: "foo"}))
+	})
+
+	t.Run("class id from reused buffer", func(t *testing.T) {
+		bus := NewClassEventBusMem(10)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// fiber route params point into a buffer that is reused by the next request
+		b := []byte("foo")
+		ch, err := bus.Subscribe(ctx, utils.UnsafeString(b), "")
+		assert.Nil(t, err)
+		assert.Nil(t, bus.Publish(context.Background(), &domain.ClassEvent{ClassId: utils.UnsafeString(b)}))
+		copy(b, "bar")
+
+		assert.Equal(t, "foo", receive(t, ch).ClassId)
+		assert.Nil(t, bus.Publish(context.Background(), &domain.ClassEvent{ClassId: "foo"}))
+		assert.Equal(t, "foo", receive(t, ch).ClassId)
+
+		replay, err := bus.Subscribe(ctx, "foo", "0")
+		assert.Nil(t, err)
+		assert.Equal(t, "foo", receive(t, replay).ClassId)
+	})
+
+	t.Run("class deleted", func(t *testing.T) {
+		bus := NewClassEventBusMem(10)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		assert.Nil(t, bus.Publish(context.Background(), &domain.ClassEvent{ClassId: "foo", Type: domain.EventTaskCreated}))
+		ch, err := bus.Subscribe(ctx, "foo", "")
+		assert.Nil(t, err)
+		assert.Nil(t, bus.Publish(context.Background(), &domain.ClassEvent{ClassId: "foo", Type: domain.EventClassDeleted}))
+
+		assert.Equal(t, domain.EventClassDeleted, receive(t, ch).Type)
+		select {
+		case _, ok := <-ch:
+			assert.False(t, ok, "channel should be closed")
+		case <-time.After(time.Second):
+			t.Fatal("channel is not closed after class deleted")
+		}
+
+		ch, err = bus.Subscribe(ctx, "foo", "0")
+		assert.Nil(t, err)
+		select {
+		case e := <-ch:
+			t.Errorf("buffer of deleted class should be dropped, got %#+v", e)
+		default:
+		}
+	})
+
+	t.Run("slow subscriber", func(t *testing.T) {
+		bus := NewClassEventBusMem(0)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch, err := bus.Subscribe(ctx, "foo", "")
+		assert.Nil(t, err)
+
+		for i := 0; i < 1000; i++ {
+			assert.Nil(t, bus.Publish(context.Background(), &domain.ClassEvent{ClassId: "foo"}))
+		}
+		n := 0
+		for range ch {
+			n++
+		}
+		assert.Less(t, n, 1000, "slow subscriber should be dropped")
+	})
+}
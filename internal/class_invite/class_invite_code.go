@@ -0,0 +1,18 @@
+package classinvite
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+)
+
+var codeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateCode create random code that is hard to guess, unlike xid
+func generateCode() string {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return strings.ToLower(codeEncoding.EncodeToString(b))
+}
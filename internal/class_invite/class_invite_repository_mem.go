@@ -0,0 +1,83 @@
+package classinvite
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type ClassInviteRepositoryMem struct {
+	mx sync.Mutex
+	m  map[string]*domain.ClassInvite
+}
+
+func NewClassInviteRepositoryMem() *ClassInviteRepositoryMem {
+	return &ClassInviteRepositoryMem{
+		m: make(map[string]*domain.ClassInvite),
+	}
+}
+
+func (repo *ClassInviteRepositoryMem) CreateInvite(ctx context.Context, invite *domain.ClassInvite) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	invite.Code = generateCode()
+	invite.CreatedAt = time.Now().UTC()
+	i := *invite
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, invite.Code)
+	repo.m[invite.Code] = &i
+	return nil
+}
+
+func (repo *ClassInviteRepositoryMem) GetInvite(ctx context.Context, code string) (*domain.ClassInvite, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	invite, ok := repo.m[code]
+	if !ok {
+		return nil, domain.ErrClassInviteNotExists
+	}
+	i := *invite
+	return &i, nil
+}
+
+func (repo *ClassInviteRepositoryMem) ListInvites(ctx context.Context, classId string) ([]*domain.ClassInvite, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	invites := make([]*domain.ClassInvite, 0)
+	for _, invite := range repo.m {
+		if invite.ClassId == classId {
+			i := *invite
+			invites = append(invites, &i)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+	})
+	return invites, nil
+}
+
+func (repo *ClassInviteRepositoryMem) UseInvite(ctx context.Context, code string) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	invite, ok := repo.m[code]
+	if !ok {
+		return domain.ErrClassInviteNotExists
+	}
+	if invite.Exhausted() {
+		return domain.ErrClassInviteExhausted
+	}
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, code)
+	invite.Uses++
+	return nil
+}
+
+func (repo *ClassInviteRepositoryMem) DeleteInvite(ctx context.Context, code string) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, code)
+	delete(repo.m, code)
+	return nil
+}
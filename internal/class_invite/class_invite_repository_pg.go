@@ -0,0 +1,121 @@
+package classinvite
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type ClassInviteRepositoryPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewClassInviteRepositoryPostgres(pool *pgxpool.Pool) *ClassInviteRepositoryPostgres {
+	return &ClassInviteRepositoryPostgres{pool}
+}
+
+func (repo *ClassInviteRepositoryPostgres) CreateInvite(ctx context.Context, invite *domain.ClassInvite) error {
+	invite.Code = generateCode()
+	_, err := database.Conn(ctx, repo.pool).Exec(
+		ctx,
+		"INSERT INTO class_invite(code, class_id, author_id, level, expires_at, max_uses) VALUES($1, $2, $3, $4, $5, $6)",
+		invite.Code,
+		invite.ClassId,
+		invite.AuthorId,
+		invite.Level,
+		invite.ExpiresAt,
+		invite.MaxUses,
+	)
+	return err
+}
+
+func (repo *ClassInviteRepositoryPostgres) GetInvite(ctx context.Context, code string) (*domain.ClassInvite, error) {
+	invite := &domain.ClassInvite{
+		Code: code,
+	}
+	row := database.Conn(ctx, repo.pool).QueryRow(
+		ctx,
+		"SELECT class_id, author_id, created_at, level, expires_at, max_uses, uses FROM class_invite WHERE code = $1",
+		code,
+	)
+	err := row.Scan(
+		&invite.ClassId,
+		&invite.AuthorId,
+		&invite.CreatedAt,
+		&invite.Level,
+		&invite.ExpiresAt,
+		&invite.MaxUses,
+		&invite.Uses,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrClassInviteNotExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+func (repo *ClassInviteRepositoryPostgres) ListInvites(ctx context.Context, classId string) ([]*domain.ClassInvite, error) {
+	invites := make([]*domain.ClassInvite, 0)
+	rows, err := database.Conn(ctx, repo.pool).Query(
+		ctx,
+		"SELECT code, author_id, created_at, level, expires_at, max_uses, uses FROM class_invite WHERE class_id = $1 ORDER BY created_at",
+		classId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		invite := &domain.ClassInvite{
+			ClassId: classId,
+		}
+		err := rows.Scan(
+			&invite.Code,
+			&invite.AuthorId,
+			&invite.CreatedAt,
+			&invite.Level,
+			&invite.ExpiresAt,
+			&invite.MaxUses,
+			&invite.Uses,
+		)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, nil
+}
+
+func (repo *ClassInviteRepositoryPostgres) UseInvite(ctx context.Context, code string) error {
+	tag, err := database.Conn(ctx, repo.pool).Exec(
+		ctx,
+		"UPDATE class_invite SET uses = uses + 1 WHERE code = $1 AND (max_uses = 0 OR uses < max_uses)",
+		code,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+	// nothing updated, either the invite does not exists or it is exhausted
+	if _, err := repo.GetInvite(ctx, code); err != nil {
+		return err
+	}
+	return domain.ErrClassInviteExhausted
+}
+
+func (repo *ClassInviteRepositoryPostgres) DeleteInvite(ctx context.Context, code string) error {
+	_, err := database.Conn(ctx, repo.pool).Exec(
+		ctx,
+		"DELETE FROM class_invite WHERE code = $1",
+		code,
+	)
+	return err
+}
@@ -0,0 +1,162 @@
+package classinvite_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"nory/domain"
+	"nory/internal/class"
+	. "nory/internal/class_invite"
+	"nory/internal/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassInviteRepository(t *testing.T) {
+	t.Parallel()
+	pool, err := pgxpool.New(context.Background(), os.Getenv("DATABASE_URL"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	repos := []Repository{
+		{
+			Name:                  "memory",
+			ClassInviteRepository: NewClassInviteRepositoryMem(),
+			ClassRepository:       class.NewClassRepositoryMem(),
+			UserRepository:        user.NewUserRepositoryMem(),
+		},
+		{
+			Name:                  "postgres",
+			ClassInviteRepository: NewClassInviteRepositoryPostgres(pool),
+			ClassRepository:       class.NewClassRepositoryPostgres(pool),
+			UserRepository:        user.NewUserRepositoryPostgres(pool),
+			Skip:                  os.Getenv("DATABASE_URL") == "",
+		},
+	}
+
+	for _, repo := range repos {
+		repo := repo
+		t.Run(repo.Name, func(t *testing.T) {
+			repo.t = t
+			if repo.Skip {
+				t.Skipf("skipping %s", repo.Name)
+			}
+			t.Parallel()
+			t.Run("CreateInvite", repo.testCreateInvite)
+			t.Run("GetInvite", repo.testGetInvite)
+			t.Run("ListInvites", repo.testListInvites)
+			t.Run("UseInvite", repo.testUseInvite)
+			t.Run("DeleteInvite", repo.testDeleteInvite)
+		})
+	}
+}
+
+type Repository struct {
+	Name                  string
+	ClassInviteRepository domain.ClassInviteRepository
+	ClassRepository       domain.ClassRepository
+	UserRepository        domain.UserRepository
+	Skip                  bool
+
+	invites []domain.ClassInvite
+	class   *domain.Class
+	t       *testing.T
+}
+
+func (r *Repository) getClass() *domain.Class {
+	if r.class != nil {
+		return r.class
+	}
+
+	u := &domain.User{
+		UserId:   uuid.NewString(),
+		Email:    xid.New().String(),
+		Username: xid.New().String(),
+	}
+	err := r.UserRepository.CreateUser(context.Background(), u)
+	assert.Nil(r.t, err)
+
+	r.class = &domain.Class{
+		Name:    xid.New().String(),
+		OwnerId: u.UserId,
+	}
+	err = r.ClassRepository.CreateClass(context.Background(), r.class)
+	assert.Nil(r.t, err)
+	return r.class
+}
+
+func (r *Repository) testCreateInvite(t *testing.T) {
+	class := r.getClass()
+	expiresAt := time.Now().UTC().Add(time.Hour).Round(time.Second)
+	for _, invite := range []domain.ClassInvite{
+		{ClassId: class.ClassId, AuthorId: class.OwnerId, Level: "member"},
+		{ClassId: class.ClassId, AuthorId: class.OwnerId, Level: "admin", MaxUses: 1},
+		{ClassId: class.ClassId, AuthorId: class.OwnerId, Level: "member", ExpiresAt: &expiresAt},
+	} {
+		invite := invite
+		err := r.ClassInviteRepository.CreateInvite(context.Background(), &invite)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", invite.Code, "CreateInvite should update (*ClassInvite).Code to generated code")
+		r.invites = append(r.invites, invite)
+	}
+}
+
+func (r *Repository) testGetInvite(t *testing.T) {
+	for _, invite := range r.invites {
+		got, err := r.ClassInviteRepository.GetInvite(context.Background(), invite.Code)
+		assert.Nil(t, err)
+		assert.Equal(t, invite.ClassId, got.ClassId)
+		assert.Equal(t, invite.Level, got.Level)
+		assert.Equal(t, invite.MaxUses, got.MaxUses)
+		assert.Equal(t, invite.ExpiresAt != nil, got.ExpiresAt != nil)
+	}
+
+	_, err := r.ClassInviteRepository.GetInvite(context.Background(), xid.New().String())
+	assert.Equal(t, domain.ErrClassInviteNotExists, err)
+}
+
+func (r *Repository) testListInvites(t *testing.T) {
+	invites, err := r.ClassInviteRepository.ListInvites(context.Background(), r.getClass().ClassId)
+	assert.Nil(t, err)
+	assert.Equal(t, len(r.invites), len(invites))
+
+	invites, err = r.ClassInviteRepository.ListInvites(context.Background(), xid.New().String())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(invites))
+}
+
+func (r *Repository) testUseInvite(t *testing.T) {
+	limited := r.invites[1]
+	err := r.ClassInviteRepository.UseInvite(context.Background(), limited.Code)
+	assert.Nil(t, err)
+	err = r.ClassInviteRepository.UseInvite(context.Background(), limited.Code)
+	assert.Equal(t, domain.ErrClassInviteExhausted, err)
+
+	unlimited := r.invites[0]
+	for i := 0; i < 3; i++ {
+		err := r.ClassInviteRepository.UseInvite(context.Background(), unlimited.Code)
+		assert.Nil(t, err)
+	}
+	invite, err := r.ClassInviteRepository.GetInvite(context.Background(), unlimited.Code)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, invite.Uses)
+
+	err = r.ClassInviteRepository.UseInvite(context.Background(), xid.New().String())
+	assert.Equal(t, domain.ErrClassInviteNotExists, err)
+}
+
+func (r *Repository) testDeleteInvite(t *testing.T) {
+	for _, invite := range r.invites {
+		err := r.ClassInviteRepository.DeleteInvite(context.Background(), invite.Code)
+		assert.Nil(t, err)
+
+		_, err = r.ClassInviteRepository.GetInvite(context.Background(), invite.Code)
+		assert.Equal(t, domain.ErrClassInviteNotExists, err)
+	}
+}
@@ -0,0 +1,95 @@
+package classjoinrequest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type ClassJoinRequestRepositoryMem struct {
+	mx sync.Mutex
+	m  map[string]*domain.ClassJoinRequest
+}
+
+func NewClassJoinRequestRepositoryMem() *ClassJoinRequestRepositoryMem {
+	return &ClassJoinRequestRepositoryMem{
+		m: make(map[string]*domain.ClassJoinRequest),
+	}
+}
+
+func mapKey(classId, userId string) string {
+	return classId + "/" + userId
+}
+
+func (repo *ClassJoinRequestRepositoryMem) CreateJoinRequest(ctx context.Context, request *domain.ClassJoinRequest) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	key := mapKey(request.ClassId, request.UserId)
+	if _, ok := repo.m[key]; ok {
+		return domain.ErrClassJoinRequestAlreadyExists
+	}
+	request.CreatedAt = time.Now().UTC()
+	r := *request
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, key)
+	repo.m[key] = &r
+	return nil
+}
+
+func (repo *ClassJoinRequestRepositoryMem) GetJoinRequest(ctx context.Context, classId, userId string) (*domain.ClassJoinRequest, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	request, ok := repo.m[mapKey(classId, userId)]
+	if !ok {
+		return nil, domain.ErrClassJoinRequestNotExists
+	}
+	r := *request
+	return &r, nil
+}
+
+func (repo *ClassJoinRequestRepositoryMem) ListJoinRequests(ctx context.Context, classId string, page domain.Pagination) ([]*domain.ClassJoinRequest, string, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	requests := make([]*domain.ClassJoinRequest, 0)
+	for _, request := range repo.m {
+		if request.ClassId == classId {
+			r := *request
+			requests = append(requests, &r)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if !requests[i].CreatedAt.Equal(requests[j].CreatedAt) {
+			return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+		}
+		return requests[i].UserId < requests[j].UserId
+	})
+	return domain.Paginate(requests, page, joinRequestKey)
+}
+
+func joinRequestKey(r *domain.ClassJoinRequest) []string {
+	return []string{domain.CursorTime(r.CreatedAt), r.UserId}
+}
+
+func (repo *ClassJoinRequestRepositoryMem) DeleteJoinRequest(ctx context.Context, classId, userId string) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	key := mapKey(classId, userId)
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, key)
+	delete(repo.m, key)
+	return nil
+}
+
+func (repo *ClassJoinRequestRepositoryMem) DeleteExpiredJoinRequests(ctx context.Context, classId string, now time.Time) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	for key, request := range repo.m {
+		if request.ClassId == classId && request.Expired(now) {
+			database.RestoreOnRollback(ctx, &repo.mx, repo.m, key)
+			delete(repo.m, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package classjoinrequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type ClassJoinRequestRepositoryPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewClassJoinRequestRepositoryPostgres(pool *pgxpool.Pool) *ClassJoinRequestRepositoryPostgres {
+	return &ClassJoinRequestRepositoryPostgres{pool}
+}
+
+func (repo *ClassJoinRequestRepositoryPostgres) CreateJoinRequest(ctx context.Context, request *domain.ClassJoinRequest) error {
+	row := database.Conn(ctx, repo.pool).QueryRow(
+		ctx,
+		`INSERT INTO class_join_request(class_id, user_id, expires_at, message)
+		VALUES($1, $2, $3, $4) RETURNING created_at`,
+		request.ClassId,
+		request.UserId,
+		request.ExpiresAt.UTC(),
+		request.Message,
+	)
+	err := row.Scan(&request.CreatedAt)
+	if pgerr, ok := err.(*pgconn.PgError); ok && pgerr.Code == "23505" {
+		return domain.ErrClassJoinRequestAlreadyExists
+	}
+	return err
+}
+
+// joinRequestColumns is the columns read by scanJoinRequest
+const joinRequestColumns = "class_id, user_id, created_at, expires_at, message"
+
+func scanJoinRequest(row pgx.Row) (*domain.ClassJoinRequest, error) {
+	request := &domain.ClassJoinRequest{}
+	err := row.Scan(
+		&request.ClassId,
+		&request.UserId,
+		&request.CreatedAt,
+		&request.ExpiresAt,
+		&request.Message,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (repo *ClassJoinRequestRepositoryPostgres) GetJoinRequest(ctx context.Context, classId, userId string) (*domain.ClassJoinRequest, error) {
+	row := database.Conn(ctx, repo.pool).QueryRow(
+		ctx,
+		"SELECT "+joinRequestColumns+" FROM class_join_request WHERE class_id = $1 AND user_id = $2",
+		classId,
+		userId,
+	)
+	request, err := scanJoinRequest(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrClassJoinRequestNotExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (repo *ClassJoinRequestRepositoryPostgres) ListJoinRequests(ctx context.Context, classId string, page domain.Pagination) ([]*domain.ClassJoinRequest, string, error) {
+	after, err := page.Keys(2)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "SELECT " + joinRequestColumns + " FROM class_join_request WHERE class_id = $1"
+	args := []any{classId}
+	if after != nil {
+		createdAt, err := domain.ParseCursorTime(after[0])
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, createdAt, after[1])
+		query += " AND (created_at, user_id) > ($2, $3)"
+	}
+	query += " ORDER BY created_at, user_id"
+	if page.Limit > 0 {
+		args = append(args, page.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	requests := make([]*domain.ClassJoinRequest, 0)
+	rows, err := database.Conn(ctx, repo.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	for rows.Next() {
+		request, err := scanJoinRequest(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		requests = append(requests, request)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	requests, next := domain.NextPage(requests, page, joinRequestKey)
+	return requests, next, nil
+}
+
+func (repo *ClassJoinRequestRepositoryPostgres) DeleteJoinRequest(ctx context.Context, classId, userId string) error {
+	_, err := database.Conn(ctx, repo.pool).Exec(
+		ctx,
+		"DELETE FROM class_join_request WHERE class_id = $1 AND user_id = $2",
+		classId,
+		userId,
+	)
+	return err
+}
+
+func (repo *ClassJoinRequestRepositoryPostgres) DeleteExpiredJoinRequests(ctx context.Context, classId string, now time.Time) error {
+	_, err := database.Conn(ctx, repo.pool).Exec(
+		ctx,
+		"DELETE FROM class_join_request WHERE class_id = $1 AND expires_at <= $2",
+		classId,
+		now.UTC(),
+	)
+	return err
+}
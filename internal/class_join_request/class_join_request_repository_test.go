@@ -0,0 +1,183 @@
+package classjoinrequest_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"nory/domain"
+	"nory/internal/class"
+	. "nory/internal/class_join_request"
+	"nory/internal/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassJoinRequestRepository(t *testing.T) {
+	t.Parallel()
+	pool, err := pgxpool.New(context.Background(), os.Getenv("DATABASE_URL"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	repos := []Repository{
+		{
+			Name:                       "memory",
+			ClassJoinRequestRepository: NewClassJoinRequestRepositoryMem(),
+			ClassRepository:            class.NewClassRepositoryMem(),
+			UserRepository:             user.NewUserRepositoryMem(),
+		},
+		{
+			Name:                       "postgres",
+			ClassJoinRequestRepository: NewClassJoinRequestRepositoryPostgres(pool),
+			ClassRepository:            class.NewClassRepositoryPostgres(pool),
+			UserRepository:             user.NewUserRepositoryPostgres(pool),
+			Skip:                       os.Getenv("DATABASE_URL") == "",
+		},
+	}
+
+	for _, repo := range repos {
+		repo := repo
+		t.Run(repo.Name, func(t *testing.T) {
+			repo.t = t
+			if repo.Skip {
+				t.Skipf("skipping %s", repo.Name)
+			}
+			t.Parallel()
+			t.Run("CreateJoinRequest", repo.testCreateJoinRequest)
+			t.Run("GetJoinRequest", repo.testGetJoinRequest)
+			t.Run("ListJoinRequests", repo.testListJoinRequests)
+			t.Run("DeleteExpiredJoinRequests", repo.testDeleteExpiredJoinRequests)
+			t.Run("DeleteJoinRequest", repo.testDeleteJoinRequest)
+		})
+	}
+}
+
+type Repository struct {
+	Name                       string
+	ClassJoinRequestRepository domain.ClassJoinRequestRepository
+	ClassRepository            domain.ClassRepository
+	UserRepository             domain.UserRepository
+	Skip                       bool
+
+	requests []domain.ClassJoinRequest
+	class    *domain.Class
+	t        *testing.T
+}
+
+func (r *Repository) getUser() string {
+	u := &domain.User{
+		UserId:   uuid.NewString(),
+		Email:    xid.New().String(),
+		Username: xid.New().String(),
+	}
+	err := r.UserRepository.CreateUser(context.Background(), u)
+	assert.Nil(r.t, err)
+	return u.UserId
+}
+
+func (r *Repository) getClass() *domain.Class {
+	if r.class != nil {
+		return r.class
+	}
+	r.class = &domain.Class{
+		Name:    xid.New().String(),
+		OwnerId: r.getUser(),
+	}
+	err := r.ClassRepository.CreateClass(context.Background(), r.class)
+	assert.Nil(r.t, err)
+	return r.class
+}
+
+func (r *Repository) testCreateJoinRequest(t *testing.T) {
+	class := r.getClass()
+	now := time.Now().UTC().Truncate(time.Second)
+	// the second request expire first
+	for i, ttl := range []time.Duration{2 * time.Hour, time.Hour, 3 * time.Hour} {
+		request := domain.ClassJoinRequest{
+			ClassId:   class.ClassId,
+			UserId:    r.getUser(),
+			ExpiresAt: now.Add(ttl),
+			Message:   xid.New().String(),
+		}
+		err := r.ClassJoinRequestRepository.CreateJoinRequest(context.Background(), &request)
+		assert.Nil(t, err, i)
+		assert.False(t, request.CreatedAt.IsZero(), "CreateJoinRequest should update (*ClassJoinRequest).CreatedAt")
+		r.requests = append(r.requests, request)
+	}
+
+	dup := r.requests[0]
+	err := r.ClassJoinRequestRepository.CreateJoinRequest(context.Background(), &dup)
+	assert.Equal(t, domain.ErrClassJoinRequestAlreadyExists, err)
+}
+
+func (r *Repository) testGetJoinRequest(t *testing.T) {
+	for _, request := range r.requests {
+		got, err := r.ClassJoinRequestRepository.GetJoinRequest(context.Background(), request.ClassId, request.UserId)
+		assert.Nil(t, err)
+		assert.Equal(t, request.Message, got.Message)
+		assert.True(t, request.ExpiresAt.Equal(got.ExpiresAt))
+	}
+
+	_, err := r.ClassJoinRequestRepository.GetJoinRequest(context.Background(), r.getClass().ClassId, uuid.NewString())
+	assert.Equal(t, domain.ErrClassJoinRequestNotExists, err)
+}
+
+func (r *Repository) testListJoinRequests(t *testing.T) {
+	classId := r.getClass().ClassId
+	requests, next, err := r.ClassJoinRequestRepository.ListJoinRequests(context.Background(), classId, domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", next)
+	if assert.Equal(t, len(r.requests), len(requests)) {
+		for i, request := range r.requests {
+			assert.Equal(t, request.UserId, requests[i].UserId)
+		}
+	}
+
+	page := domain.Pagination{Limit: 2}
+	requests, next, err = r.ClassJoinRequestRepository.ListJoinRequests(context.Background(), classId, page)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(requests))
+	assert.NotEqual(t, "", next)
+
+	page.Cursor = next
+	requests, next, err = r.ClassJoinRequestRepository.ListJoinRequests(context.Background(), classId, page)
+	assert.Nil(t, err)
+	assert.Equal(t, "", next)
+	if assert.Equal(t, 1, len(requests)) {
+		assert.Equal(t, r.requests[2].UserId, requests[0].UserId)
+	}
+
+	requests, _, err = r.ClassJoinRequestRepository.ListJoinRequests(context.Background(), xid.New().String(), domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(requests))
+}
+
+func (r *Repository) testDeleteExpiredJoinRequests(t *testing.T) {
+	expired := r.requests[1]
+	err := r.ClassJoinRequestRepository.DeleteExpiredJoinRequests(context.Background(), expired.ClassId, expired.ExpiresAt)
+	assert.Nil(t, err)
+
+	_, err = r.ClassJoinRequestRepository.GetJoinRequest(context.Background(), expired.ClassId, expired.UserId)
+	assert.Equal(t, domain.ErrClassJoinRequestNotExists, err)
+	for _, request := range []domain.ClassJoinRequest{r.requests[0], r.requests[2]} {
+		_, err = r.ClassJoinRequestRepository.GetJoinRequest(context.Background(), request.ClassId, request.UserId)
+		assert.Nil(t, err, "request that is not expired should be kept")
+	}
+}
+
+func (r *Repository) testDeleteJoinRequest(t *testing.T) {
+	request := r.requests[0]
+	err := r.ClassJoinRequestRepository.DeleteJoinRequest(context.Background(), request.ClassId, request.UserId)
+	assert.Nil(t, err)
+	_, err = r.ClassJoinRequestRepository.GetJoinRequest(context.Background(), request.ClassId, request.UserId)
+	assert.Equal(t, domain.ErrClassJoinRequestNotExists, err)
+
+	// request can be created again once deleted
+	err = r.ClassJoinRequestRepository.CreateJoinRequest(context.Background(), &request)
+	assert.Nil(t, err)
+}
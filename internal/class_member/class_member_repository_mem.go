@@ -2,8 +2,11 @@ package classmember
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"time"
 
+	"nory/common/database"
 	"nory/domain"
 )
 
@@ -16,7 +19,7 @@ func NewClassMemberRepositoryMem() *ClassMemberRepositoryMem {
 	return &ClassMemberRepositoryMem{}
 }
 
-func (repo *ClassMemberRepositoryMem) ListMembers(ctx context.Context, classId string) ([]*domain.ClassMember, error) {
+func (repo *ClassMemberRepositoryMem) ListMembers(ctx context.Context, classId string, page domain.Pagination) ([]*domain.ClassMember, string, error) {
 	repo.mx.Lock()
 	defer repo.mx.Unlock()
 
@@ -27,10 +30,11 @@ func (repo *ClassMemberRepositoryMem) ListMembers(ctx context.Context, classId s
 			result = append(result, m)
 		}
 	}
-	return result, nil
+	sortMembers(result, func(m *domain.ClassMember) string { return m.UserId })
+	return domain.Paginate(result, page, memberKey)
 }
 
-func (repo *ClassMemberRepositoryMem) ListJoined(ctx context.Context, userId string) ([]*domain.ClassMember, error) {
+func (repo *ClassMemberRepositoryMem) ListJoined(ctx context.Context, userId string, page domain.Pagination) ([]*domain.ClassMember, string, error) {
 	repo.mx.Lock()
 	defer repo.mx.Unlock()
 
@@ -41,7 +45,26 @@ func (repo *ClassMemberRepositoryMem) ListJoined(ctx context.Context, userId str
 			result = append(result, m)
 		}
 	}
-	return result, nil
+	sortMembers(result, func(m *domain.ClassMember) string { return m.ClassId })
+	return domain.Paginate(result, page, joinedKey)
+}
+
+// sortMembers sort by CreatedAt, tie is broken with id
+func sortMembers(members []*domain.ClassMember, id func(*domain.ClassMember) string) {
+	sort.SliceStable(members, func(i, j int) bool {
+		if !members[i].CreatedAt.Equal(members[j].CreatedAt) {
+			return members[i].CreatedAt.Before(members[j].CreatedAt)
+		}
+		return id(members[i]) < id(members[j])
+	})
+}
+
+func memberKey(m *domain.ClassMember) []string {
+	return []string{domain.CursorTime(m.CreatedAt), m.UserId}
+}
+
+func joinedKey(m *domain.ClassMember) []string {
+	return []string{domain.CursorTime(m.CreatedAt), m.ClassId}
 }
 
 func (repo *ClassMemberRepositoryMem) GetMember(ctx context.Context, member *domain.ClassMember) (*domain.ClassMember, error) {
@@ -66,7 +89,13 @@ func (repo *ClassMemberRepositoryMem) CreateMember(ctx context.Context, member *
 	repo.mx.Lock()
 	defer repo.mx.Unlock()
 
+	if member.CreatedAt.IsZero() {
+		member.CreatedAt = time.Now()
+	}
 	repo.members = append(repo.members, member)
+	database.OnRollback(ctx, func() {
+		repo.remove(member)
+	})
 	return nil
 }
 
@@ -77,6 +106,12 @@ func (repo *ClassMemberRepositoryMem) UpdateMember(ctx context.Context, member *
 	for _, m := range repo.members {
 		m := m
 		if m.ClassId == member.ClassId && m.UserId == member.UserId {
+			saved := *m
+			database.OnRollback(ctx, func() {
+				repo.mx.Lock()
+				defer repo.mx.Unlock()
+				*m = saved
+			})
 			m.Update(member)
 		}
 	}
@@ -90,8 +125,25 @@ func (repo *ClassMemberRepositoryMem) DeleteMember(ctx context.Context, member *
 	for i, m := range repo.members {
 		if m.ClassId == member.ClassId && m.UserId == member.UserId {
 			repo.members = append(repo.members[:i], repo.members[i+1:]...)
+			database.OnRollback(ctx, func() {
+				repo.mx.Lock()
+				defer repo.mx.Unlock()
+				repo.members = append(repo.members, m)
+			})
 			return nil
 		}
 	}
 	return nil
 }
+
+// remove delete member by identity, it is used to undo CreateMember
+func (repo *ClassMemberRepositoryMem) remove(member *domain.ClassMember) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	for i, m := range repo.members {
+		if m == member {
+			repo.members = append(repo.members[:i], repo.members[i+1:]...)
+			return
+		}
+	}
+}
@@ -3,6 +3,8 @@ package classmember
 import (
 	"context"
 	"errors"
+	"fmt"
+	"nory/common/database"
 	"nory/domain"
 
 	"github.com/jackc/pgx/v5"
@@ -18,15 +20,32 @@ func NewClassMemberRepositoryPostgres(pool *pgxpool.Pool) *ClassMemberRepository
 	return &ClassMemberRepositoryPostgres{pool}
 }
 
-func (repo *ClassMemberRepositoryPostgres) ListMembers(ctx context.Context, classId string) ([]*domain.ClassMember, error) {
+func (repo *ClassMemberRepositoryPostgres) ListMembers(ctx context.Context, classId string, page domain.Pagination) ([]*domain.ClassMember, string, error) {
+	after, err := page.Keys(2)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "SELECT user_id, created_at, level FROM class_member WHERE class_id = $1"
+	args := []any{classId}
+	if after != nil {
+		createdAt, err := domain.ParseCursorTime(after[0])
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, createdAt, after[1])
+		query += " AND (created_at, user_id) > ($2, $3)"
+	}
+	query += " ORDER BY created_at, user_id"
+	if page.Limit > 0 {
+		args = append(args, page.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
 	members := make([]*domain.ClassMember, 0)
-	rows, err := repo.pool.Query(
-		ctx,
-		"SELECT user_id, created_at, level FROM class_member WHERE class_id = $1 ORDER BY created_at",
-		classId,
-	)
+	rows, err := database.Conn(ctx, repo.pool).Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	for rows.Next() {
 		member := &domain.ClassMember{
@@ -40,23 +59,41 @@ func (repo *ClassMemberRepositoryPostgres) ListMembers(ctx context.Context, clas
 		)
 
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
 		members = append(members, member)
 	}
-	return members, nil
+	members, next := domain.NextPage(members, page, memberKey)
+	return members, next, nil
 }
 
-func (repo *ClassMemberRepositoryPostgres) ListJoined(ctx context.Context, userId string) ([]*domain.ClassMember, error) {
+func (repo *ClassMemberRepositoryPostgres) ListJoined(ctx context.Context, userId string, page domain.Pagination) ([]*domain.ClassMember, string, error) {
+	after, err := page.Keys(2)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "SELECT class_id, created_at, level FROM class_member WHERE user_id = $1"
+	args := []any{userId}
+	if after != nil {
+		createdAt, err := domain.ParseCursorTime(after[0])
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, createdAt, after[1])
+		query += " AND (created_at, class_id) > ($2, $3)"
+	}
+	query += " ORDER BY created_at, class_id"
+	if page.Limit > 0 {
+		args = append(args, page.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
 	members := make([]*domain.ClassMember, 0)
-	rows, err := repo.pool.Query(
-		ctx,
-		"SELECT class_id, created_at, level FROM class_member WHERE user_id = $1 ORDER BY created_at",
-		userId,
-	)
+	rows, err := database.Conn(ctx, repo.pool).Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	for rows.Next() {
 		member := &domain.ClassMember{
@@ -70,12 +107,13 @@ func (repo *ClassMemberRepositoryPostgres) ListJoined(ctx context.Context, userI
 		)
 
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
 		members = append(members, member)
 	}
-	return members, nil
+	members, next := domain.NextPage(members, page, joinedKey)
+	return members, next, nil
 }
 
 func (repo *ClassMemberRepositoryPostgres) GetMember(ctx context.Context, member *domain.ClassMember) (*domain.ClassMember, error) {
@@ -83,7 +121,7 @@ func (repo *ClassMemberRepositoryPostgres) GetMember(ctx context.Context, member
 		UserId:  member.UserId,
 		ClassId: member.ClassId,
 	}
-	row := repo.pool.QueryRow(
+	row := database.Conn(ctx, repo.pool).QueryRow(
 		ctx,
 		"SELECT level, created_at FROM class_member WHERE user_id = $1 AND class_id = $2",
 		member.UserId,
@@ -100,7 +138,7 @@ func (repo *ClassMemberRepositoryPostgres) GetMember(ctx context.Context, member
 }
 
 func (repo *ClassMemberRepositoryPostgres) CreateMember(ctx context.Context, member *domain.ClassMember) error {
-	_, err := repo.pool.Exec(
+	_, err := database.Conn(ctx, repo.pool).Exec(
 		ctx,
 		"INSERT INTO class_member(class_id, user_id, level) VALUES($1, $2, $3)",
 		member.ClassId,
@@ -114,7 +152,7 @@ func (repo *ClassMemberRepositoryPostgres) CreateMember(ctx context.Context, mem
 }
 
 func (repo *ClassMemberRepositoryPostgres) UpdateMember(ctx context.Context, member *domain.ClassMember) error {
-	_, err := repo.pool.Exec(
+	_, err := database.Conn(ctx, repo.pool).Exec(
 		ctx,
 		"UPDATE class_member SET level = $1 WHERE user_id = $2 AND class_id = $3",
 		member.Level,
@@ -125,7 +163,7 @@ func (repo *ClassMemberRepositoryPostgres) UpdateMember(ctx context.Context, mem
 }
 
 func (repo *ClassMemberRepositoryPostgres) DeleteMember(ctx context.Context, member *domain.ClassMember) error {
-	_, err := repo.pool.Exec(
+	_, err := database.Conn(ctx, repo.pool).Exec(
 		ctx,
 		"DELETE FROM class_member WHERE user_id = $1 AND class_id = $2",
 		member.UserId,
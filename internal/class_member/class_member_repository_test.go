@@ -132,7 +132,7 @@ func TestClassMemberRepository(t *testing.T) {
 					{classBar, 1},
 					{xid.New().String(), 0},
 				} {
-					members, err := repo.Repo.ListMembers(context.Background(), tc.ClassId)
+					members, _, err := repo.Repo.ListMembers(context.Background(), tc.ClassId, domain.Pagination{})
 					assert.Nil(t, err)
 					assert.Equal(t, tc.Len, len(members))
 				}
@@ -147,11 +147,34 @@ func TestClassMemberRepository(t *testing.T) {
 					{userBar, 1},
 					{xid.New().String(), 0},
 				} {
-					members, err := repo.Repo.ListJoined(context.Background(), tc.UserId)
+					members, _, err := repo.Repo.ListJoined(context.Background(), tc.UserId, domain.Pagination{})
 					assert.Nil(t, err)
 					assert.Equal(t, tc.Len, len(members))
 				}
 			})
+
+			t.Run("pagination", func(t *testing.T) {
+				first, next, err := repo.Repo.ListMembers(context.Background(), classFoo, domain.Pagination{Limit: 1})
+				assert.Nil(t, err)
+				assert.Equal(t, 1, len(first))
+				assert.NotEqual(t, "", next)
+
+				second, next, err := repo.Repo.ListMembers(context.Background(), classFoo, domain.Pagination{Limit: 1, Cursor: next})
+				assert.Nil(t, err)
+				assert.Equal(t, 1, len(second))
+				assert.Equal(t, "", next, "last page should not have next cursor")
+				if len(first) == 1 && len(second) == 1 {
+					assert.NotEqual(t, first[0].UserId, second[0].UserId)
+				}
+
+				joined, next, err := repo.Repo.ListJoined(context.Background(), userFoo, domain.Pagination{Limit: 1})
+				assert.Nil(t, err)
+				assert.Equal(t, 1, len(joined))
+				assert.NotEqual(t, "", next)
+
+				_, _, err = repo.Repo.ListJoined(context.Background(), userFoo, domain.Pagination{Limit: 1, Cursor: "foo"})
+				assert.Equal(t, domain.ErrInvalidCursor, err)
+			})
 		})
 	}
 }
@@ -2,7 +2,9 @@ package classschedule
 
 import (
 	"context"
+	"nory/common/database"
 	"nory/domain"
+	"sort"
 	"sync"
 
 	"github.com/rs/xid"
@@ -23,6 +25,7 @@ func (csrm *ClassScheduleRepositoryMem) CreateSchedule(ctx context.Context, sche
 	csrm.mx.Lock()
 	defer csrm.mx.Unlock()
 	schedule.ScheduleId = xid.New().String()
+	database.RestoreOnRollback(ctx, &csrm.mx, csrm.m, schedule.ScheduleId)
 	csrm.m[schedule.ScheduleId] = schedule
 	return nil
 }
@@ -37,7 +40,7 @@ func (csrm *ClassScheduleRepositoryMem) GetSchedule(ctx context.Context, schedul
 	return schedule, nil
 }
 
-func (csrm *ClassScheduleRepositoryMem) GetSchedules(ctx context.Context, classId string) ([]*domain.ClassSchedule, error) {
+func (csrm *ClassScheduleRepositoryMem) GetSchedules(ctx context.Context, classId string, page domain.Pagination) ([]*domain.ClassSchedule, string, error) {
 	csrm.mx.Lock()
 	defer csrm.mx.Unlock()
 	schedules := make([]*domain.ClassSchedule, 0)
@@ -46,12 +49,39 @@ func (csrm *ClassScheduleRepositoryMem) GetSchedules(ctx context.Context, classI
 			schedules = append(schedules, sch)
 		}
 	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].ScheduleId < schedules[j].ScheduleId
+	})
+	return domain.Paginate(schedules, page, scheduleKey)
+}
+
+func (csrm *ClassScheduleRepositoryMem) GetSchedulesByClassIds(ctx context.Context, classIds []string) ([]*domain.ClassSchedule, error) {
+	csrm.mx.Lock()
+	defer csrm.mx.Unlock()
+	classes := make(map[string]bool, len(classIds))
+	for _, classId := range classIds {
+		classes[classId] = true
+	}
+	schedules := make([]*domain.ClassSchedule, 0)
+	for _, sch := range csrm.m {
+		if classes[sch.ClassId] {
+			schedules = append(schedules, sch)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].ScheduleId < schedules[j].ScheduleId
+	})
 	return schedules, nil
 }
 
+func scheduleKey(schedule *domain.ClassSchedule) []string {
+	return []string{schedule.ScheduleId}
+}
+
 func (csrm *ClassScheduleRepositoryMem) DeleteSchedule(ctx context.Context, scheduleId string) error {
 	csrm.mx.Lock()
 	defer csrm.mx.Unlock()
+	database.RestoreOnRollback(ctx, &csrm.mx, csrm.m, scheduleId)
 	delete(csrm.m, scheduleId)
 	return nil
 }
@@ -59,10 +89,29 @@ func (csrm *ClassScheduleRepositoryMem) DeleteSchedule(ctx context.Context, sche
 func (csrm *ClassScheduleRepositoryMem) ClearSchedules(ctx context.Context, classId string, day int8) error {
 	csrm.mx.Lock()
 	defer csrm.mx.Unlock()
+	csrm.clearSchedules(ctx, classId, day)
+	return nil
+}
+
+func (csrm *ClassScheduleRepositoryMem) clearSchedules(ctx context.Context, classId string, day int8) {
 	for key, schedule := range csrm.m {
 		if schedule.ClassId == classId && schedule.Day == day {
+			database.RestoreOnRollback(ctx, &csrm.mx, csrm.m, key)
 			delete(csrm.m, key)
 		}
 	}
+}
+
+func (csrm *ClassScheduleRepositoryMem) ReplaceSchedules(ctx context.Context, classId string, day int8, schedules []*domain.ClassSchedule) error {
+	csrm.mx.Lock()
+	defer csrm.mx.Unlock()
+	csrm.clearSchedules(ctx, classId, day)
+	for _, schedule := range schedules {
+		schedule.ScheduleId = xid.New().String()
+		database.RestoreOnRollback(ctx, &csrm.mx, csrm.m, schedule.ScheduleId)
+		schedule.ClassId = classId
+		schedule.Day = day
+		csrm.m[schedule.ScheduleId] = schedule
+	}
 	return nil
 }
@@ -3,11 +3,13 @@ package classschedule
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/xid"
 
+	"nory/common/database"
 	"nory/domain"
 )
 
@@ -22,7 +24,7 @@ func NewClassScheduleRepositoryPg(pool *pgxpool.Pool) *ClassScheduleRepositoryPg
 func (csrp *ClassScheduleRepositoryPg) CreateSchedule(ctx context.Context, schedule *domain.ClassSchedule) error {
 	schedule.ScheduleId = xid.New().String()
 
-	_, err := csrp.pool.Exec(
+	_, err := database.Conn(ctx, csrp.pool).Exec(
 		ctx,
 		`INSERT INTO class_schedule(schedule_id, class_id, author_id, name, start_at, duration, day) VALUES($1, $2, $3, $4, $5, $6, $7)`,
 		schedule.ScheduleId,
@@ -41,7 +43,7 @@ func (csrp *ClassScheduleRepositoryPg) GetSchedule(ctx context.Context, schedule
 	schedule := &domain.ClassSchedule{
 		ScheduleId: scheduleId,
 	}
-	row := csrp.pool.QueryRow(
+	row := database.Conn(ctx, csrp.pool).QueryRow(
 		ctx,
 		"SELECT class_id, author_id, created_at, name, start_at, duration, day FROM class_schedule WHERE schedule_id = $1",
 		scheduleId,
@@ -64,16 +66,29 @@ func (csrp *ClassScheduleRepositoryPg) GetSchedule(ctx context.Context, schedule
 	return schedule, nil
 }
 
-func (csrp *ClassScheduleRepositoryPg) GetSchedules(ctx context.Context, classId string) ([]*domain.ClassSchedule, error) {
+func (csrp *ClassScheduleRepositoryPg) GetSchedules(ctx context.Context, classId string, page domain.Pagination) ([]*domain.ClassSchedule, string, error) {
+	after, err := page.Keys(1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "SELECT schedule_id, author_id, created_at, name, start_at, duration, day FROM class_schedule WHERE class_id = $1"
+	args := []any{classId}
+	if after != nil {
+		args = append(args, after[0])
+		query += " AND schedule_id > $2"
+	}
+	query += " ORDER BY schedule_id"
+	if page.Limit > 0 {
+		args = append(args, page.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
 	schedules := make([]*domain.ClassSchedule, 0)
 
-	rows, err := csrp.pool.Query(
-		ctx,
-		"SELECT schedule_id, author_id, created_at, name, start_at, duration, day FROM class_schedule WHERE class_id = $1 ORDER BY schedule_id",
-		classId,
-	)
+	rows, err := database.Conn(ctx, csrp.pool).Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	for rows.Next() {
@@ -91,17 +106,48 @@ func (csrp *ClassScheduleRepositoryPg) GetSchedules(ctx context.Context, classId
 			&schedule.Day,
 		)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
 		schedules = append(schedules, schedule)
 	}
 
-	return schedules, nil
+	schedules, next := domain.NextPage(schedules, page, scheduleKey)
+	return schedules, next, nil
+}
+
+func (csrp *ClassScheduleRepositoryPg) GetSchedulesByClassIds(ctx context.Context, classIds []string) ([]*domain.ClassSchedule, error) {
+	schedules := make([]*domain.ClassSchedule, 0)
+	rows, err := database.Conn(ctx, csrp.pool).Query(
+		ctx,
+		"SELECT schedule_id, class_id, author_id, created_at, name, start_at, duration, day FROM class_schedule WHERE class_id = ANY($1) ORDER BY schedule_id",
+		classIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		schedule := &domain.ClassSchedule{}
+		err := rows.Scan(
+			&schedule.ScheduleId,
+			&schedule.ClassId,
+			&schedule.AuthorId,
+			&schedule.CreatedAt,
+			&schedule.Name,
+			&schedule.StartAt,
+			&schedule.Duration,
+			&schedule.Day,
+		)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
 }
 
 func (csrp *ClassScheduleRepositoryPg) DeleteSchedule(ctx context.Context, scheduleId string) error {
-	_, err := csrp.pool.Exec(
+	_, err := database.Conn(ctx, csrp.pool).Exec(
 		ctx,
 		"DELETE FROM class_schedule WHERE schedule_id = $1",
 		scheduleId,
@@ -110,10 +156,27 @@ func (csrp *ClassScheduleRepositoryPg) DeleteSchedule(ctx context.Context, sched
 }
 
 func (csrp *ClassScheduleRepositoryPg) ClearSchedules(ctx context.Context, classId string, day int8) error {
-	_, err := csrp.pool.Exec(
+	_, err := database.Conn(ctx, csrp.pool).Exec(
 		ctx,
-		"DELETE FROM class_schedule WHERE day = $1",
+		"DELETE FROM class_schedule WHERE class_id = $1 AND day = $2",
+		classId,
 		day,
 	)
 	return err
 }
+
+func (csrp *ClassScheduleRepositoryPg) ReplaceSchedules(ctx context.Context, classId string, day int8, schedules []*domain.ClassSchedule) error {
+	return database.NewTxRunnerPostgres(csrp.pool).RunInTx(ctx, func(ctx context.Context) error {
+		if err := csrp.ClearSchedules(ctx, classId, day); err != nil {
+			return err
+		}
+		for _, schedule := range schedules {
+			schedule.ClassId = classId
+			schedule.Day = day
+			if err := csrp.CreateSchedule(ctx, schedule); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -52,8 +52,11 @@ func TestClassScheduleRepository(t *testing.T) {
 			t.Parallel()
 			t.Run("CreateSchedule", repo.testCreateSchedule)
 			t.Run("GetSchedules", repo.testGetSchedules)
+			t.Run("GetSchedules pagination", repo.testGetSchedulesPagination)
+			t.Run("GetSchedulesByClassIds", repo.testGetSchedulesByClassIds)
 			t.Run("GetSchedule", repo.testGetSchedule)
 			t.Run("ClearSchedules", repo.testClearSchedules)
+			t.Run("ReplaceSchedules", repo.testReplaceSchedules)
 			t.Run("DeleteSchedule", repo.testDeleteSchedule)
 		})
 	}
@@ -167,7 +170,7 @@ func (r *Repository) testGetSchedules(t *testing.T) {
 	for _, tc := range testCases {
 		tc := tc
 		t.Run(tc.Name, func(t *testing.T) {
-			schedules, err := r.ClassScheduleRepository.GetSchedules(context.Background(), tc.ClassId)
+			schedules, _, err := r.ClassScheduleRepository.GetSchedules(context.Background(), tc.ClassId, domain.Pagination{})
 			assert.Equal(t, tc.Err, err, "missmatch error")
 			assert.Equal(t, tc.Len, len(schedules), "missmatch schedules length")
 			for _, schedule := range schedules {
@@ -177,6 +180,55 @@ func (r *Repository) testGetSchedules(t *testing.T) {
 	}
 }
 
+func (r *Repository) testGetSchedulesByClassIds(t *testing.T) {
+	foo, bar, baz := r.getClass("classFoo"), r.getClass("classBar"), r.getClass("classBaz")
+	testCases := []struct {
+		Name     string
+		ClassIds []string
+		Len      int
+	}{
+		{"all", []string{foo, bar, baz}, 5},
+		{"single", []string{bar}, 2},
+		{"empty", []string{}, 0},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			schedules, err := r.ClassScheduleRepository.GetSchedulesByClassIds(context.Background(), tc.ClassIds)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.Len, len(schedules), "missmatch schedules length")
+			for _, schedule := range schedules {
+				assert.Contains(t, tc.ClassIds, schedule.ClassId, "unknown ClassId received")
+			}
+		})
+	}
+}
+
+func (r *Repository) testGetSchedulesPagination(t *testing.T) {
+	classId := r.getClass("classFoo")
+	all, next, err := r.ClassScheduleRepository.GetSchedules(context.Background(), classId, domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", next, "unlimited page should not have next cursor")
+
+	var paged []*domain.ClassSchedule
+	page := domain.Pagination{Limit: 2}
+	for i := 0; i < 3; i++ {
+		schedules, next, err := r.ClassScheduleRepository.GetSchedules(context.Background(), classId, page)
+		assert.Nil(t, err)
+		assert.LessOrEqual(t, len(schedules), page.Limit)
+		paged = append(paged, schedules...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+	assert.Equal(t, all, paged, "paged schedules should be equal to unlimited page")
+
+	_, _, err = r.ClassScheduleRepository.GetSchedules(context.Background(), classId, domain.Pagination{Limit: 2, Cursor: "foo"})
+	assert.Equal(t, domain.ErrInvalidCursor, err)
+}
+
 func (r *Repository) testClearSchedules(t *testing.T) {
 	testCases := []struct {
 		Name    string
@@ -195,13 +247,59 @@ func (r *Repository) testClearSchedules(t *testing.T) {
 			err := r.ClassScheduleRepository.ClearSchedules(context.Background(), tc.ClassId, tc.Day)
 			assert.Equal(t, tc.Err, err, "missmatch error")
 
-			schedules, err := r.ClassScheduleRepository.GetSchedules(context.Background(), tc.ClassId)
+			schedules, _, err := r.ClassScheduleRepository.GetSchedules(context.Background(), tc.ClassId, domain.Pagination{})
 			assert.Nil(t, err)
 			assert.Equal(t, tc.Len, len(schedules))
 		})
 	}
 }
 
+func (r *Repository) testReplaceSchedules(t *testing.T) {
+	classId := r.getClass("classQux")
+	otherClassId := r.getClass("classQuux")
+	for _, id := range []string{classId, otherClassId} {
+		for _, day := range []int8{1, 1, 2} {
+			err := r.ClassScheduleRepository.CreateSchedule(context.Background(), &domain.ClassSchedule{
+				ClassId:  id,
+				AuthorId: r.getUser("classQux"),
+				Day:      day,
+				StartAt:  now,
+			})
+			assert.Nil(t, err)
+		}
+	}
+
+	replacement := []*domain.ClassSchedule{
+		{AuthorId: r.getUser("classQux"), StartAt: now, Name: "foo"},
+		{AuthorId: r.getUser("classQux"), StartAt: now.Add(time.Hour), Name: "bar", Day: 5},
+		{AuthorId: r.getUser("classQux"), StartAt: now.Add(2 * time.Hour), Name: "baz"},
+	}
+	err := r.ClassScheduleRepository.ReplaceSchedules(context.Background(), classId, 1, replacement)
+	assert.Nil(t, err)
+
+	schedules, _, err := r.ClassScheduleRepository.GetSchedules(context.Background(), classId, domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(schedules))
+	for _, schedule := range replacement {
+		assert.NotEqual(t, "", schedule.ScheduleId, "ReplaceSchedules must assign generated id")
+		s, err := r.ClassScheduleRepository.GetSchedule(context.Background(), schedule.ScheduleId)
+		if assert.Nil(t, err) {
+			assert.Equal(t, classId, s.ClassId)
+			assert.Equal(t, int8(1), s.Day, "ReplaceSchedules must force the replaced day")
+		}
+	}
+
+	schedules, _, err = r.ClassScheduleRepository.GetSchedules(context.Background(), otherClassId, domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(schedules), "other class should not be affected")
+
+	err = r.ClassScheduleRepository.ReplaceSchedules(context.Background(), classId, 1, nil)
+	assert.Nil(t, err)
+	schedules, _, err = r.ClassScheduleRepository.GetSchedules(context.Background(), classId, domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(schedules))
+}
+
 func (r *Repository) testDeleteSchedule(t *testing.T) {
 	for _, s := range r.Schedules {
 		err := r.ClassScheduleRepository.DeleteSchedule(context.Background(), s.ScheduleId)
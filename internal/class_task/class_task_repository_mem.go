@@ -3,22 +3,26 @@ package classtask
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync"
 	"time"
 
+	"nory/common/database"
 	"nory/domain"
 
 	"github.com/rs/xid"
 )
 
 type ClassTaskRepositoryMem struct {
-	mx sync.Mutex
-	m  map[string]*domain.ClassTask
+	mx       sync.Mutex
+	m        map[string]*domain.ClassTask
+	progress map[string]*domain.ClassTaskProgress
 }
 
 func NewClassTaskRepositoryMem() *ClassTaskRepositoryMem {
 	return &ClassTaskRepositoryMem{
-		m: make(map[string]*domain.ClassTask),
+		m:        make(map[string]*domain.ClassTask),
+		progress: make(map[string]*domain.ClassTaskProgress),
 	}
 }
 
@@ -29,6 +33,7 @@ func (ctrm *ClassTaskRepositoryMem) CreateTask(ctx context.Context, task *domain
 	ctrm.mx.Lock()
 	defer ctrm.mx.Unlock()
 	task.TaskId = xid.New().String()
+	database.RestoreOnRollback(ctx, &ctrm.mx, ctrm.m, task.TaskId)
 	ctrm.m[task.TaskId] = task
 	return nil
 }
@@ -37,27 +42,174 @@ func (ctrm *ClassTaskRepositoryMem) GetTask(ctx context.Context, taskId string)
 	ctrm.mx.Lock()
 	defer ctrm.mx.Unlock()
 	task, ok := ctrm.m[taskId]
+	if ok && task.Skipped {
+		return nil, domain.ErrClassTaskNotExists
+	}
+	if ok {
+		return task, nil
+	}
+
+	seriesId, _, isOccurrence := domain.ParseOccurrenceId(taskId)
+	series, ok := ctrm.m[seriesId]
+	if !isOccurrence || !ok || !series.Recurring() {
+		return nil, domain.ErrClassTaskNotExists
+	}
+	occurrence, ok := series.Occurrence(taskId)
 	if !ok {
 		return nil, domain.ErrClassTaskNotExists
 	}
-	return task, nil
+	return occurrence, nil
 }
 
 func (ctrm *ClassTaskRepositoryMem) GetTasks(ctx context.Context, classId string) ([]*domain.ClassTask, error) {
-	return ctrm.GetTasksWithRange(ctx, classId, time.Unix(1, 0), time.Date(2030, time.August, 11, 0, 0, 0, 0, time.UTC))
+	tasks, _, err := ctrm.FindTasks(ctx, classId, domain.ClassTaskFilter{}, domain.Pagination{})
+	return tasks, err
+}
+
+func (ctrm *ClassTaskRepositoryMem) GetTasksWithRange(ctx context.Context, classId string, from, to time.Time, page domain.Pagination) ([]*domain.ClassTask, string, error) {
+	return ctrm.FindTasks(ctx, classId, domain.ClassTaskFilter{From: from, To: to}, page)
 }
 
-func (ctrm *ClassTaskRepositoryMem) GetTasksWithRange(ctx context.Context, classId string, from, to time.Time) ([]*domain.ClassTask, error) {
+func (ctrm *ClassTaskRepositoryMem) FindTasks(ctx context.Context, classId string, filter domain.ClassTaskFilter, page domain.Pagination) ([]*domain.ClassTask, string, error) {
+	// cursor of other sort order has different number of keys
+	if _, err := page.Keys(len(filter.TaskKey(&domain.ClassTask{}))); err != nil {
+		return nil, "", err
+	}
 	ctrm.mx.Lock()
 	defer ctrm.mx.Unlock()
+	from, to := filter.OccurrenceRange()
 	tasks := make([]*domain.ClassTask, 0)
-	for _, task := range ctrm.m {
-		if task.ClassId == classId &&
+	for _, task := range ctrm.expand(from, to) {
+		if task.ClassId != classId {
+			continue
+		}
+		status := domain.ClassTaskStatusTodo
+		if p, ok := ctrm.progress[progressKey(task.TaskId, filter.UserId)]; ok && filter.UserId != "" {
+			status = p.Status
+		}
+		if filter.Match(task, status) {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return filter.Less(tasks[i], tasks[j])
+	})
+	if filter.Descending() {
+		return domain.PaginateDesc(tasks, page, filter.TaskKey)
+	}
+	return domain.Paginate(tasks, page, filter.TaskKey)
+}
+
+func (ctrm *ClassTaskRepositoryMem) GetTasksByClassIds(ctx context.Context, classIds []string, from, to time.Time) ([]*domain.ClassTask, error) {
+	ctrm.mx.Lock()
+	defer ctrm.mx.Unlock()
+	classes := make(map[string]bool, len(classIds))
+	for _, classId := range classIds {
+		classes[classId] = true
+	}
+	tasks := make([]*domain.ClassTask, 0)
+	for _, task := range ctrm.expand(from, to) {
+		if classes[task.ClassId] &&
 			!task.DueDate.Before(from) &&
 			task.DueDate.Before(to) {
 			tasks = append(tasks, task)
 		}
 	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].DueDate.Equal(tasks[j].DueDate) {
+			return tasks[i].DueDate.Before(tasks[j].DueDate)
+		}
+		return tasks[i].TaskId < tasks[j].TaskId
+	})
+	return tasks, nil
+}
+
+// expand list every listed task, series are replaced by their occurrences in [from, to)
+func (ctrm *ClassTaskRepositoryMem) expand(from, to time.Time) []*domain.ClassTask {
+	stored := make(map[string]bool)
+	for _, task := range ctrm.m {
+		if task.SeriesId != "" {
+			stored[task.TaskId] = true
+		}
+	}
+	tasks := make([]*domain.ClassTask, 0, len(ctrm.m))
+	for _, task := range ctrm.m {
+		switch {
+		case task.Skipped:
+		case task.Recurring():
+			tasks = append(tasks, task.Occurrences(from, to, stored)...)
+		default:
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+func (ctrm *ClassTaskRepositoryMem) CreateOccurrence(ctx context.Context, task *domain.ClassTask) error {
+	if task.SeriesId == "" {
+		return errors.New("not an occurrence")
+	}
+	ctrm.mx.Lock()
+	defer ctrm.mx.Unlock()
+	if _, ok := ctrm.m[task.TaskId]; ok {
+		return nil
+	}
+	database.RestoreOnRollback(ctx, &ctrm.mx, ctrm.m, task.TaskId)
+	occurrence := *task
+	ctrm.m[task.TaskId] = &occurrence
+	return nil
+}
+
+func (ctrm *ClassTaskRepositoryMem) SkipOccurrence(ctx context.Context, task *domain.ClassTask) error {
+	if task.SeriesId == "" {
+		return errors.New("not an occurrence")
+	}
+	ctrm.mx.Lock()
+	defer ctrm.mx.Unlock()
+	database.RestoreOnRollback(ctx, &ctrm.mx, ctrm.m, task.TaskId)
+	occurrence := *task
+	occurrence.Skipped = true
+	ctrm.m[task.TaskId] = &occurrence
+	for key, p := range ctrm.progress {
+		if p.TaskId == task.TaskId {
+			database.RestoreOnRollback(ctx, &ctrm.mx, ctrm.progress, key)
+			delete(ctrm.progress, key)
+		}
+	}
+	return nil
+}
+
+func (ctrm *ClassTaskRepositoryMem) GetOccurrences(ctx context.Context, seriesId string) ([]*domain.ClassTask, error) {
+	ctrm.mx.Lock()
+	defer ctrm.mx.Unlock()
+	tasks := make([]*domain.ClassTask, 0)
+	for _, task := range ctrm.m {
+		if task.SeriesId == seriesId {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].TaskId < tasks[j].TaskId
+	})
+	return tasks, nil
+}
+
+func (ctrm *ClassTaskRepositoryMem) GetSeriesByClassIds(ctx context.Context, classIds []string) ([]*domain.ClassTask, error) {
+	ctrm.mx.Lock()
+	defer ctrm.mx.Unlock()
+	classes := make(map[string]bool, len(classIds))
+	for _, classId := range classIds {
+		classes[classId] = true
+	}
+	tasks := make([]*domain.ClassTask, 0)
+	for _, task := range ctrm.m {
+		if classes[task.ClassId] && (task.Recurring() || task.SeriesId != "") {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].TaskId < tasks[j].TaskId
+	})
 	return tasks, nil
 }
 
@@ -80,13 +232,118 @@ func (ctrm *ClassTaskRepositoryMem) UpdateTask(ctx context.Context, task *domain
 	if !ok {
 		return domain.ErrClassTaskNotExists
 	}
+	database.RestoreOnRollback(ctx, &ctrm.mx, ctrm.m, task.TaskId)
 	t.Update(task)
+	now := time.Now().UTC()
+	t.UpdatedAt = &now
+	t.Edited = t.SeriesId != ""
+	task.UpdatedAt = &now
+	if !t.Recurring() {
+		return nil
+	}
+	for id, occurrence := range ctrm.m {
+		if occurrence.SeriesId == t.TaskId && !occurrence.Edited && !occurrence.Skipped {
+			database.RestoreOnRollback(ctx, &ctrm.mx, ctrm.m, id)
+			occurrence.Follow(t)
+		}
+	}
 	return nil
 }
 
 func (ctrm *ClassTaskRepositoryMem) DeleteTask(ctx context.Context, taskId string) error {
 	ctrm.mx.Lock()
 	defer ctrm.mx.Unlock()
-	delete(ctrm.m, taskId)
+	taskIds := map[string]bool{taskId: true}
+	for id, task := range ctrm.m {
+		if task.SeriesId == taskId {
+			taskIds[id] = true
+		}
+	}
+	for id := range taskIds {
+		database.RestoreOnRollback(ctx, &ctrm.mx, ctrm.m, id)
+		delete(ctrm.m, id)
+	}
+	for key, p := range ctrm.progress {
+		if taskIds[p.TaskId] {
+			database.RestoreOnRollback(ctx, &ctrm.mx, ctrm.progress, key)
+			delete(ctrm.progress, key)
+		}
+	}
+	return nil
+}
+
+func (ctrm *ClassTaskRepositoryMem) SetProgress(ctx context.Context, progress *domain.ClassTaskProgress) error {
+	ctrm.mx.Lock()
+	defer ctrm.mx.Unlock()
+	if _, ok := ctrm.m[progress.TaskId]; !ok {
+		return domain.ErrClassTaskNotExists
+	}
+	progress.UpdatedAt = time.Now().UTC()
+	p := *progress
+	key := progressKey(progress.TaskId, progress.UserId)
+	database.RestoreOnRollback(ctx, &ctrm.mx, ctrm.progress, key)
+	ctrm.progress[key] = &p
 	return nil
 }
+
+func (ctrm *ClassTaskRepositoryMem) GetProgress(ctx context.Context, taskId, userId string) (*domain.ClassTaskProgress, error) {
+	ctrm.mx.Lock()
+	defer ctrm.mx.Unlock()
+	p, ok := ctrm.progress[progressKey(taskId, userId)]
+	if !ok {
+		return nil, domain.ErrClassTaskProgressNotExists
+	}
+	progress := *p
+	return &progress, nil
+}
+
+func (ctrm *ClassTaskRepositoryMem) DeleteUserProgress(ctx context.Context, classId, userId string) error {
+	ctrm.mx.Lock()
+	defer ctrm.mx.Unlock()
+	for key, p := range ctrm.progress {
+		task, ok := ctrm.m[p.TaskId]
+		if p.UserId != userId || !ok || task.ClassId != classId {
+			continue
+		}
+		database.RestoreOnRollback(ctx, &ctrm.mx, ctrm.progress, key)
+		delete(ctrm.progress, key)
+	}
+	return nil
+}
+
+func (ctrm *ClassTaskRepositoryMem) GetUserProgress(ctx context.Context, userId string, taskIds []string) ([]*domain.ClassTaskProgress, error) {
+	ctrm.mx.Lock()
+	defer ctrm.mx.Unlock()
+	result := make([]*domain.ClassTaskProgress, 0)
+	for _, taskId := range taskIds {
+		p, ok := ctrm.progress[progressKey(taskId, userId)]
+		if !ok {
+			continue
+		}
+		progress := *p
+		result = append(result, &progress)
+	}
+	return result, nil
+}
+
+func (ctrm *ClassTaskRepositoryMem) GetProgressSummary(ctx context.Context, taskId string) (*domain.ClassTaskSummary, error) {
+	ctrm.mx.Lock()
+	defer ctrm.mx.Unlock()
+	summary := &domain.ClassTaskSummary{TaskId: taskId}
+	for _, p := range ctrm.progress {
+		if p.TaskId != taskId {
+			continue
+		}
+		switch p.Status {
+		case domain.ClassTaskStatusInProgress:
+			summary.InProgress++
+		case domain.ClassTaskStatusDone:
+			summary.Done++
+		}
+	}
+	return summary, nil
+}
+
+func progressKey(taskId, userId string) string {
+	return taskId + "/" + userId
+}
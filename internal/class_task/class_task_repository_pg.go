@@ -3,12 +3,17 @@ package classtask
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/xid"
 
+	"nory/common/database"
 	"nory/domain"
 )
 
@@ -22,9 +27,9 @@ func NewClassTaskRepositoryPostgres(pool *pgxpool.Pool) *ClassTaskRepositoryPost
 
 func (ctrp *ClassTaskRepositoryPostgres) CreateTask(ctx context.Context, task *domain.ClassTask) error {
 	task.TaskId = xid.New().String()
-	_, err := ctrp.pool.Exec(
+	_, err := database.Conn(ctx, ctrp.pool).Exec(
 		ctx,
-		"INSERT INTO class_task(task_id, class_id, author_id, author_display_name, name, description, due_date) VALUES($1, $2, $3, $4, $5, $6, $7);",
+		"INSERT INTO class_task(task_id, class_id, author_id, author_display_name, name, description, due_date, recurrence) VALUES($1, $2, $3, $4, $5, $6, $7, $8);",
 		task.TaskId,
 		task.ClassId,
 		task.AuthorId,
@@ -32,21 +37,49 @@ func (ctrp *ClassTaskRepositoryPostgres) CreateTask(ctx context.Context, task *d
 		task.Name,
 		task.Description,
 		task.DueDate,
+		task.Recurrence,
 	)
 
 	return err
 }
 
 func (ctrp *ClassTaskRepositoryPostgres) GetTask(ctx context.Context, taskId string) (*domain.ClassTask, error) {
-	ct := &domain.ClassTask{
-		TaskId: taskId,
-	}
-	row := ctrp.pool.QueryRow(
+	row := database.Conn(ctx, ctrp.pool).QueryRow(
 		ctx,
-		"SELECT class_id, author_id, created_at, author_display_name, name, description, due_date FROM class_task WHERE task_id = $1 ORDER BY due_date",
+		"SELECT "+taskColumns+" FROM class_task WHERE task_id = $1",
 		taskId,
 	)
+	ct, err := scanTask(row)
+	if err == nil && ct.Skipped {
+		return nil, domain.ErrClassTaskNotExists
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return ct, err
+	}
+
+	seriesId, _, ok := domain.ParseOccurrenceId(taskId)
+	if !ok {
+		return nil, domain.ErrClassTaskNotExists
+	}
+	series, err := ctrp.GetTask(ctx, seriesId)
+	if err != nil {
+		return nil, err
+	}
+	occurrence, ok := series.Occurrence(taskId)
+	if !series.Recurring() || !ok {
+		return nil, domain.ErrClassTaskNotExists
+	}
+	return occurrence, nil
+}
+
+// taskColumns is the columns read by scanTask
+const taskColumns = "task_id, class_id, author_id, created_at, author_display_name, name, description, due_date, updated_at, updated_by, recurrence, series_id, skipped, edited"
+
+func scanTask(row pgx.Row) (*domain.ClassTask, error) {
+	ct := &domain.ClassTask{}
+	var updatedBy, seriesId *string
 	err := row.Scan(
+		&ct.TaskId,
 		&ct.ClassId,
 		&ct.AuthorId,
 		&ct.CreatedAt,
@@ -54,72 +87,493 @@ func (ctrp *ClassTaskRepositoryPostgres) GetTask(ctx context.Context, taskId str
 		&ct.Name,
 		&ct.Description,
 		&ct.DueDate,
+		&ct.UpdatedAt,
+		&updatedBy,
+		&ct.Recurrence,
+		&seriesId,
+		&ct.Skipped,
+		&ct.Edited,
 	)
-	if errors.Is(err, pgx.ErrNoRows) {
-		err = domain.ErrClassTaskNotExists
+	if err != nil {
+		return nil, err
+	}
+	if updatedBy != nil {
+		ct.UpdatedBy = *updatedBy
+	}
+	if seriesId != nil {
+		ct.SeriesId = *seriesId
 	}
-	return ct, err
+	return ct, nil
 }
 
-func (ctrp *ClassTaskRepositoryPostgres) GetTasks(ctx context.Context, classId string) ([]*domain.ClassTask, error) {
-	return ctrp.GetTasksWithRange(ctx, classId, time.Unix(1, 0), time.Date(2030, time.August, 11, 0, 0, 0, 0, time.UTC))
+func (ctrp *ClassTaskRepositoryPostgres) queryTasks(ctx context.Context, query string, args ...any) ([]*domain.ClassTask, error) {
+	rows, err := database.Conn(ctx, ctrp.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tasks := make([]*domain.ClassTask, 0)
+	for rows.Next() {
+		ct, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, ct)
+	}
+	return tasks, rows.Err()
 }
 
-func (ctrp *ClassTaskRepositoryPostgres) GetTasksWithRange(ctx context.Context, classId string, from, to time.Time) ([]*domain.ClassTask, error) {
-	tasks := make([]*domain.ClassTask, 0)
-	rows, err := ctrp.pool.Query(
+// occurrences expand series into their occurrences in [from, to) which are not stored
+func (ctrp *ClassTaskRepositoryPostgres) occurrences(ctx context.Context, series []*domain.ClassTask, from, to time.Time) ([]*domain.ClassTask, error) {
+	if len(series) == 0 {
+		return nil, nil
+	}
+	seriesIds := make([]string, 0, len(series))
+	for _, s := range series {
+		seriesIds = append(seriesIds, s.TaskId)
+	}
+	rows, err := database.Conn(ctx, ctrp.pool).Query(
 		ctx,
-		"SELECT task_id, author_id, created_at, author_display_name, name, description, due_date FROM class_task WHERE class_id = $1 AND due_date >= $2 AND due_date < $3 ORDER BY task_id",
-		classId,
-		from,
-		to,
+		"SELECT task_id FROM class_task WHERE series_id = ANY($1)",
+		seriesIds,
 	)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+	stored := make(map[string]bool)
 	for rows.Next() {
-		ct := &domain.ClassTask{
-			ClassId: classId,
-		}
-		err := rows.Scan(
-			&ct.TaskId,
-			&ct.AuthorId,
-			&ct.CreatedAt,
-			&ct.AuthorDisplayName,
-			&ct.Name,
-			&ct.Description,
-			&ct.DueDate,
-		)
-		if err != nil {
+		var taskId string
+		if err := rows.Scan(&taskId); err != nil {
 			return nil, err
 		}
-		tasks = append(tasks, ct)
+		stored[taskId] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	occurrences := make([]*domain.ClassTask, 0)
+	for _, s := range series {
+		occurrences = append(occurrences, s.Occurrences(from, to, stored)...)
+	}
+	return occurrences, nil
+}
+
+func (ctrp *ClassTaskRepositoryPostgres) GetTasks(ctx context.Context, classId string) ([]*domain.ClassTask, error) {
+	tasks, _, err := ctrp.FindTasks(ctx, classId, domain.ClassTaskFilter{}, domain.Pagination{})
+	return tasks, err
+}
+
+func (ctrp *ClassTaskRepositoryPostgres) GetTasksWithRange(ctx context.Context, classId string, from, to time.Time, page domain.Pagination) ([]*domain.ClassTask, string, error) {
+	return ctrp.FindTasks(ctx, classId, domain.ClassTaskFilter{From: from, To: to}, page)
+}
+
+func (ctrp *ClassTaskRepositoryPostgres) FindTasks(ctx context.Context, classId string, filter domain.ClassTaskFilter, page domain.Pagination) ([]*domain.ClassTask, string, error) {
+	column := ""
+	switch strings.TrimPrefix(filter.Sort, "-") {
+	case domain.ClassTaskSortDueDate:
+		column = "due_date"
+	case domain.ClassTaskSortCreatedAt:
+		column = "created_at"
+	}
+	keys := 1
+	if column != "" {
+		keys = 2
+	}
+	after, err := page.Keys(keys)
+	if err != nil {
+		return nil, "", err
 	}
+
+	cond := ""
+	args := []any{classId}
+	where := func(c string, arg ...any) {
+		n := make([]any, 0, len(arg))
+		for _, a := range arg {
+			args = append(args, a)
+			n = append(n, len(args))
+		}
+		cond += fmt.Sprintf(" AND "+c, n...)
+	}
+	if filter.Query != "" {
+		q := strings.ToLower(strings.TrimSpace(filter.Query))
+		args = append(args, database.LikePrefix(q))
+		like := len(args)
+		var words string
+		words, args = database.WordPrefix("LOWER(name || ' ' || description)", domain.SearchTerms(q), args)
+		cond += fmt.Sprintf(` AND (LOWER(name) LIKE $%d ESCAPE '\' OR %s)`, like, words)
+	}
+	if filter.AuthorId != "" {
+		where("author_id = $%d", filter.AuthorId)
+	}
+
+	// series are expanded here, only the conditions above apply to them
+	from, to := filter.OccurrenceRange()
+	seriesQuery := "SELECT " + taskColumns + " FROM class_task WHERE class_id = $1 AND recurrence <> '' AND NOT skipped" + cond + fmt.Sprintf(" AND due_date < $%d", len(args)+1)
+	series, err := ctrp.queryTasks(ctx, seriesQuery, append(args, to)...)
+	if err != nil {
+		return nil, "", err
+	}
+	occurrences, err := ctrp.occurrences(ctx, series, from, to)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !filter.From.IsZero() {
+		where("due_date >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("due_date < $%d", filter.To)
+	}
+	if filter.Status != "" || filter.Overdue {
+		// task without progress of the user is todo
+		status := "'" + domain.ClassTaskStatusTodo + "'"
+		if filter.UserId != "" {
+			args = append(args, filter.UserId)
+			status = fmt.Sprintf("COALESCE((SELECT status FROM class_task_progress p WHERE p.task_id = class_task.task_id AND p.user_id = $%d), %s)", len(args), status)
+		}
+		if filter.Status != "" {
+			where(status+" = $%d", filter.Status)
+		}
+		if filter.Overdue {
+			where("due_date < $%d AND "+status+" <> '"+domain.ClassTaskStatusDone+"'", filter.Now)
+		}
+	}
+
+	// task_id contain '_' of occurrence id, compare it byte by byte like the occurrences
+	op, direction := ">", ""
+	if filter.Descending() {
+		op, direction = "<", " DESC"
+	}
+	if after != nil {
+		if column == "" {
+			where(`task_id COLLATE "C" `+op+" $%d", after[0])
+		} else {
+			t, err := domain.ParseCursorTime(after[0])
+			if err != nil {
+				return nil, "", err
+			}
+			where("("+column+`, task_id COLLATE "C") `+op+" ($%d, $%d)", t, after[1])
+		}
+	}
+	query := "SELECT " + taskColumns + " FROM class_task WHERE class_id = $1 AND recurrence = '' AND NOT skipped" + cond
+	if column != "" {
+		query += " ORDER BY " + column + direction + `, task_id COLLATE "C"` + direction
+	} else {
+		query += ` ORDER BY task_id COLLATE "C"` + direction
+	}
+	if page.Limit > 0 {
+		args = append(args, page.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	tasks, err := ctrp.queryTasks(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// occurrence of a task is never done by anyone, it is stored before its progress
+	for _, occurrence := range occurrences {
+		if filter.Match(occurrence, domain.ClassTaskStatusTodo) && (after == nil || filter.After(occurrence, after)) {
+			tasks = append(tasks, occurrence)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return filter.Less(tasks[i], tasks[j])
+	})
+	if page.Limit > 0 && len(tasks) > page.Limit+1 {
+		tasks = tasks[:page.Limit+1]
+	}
+	tasks, next := domain.NextPage(tasks, page, filter.TaskKey)
+	return tasks, next, nil
+}
+
+func (ctrp *ClassTaskRepositoryPostgres) GetTasksByClassIds(ctx context.Context, classIds []string, from, to time.Time) ([]*domain.ClassTask, error) {
+	tasks, err := ctrp.queryTasks(
+		ctx,
+		"SELECT "+taskColumns+" FROM class_task WHERE class_id = ANY($1) AND due_date >= $2 AND due_date < $3 AND recurrence = '' AND NOT skipped",
+		classIds,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	series, err := ctrp.queryTasks(
+		ctx,
+		"SELECT "+taskColumns+" FROM class_task WHERE class_id = ANY($1) AND due_date < $2 AND recurrence <> '' AND NOT skipped",
+		classIds,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	occurrences, err := ctrp.occurrences(ctx, series, from, to)
+	if err != nil {
+		return nil, err
+	}
+	tasks = append(tasks, occurrences...)
+
+	order := domain.ClassTaskFilter{Sort: domain.ClassTaskSortDueDate}
+	sort.Slice(tasks, func(i, j int) bool {
+		return order.Less(tasks[i], tasks[j])
+	})
 	return tasks, nil
 }
 
+func (ctrp *ClassTaskRepositoryPostgres) CreateOccurrence(ctx context.Context, task *domain.ClassTask) error {
+	if task.SeriesId == "" {
+		return errors.New("not an occurrence")
+	}
+	_, err := database.Conn(ctx, ctrp.pool).Exec(
+		ctx,
+		`INSERT INTO class_task(task_id, class_id, author_id, created_at, author_display_name, name, description, due_date, series_id)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (task_id) DO NOTHING`,
+		task.TaskId,
+		task.ClassId,
+		task.AuthorId,
+		task.CreatedAt,
+		task.AuthorDisplayName,
+		task.Name,
+		task.Description,
+		task.DueDate,
+		task.SeriesId,
+	)
+	return err
+}
+
+func (ctrp *ClassTaskRepositoryPostgres) SkipOccurrence(ctx context.Context, task *domain.ClassTask) error {
+	if task.SeriesId == "" {
+		return errors.New("not an occurrence")
+	}
+	_, err := database.Conn(ctx, ctrp.pool).Exec(
+		ctx,
+		`INSERT INTO class_task(task_id, class_id, author_id, created_at, author_display_name, name, description, due_date, series_id, skipped)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, TRUE) ON CONFLICT (task_id) DO UPDATE SET skipped = TRUE`,
+		task.TaskId,
+		task.ClassId,
+		task.AuthorId,
+		task.CreatedAt,
+		task.AuthorDisplayName,
+		task.Name,
+		task.Description,
+		task.DueDate,
+		task.SeriesId,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = database.Conn(ctx, ctrp.pool).Exec(
+		ctx,
+		"DELETE FROM class_task_progress WHERE task_id = $1",
+		task.TaskId,
+	)
+	return err
+}
+
+func (ctrp *ClassTaskRepositoryPostgres) GetOccurrences(ctx context.Context, seriesId string) ([]*domain.ClassTask, error) {
+	return ctrp.queryTasks(
+		ctx,
+		"SELECT "+taskColumns+` FROM class_task WHERE series_id = $1 ORDER BY task_id COLLATE "C"`,
+		seriesId,
+	)
+}
+
+func (ctrp *ClassTaskRepositoryPostgres) GetSeriesByClassIds(ctx context.Context, classIds []string) ([]*domain.ClassTask, error) {
+	return ctrp.queryTasks(
+		ctx,
+		"SELECT "+taskColumns+` FROM class_task WHERE class_id = ANY($1) AND (recurrence <> '' OR series_id IS NOT NULL) ORDER BY task_id COLLATE "C"`,
+		classIds,
+	)
+}
+
 func (ctrp *ClassTaskRepositoryPostgres) UpdateTask(ctx context.Context, task *domain.ClassTask) error {
 	ct, err := ctrp.GetTask(ctx, task.TaskId)
 	if err != nil {
 		return err
 	}
 	ct.Update(task)
-	_, err = ctrp.pool.Exec(
+	// database only store microsecond
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	task.UpdatedAt = &now
+	var updatedBy any
+	if ct.UpdatedBy != "" {
+		updatedBy = ct.UpdatedBy
+	}
+	_, err = database.Conn(ctx, ctrp.pool).Exec(
 		ctx,
-		"UPDATE class_task SET name = $1, description = $2, due_date = $3 WHERE task_id = $4",
+		"UPDATE class_task SET name = $1, description = $2, due_date = $3, updated_at = $4, updated_by = $5, recurrence = $6, edited = series_id IS NOT NULL WHERE task_id = $7",
 		ct.Name,
 		ct.Description,
 		ct.DueDate,
+		now,
+		updatedBy,
+		ct.Recurrence,
 		ct.TaskId,
 	)
-	return err
+	if err != nil || !ct.Recurring() {
+		return err
+	}
+
+	// stored occurrences which are not edited on their own follow the series
+	ct.UpdatedAt = &now
+	occurrences, err := ctrp.queryTasks(
+		ctx,
+		"SELECT "+taskColumns+" FROM class_task WHERE series_id = $1 AND NOT edited AND NOT skipped",
+		ct.TaskId,
+	)
+	if err != nil {
+		return err
+	}
+	for _, occurrence := range occurrences {
+		occurrence.Follow(ct)
+		_, err := database.Conn(ctx, ctrp.pool).Exec(
+			ctx,
+			"UPDATE class_task SET author_display_name = $1, name = $2, description = $3, due_date = $4, updated_at = $5, updated_by = $6 WHERE task_id = $7",
+			occurrence.AuthorDisplayName,
+			occurrence.Name,
+			occurrence.Description,
+			occurrence.DueDate,
+			occurrence.UpdatedAt,
+			updatedBy,
+			occurrence.TaskId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (ctrp *ClassTaskRepositoryPostgres) DeleteTask(ctx context.Context, taskId string) error {
-	_, err := ctrp.pool.Exec(
+	_, err := database.Conn(ctx, ctrp.pool).Exec(
 		ctx,
 		"DELETE FROM class_task WHERE task_id = $1",
 		taskId,
 	)
 	return err
 }
+
+func (ctrp *ClassTaskRepositoryPostgres) SetProgress(ctx context.Context, progress *domain.ClassTaskProgress) error {
+	progress.UpdatedAt = time.Now().UTC()
+	_, err := database.Conn(ctx, ctrp.pool).Exec(
+		ctx,
+		`INSERT INTO class_task_progress(task_id, user_id, updated_at, status, completed_at) VALUES($1, $2, $3, $4, $5)
+		ON CONFLICT (task_id, user_id) DO UPDATE SET updated_at = excluded.updated_at, status = excluded.status, completed_at = excluded.completed_at`,
+		progress.TaskId,
+		progress.UserId,
+		progress.UpdatedAt,
+		progress.Status,
+		progress.CompletedAt,
+	)
+	if pgerr, ok := err.(*pgconn.PgError); ok && pgerr.Code == "23503" {
+		return domain.ErrClassTaskNotExists
+	}
+	return err
+}
+
+func (ctrp *ClassTaskRepositoryPostgres) GetProgress(ctx context.Context, taskId, userId string) (*domain.ClassTaskProgress, error) {
+	progress := &domain.ClassTaskProgress{
+		TaskId: taskId,
+		UserId: userId,
+	}
+	row := database.Conn(ctx, ctrp.pool).QueryRow(
+		ctx,
+		"SELECT updated_at, status, completed_at FROM class_task_progress WHERE task_id = $1 AND user_id = $2",
+		taskId,
+		userId,
+	)
+	err := row.Scan(
+		&progress.UpdatedAt,
+		&progress.Status,
+		&progress.CompletedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrClassTaskProgressNotExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (ctrp *ClassTaskRepositoryPostgres) DeleteUserProgress(ctx context.Context, classId, userId string) error {
+	_, err := database.Conn(ctx, ctrp.pool).Exec(
+		ctx,
+		"DELETE FROM class_task_progress WHERE user_id = $1 AND task_id IN (SELECT task_id FROM class_task WHERE class_id = $2)",
+		userId,
+		classId,
+	)
+	return err
+}
+
+// progressOfMembers join class_task_progress p with class_member m, progress left by
+// user who is no longer a member of the class is ignored
+const progressOfMembers = `class_task_progress p
+	JOIN class_task t ON t.task_id = p.task_id
+	JOIN class_member m ON m.class_id = t.class_id AND m.user_id = p.user_id`
+
+func (ctrp *ClassTaskRepositoryPostgres) GetUserProgress(ctx context.Context, userId string, taskIds []string) ([]*domain.ClassTaskProgress, error) {
+	result := make([]*domain.ClassTaskProgress, 0)
+	rows, err := database.Conn(ctx, ctrp.pool).Query(
+		ctx,
+		"SELECT p.task_id, p.updated_at, p.status, p.completed_at FROM "+progressOfMembers+" WHERE p.user_id = $1 AND p.task_id = ANY($2)",
+		userId,
+		taskIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		progress := &domain.ClassTaskProgress{
+			UserId: userId,
+		}
+		err := rows.Scan(
+			&progress.TaskId,
+			&progress.UpdatedAt,
+			&progress.Status,
+			&progress.CompletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, progress)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (ctrp *ClassTaskRepositoryPostgres) GetProgressSummary(ctx context.Context, taskId string) (*domain.ClassTaskSummary, error) {
+	summary := &domain.ClassTaskSummary{
+		TaskId: taskId,
+	}
+	rows, err := database.Conn(ctx, ctrp.pool).Query(
+		ctx,
+		"SELECT p.status, COUNT(*) FROM "+progressOfMembers+" WHERE p.task_id = $1 GROUP BY p.status",
+		taskId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		switch status {
+		case domain.ClassTaskStatusInProgress:
+			summary.InProgress = count
+		case domain.ClassTaskStatusDone:
+			summary.Done = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
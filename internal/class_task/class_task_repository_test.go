@@ -8,6 +8,7 @@ import (
 
 	"nory/domain"
 	"nory/internal/class"
+	classmember "nory/internal/class_member"
 	. "nory/internal/class_task"
 	"nory/internal/user"
 
@@ -32,17 +33,19 @@ func TestClassTaskRepository(t *testing.T) {
 
 	repos := []Repository{
 		{
-			Name:                "memory",
-			ClassTaskRepository: NewClassTaskRepositoryMem(),
-			ClassRepository:     class.NewClassRepositoryMem(),
-			UserRepository:      user.NewUserRepositoryMem(),
+			Name:                  "memory",
+			ClassTaskRepository:   NewClassTaskRepositoryMem(),
+			ClassRepository:       class.NewClassRepositoryMem(),
+			UserRepository:        user.NewUserRepositoryMem(),
+			ClassMemberRepository: classmember.NewClassMemberRepositoryMem(),
 		},
 		{
-			Name:                "postgres",
-			ClassTaskRepository: NewClassTaskRepositoryPostgres(pool),
-			ClassRepository:     class.NewClassRepositoryPostgres(pool),
-			UserRepository:      user.NewUserRepositoryPostgres(pool),
-			Skip:                os.Getenv("DATABASE_URL") == "",
+			Name:                  "postgres",
+			ClassTaskRepository:   NewClassTaskRepositoryPostgres(pool),
+			ClassRepository:       class.NewClassRepositoryPostgres(pool),
+			UserRepository:        user.NewUserRepositoryPostgres(pool),
+			ClassMemberRepository: classmember.NewClassMemberRepositoryPostgres(pool),
+			Skip:                  os.Getenv("DATABASE_URL") == "",
 		},
 	}
 
@@ -58,18 +61,24 @@ func TestClassTaskRepository(t *testing.T) {
 			t.Run("GetTask", repo.testGetTask)
 			t.Run("GetTasks", repo.testGetTasks)
 			t.Run("GetTasksWithRange", repo.testGetTasksWithRange)
+			t.Run("GetTasksByClassIds", repo.testGetTasksByClassIds)
+			t.Run("FindTasks", repo.testFindTasks)
+			t.Run("Recurrence", repo.testRecurrence)
 			t.Run("UpdateTask", repo.testUpdateTasks)
+			t.Run("Progress", repo.testProgress)
+			t.Run("DeleteUserProgress", repo.testDeleteUserProgress)
 			t.Run("DeleteTask", repo.testDeleteTask)
 		})
 	}
 }
 
 type Repository struct {
-	Name                string
-	ClassTaskRepository domain.ClassTaskRepository
-	ClassRepository     domain.ClassRepository
-	UserRepository      domain.UserRepository
-	Skip                bool
+	Name                  string
+	ClassTaskRepository   domain.ClassTaskRepository
+	ClassRepository       domain.ClassRepository
+	UserRepository        domain.UserRepository
+	ClassMemberRepository domain.ClassMemberRepository
+	Skip                  bool
 
 	tasks   []domain.ClassTask
 	classes map[string]*domain.Class
@@ -176,6 +185,286 @@ func (r *Repository) testGetTasks(t *testing.T) {
 	}
 }
 
+func (r *Repository) testGetTasksByClassIds(t *testing.T) {
+	foo, bar, baz, qux := r.getClass("foo"), r.getClass("bar"), r.getClass("baz"), r.getClass("qux")
+	testCases := []struct {
+		Name     string
+		ClassIds []string
+		From     time.Time
+		To       time.Time
+		Len      int
+	}{
+		{"all", []string{foo, bar, baz}, SpecialDate, Tomorrow, 4},
+		{"subset", []string{foo, qux}, SpecialDate, Tomorrow, 2},
+		{"range", []string{foo, bar, baz}, Now, Tomorrow, 2},
+		{"empty", []string{}, SpecialDate, Tomorrow, 0},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			tasks, err := r.ClassTaskRepository.GetTasksByClassIds(context.Background(), tc.ClassIds, tc.From, tc.To)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.Len, len(tasks), "unexpected result length")
+			for i, task := range tasks {
+				assert.Contains(t, tc.ClassIds, task.ClassId, "unknown ClassId")
+				if i > 0 {
+					assert.False(t, task.DueDate.Before(tasks[i-1].DueDate), "tasks should be ordered by DueDate")
+				}
+			}
+		})
+	}
+}
+
+func (r *Repository) testFindTasks(t *testing.T) {
+	classId := r.getClass("find")
+	alice, bob := r.getUser("find"), r.getUser("find bob")
+	day := 24 * time.Hour
+	tasks := []*domain.ClassTask{
+		{ClassId: classId, AuthorId: alice, Name: "Essay draft", Description: "history", DueDate: Now.Add(-2 * day)},
+		{ClassId: classId, AuthorId: bob, Name: "Lab report", Description: "chemistry essay", DueDate: Now.Add(day)},
+		{ClassId: classId, AuthorId: alice, Name: "Reading", DueDate: Now.Add(3 * day)},
+		{ClassId: classId, AuthorId: bob, Name: "Quiz", DueDate: Now.Add(-day)},
+	}
+	for _, task := range tasks {
+		err := r.ClassTaskRepository.CreateTask(context.Background(), task)
+		assert.Nil(t, err)
+	}
+	for i, status := range []string{domain.ClassTaskStatusDone, domain.ClassTaskStatusInProgress} {
+		err := r.ClassTaskRepository.SetProgress(context.Background(), &domain.ClassTaskProgress{
+			TaskId: tasks[i].TaskId,
+			UserId: alice,
+			Status: status,
+		})
+		assert.Nil(t, err)
+	}
+
+	testCases := []struct {
+		Name   string
+		Filter domain.ClassTaskFilter
+		Want   []int
+	}{
+		{"every task", domain.ClassTaskFilter{}, []int{0, 1, 2, 3}},
+		{"query name and description", domain.ClassTaskFilter{Query: "ESSAY"}, []int{0, 1}},
+		{"query every term", domain.ClassTaskFilter{Query: "lab rep"}, []int{1}},
+		{"query no match", domain.ClassTaskFilter{Query: "math"}, []int{}},
+		{"author", domain.ClassTaskFilter{AuthorId: bob}, []int{1, 3}},
+		{"due date range", domain.ClassTaskFilter{From: Now, To: Now.Add(2 * day)}, []int{1}},
+		{"status done", domain.ClassTaskFilter{UserId: alice, Status: domain.ClassTaskStatusDone}, []int{0}},
+		{"status in progress", domain.ClassTaskFilter{UserId: alice, Status: domain.ClassTaskStatusInProgress}, []int{1}},
+		{"status todo without progress", domain.ClassTaskFilter{UserId: alice, Status: domain.ClassTaskStatusTodo}, []int{2, 3}},
+		{"status of other user", domain.ClassTaskFilter{UserId: bob, Status: domain.ClassTaskStatusTodo}, []int{0, 1, 2, 3}},
+		{"overdue skip done", domain.ClassTaskFilter{UserId: alice, Overdue: true, Now: Now}, []int{3}},
+		{"overdue without user", domain.ClassTaskFilter{Overdue: true, Now: Now}, []int{0, 3}},
+		{"sort due date", domain.ClassTaskFilter{Sort: domain.ClassTaskSortDueDate}, []int{0, 3, 1, 2}},
+		{"sort due date descending", domain.ClassTaskFilter{Sort: "-" + domain.ClassTaskSortDueDate}, []int{2, 1, 3, 0}},
+		{"sort created date", domain.ClassTaskFilter{Sort: domain.ClassTaskSortCreatedAt}, []int{0, 1, 2, 3}},
+		{"sort created date descending", domain.ClassTaskFilter{Sort: "-" + domain.ClassTaskSortCreatedAt}, []int{3, 2, 1, 0}},
+		{"combined", domain.ClassTaskFilter{Query: "essay", AuthorId: alice, UserId: alice, Status: domain.ClassTaskStatusDone}, []int{0}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			want := make([]string, 0)
+			for _, i := range tc.Want {
+				want = append(want, tasks[i].TaskId)
+			}
+
+			got, _, err := r.ClassTaskRepository.FindTasks(context.Background(), classId, tc.Filter, domain.Pagination{})
+			assert.Nil(t, err)
+			ids := make([]string, 0)
+			for _, task := range got {
+				ids = append(ids, task.TaskId)
+			}
+			assert.Equal(t, want, ids)
+
+			// walking page by page give the same order
+			ids = make([]string, 0)
+			page := domain.Pagination{Limit: 1}
+			for i := 0; i <= len(tasks); i++ {
+				got, next, err := r.ClassTaskRepository.FindTasks(context.Background(), classId, tc.Filter, page)
+				assert.Nil(t, err)
+				for _, task := range got {
+					ids = append(ids, task.TaskId)
+				}
+				if next == "" {
+					break
+				}
+				page.Cursor = next
+			}
+			assert.Equal(t, want, ids, "paginated")
+		})
+	}
+
+	_, _, err := r.ClassTaskRepository.FindTasks(context.Background(), classId, domain.ClassTaskFilter{Sort: domain.ClassTaskSortDueDate}, domain.Pagination{Cursor: domain.EncodeCursor("foo")})
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
+
+func (r *Repository) testRecurrence(t *testing.T) {
+	classId := r.getClass("recurrence")
+	author := r.getUser("recurrence")
+	// 2030-01-07 is a monday
+	monday := time.Date(2030, time.January, 7, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	series := &domain.ClassTask{
+		ClassId:    classId,
+		AuthorId:   author,
+		Name:       "quiz",
+		DueDate:    monday,
+		Recurrence: "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+	}
+	err := r.ClassTaskRepository.CreateTask(context.Background(), series)
+	assert.Nil(t, err)
+	once := &domain.ClassTask{ClassId: classId, AuthorId: author, Name: "essay", DueDate: monday.Add(day)}
+	err = r.ClassTaskRepository.CreateTask(context.Background(), once)
+	assert.Nil(t, err)
+
+	list := func(filter domain.ClassTaskFilter) []string {
+		t.Helper()
+		tasks, _, err := r.ClassTaskRepository.FindTasks(context.Background(), classId, filter, domain.Pagination{})
+		assert.Nil(t, err)
+		ids := make([]string, 0)
+		for _, task := range tasks {
+			assert.Equal(t, "", task.Recurrence, "series should not be listed")
+			ids = append(ids, task.TaskId)
+		}
+		return ids
+	}
+	occurrence := func(date time.Time) string {
+		return domain.OccurrenceId(series.TaskId, date)
+	}
+	week := domain.ClassTaskFilter{From: monday, To: monday.Add(7 * day), Sort: domain.ClassTaskSortDueDate}
+	whole := domain.ClassTaskFilter{From: monday, To: monday.Add(30 * day), Sort: domain.ClassTaskSortDueDate}
+
+	assert.Equal(t, []string{occurrence(monday), once.TaskId, occurrence(monday.Add(2 * day))}, list(week))
+	assert.Equal(t, []string{
+		occurrence(monday),
+		once.TaskId,
+		occurrence(monday.Add(2 * day)),
+		occurrence(monday.Add(7 * day)),
+		occurrence(monday.Add(9 * day)),
+	}, list(whole), "COUNT should end the series")
+	assert.Equal(t, []string{occurrence(monday.Add(9 * day))}, list(domain.ClassTaskFilter{From: monday.Add(8 * day), To: monday.Add(30 * day)}))
+
+	tasks, err := r.ClassTaskRepository.GetTasksByClassIds(context.Background(), []string{classId}, monday, monday.Add(3*day))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(tasks), "GetTasksByClassIds should expand series")
+
+	got, err := r.ClassTaskRepository.GetTask(context.Background(), occurrence(monday.Add(2*day)))
+	if assert.Nil(t, err) {
+		assert.Equal(t, series.TaskId, got.SeriesId)
+		assert.Equal(t, "quiz", got.Name)
+		assert.True(t, got.DueDate.Equal(monday.Add(2*day)))
+	}
+	for _, id := range []string{occurrence(monday.Add(day)), occurrence(monday.Add(14 * day)), once.TaskId + "_20300107", series.TaskId + "_foo"} {
+		_, err = r.ClassTaskRepository.GetTask(context.Background(), id)
+		assert.Equal(t, domain.ErrClassTaskNotExists, err, id)
+	}
+
+	// edit a single occurrence
+	err = r.ClassTaskRepository.CreateOccurrence(context.Background(), got)
+	assert.Nil(t, err)
+	err = r.ClassTaskRepository.CreateOccurrence(context.Background(), got)
+	assert.Nil(t, err, "storing occurrence twice should do nothing")
+	err = r.ClassTaskRepository.UpdateTask(context.Background(), &domain.ClassTask{TaskId: got.TaskId, Name: "moved quiz", DueDate: monday.Add(3 * day)})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{occurrence(monday), once.TaskId, occurrence(monday.Add(2 * day))}, list(week))
+	edited, err := r.ClassTaskRepository.GetTask(context.Background(), got.TaskId)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "moved quiz", edited.Name)
+		assert.Equal(t, series.TaskId, edited.SeriesId)
+	}
+	err = r.ClassTaskRepository.SetProgress(context.Background(), &domain.ClassTaskProgress{TaskId: got.TaskId, UserId: author, Status: domain.ClassTaskStatusDone})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{got.TaskId}, list(domain.ClassTaskFilter{From: monday, To: monday.Add(7 * day), UserId: author, Status: domain.ClassTaskStatusDone}))
+
+	// skip a single occurrence
+	first, err := r.ClassTaskRepository.GetTask(context.Background(), occurrence(monday))
+	assert.Nil(t, err)
+	err = r.ClassTaskRepository.SkipOccurrence(context.Background(), first)
+	assert.Nil(t, err)
+	err = r.ClassTaskRepository.SkipOccurrence(context.Background(), edited)
+	assert.Nil(t, err)
+	_, err = r.ClassTaskRepository.GetTask(context.Background(), occurrence(monday))
+	assert.Equal(t, domain.ErrClassTaskNotExists, err)
+	_, err = r.ClassTaskRepository.GetProgress(context.Background(), edited.TaskId, author)
+	assert.Equal(t, domain.ErrClassTaskProgressNotExists, err, "progress of skipped occurrence should be deleted")
+	assert.Equal(t, []string{once.TaskId}, list(week))
+
+	occurrences, err := r.ClassTaskRepository.GetOccurrences(context.Background(), series.TaskId)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(occurrences))
+	all, err := r.ClassTaskRepository.GetSeriesByClassIds(context.Background(), []string{classId, r.getClass("foo")})
+	if assert.Nil(t, err) && assert.Equal(t, 3, len(all)) {
+		assert.Equal(t, series.TaskId, all[0].TaskId)
+		assert.Equal(t, []string{first.TaskId, edited.TaskId}, []string{all[1].TaskId, all[2].TaskId})
+		assert.True(t, all[1].Skipped)
+	}
+
+	// occurrence stored for its progress follow the series, edited occurrence does not
+	worked, err := r.ClassTaskRepository.GetTask(context.Background(), occurrence(monday.Add(7*day)))
+	assert.Nil(t, err)
+	err = r.ClassTaskRepository.CreateOccurrence(context.Background(), worked)
+	assert.Nil(t, err)
+	err = r.ClassTaskRepository.SetProgress(context.Background(), &domain.ClassTaskProgress{TaskId: worked.TaskId, UserId: author, Status: domain.ClassTaskStatusInProgress})
+	assert.Nil(t, err)
+	retake, err := r.ClassTaskRepository.GetTask(context.Background(), occurrence(monday.Add(9*day)))
+	assert.Nil(t, err)
+	err = r.ClassTaskRepository.CreateOccurrence(context.Background(), retake)
+	assert.Nil(t, err)
+	err = r.ClassTaskRepository.UpdateTask(context.Background(), &domain.ClassTask{TaskId: retake.TaskId, Name: "retake"})
+	assert.Nil(t, err)
+
+	// edit the whole series
+	err = r.ClassTaskRepository.UpdateTask(context.Background(), &domain.ClassTask{
+		TaskId:      series.TaskId,
+		Name:        "weekly quiz",
+		Description: "chapter 2",
+		DueDate:     monday.Add(9 * time.Hour),
+		Recurrence:  "FREQ=DAILY;COUNT=3",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{once.TaskId, occurrence(monday.Add(day))}, list(week))
+	got, err = r.ClassTaskRepository.GetTask(context.Background(), worked.TaskId)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "weekly quiz", got.Name)
+		assert.Equal(t, "chapter 2", got.Description)
+		assert.True(t, got.DueDate.Equal(monday.Add(7*day+9*time.Hour)), got.DueDate)
+	}
+	_, err = r.ClassTaskRepository.GetProgress(context.Background(), worked.TaskId, author)
+	assert.Nil(t, err, "progress should be kept when the series is updated")
+	got, err = r.ClassTaskRepository.GetTask(context.Background(), retake.TaskId)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "retake", got.Name)
+		assert.Equal(t, "", got.Description)
+		assert.True(t, got.DueDate.Equal(monday.Add(9*day)), got.DueDate)
+	}
+
+	// pages walk through occurrences and tasks together
+	ids := make([]string, 0)
+	page := domain.Pagination{Limit: 1}
+	for i := 0; i < 5; i++ {
+		tasks, next, err := r.ClassTaskRepository.FindTasks(context.Background(), classId, domain.ClassTaskFilter{From: monday, To: monday.Add(7 * day)}, page)
+		assert.Nil(t, err)
+		for _, task := range tasks {
+			ids = append(ids, task.TaskId)
+		}
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+	assert.ElementsMatch(t, []string{once.TaskId, occurrence(monday.Add(day))}, ids)
+
+	// delete the whole series
+	err = r.ClassTaskRepository.DeleteTask(context.Background(), series.TaskId)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{once.TaskId}, list(whole))
+	occurrences, err = r.ClassTaskRepository.GetOccurrences(context.Background(), series.TaskId)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(occurrences))
+}
+
 func (r *Repository) testGetTasksWithRange(t *testing.T) {
 	testCases := []struct {
 		Name    string
@@ -201,7 +490,7 @@ func (r *Repository) testGetTasksWithRange(t *testing.T) {
 		t.Run(tc.Name, func(t *testing.T) {
 			t.Helper()
 			id := r.getClass(tc.ClassId)
-			tasks, err := r.ClassTaskRepository.GetTasksWithRange(context.Background(), id, tc.From, tc.To)
+			tasks, _, err := r.ClassTaskRepository.GetTasksWithRange(context.Background(), id, tc.From, tc.To, domain.Pagination{})
 			assert.Equal(t, tc.Err, err, "missmatch error")
 			if !assert.Equal(t, tc.Len, len(tasks), "unexpected result length") {
 				for _, task := range tasks {
@@ -231,12 +520,14 @@ func (r *Repository) testUpdateTasks(t *testing.T) {
 			before := tc.Task
 			before.Name = "Abelia"
 			before.Description = xid.New().String()
+			before.UpdatedBy = r.getUser("foo")
 
 			err := r.ClassTaskRepository.UpdateTask(context.Background(), &before)
 			assert.Equal(t, tc.Err, err)
 			if err != nil {
 				return
 			}
+			assert.NotNil(t, before.UpdatedAt, "UpdateTask must update (ClassTask).UpdatedAt")
 
 			after, err := r.ClassTaskRepository.GetTask(context.Background(), before.TaskId)
 			after.CreatedAt = time.Time{}
@@ -247,6 +538,72 @@ func (r *Repository) testUpdateTasks(t *testing.T) {
 	}
 }
 
+func (r *Repository) testProgress(t *testing.T) {
+	task := r.tasks[0]
+	foo := r.getUser("foo")
+	bar := r.getUser("bar")
+
+	_, err := r.ClassTaskRepository.GetProgress(context.Background(), task.TaskId, foo)
+	assert.Equal(t, domain.ErrClassTaskProgressNotExists, err)
+	// only progress of class members is counted
+	for _, userId := range []string{foo, bar} {
+		err := r.ClassMemberRepository.CreateMember(context.Background(), &domain.ClassMember{ClassId: task.ClassId, UserId: userId, Level: domain.RoleMember})
+		assert.Nil(t, err)
+	}
+
+	completedAt := Now
+	for _, p := range []domain.ClassTaskProgress{
+		{TaskId: task.TaskId, UserId: foo, Status: domain.ClassTaskStatusInProgress},
+		{TaskId: task.TaskId, UserId: foo, Status: domain.ClassTaskStatusDone, CompletedAt: &completedAt},
+		{TaskId: task.TaskId, UserId: bar, Status: domain.ClassTaskStatusInProgress},
+	} {
+		p := p
+		err := r.ClassTaskRepository.SetProgress(context.Background(), &p)
+		assert.Nil(t, err)
+		assert.False(t, p.UpdatedAt.IsZero(), "SetProgress should update (*ClassTaskProgress).UpdatedAt")
+
+		got, err := r.ClassTaskRepository.GetProgress(context.Background(), p.TaskId, p.UserId)
+		assert.Nil(t, err)
+		assert.Equal(t, p.Status, got.Status)
+		assert.Equal(t, p.CompletedAt != nil, got.CompletedAt != nil)
+	}
+
+	err = r.ClassTaskRepository.SetProgress(context.Background(), &domain.ClassTaskProgress{
+		TaskId: xid.New().String(),
+		UserId: foo,
+		Status: domain.ClassTaskStatusDone,
+	})
+	assert.Equal(t, domain.ErrClassTaskNotExists, err)
+
+	progress, err := r.ClassTaskRepository.GetUserProgress(context.Background(), foo, []string{task.TaskId, r.tasks[1].TaskId})
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(progress)) {
+		assert.Equal(t, domain.ClassTaskStatusDone, progress[0].Status)
+	}
+
+	summary, err := r.ClassTaskRepository.GetProgressSummary(context.Background(), task.TaskId)
+	assert.Nil(t, err)
+	assert.Equal(t, &domain.ClassTaskSummary{TaskId: task.TaskId, InProgress: 1, Done: 1}, summary)
+}
+
+func (r *Repository) testDeleteUserProgress(t *testing.T) {
+	task := r.tasks[0]
+	foo := r.getUser("foo")
+	bar := r.getUser("bar")
+
+	err := r.ClassTaskRepository.DeleteUserProgress(context.Background(), xid.New().String(), foo)
+	assert.Nil(t, err)
+	_, err = r.ClassTaskRepository.GetProgress(context.Background(), task.TaskId, foo)
+	assert.Nil(t, err, "progress in other class should be kept")
+
+	err = r.ClassTaskRepository.DeleteUserProgress(context.Background(), task.ClassId, foo)
+	assert.Nil(t, err)
+	_, err = r.ClassTaskRepository.GetProgress(context.Background(), task.TaskId, foo)
+	assert.Equal(t, domain.ErrClassTaskProgressNotExists, err)
+	_, err = r.ClassTaskRepository.GetProgress(context.Background(), task.TaskId, bar)
+	assert.Nil(t, err, "progress of other user should be kept")
+}
+
 func (r *Repository) testDeleteTask(t *testing.T) {
 	for _, task := range r.tasks {
 		err := r.ClassTaskRepository.DeleteTask(context.Background(), task.TaskId)
@@ -255,4 +612,8 @@ func (r *Repository) testDeleteTask(t *testing.T) {
 		_, err = r.ClassTaskRepository.GetTask(context.Background(), task.TaskId)
 		assert.Equal(t, domain.ErrClassTaskNotExists, err, "failed deleting task")
 	}
+
+	summary, err := r.ClassTaskRepository.GetProgressSummary(context.Background(), r.tasks[0].TaskId)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, summary.Done, "progress should be deleted with the task")
 }
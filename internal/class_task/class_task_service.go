@@ -2,7 +2,6 @@ package classtask
 
 import (
 	"context"
-	"fmt"
 
 	"nory/common/response"
 	"nory/common/validator"
@@ -45,11 +44,16 @@ func (cts *ClassTaskService) DeleteTask(ctx context.Context, taskId, userId stri
 	}
 
 	if class.OwnerId != userId {
-		msg := fmt.Sprintf("user with id %q does not has access to class with id %q", userId, task.ClassId)
-		return nil, response.NewForbidden(msg)
+		return nil, response.NewForbidden("class.forbidden", response.Params{"userId": userId, "classId": task.ClassId})
 	}
 
-	if err := cts.ClassTaskRepository.DeleteTask(ctx, task.TaskId); err != nil {
+	// deleted occurrence is skipped, otherwise the series would expand it again
+	if task.SeriesId != "" {
+		err = cts.ClassTaskRepository.SkipOccurrence(ctx, task)
+	} else {
+		err = cts.ClassTaskRepository.DeleteTask(ctx, task.TaskId)
+	}
+	if err != nil {
 		return nil, err
 	}
 
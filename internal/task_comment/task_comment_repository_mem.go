@@ -0,0 +1,98 @@
+package taskcomment
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type TaskCommentRepositoryMem struct {
+	mx sync.Mutex
+	m  map[string]*domain.TaskComment
+}
+
+func NewTaskCommentRepositoryMem() *TaskCommentRepositoryMem {
+	return &TaskCommentRepositoryMem{
+		m: make(map[string]*domain.TaskComment),
+	}
+}
+
+func (repo *TaskCommentRepositoryMem) CreateComment(ctx context.Context, comment *domain.TaskComment) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	comment.CommentId = xid.New().String()
+	comment.CreatedAt = time.Now().UTC()
+	c := *comment
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, comment.CommentId)
+	repo.m[comment.CommentId] = &c
+	return nil
+}
+
+func (repo *TaskCommentRepositoryMem) GetComment(ctx context.Context, commentId string) (*domain.TaskComment, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	comment, ok := repo.m[commentId]
+	if !ok {
+		return nil, domain.ErrTaskCommentNotExists
+	}
+	c := *comment
+	return &c, nil
+}
+
+func (repo *TaskCommentRepositoryMem) ListComments(ctx context.Context, taskId string, page domain.Pagination) ([]*domain.TaskComment, string, error) {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	comments := make([]*domain.TaskComment, 0)
+	for _, comment := range repo.m {
+		if comment.TaskId == taskId {
+			c := *comment
+			comments = append(comments, &c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return comments[i].CommentId < comments[j].CommentId
+	})
+	return domain.Paginate(comments, page, commentKey)
+}
+
+func commentKey(c *domain.TaskComment) []string {
+	return []string{domain.CursorTime(c.CreatedAt), c.CommentId}
+}
+
+func (repo *TaskCommentRepositoryMem) UpdateComment(ctx context.Context, comment *domain.TaskComment) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	c, ok := repo.m[comment.CommentId]
+	if !ok {
+		return domain.ErrTaskCommentNotExists
+	}
+	database.RestoreOnRollback(ctx, &repo.mx, repo.m, comment.CommentId)
+	updated := *c
+	updated.Body = comment.Body
+	now := time.Now().UTC()
+	updated.UpdatedAt = &now
+	comment.UpdatedAt = &now
+	repo.m[comment.CommentId] = &updated
+	return nil
+}
+
+func (repo *TaskCommentRepositoryMem) DeleteComment(ctx context.Context, commentId string) error {
+	repo.mx.Lock()
+	defer repo.mx.Unlock()
+	for key, c := range repo.m {
+		if key == commentId || c.ParentId == commentId {
+			database.RestoreOnRollback(ctx, &repo.mx, repo.m, key)
+			delete(repo.m, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package taskcomment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+
+	"nory/common/database"
+	"nory/domain"
+)
+
+type TaskCommentRepositoryPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewTaskCommentRepositoryPostgres(pool *pgxpool.Pool) *TaskCommentRepositoryPostgres {
+	return &TaskCommentRepositoryPostgres{pool}
+}
+
+func (repo *TaskCommentRepositoryPostgres) CreateComment(ctx context.Context, comment *domain.TaskComment) error {
+	comment.CommentId = xid.New().String()
+	var parentId any
+	if comment.ParentId != "" {
+		parentId = comment.ParentId
+	}
+	row := database.Conn(ctx, repo.pool).QueryRow(
+		ctx,
+		`INSERT INTO task_comment(comment_id, class_id, task_id, author_id, parent_id, body)
+		VALUES($1, $2, $3, $4, $5, $6) RETURNING created_at`,
+		comment.CommentId,
+		comment.ClassId,
+		comment.TaskId,
+		comment.AuthorId,
+		parentId,
+		comment.Body,
+	)
+	return row.Scan(&comment.CreatedAt)
+}
+
+// commentColumns is the columns read by scanComment
+const commentColumns = "comment_id, class_id, task_id, author_id, parent_id, created_at, body, updated_at"
+
+func scanComment(row pgx.Row) (*domain.TaskComment, error) {
+	comment := &domain.TaskComment{}
+	var parentId *string
+	err := row.Scan(
+		&comment.CommentId,
+		&comment.ClassId,
+		&comment.TaskId,
+		&comment.AuthorId,
+		&parentId,
+		&comment.CreatedAt,
+		&comment.Body,
+		&comment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if parentId != nil {
+		comment.ParentId = *parentId
+	}
+	return comment, nil
+}
+
+func (repo *TaskCommentRepositoryPostgres) GetComment(ctx context.Context, commentId string) (*domain.TaskComment, error) {
+	row := database.Conn(ctx, repo.pool).QueryRow(
+		ctx,
+		"SELECT "+commentColumns+" FROM task_comment WHERE comment_id = $1",
+		commentId,
+	)
+	comment, err := scanComment(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrTaskCommentNotExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (repo *TaskCommentRepositoryPostgres) ListComments(ctx context.Context, taskId string, page domain.Pagination) ([]*domain.TaskComment, string, error) {
+	after, err := page.Keys(2)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "SELECT " + commentColumns + " FROM task_comment WHERE task_id = $1"
+	args := []any{taskId}
+	if after != nil {
+		createdAt, err := domain.ParseCursorTime(after[0])
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, createdAt, after[1])
+		query += " AND (created_at, comment_id) > ($2, $3)"
+	}
+	query += " ORDER BY created_at, comment_id"
+	if page.Limit > 0 {
+		args = append(args, page.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	comments := make([]*domain.TaskComment, 0)
+	rows, err := database.Conn(ctx, repo.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	comments, next := domain.NextPage(comments, page, commentKey)
+	return comments, next, nil
+}
+
+func (repo *TaskCommentRepositoryPostgres) UpdateComment(ctx context.Context, comment *domain.TaskComment) error {
+	// database only store microsecond
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	tag, err := database.Conn(ctx, repo.pool).Exec(
+		ctx,
+		"UPDATE task_comment SET body = $1, updated_at = $2 WHERE comment_id = $3",
+		comment.Body,
+		now,
+		comment.CommentId,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrTaskCommentNotExists
+	}
+	comment.UpdatedAt = &now
+	return nil
+}
+
+// DeleteComment rely on fk_parent to delete the replies
+func (repo *TaskCommentRepositoryPostgres) DeleteComment(ctx context.Context, commentId string) error {
+	_, err := database.Conn(ctx, repo.pool).Exec(
+		ctx,
+		"DELETE FROM task_comment WHERE comment_id = $1",
+		commentId,
+	)
+	return err
+}
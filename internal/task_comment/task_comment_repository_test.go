@@ -0,0 +1,192 @@
+package taskcomment_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"nory/domain"
+	"nory/internal/class"
+	classtask "nory/internal/class_task"
+	. "nory/internal/task_comment"
+	"nory/internal/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskCommentRepository(t *testing.T) {
+	t.Parallel()
+	pool, err := pgxpool.New(context.Background(), os.Getenv("DATABASE_URL"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	repos := []Repository{
+		{
+			Name:                  "memory",
+			TaskCommentRepository: NewTaskCommentRepositoryMem(),
+			ClassTaskRepository:   classtask.NewClassTaskRepositoryMem(),
+			ClassRepository:       class.NewClassRepositoryMem(),
+			UserRepository:        user.NewUserRepositoryMem(),
+		},
+		{
+			Name:                  "postgres",
+			TaskCommentRepository: NewTaskCommentRepositoryPostgres(pool),
+			ClassTaskRepository:   classtask.NewClassTaskRepositoryPostgres(pool),
+			ClassRepository:       class.NewClassRepositoryPostgres(pool),
+			UserRepository:        user.NewUserRepositoryPostgres(pool),
+			Skip:                  os.Getenv("DATABASE_URL") == "",
+		},
+	}
+
+	for _, repo := range repos {
+		repo := repo
+		t.Run(repo.Name, func(t *testing.T) {
+			repo.t = t
+			if repo.Skip {
+				t.Skipf("skipping %s", repo.Name)
+			}
+			t.Parallel()
+			t.Run("CreateComment", repo.testCreateComment)
+			t.Run("GetComment", repo.testGetComment)
+			t.Run("ListComments", repo.testListComments)
+			t.Run("UpdateComment", repo.testUpdateComment)
+			t.Run("DeleteComment", repo.testDeleteComment)
+		})
+	}
+}
+
+type Repository struct {
+	Name                  string
+	TaskCommentRepository domain.TaskCommentRepository
+	ClassTaskRepository   domain.ClassTaskRepository
+	ClassRepository       domain.ClassRepository
+	UserRepository        domain.UserRepository
+	Skip                  bool
+
+	comments []domain.TaskComment
+	task     *domain.ClassTask
+	t        *testing.T
+}
+
+// getTask create a class with a task
+func (r *Repository) getTask() *domain.ClassTask {
+	if r.task != nil {
+		return r.task
+	}
+
+	u := &domain.User{
+		UserId:   uuid.NewString(),
+		Email:    xid.New().String(),
+		Username: xid.New().String(),
+	}
+	err := r.UserRepository.CreateUser(context.Background(), u)
+	assert.Nil(r.t, err)
+
+	c := &domain.Class{
+		Name:    xid.New().String(),
+		OwnerId: u.UserId,
+	}
+	err = r.ClassRepository.CreateClass(context.Background(), c)
+	assert.Nil(r.t, err)
+
+	r.task = &domain.ClassTask{ClassId: c.ClassId, AuthorId: u.UserId, Name: "foo"}
+	err = r.ClassTaskRepository.CreateTask(context.Background(), r.task)
+	assert.Nil(r.t, err)
+	return r.task
+}
+
+func (r *Repository) testCreateComment(t *testing.T) {
+	task := r.getTask()
+	for i, body := range []string{"foo", "bar", "baz"} {
+		comment := domain.TaskComment{ClassId: task.ClassId, TaskId: task.TaskId, AuthorId: task.AuthorId, Body: body}
+		// the last comment reply to the first one
+		if i == 2 {
+			comment.ParentId = r.comments[0].CommentId
+		}
+		err := r.TaskCommentRepository.CreateComment(context.Background(), &comment)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", comment.CommentId, "CreateComment should update (*TaskComment).CommentId")
+		assert.False(t, comment.CreatedAt.IsZero(), "CreateComment should update (*TaskComment).CreatedAt")
+		r.comments = append(r.comments, comment)
+	}
+}
+
+func (r *Repository) testGetComment(t *testing.T) {
+	for _, comment := range r.comments {
+		got, err := r.TaskCommentRepository.GetComment(context.Background(), comment.CommentId)
+		assert.Nil(t, err)
+		assert.Equal(t, comment.TaskId, got.TaskId)
+		assert.Equal(t, comment.ClassId, got.ClassId)
+		assert.Equal(t, comment.ParentId, got.ParentId)
+		assert.Equal(t, comment.Body, got.Body)
+		assert.Nil(t, got.UpdatedAt)
+	}
+
+	_, err := r.TaskCommentRepository.GetComment(context.Background(), xid.New().String())
+	assert.Equal(t, domain.ErrTaskCommentNotExists, err)
+}
+
+func (r *Repository) testListComments(t *testing.T) {
+	task := r.getTask()
+	comments, next, err := r.TaskCommentRepository.ListComments(context.Background(), task.TaskId, domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", next)
+	if assert.Equal(t, len(r.comments), len(comments)) {
+		for i, comment := range r.comments {
+			assert.Equal(t, comment.CommentId, comments[i].CommentId)
+		}
+	}
+
+	page := domain.Pagination{Limit: 2}
+	comments, next, err = r.TaskCommentRepository.ListComments(context.Background(), task.TaskId, page)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(comments))
+	assert.NotEqual(t, "", next)
+
+	page.Cursor = next
+	comments, next, err = r.TaskCommentRepository.ListComments(context.Background(), task.TaskId, page)
+	assert.Nil(t, err)
+	assert.Equal(t, "", next)
+	if assert.Equal(t, 1, len(comments)) {
+		assert.Equal(t, r.comments[2].CommentId, comments[0].CommentId)
+	}
+
+	comments, _, err = r.TaskCommentRepository.ListComments(context.Background(), xid.New().String(), domain.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(comments))
+}
+
+func (r *Repository) testUpdateComment(t *testing.T) {
+	comment := &domain.TaskComment{CommentId: r.comments[1].CommentId, Body: "updated"}
+	err := r.TaskCommentRepository.UpdateComment(context.Background(), comment)
+	assert.Nil(t, err)
+	assert.NotNil(t, comment.UpdatedAt, "UpdateComment should update (*TaskComment).UpdatedAt")
+
+	got, err := r.TaskCommentRepository.GetComment(context.Background(), comment.CommentId)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated", got.Body)
+	assert.Equal(t, r.comments[1].AuthorId, got.AuthorId)
+	if assert.NotNil(t, got.UpdatedAt) {
+		assert.True(t, comment.UpdatedAt.Equal(*got.UpdatedAt))
+	}
+
+	err = r.TaskCommentRepository.UpdateComment(context.Background(), &domain.TaskComment{CommentId: xid.New().String(), Body: "foo"})
+	assert.Equal(t, domain.ErrTaskCommentNotExists, err)
+}
+
+func (r *Repository) testDeleteComment(t *testing.T) {
+	// deleting the parent delete its reply
+	err := r.TaskCommentRepository.DeleteComment(context.Background(), r.comments[0].CommentId)
+	assert.Nil(t, err)
+	for _, comment := range []domain.TaskComment{r.comments[0], r.comments[2]} {
+		_, err = r.TaskCommentRepository.GetComment(context.Background(), comment.CommentId)
+		assert.Equal(t, domain.ErrTaskCommentNotExists, err)
+	}
+
+	_, err = r.TaskCommentRepository.GetComment(context.Background(), r.comments[1].CommentId)
+	assert.Nil(t, err)
+}
@@ -2,8 +2,11 @@ package user
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"sync"
 
+	"nory/common/database"
 	"nory/domain"
 )
 
@@ -50,6 +53,7 @@ func (urm *UserRepositoryMem) CreateUser(ctx context.Context, u *domain.User) er
 			return domain.ErrUserAlreadyExists
 		}
 	}
+	database.RestoreOnRollback(ctx, &urm.mu, urm.m, u.UserId)
 	urm.m[u.UserId] = u
 	return nil
 }
@@ -57,11 +61,14 @@ func (urm *UserRepositoryMem) CreateUser(ctx context.Context, u *domain.User) er
 func (urm *UserRepositoryMem) DeleteUser(ctx context.Context, id string) error {
 	urm.mu.Lock()
 	defer urm.mu.Unlock()
+	database.RestoreOnRollback(ctx, &urm.mu, urm.m, id)
 	delete(urm.m, id)
 	return nil
 }
 
 func (urm *UserRepositoryMem) UpdateUser(ctx context.Context, u *domain.User) error {
+	urm.mu.Lock()
+	defer urm.mu.Unlock()
 	for _, uu := range urm.m {
 		if uu.UserId == u.UserId {
 			continue
@@ -70,10 +77,46 @@ func (urm *UserRepositoryMem) UpdateUser(ctx context.Context, u *domain.User) er
 			return domain.ErrUserAlreadyExists
 		}
 	}
-	uu, err := urm.GetUserByUserId(ctx, u.UserId)
-	if err != nil {
-		return err
+	uu, ok := urm.m[u.UserId]
+	if !ok {
+		return domain.ErrUserNotExists
 	}
+	database.RestoreOnRollback(ctx, &urm.mu, urm.m, u.UserId)
 	uu.Update(u)
 	return nil
 }
+
+func (urm *UserRepositoryMem) SearchUsers(ctx context.Context, query string, limit int) ([]*domain.User, error) {
+	urm.mu.Lock()
+	defer urm.mu.Unlock()
+	type ranked struct {
+		user     *domain.User
+		rank     int
+		username string
+	}
+	matches := make([]ranked, 0)
+	for _, u := range urm.m {
+		if rank := domain.SearchRank(query, u.Username, u.Name); rank != domain.SearchRankNone {
+			matches = append(matches, ranked{u, rank, strings.ToLower(u.Username)})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank > matches[j].rank
+		}
+		return matches[i].username < matches[j].username
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	users := make([]*domain.User, 0, len(matches))
+	for _, m := range matches {
+		users = append(users, &domain.User{
+			UserId:    m.user.UserId,
+			CreatedAt: m.user.CreatedAt,
+			Username:  m.user.Username,
+			Name:      m.user.Name,
+		})
+	}
+	return users, nil
+}
@@ -3,12 +3,14 @@ package user
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"nory/common/database"
 	"nory/domain"
 )
 
@@ -28,7 +30,7 @@ func (urp *UserRepositoryPostgres) GetUserByUserId(ctx context.Context, id strin
 		Name:      "",
 		Email:     "",
 	}
-	row := urp.pool.QueryRow(ctx, "SELECT username, name, email, created_at FROM app_user WHERE user_id = $1", id)
+	row := database.Conn(ctx, urp.pool).QueryRow(ctx, "SELECT username, name, email, created_at FROM app_user WHERE user_id = $1", id)
 	err := row.Scan(
 		&u.Username,
 		&u.Name,
@@ -49,7 +51,7 @@ func (urp *UserRepositoryPostgres) GetUserByUsername(ctx context.Context, userna
 		Name:      "",
 		Email:     "",
 	}
-	row := urp.pool.QueryRow(ctx, "SELECT user_id, name, email, created_at FROM app_user WHERE username = $1", username)
+	row := database.Conn(ctx, urp.pool).QueryRow(ctx, "SELECT user_id, name, email, created_at FROM app_user WHERE username = $1", username)
 	err := row.Scan(
 		&u.UserId,
 		&u.Name,
@@ -63,7 +65,7 @@ func (urp *UserRepositoryPostgres) GetUserByUsername(ctx context.Context, userna
 }
 
 func (urp *UserRepositoryPostgres) DeleteUser(ctx context.Context, id string) error {
-	_, err := urp.pool.Exec(
+	_, err := database.Conn(ctx, urp.pool).Exec(
 		ctx,
 		"DELETE FROM app_user WHERE user_id = $1",
 		id,
@@ -72,7 +74,7 @@ func (urp *UserRepositoryPostgres) DeleteUser(ctx context.Context, id string) er
 }
 
 func (urp *UserRepositoryPostgres) CreateUser(ctx context.Context, user *domain.User) error {
-	_, err := urp.pool.Exec(
+	_, err := database.Conn(ctx, urp.pool).Exec(
 		ctx,
 		"INSERT INTO app_user(user_id, username, name, email) VALUES($1, $2, $3, $4)",
 		user.UserId,
@@ -92,7 +94,7 @@ func (urp *UserRepositoryPostgres) UpdateUser(ctx context.Context, user *domain.
 		return err
 	}
 	u.Update(user)
-	_, err = urp.pool.Exec(
+	_, err = database.Conn(ctx, urp.pool).Exec(
 		ctx,
 		`UPDATE app_user SET username = $1, name = $2 WHERE user_id = $3`,
 		u.Username,
@@ -104,3 +106,47 @@ func (urp *UserRepositoryPostgres) UpdateUser(ctx context.Context, user *domain.
 	}
 	return err
 }
+
+// SearchUsers match username by prefix using the app_user_lower_username_index and words
+// of username and name, rank is computed the same way as domain.SearchRank
+func (urp *UserRepositoryPostgres) SearchUsers(ctx context.Context, query string, limit int) ([]*domain.User, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return []*domain.User{}, nil
+	}
+	var maxRows any
+	if limit > 0 {
+		maxRows = limit
+	}
+	words, args := database.WordPrefix("LOWER(username || ' ' || name)", domain.SearchTerms(query),
+		[]any{query, database.LikePrefix(query), maxRows})
+
+	rows, err := database.Conn(ctx, urp.pool).Query(
+		ctx,
+		`SELECT user_id, created_at, username, name
+		FROM app_user
+		WHERE LOWER(username) LIKE $2 ESCAPE '\' OR `+words+`
+		ORDER BY
+			CASE WHEN LOWER(username) = $1 THEN 3 WHEN LOWER(username) LIKE $2 ESCAPE '\' THEN 2 ELSE 1 END DESC,
+			LOWER(username) COLLATE "C"
+		LIMIT $3`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*domain.User, 0)
+	for rows.Next() {
+		u := &domain.User{}
+		if err := rows.Scan(
+			&u.UserId,
+			&u.CreatedAt,
+			&u.Username,
+			&u.Name,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
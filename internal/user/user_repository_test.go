@@ -3,6 +3,7 @@ package user_test
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 
 	"nory/domain"
@@ -50,6 +51,7 @@ func TestUserRepository(t *testing.T) {
 			t.Run("GetUser", repo.testGetUser)
 			t.Run("UpdateUser", repo.testUpdateUser)
 			t.Run("DeleteUser", repo.testDeleteUser)
+			t.Run("SearchUsers", repo.testSearchUsers)
 		})
 	}
 }
@@ -176,3 +178,55 @@ func (r *Repository) testDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func (r *Repository) testSearchUsers(t *testing.T) {
+	// marker keep other users in the shared database out of the result
+	marker := strings.ReplaceAll(uuid.NewString(), "-", "")[:8]
+	users := []*domain.User{
+		{Username: marker, Name: "Exact"},
+		{Username: marker + "_b", Name: "Prefix"},
+		{Username: "z" + marker[:6], Name: "Abelia " + marker + "x"},
+		{Username: "q" + marker, Name: "Nope"},
+	}
+	for _, u := range users {
+		u.UserId = uuid.NewString()
+		u.Email = u.UserId + "@bel.ia"
+		err := r.UserRepository.CreateUser(context.Background(), u)
+		assert.Nil(t, err)
+		id := u.UserId
+		t.Cleanup(func() {
+			r.UserRepository.DeleteUser(context.Background(), id)
+		})
+	}
+
+	testCases := []struct {
+		Name  string
+		Query string
+		Limit int
+		Want  []*domain.User
+	}{
+		{"rank exact, prefix then word", marker, 0, users[:3]},
+		{"case-insensitive", strings.ToUpper(marker), 0, users[:3]},
+		{"limit", marker, 1, users[:1]},
+		{"every term must match", "abelia " + marker, 0, users[2:3]},
+		{"underscore is not a wildcard", marker + "_", 0, []*domain.User{users[1], users[0], users[2]}},
+		{"no match", "none " + marker, 0, nil},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := r.UserRepository.SearchUsers(context.Background(), tc.Query, tc.Limit)
+			assert.Nil(t, err)
+			ids := make([]string, 0)
+			for _, u := range got {
+				ids = append(ids, u.UserId)
+				assert.Equal(t, "", u.Email, "email must not be returned")
+			}
+			want := make([]string, 0)
+			for _, u := range tc.Want {
+				want = append(want, u.UserId)
+			}
+			assert.Equal(t, want, ids)
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package user
 
 import (
+	"time"
+
 	"nory/common/auth"
+	"nory/common/response"
 	"nory/domain"
 
 	"github.com/gofiber/fiber/v2"
@@ -21,12 +24,19 @@ func Route(userService UserService) func(router fiber.Router) {
 	if userService.ClassMemberRepository == nil {
 		panic("userRoute: nil UserService.ClassMemberRepository")
 	}
+	if userService.ClassTaskRepository == nil {
+		panic("userRoute: nil UserService.ClassTaskRepository")
+	}
+	if userService.ClassScheduleRepository == nil {
+		panic("userRoute: nil UserService.ClassScheduleRepository")
+	}
 
 	ur := userRouter{userService}
 	return func(router fiber.Router) {
 		router.Get("/profile", ur.GetUserProfile)
 		router.Get("/class", ur.GetUserClasses)
 		router.Get("/joined", ur.GetUserJoinedClasses)
+		router.Get("/agenda", ur.GetAgenda)
 		router.Get("/id/:userId/profile", ur.GetOtherUserProfile)
 		router.Get("/username/:username/profile", ur.GetOtherUserProfileByUsername)
 		router.Patch("/profile", ur.PatchUser)
@@ -95,7 +105,12 @@ func (ur userRouter) GetUserClasses(c *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := ur.us.GetUserClasses(c.Context(), user)
+	var page domain.Pagination
+	if err := c.QueryParser(&page); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
+
+	res, err := ur.us.GetUserClasses(c.Context(), user, page)
 	if err != nil {
 		return err
 	}
@@ -109,7 +124,34 @@ func (ur userRouter) GetUserJoinedClasses(c *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := ur.us.GetUserJoinedClasses(c.Context(), user)
+	var page domain.Pagination
+	if err := c.QueryParser(&page); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
+
+	res, err := ur.us.GetUserJoinedClasses(c.Context(), user, page)
+	if err != nil {
+		return err
+	}
+
+	return res.Respond(c)
+}
+
+func (ur userRouter) GetAgenda(c *fiber.Ctx) error {
+	user, err := auth.GetUser(c)
+	if err != nil {
+		return err
+	}
+
+	var q struct {
+		From time.Time
+		To   time.Time
+	}
+	if err := c.QueryParser(&q); err != nil {
+		return response.NewBadRequest("request.invalid_query", response.Params{"error": err.Error()})
+	}
+
+	res, err := ur.us.GetAgenda(c.Context(), user, q.From, q.To)
 	if err != nil {
 		return err
 	}
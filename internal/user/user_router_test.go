@@ -6,13 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http/httptest"
+	"net/url"
+	"sync"
 	"testing"
+	"time"
 
 	"nory/common/auth"
 	"nory/common/response"
 	"nory/domain"
 	"nory/internal/class"
 	classmember "nory/internal/class_member"
+	classschedule "nory/internal/class_schedule"
+	classtask "nory/internal/class_task"
 	. "nory/internal/user"
 
 	"github.com/gofiber/fiber/v2"
@@ -27,10 +32,15 @@ func TestUserRouter(t *testing.T) {
 	userRepository := NewUserRepositoryMem()
 	classRepository := class.NewClassRepositoryMem()
 	classMemberRepository := classmember.NewClassMemberRepositoryMem()
+	classTaskRepository := classtask.NewClassTaskRepositoryMem()
+	userCache := &userCacheRecorder{}
 	classRoute := Route(UserService{
-		UserRepository:        userRepository,
-		ClassRepository:       classRepository,
-		ClassMemberRepository: classMemberRepository,
+		UserRepository:          userRepository,
+		ClassRepository:         classRepository,
+		ClassMemberRepository:   classMemberRepository,
+		ClassTaskRepository:     classTaskRepository,
+		ClassScheduleRepository: classschedule.NewClassScheduleRepositoryMem(),
+		UserCache:               userCache,
 	})
 
 	app := fiber.New(fiber.Config{
@@ -54,6 +64,7 @@ func TestUserRouter(t *testing.T) {
 		}{
 			{"GET", "/profile"},
 			{"GET", "/class"},
+			{"GET", "/agenda"},
 			{"PATCH", "/profile"},
 		} {
 			req := httptest.NewRequest(tc.Method, tc.Path, nil)
@@ -193,4 +204,111 @@ func TestUserRouter(t *testing.T) {
 		assert.Equal(t, "hai", other.Data.Username)
 		assert.Equal(t, user.Email, other.Data.Email)
 	})
+	t.Run("patch then read profile", func(t *testing.T) {
+		user := domain.User{
+			UserId:   uuid.NewString(),
+			Name:     "old name",
+			Username: xid.New().String(),
+			Email:    xid.New().String(),
+		}
+		err := userRepository.CreateUser(context.Background(), &domain.User{
+			UserId:   user.UserId,
+			Name:     user.Name,
+			Username: user.Username,
+			Email:    user.Email,
+		})
+		assert.Nil(t, err)
+		username := xid.New().String()
+
+		body, _ := json.Marshal(domain.User{Username: username, Name: "new name"})
+		req := httptest.NewRequest("PATCH", "/profile", bytes.NewReader(body))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-id", user.UserId)
+		req.Header.Set("username", user.Username)
+		req.Header.Set("name", user.Name)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+		assert.True(t, userCache.invalidated(user.UserId), "updated user should be dropped from the cache")
+
+		// authenticated user still carry the old profile, as it would when it is cached
+		req = httptest.NewRequest("GET", "/profile", nil)
+		req.Header.Set("user-id", user.UserId)
+		req.Header.Set("username", user.Username)
+		req.Header.Set("name", user.Name)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var profile response.Response[*domain.User]
+		err = json.NewDecoder(resp.Body).Decode(&profile)
+		assert.Nil(t, err)
+		assert.Equal(t, username, profile.Data.Username)
+		assert.Equal(t, "new name", profile.Data.Name)
+		assert.Equal(t, user.Email, profile.Data.Email)
+	})
+	t.Run("agenda", func(t *testing.T) {
+		userId := uuid.NewString()
+		class := &domain.Class{OwnerId: uuid.NewString(), Name: "foo"}
+		err := classRepository.CreateClass(context.Background(), class)
+		assert.Nil(t, err)
+		err = classMemberRepository.CreateMember(context.Background(), &domain.ClassMember{
+			UserId:  userId,
+			ClassId: class.ClassId,
+		})
+		assert.Nil(t, err)
+
+		from := time.Date(2022, time.October, 3, 0, 0, 0, 0, time.UTC)
+		err = classTaskRepository.CreateTask(context.Background(), &domain.ClassTask{
+			ClassId:  class.ClassId,
+			AuthorId: class.OwnerId,
+			Name:     "foo",
+			DueDate:  from.AddDate(0, 0, 1),
+		})
+		assert.Nil(t, err)
+
+		q := url.Values{}
+		q.Add("from", from.Format(time.RFC3339))
+		q.Add("to", from.AddDate(0, 0, 7).Format(time.RFC3339))
+		req := httptest.NewRequest("GET", "/agenda?"+q.Encode(), nil)
+		req.Header.Set("user-id", userId)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var agenda response.Response[[]*domain.AgendaItem]
+		err = json.NewDecoder(resp.Body).Decode(&agenda)
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(agenda.Data)) {
+			assert.Equal(t, domain.AgendaItemTask, agenda.Data[0].Type)
+			assert.Equal(t, "foo", agenda.Data[0].ClassName)
+		}
+
+		q.Set("to", from.AddDate(0, 0, -1).Format(time.RFC3339))
+		req = httptest.NewRequest("GET", "/agenda?"+q.Encode(), nil)
+		req.Header.Set("user-id", userId)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+}
+
+type userCacheRecorder struct {
+	mx  sync.Mutex
+	ids []string
+}
+
+func (ucr *userCacheRecorder) Invalidate(userId string) {
+	ucr.mx.Lock()
+	defer ucr.mx.Unlock()
+	ucr.ids = append(ucr.ids, userId)
+}
+
+func (ucr *userCacheRecorder) invalidated(userId string) bool {
+	ucr.mx.Lock()
+	defer ucr.mx.Unlock()
+	for _, id := range ucr.ids {
+		if id == userId {
+			return true
+		}
+	}
+	return false
 }
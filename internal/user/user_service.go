@@ -3,7 +3,8 @@ package user
 import (
 	"context"
 	"errors"
-	"fmt"
+	"sort"
+	"time"
 
 	"nory/common/response"
 	"nory/common/validator"
@@ -18,66 +19,114 @@ type UserProfile struct {
 }
 
 type UserService struct {
-	UserRepository        domain.UserRepository
-	ClassRepository       domain.ClassRepository
-	ClassMemberRepository domain.ClassMemberRepository
+	UserRepository          domain.UserRepository
+	ClassRepository         domain.ClassRepository
+	ClassMemberRepository   domain.ClassMemberRepository
+	ClassTaskRepository     domain.ClassTaskRepository
+	ClassScheduleRepository domain.ClassScheduleRepository
+	// UserCache is optional, it is told when a user is updated
+	UserCache domain.UserCache
 }
 
+// maxAgendaRange limit how far GetAgenda may look ahead
+const maxAgendaRange = 31 * 24 * time.Hour
+
+// GetUserProfile get profile of the authenticated user, the user is read again
+// because the authenticated user may come from a cache
 func (us UserService) GetUserProfile(ctx context.Context, user *domain.User) (*response.Response[*domain.User], error) {
-	classes, err := us.ClassRepository.GetClassesByOwnerId(ctx, user.UserId)
+	u, err := us.UserRepository.GetUserByUserId(ctx, user.UserId)
+	if errors.Is(err, domain.ErrUserNotExists) {
+		return nil, response.NewNotFound("user.not_found", response.Params{"userId": user.UserId})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return us.profile(ctx, u)
+}
+
+// profile fill a copy of the user with its classes and statistics
+func (us UserService) profile(ctx context.Context, user *domain.User) (*response.Response[*domain.User], error) {
+	classes, _, err := us.ClassRepository.GetClassesByOwnerId(ctx, user.UserId, domain.Pagination{})
 	if err != nil {
 		return nil, err
 	}
 
-	members, err := us.ClassMemberRepository.ListJoined(ctx, user.UserId)
+	members, _, err := us.ClassMemberRepository.ListJoined(ctx, user.UserId, domain.Pagination{})
 	if err != nil {
 		return nil, err
 	}
 
-	user.UserStatistics = &domain.UserStatistics{
+	u := *user
+	u.UserStatistics = &domain.UserStatistics{
 		OwnedClass:  len(classes),
 		JoinedClass: len(members),
 	}
-	user.OwnedClass = classes
+	u.OwnedClass = classes
 
-	return response.New(200, user), nil
+	return response.New(200, &u), nil
 }
 
 func (us UserService) GetUserProfileById(ctx context.Context, userId string) (*response.Response[*domain.User], error) {
 	user, err := us.UserRepository.GetUserByUserId(ctx, userId)
 	if errors.Is(err, domain.ErrUserNotExists) {
-		msg := fmt.Sprintf("can not find user with id %q", userId)
-		return nil, response.NewNotFound(msg)
+		return nil, response.NewNotFound("user.not_found", response.Params{"userId": userId})
 	}
 	if err != nil {
 		return nil, err
 	}
-	return us.GetUserProfile(ctx, user)
+	return us.publicProfile(ctx, user)
 }
 
 func (us UserService) GetUserProfileByUsername(ctx context.Context, username string) (*response.Response[*domain.User], error) {
 	user, err := us.UserRepository.GetUserByUsername(ctx, username)
 	if errors.Is(err, domain.ErrUserNotExists) {
-		msg := fmt.Sprintf("can not find user with id %q", username)
-		return nil, response.NewNotFound(msg)
+		return nil, response.NewNotFound("user.not_found_by_username", response.Params{"username": username})
 	}
 	if err != nil {
 		return nil, err
 	}
-	return us.GetUserProfile(ctx, user)
+	return us.publicProfile(ctx, user)
 }
 
-func (us UserService) GetUserClasses(ctx context.Context, user *domain.User) (*response.Response[[]*domain.Class], error) {
-	classes, err := us.ClassRepository.GetClassesByOwnerId(ctx, user.UserId)
-	return response.New(200, classes), err
+// publicProfile is the profile seen by other users, classes that are not listed are hidden
+func (us UserService) publicProfile(ctx context.Context, user *domain.User) (*response.Response[*domain.User], error) {
+	res, err := us.profile(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	classes := make([]*domain.Class, 0, len(res.Data.OwnedClass))
+	for _, class := range res.Data.OwnedClass {
+		if class.Listed() {
+			classes = append(classes, class)
+		}
+	}
+	res.Data.OwnedClass = classes
+	res.Data.UserStatistics.OwnedClass = len(classes)
+	return res, nil
 }
 
-func (us UserService) GetUserJoinedClasses(ctx context.Context, user *domain.User) (*response.Response[[]*domain.ClassMember], error) {
-	classes, err := us.ClassMemberRepository.ListJoined(ctx, user.UserId)
+func (us UserService) GetUserClasses(ctx context.Context, user *domain.User, page domain.Pagination) (*response.Response[[]*domain.Class], error) {
+	page = page.Normalize()
+	classes, next, err := us.ClassRepository.GetClassesByOwnerId(ctx, user.UserId, page)
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return nil, response.NewBadRequest("pagination.invalid_cursor", nil)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return response.New(200, classes), nil
+	return response.NewPaginated(200, classes, page.Limit, next), nil
+}
+
+func (us UserService) GetUserJoinedClasses(ctx context.Context, user *domain.User, page domain.Pagination) (*response.Response[[]*domain.ClassMember], error) {
+	page = page.Normalize()
+	classes, next, err := us.ClassMemberRepository.ListJoined(ctx, user.UserId, page)
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return nil, response.NewBadRequest("pagination.invalid_cursor", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response.NewPaginated(200, classes, page.Limit, next), nil
 }
 
 func (us UserService) UpdateUser(ctx context.Context, user *domain.User) (*response.Response[any], error) {
@@ -86,9 +135,120 @@ func (us UserService) UpdateUser(ctx context.Context, user *domain.User) (*respo
 	}
 	if err := us.UserRepository.UpdateUser(ctx, user); err != nil {
 		if errors.Is(err, domain.ErrUserAlreadyExists) {
-			return nil, response.NewConflict("user already exists")
+			return nil, response.NewConflict("user.already_exists", nil)
 		}
 		return nil, err
 	}
+	if us.UserCache != nil {
+		us.UserCache.Invalidate(user.UserId)
+	}
 	return response.New[any](204, nil), nil
 }
+
+// GetAgenda merge tasks and schedule occurrences in [from, to) of every class
+// joined by the user into a single feed ordered by time. By default it
+// return the agenda for the next 7 days starting from today.
+func (us UserService) GetAgenda(ctx context.Context, user *domain.User, from, to time.Time) (*response.Response[[]*domain.AgendaItem], error) {
+	if from.IsZero() {
+		from = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	if to.IsZero() {
+		to = from.Add(7 * 24 * time.Hour)
+	}
+	if !to.After(from) {
+		return nil, response.NewBadRequest("agenda.invalid_range", nil)
+	}
+	if to.Sub(from) > maxAgendaRange {
+		return nil, response.NewBadRequest("agenda.range_too_long", response.Params{"days": int(maxAgendaRange.Hours() / 24)})
+	}
+
+	members, _, err := us.ClassMemberRepository.ListJoined(ctx, user.UserId, domain.Pagination{})
+	if err != nil {
+		return nil, err
+	}
+	classIds := make([]string, 0, len(members))
+	for _, member := range members {
+		classIds = append(classIds, member.ClassId)
+	}
+	items := make([]*domain.AgendaItem, 0)
+	if len(classIds) == 0 {
+		return response.New(200, items), nil
+	}
+
+	classes, err := us.ClassRepository.GetClassesByIds(ctx, classIds)
+	if err != nil {
+		return nil, err
+	}
+	classNames := make(map[string]string, len(classes))
+	for _, class := range classes {
+		classNames[class.ClassId] = class.Name
+	}
+
+	tasks, err := us.ClassTaskRepository.GetTasksByClassIds(ctx, classIds, from, to)
+	if err != nil {
+		return nil, err
+	}
+	taskIds := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		taskIds = append(taskIds, task.TaskId)
+	}
+	progress, err := us.ClassTaskRepository.GetUserProgress(ctx, user.UserId, taskIds)
+	if err != nil {
+		return nil, err
+	}
+	progressMap := make(map[string]*domain.ClassTaskProgress, len(progress))
+	for _, p := range progress {
+		progressMap[p.TaskId] = p
+	}
+	for _, task := range tasks {
+		name, ok := classNames[task.ClassId]
+		if !ok {
+			continue
+		}
+		t := *task
+		t.Progress = progressMap[task.TaskId]
+		items = append(items, &domain.AgendaItem{
+			Type:      domain.AgendaItemTask,
+			ClassId:   task.ClassId,
+			ClassName: name,
+			StartAt:   task.DueDate,
+			Task:      &t,
+		})
+	}
+
+	schedules, err := us.ClassScheduleRepository.GetSchedulesByClassIds(ctx, classIds)
+	if err != nil {
+		return nil, err
+	}
+	// start a day early so schedules that run past midnight are included
+	day := time.Date(from.Year(), from.Month(), from.Day()-1, 0, 0, 0, 0, from.Location())
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, schedule := range schedules {
+			name, ok := classNames[schedule.ClassId]
+			if !ok || time.Weekday(schedule.Day) != day.Weekday() {
+				continue
+			}
+			startAt := day.Add(time.Duration(schedule.StartAt.Hour())*time.Hour + time.Duration(schedule.StartAt.Minute())*time.Minute)
+			endAt := startAt.Add(time.Duration(schedule.Duration) * time.Minute)
+			if !startAt.Before(to) || !endAt.After(from) {
+				continue
+			}
+			items = append(items, &domain.AgendaItem{
+				Type:      domain.AgendaItemSchedule,
+				ClassId:   schedule.ClassId,
+				ClassName: name,
+				StartAt:   startAt,
+				EndAt:     &endAt,
+				Schedule:  schedule,
+			})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].StartAt.Equal(items[j].StartAt) {
+			return items[i].StartAt.Before(items[j].StartAt)
+		}
+		return items[i].ClassName < items[j].ClassName
+	})
+	return response.New(200, items), nil
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"nory/common/response"
 	"nory/domain"
 	"nory/internal/class"
 	classmember "nory/internal/class_member"
+	classschedule "nory/internal/class_schedule"
+	classtask "nory/internal/class_task"
 	. "nory/internal/user"
 
 	"github.com/google/uuid"
@@ -23,9 +26,11 @@ func TestUserService(t *testing.T) {
 	classMemberRepository := classmember.NewClassMemberRepositoryMem()
 
 	us := UserService{
-		UserRepository:        userRepository,
-		ClassRepository:       classRepository,
-		ClassMemberRepository: classMemberRepository,
+		UserRepository:          userRepository,
+		ClassRepository:         classRepository,
+		ClassMemberRepository:   classMemberRepository,
+		ClassTaskRepository:     classtask.NewClassTaskRepositoryMem(),
+		ClassScheduleRepository: classschedule.NewClassScheduleRepositoryMem(),
 	}
 
 	t.Run("GetUserProfile", func(t *testing.T) {
@@ -104,11 +109,24 @@ func TestUserService(t *testing.T) {
 		err := us.UserRepository.CreateUser(context.Background(), user)
 		assert.Nil(t, err)
 
+		for _, visibility := range []string{domain.ClassVisibilityPublic, domain.ClassVisibilityUnlisted, domain.ClassVisibilityPrivate} {
+			err := us.ClassRepository.CreateClass(context.Background(), &domain.Class{
+				OwnerId:    user.UserId,
+				Name:       visibility,
+				Visibility: visibility,
+			})
+			assert.Nil(t, err)
+		}
+
 		res, err := us.GetUserProfileById(context.Background(), user.UserId)
 		assert.Nil(t, err)
 		assert.Equal(t, 200, res.Code)
 		assert.Equal(t, user.Name, res.Data.Name)
 		assert.Equal(t, user.UserId, res.Data.UserId)
+		assert.Equal(t, 1, res.Data.UserStatistics.OwnedClass, "only public class is shown to others")
+		if assert.Len(t, res.Data.OwnedClass, 1) {
+			assert.Equal(t, domain.ClassVisibilityPublic, res.Data.OwnedClass[0].Visibility)
+		}
 
 		userId := uuid.NewString()
 		res, err = us.GetUserProfileById(context.Background(), userId)
@@ -139,7 +157,7 @@ func TestUserService(t *testing.T) {
 			assert.Nil(t, err)
 		}
 
-		res, err := us.GetUserClasses(context.Background(), user)
+		res, err := us.GetUserClasses(context.Background(), user, domain.Pagination{})
 		assert.Nil(t, err)
 		assert.Equal(t, len(classes), len(res.Data))
 		for _, class := range res.Data {
@@ -147,7 +165,7 @@ func TestUserService(t *testing.T) {
 			assert.Equal(t, user.UserId, class.OwnerId)
 		}
 
-		resJoined, err := us.GetUserClasses(context.Background(), user)
+		resJoined, err := us.GetUserClasses(context.Background(), user, domain.Pagination{})
 		assert.Nil(t, err)
 		assert.Equal(t, len(classes), len(resJoined.Data))
 
@@ -158,6 +176,89 @@ func TestUserService(t *testing.T) {
 		assert.Equal(t, user.UserId, upRes.Data.UserId)
 		assert.Equal(t, len(classes), upRes.Data.UserStatistics.OwnedClass)
 	})
+
+	t.Run("GetAgenda", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		user := &domain.User{UserId: uuid.NewString()}
+
+		// 2022-10-03 is a monday
+		from := time.Date(2022, time.October, 3, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 0, 7)
+		at := func(hour int) time.Time {
+			return time.Date(0, 1, 1, hour, 0, 0, 0, time.UTC)
+		}
+
+		var classIds []string
+		for _, name := range []string{"math", "physics", "other"} {
+			class := &domain.Class{OwnerId: uuid.NewString(), Name: name}
+			err := us.ClassRepository.CreateClass(ctx, class)
+			assert.Nil(t, err)
+			classIds = append(classIds, class.ClassId)
+			if name == "other" {
+				continue
+			}
+			err = us.ClassMemberRepository.CreateMember(ctx, &domain.ClassMember{
+				ClassId: class.ClassId,
+				UserId:  user.UserId,
+			})
+			assert.Nil(t, err)
+		}
+
+		for _, task := range []*domain.ClassTask{
+			{ClassId: classIds[0], Name: "homework", DueDate: from.AddDate(0, 0, 2)},
+			{ClassId: classIds[1], Name: "report", DueDate: from.AddDate(0, 0, 1)},
+			{ClassId: classIds[1], Name: "too late", DueDate: to},
+			{ClassId: classIds[2], Name: "not joined", DueDate: from},
+		} {
+			task.AuthorId = uuid.NewString()
+			err := us.ClassTaskRepository.CreateTask(ctx, task)
+			assert.Nil(t, err)
+		}
+		for _, schedule := range []*domain.ClassSchedule{
+			{ClassId: classIds[0], Name: "algebra", Day: 1, StartAt: at(8), Duration: 90},
+			{ClassId: classIds[1], Name: "mechanics", Day: 2, StartAt: at(10), Duration: 60},
+			{ClassId: classIds[2], Name: "not joined", Day: 1, StartAt: at(7), Duration: 60},
+		} {
+			schedule.AuthorId = uuid.NewString()
+			err := us.ClassScheduleRepository.CreateSchedule(ctx, schedule)
+			assert.Nil(t, err)
+		}
+
+		res, err := us.GetAgenda(ctx, user, from, to)
+		assert.Nil(t, err)
+		type item struct {
+			Type, ClassName string
+			StartAt         time.Time
+		}
+		var items []item
+		for _, it := range res.Data {
+			items = append(items, item{it.Type, it.ClassName, it.StartAt})
+		}
+		assert.Equal(t, []item{
+			{domain.AgendaItemSchedule, "math", from.Add(8 * time.Hour)},
+			{domain.AgendaItemTask, "physics", from.AddDate(0, 0, 1)},
+			{domain.AgendaItemSchedule, "physics", from.AddDate(0, 0, 1).Add(10 * time.Hour)},
+			{domain.AgendaItemTask, "math", from.AddDate(0, 0, 2)},
+		}, items)
+		if assert.Equal(t, 4, len(res.Data)) {
+			assert.Equal(t, from.Add(9*time.Hour+30*time.Minute), *res.Data[0].EndAt)
+			assert.Equal(t, "homework", res.Data[3].Task.Name)
+		}
+
+		res, err = us.GetAgenda(ctx, user, from.Add(9*time.Hour), from.Add(12*time.Hour))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(res.Data), "ongoing schedule should be included")
+
+		_, err = us.GetAgenda(ctx, user, to, from)
+		assert.NotNil(t, err)
+		_, err = us.GetAgenda(ctx, user, from, from.AddDate(0, 2, 0))
+		assert.NotNil(t, err)
+
+		res, err = us.GetAgenda(ctx, &domain.User{UserId: uuid.NewString()}, time.Time{}, time.Time{})
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(res.Data))
+	})
 }
 
 // testCases := []struct{
@@ -0,0 +1,18 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS class_task_progress (
+	task_id VARCHAR(20) NOT NULL,
+	user_id UUID NOT NULL,
+	updated_at TIMESTAMP DEFAULT NOW(),
+
+	status VARCHAR(12) NOT NULL,
+	completed_at TIMESTAMP,
+
+	CONSTRAINT class_task_progress_pk PRIMARY KEY(task_id, user_id),
+	CONSTRAINT fk_task FOREIGN KEY (task_id) REFERENCES class_task(task_id) ON DELETE CASCADE,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES app_user(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS class_task_progress_user_id_index ON class_task_progress(user_id, task_id);
+
+COMMIT;
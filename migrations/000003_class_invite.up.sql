@@ -0,0 +1,21 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS class_invite (
+	code VARCHAR(20) UNIQUE NOT NULL,
+	class_id VARCHAR(20) NOT NULL,
+	author_id UUID NOT NULL,
+	created_at TIMESTAMP DEFAULT NOW(),
+
+	level VARCHAR(10) NOT NULL,
+	expires_at TIMESTAMP,
+	max_uses INT NOT NULL,
+	uses INT NOT NULL DEFAULT 0,
+
+	CONSTRAINT class_invite_pk PRIMARY KEY(code),
+	CONSTRAINT fk_author FOREIGN KEY (author_id) REFERENCES app_user(user_id) ON DELETE CASCADE,
+	CONSTRAINT fk_class FOREIGN KEY (class_id) REFERENCES class(class_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS class_invite_class_id_index ON class_invite(class_id, created_at);
+
+COMMIT;
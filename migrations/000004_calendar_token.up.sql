@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS calendar_token (
+	user_id UUID UNIQUE NOT NULL,
+	token VARCHAR(64) UNIQUE NOT NULL,
+	created_at TIMESTAMP DEFAULT NOW(),
+
+	CONSTRAINT calendar_token_pk PRIMARY KEY(user_id),
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES app_user(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS token_index ON calendar_token(token);
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+-- class_audit intentionally has no foreign key, entries must outlive the class and the actor
+CREATE TABLE IF NOT EXISTS class_audit (
+	entry_id VARCHAR(20) UNIQUE NOT NULL,
+	class_id VARCHAR(20) NOT NULL,
+	actor_id UUID NOT NULL,
+	created_at TIMESTAMP DEFAULT NOW(),
+
+	action VARCHAR(32) NOT NULL,
+	target_id VARCHAR(64) NOT NULL,
+	before_state JSONB,
+	after_state JSONB,
+
+	CONSTRAINT class_audit_pk PRIMARY KEY(entry_id)
+);
+
+CREATE INDEX IF NOT EXISTS class_audit_class_id_index ON class_audit(class_id, entry_id DESC);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE class_task DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE class_task DROP COLUMN IF EXISTS updated_at;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE class_task ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
+ALTER TABLE class_task ADD COLUMN IF NOT EXISTS updated_by UUID;
+
+COMMIT;
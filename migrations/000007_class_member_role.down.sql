@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE class_member DROP CONSTRAINT IF EXISTS class_member_level_check;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+UPDATE class_member SET level = 'member' WHERE level NOT IN ('owner', 'admin', 'moderator', 'member', 'viewer');
+ALTER TABLE class_member ADD CONSTRAINT class_member_level_check CHECK (level IN ('owner', 'admin', 'moderator', 'member', 'viewer'));
+
+COMMIT;
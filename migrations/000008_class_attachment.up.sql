@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS class_attachment (
+	attachment_id VARCHAR(20) UNIQUE NOT NULL,
+	class_id VARCHAR(20) NOT NULL,
+	task_id VARCHAR(20) NOT NULL,
+	author_id UUID NOT NULL,
+	created_at TIMESTAMP DEFAULT NOW(),
+
+	name VARCHAR(255) NOT NULL,
+	content_type VARCHAR(127) NOT NULL,
+	size BIGINT NOT NULL,
+
+	CONSTRAINT class_attachment_pk PRIMARY KEY(attachment_id),
+	CONSTRAINT fk_task FOREIGN KEY (task_id) REFERENCES class_task(task_id) ON DELETE CASCADE,
+	CONSTRAINT fk_class FOREIGN KEY (class_id) REFERENCES class(class_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS class_attachment_task_id_index ON class_attachment(task_id, created_at);
+CREATE INDEX IF NOT EXISTS class_attachment_class_id_index ON class_attachment(class_id, created_at);
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS task_comment (
+	comment_id VARCHAR(20) UNIQUE NOT NULL,
+	class_id VARCHAR(20) NOT NULL,
+	task_id VARCHAR(20) NOT NULL,
+	author_id UUID NOT NULL,
+	parent_id VARCHAR(20),
+	created_at TIMESTAMP DEFAULT NOW(),
+
+	body VARCHAR(2000) NOT NULL,
+	updated_at TIMESTAMP,
+
+	CONSTRAINT task_comment_pk PRIMARY KEY(comment_id),
+	CONSTRAINT fk_task FOREIGN KEY (task_id) REFERENCES class_task(task_id) ON DELETE CASCADE,
+	CONSTRAINT fk_class FOREIGN KEY (class_id) REFERENCES class(class_id) ON DELETE CASCADE,
+	CONSTRAINT fk_parent FOREIGN KEY (parent_id) REFERENCES task_comment(comment_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS task_comment_task_id_index ON task_comment(task_id, created_at, comment_id);
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS class_announcement (
+	announcement_id VARCHAR(20) UNIQUE NOT NULL,
+	class_id VARCHAR(20) NOT NULL,
+	author_id UUID NOT NULL,
+	created_at TIMESTAMP DEFAULT NOW(),
+
+	title VARCHAR(100) NOT NULL,
+	body VARCHAR(10000) NOT NULL,
+	pinned BOOLEAN NOT NULL DEFAULT FALSE,
+	expires_at TIMESTAMP,
+	updated_at TIMESTAMP,
+
+	CONSTRAINT class_announcement_pk PRIMARY KEY(announcement_id),
+	CONSTRAINT fk_class FOREIGN KEY (class_id) REFERENCES class(class_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS class_announcement_class_id_index ON class_announcement(class_id, pinned, created_at, announcement_id);
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS rate_limit (
+	bucket_key VARCHAR(128) NOT NULL,
+	tokens DOUBLE PRECISION NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	full_at TIMESTAMP NOT NULL,
+
+	CONSTRAINT rate_limit_pk PRIMARY KEY(bucket_key)
+);
+
+CREATE INDEX IF NOT EXISTS rate_limit_full_at_index ON rate_limit(full_at);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE class DROP COLUMN IF EXISTS visibility;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE class ADD COLUMN IF NOT EXISTS visibility VARCHAR(10) NOT NULL DEFAULT 'public';
+
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS class_join_request (
+	class_id VARCHAR(20) NOT NULL,
+	user_id UUID NOT NULL,
+	created_at TIMESTAMP DEFAULT NOW(),
+	expires_at TIMESTAMP NOT NULL,
+
+	message VARCHAR(255) NOT NULL,
+
+	CONSTRAINT class_join_request_pk PRIMARY KEY(class_id, user_id),
+	CONSTRAINT fk_class FOREIGN KEY (class_id) REFERENCES class(class_id) ON DELETE CASCADE,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES app_user(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS class_join_request_class_id_index ON class_join_request(class_id, created_at, user_id);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS class_lower_name_index;
+DROP INDEX IF EXISTS app_user_lower_username_index;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+-- name prefix is matched with LIKE, words are matched with regular expression
+-- because CockroachDB 22.1 has no full-text search
+CREATE INDEX IF NOT EXISTS class_lower_name_index ON class(LOWER(name));
+CREATE INDEX IF NOT EXISTS app_user_lower_username_index ON app_user(LOWER(username));
+
+COMMIT;
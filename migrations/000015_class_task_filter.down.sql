@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS class_task_due_date_index;
+DROP INDEX IF EXISTS class_task_created_at_index;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS class_task_due_date_index ON class_task(class_id, due_date, task_id);
+CREATE INDEX IF NOT EXISTS class_task_created_at_index ON class_task(class_id, created_at, task_id);
+
+COMMIT;
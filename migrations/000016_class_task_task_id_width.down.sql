@@ -0,0 +1 @@
+-- CockroachDB can not shrink the width of an indexed column, the wider column is kept
@@ -0,0 +1,3 @@
+-- occurrence id is the series id followed by "_yyyymmdd". CockroachDB does not allow changing
+-- column type inside a transaction and statements of one file run together, so each table has its own file.
+ALTER TABLE class_attachment ALTER COLUMN task_id TYPE VARCHAR(32);
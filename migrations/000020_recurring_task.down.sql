@@ -0,0 +1,10 @@
+BEGIN;
+
+DELETE FROM class_task WHERE series_id IS NOT NULL;
+
+ALTER TABLE class_task DROP COLUMN IF EXISTS edited;
+ALTER TABLE class_task DROP COLUMN IF EXISTS skipped;
+ALTER TABLE class_task DROP COLUMN IF EXISTS series_id;
+ALTER TABLE class_task DROP COLUMN IF EXISTS recurrence;
+
+COMMIT;
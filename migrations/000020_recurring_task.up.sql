@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE class_task ADD COLUMN IF NOT EXISTS recurrence VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE class_task ADD COLUMN IF NOT EXISTS series_id VARCHAR(32);
+ALTER TABLE class_task ADD COLUMN IF NOT EXISTS skipped BOOLEAN NOT NULL DEFAULT FALSE;
+-- stored occurrence which is not edited follow updates of its series
+ALTER TABLE class_task ADD COLUMN IF NOT EXISTS edited BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS class_task_series_index;
+ALTER TABLE class_task DROP CONSTRAINT IF EXISTS fk_series;
+
+COMMIT;
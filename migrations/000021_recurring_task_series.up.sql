@@ -0,0 +1,7 @@
+-- series_id is only usable in constraints once the transaction adding it is committed
+BEGIN;
+
+ALTER TABLE class_task ADD CONSTRAINT fk_series FOREIGN KEY (series_id) REFERENCES class_task(task_id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS class_task_series_index ON class_task(series_id);
+
+COMMIT;